@@ -0,0 +1,355 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	retryQueueJSON         bool
+	retryQueueReplayTo     string
+	retryQueueReplayAPIKey string
+)
+
+func init() {
+	AgentCmd.AddCommand(retryQueueCmd)
+	retryQueueCmd.AddCommand(retryQueueListCmd)
+	retryQueueCmd.AddCommand(retryQueueInspectCmd)
+	retryQueueCmd.AddCommand(retryQueueReplayCmd)
+
+	retryQueueListCmd.Flags().BoolVarP(&retryQueueJSON, "json", "j", false, "print out raw json")
+	retryQueueInspectCmd.Flags().BoolVarP(&retryQueueJSON, "json", "j", false, "print out raw json")
+	retryQueueReplayCmd.Flags().StringVar(&retryQueueReplayTo, "to", "", "URL the stored transactions are sent to, for instance a local intake")
+	_ = retryQueueReplayCmd.MarkFlagRequired("to")
+	retryQueueReplayCmd.Flags().StringVar(&retryQueueReplayAPIKey, "api-key", "", "API key the stored transactions are sent with, they are sent without API key when empty")
+}
+
+var retryQueueCmd = &cobra.Command{
+	Use:   "retry-queue",
+	Short: "Inspect and replay the transactions stored in the on-disk retry queue of the forwarder",
+	Long:  ``,
+}
+
+var retryQueueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the files of the on-disk retry queue",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupRetryQueueCmd(); err != nil {
+			return err
+		}
+		return retryQueueList()
+	},
+}
+
+var retryQueueInspectCmd = &cobra.Command{
+	Use:   "inspect [file...]",
+	Short: "Print the transactions stored in the on-disk retry queue, without their API keys",
+	Long:  `Print the transactions stored in the given retry files, or in all the files of the on-disk retry queue when no file is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupRetryQueueCmd(); err != nil {
+			return err
+		}
+		return retryQueueInspect(args)
+	},
+}
+
+var retryQueueReplayCmd = &cobra.Command{
+	Use:   "replay --to <url> [--api-key <key>] [file...]",
+	Short: "Send the transactions stored in the on-disk retry queue to another URL",
+	Long: `Send the transactions stored in the given retry files, or in all the files of the on-disk retry queue
+when no file is given, to another URL. The retry files are left untouched.
+
+The API keys of the stored transactions are never sent: they are replaced with the one given with --api-key,
+or removed when it is not set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupRetryQueueCmd(); err != nil {
+			return err
+		}
+		return retryQueueReplay(args)
+	},
+}
+
+func setupRetryQueueCmd() error {
+	if flagNoColor {
+		color.NoColor = true
+	}
+
+	// The API keys are required to restore the stored transactions
+	err := common.SetupConfig(confFilePath)
+	if err != nil {
+		return fmt.Errorf("unable to set up global agent configuration: %v", err)
+	}
+
+	err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+	if err != nil {
+		fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// selectRetryFiles returns the retry files matching paths, or all of them when paths is empty.
+func selectRetryFiles(paths []string) ([]forwarder.RetryFile, error) {
+	files, err := forwarder.ListRetryFiles()
+	if err != nil {
+		if os.IsNotExist(err) && len(paths) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot list the retry files in %s: %v", forwarder.RetryQueueStoragePath(), err)
+	}
+	if len(paths) == 0 {
+		return files, nil
+	}
+
+	filesByPath := make(map[string]forwarder.RetryFile, len(files))
+	for _, file := range files {
+		filesByPath[file.Path] = file
+	}
+
+	selected := make([]forwarder.RetryFile, 0, len(paths))
+	for _, path := range paths {
+		file, found := filesByPath[path]
+		if !found {
+			return nil, fmt.Errorf("%s is not a file of the retry queue stored in %s", path, forwarder.RetryQueueStoragePath())
+		}
+		selected = append(selected, file)
+	}
+	return selected, nil
+}
+
+func retryQueueList() error {
+	files, err := selectRetryFiles(nil)
+	if err != nil {
+		return err
+	}
+
+	domains := forwarder.RetryFileDomains(getKeysPerDomain())
+	if retryQueueJSON {
+		type jsonRetryFile struct {
+			forwarder.RetryFile
+			Domain string
+		}
+		jsonFiles := make([]jsonRetryFile, 0, len(files))
+		for _, file := range files {
+			jsonFiles = append(jsonFiles, jsonRetryFile{RetryFile: file, Domain: domains[file.DomainFolder]})
+		}
+		return printRetryQueueJSON(jsonFiles)
+	}
+
+	if len(files) == 0 {
+		fmt.Printf("No retry file in %s\n", forwarder.RetryQueueStoragePath())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(color.Output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, color.BlueString("FILE\tDOMAIN\tSIZE\tMODIFIED"))
+	for _, file := range files {
+		domain, found := domains[file.DomainFolder]
+		if !found {
+			domain = color.YellowString("unknown")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", file.Path, domain, file.SizeInBytes, file.ModTime.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func retryQueueInspect(paths []string) error {
+	files, err := selectRetryFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	infosPerFile := make(map[string][]forwarder.StoredTransactionInfo, len(files))
+	for _, file := range files {
+		infos, err := forwarder.InspectRetryFile(file.Path)
+		if err != nil {
+			return fmt.Errorf("cannot read the retry file %s: %v", file.Path, err)
+		}
+		infosPerFile[file.Path] = infos
+	}
+
+	if retryQueueJSON {
+		return printRetryQueueJSON(infosPerFile)
+	}
+
+	for _, file := range files {
+		fmt.Fprintf(color.Output, "%s (%d transactions)\n", color.GreenString(file.Path), len(infosPerFile[file.Path]))
+		w := tabwriter.NewWriter(color.Output, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, color.BlueString("  ENDPOINT\tROUTE\tPAYLOAD\tENCODING\tERRORS\tPRIORITY\tRETRYABLE\tCREATED"))
+		for _, info := range infosPerFile[file.Path] {
+			fmt.Fprintf(w, "  %s\t%s\t%d\t%s\t%d\t%s\t%t\t%s\n",
+				info.EndpointName,
+				info.Route,
+				info.PayloadSize,
+				info.ContentEncoding,
+				info.ErrorCount,
+				priorityName(info.Priority),
+				info.Retryable,
+				info.CreatedAt.Format(time.RFC3339))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func retryQueueReplay(paths []string) error {
+	target, err := url.Parse(retryQueueReplayTo)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return fmt.Errorf("invalid URL for --to: %q", retryQueueReplayTo)
+	}
+	to := strings.TrimSuffix(retryQueueReplayTo, "/")
+
+	files, err := selectRetryFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	keysPerDomain := getKeysPerDomain()
+	client := &http.Client{
+		Timeout:   config.Datadog.GetDuration("forwarder_timeout") * time.Second,
+		Transport: httputils.CreateHTTPTransport(),
+	}
+
+	sent, failed := 0, 0
+	for _, file := range files {
+		transactions, errorCount, err := forwarder.ReadRetryFile(file, keysPerDomain)
+		if err != nil {
+			fmt.Fprintf(color.Output, "%s: %v\n", color.RedString(file.Path), err)
+			continue
+		}
+		failed += errorCount
+
+		for _, t := range transactions {
+			replaceAPIKeys(t, keysPerDomain, retryQueueReplayAPIKey)
+			if err := replayTransaction(client, t, to); err != nil {
+				fmt.Fprintf(color.Output, "%s %s: %v\n", color.RedString("Failed to replay"), t.Endpoint.Name, err)
+				failed++
+			} else {
+				sent++
+			}
+		}
+	}
+
+	fmt.Fprintf(color.Output, "%d transactions sent to %s, %d failed\n", sent, to, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d transactions could not be replayed", failed)
+	}
+	return nil
+}
+
+func replayTransaction(client *http.Client, t *transaction.HTTPTransaction, to string) error {
+	statusCode := 0
+	t.Domain = to
+	t.CompletionHandler = func(_ *transaction.HTTPTransaction, code int, _ []byte, _ error) {
+		statusCode = code
+	}
+
+	if err := t.Process(context.Background(), client); err != nil {
+		return err
+	}
+	if statusCode == 0 || statusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", statusCode)
+	}
+	return nil
+}
+
+// replaceAPIKeys replaces the configured API keys found in the route and the headers
+// of the transaction with apiKey, they are removed when apiKey is empty.
+func replaceAPIKeys(t *transaction.HTTPTransaction, keysPerDomain map[string][]string, apiKey string) {
+	var oldnew []string
+	for _, keys := range keysPerDomain {
+		for _, key := range keys {
+			if key != "" {
+				oldnew = append(oldnew, key, apiKey)
+			}
+		}
+	}
+	if len(oldnew) == 0 {
+		return
+	}
+	replacer := strings.NewReplacer(oldnew...)
+
+	t.Endpoint.Route = replacer.Replace(t.Endpoint.Route)
+	if apiKey == "" {
+		t.Endpoint.Route = removeEmptyAPIKeyParam(t.Endpoint.Route)
+	}
+
+	for name, values := range t.Headers {
+		replaced := make([]string, 0, len(values))
+		for _, value := range values {
+			if value = replacer.Replace(value); value != "" {
+				replaced = append(replaced, value)
+			}
+		}
+		if len(replaced) == 0 {
+			t.Headers.Del(name)
+		} else {
+			t.Headers[name] = replaced
+		}
+	}
+}
+
+// removeEmptyAPIKeyParam removes the api_key query parameter left empty by replaceAPIKeys
+func removeEmptyAPIKeyParam(route string) string {
+	u, err := url.Parse(route)
+	if err != nil {
+		return route
+	}
+	query := u.Query()
+	if _, found := query["api_key"]; !found || query.Get("api_key") != "" {
+		return route
+	}
+	query.Del("api_key")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func getKeysPerDomain() map[string][]string {
+	keysPerDomain, err := config.GetMultipleEndpoints()
+	if err != nil {
+		fmt.Fprintf(color.Output, "%s %v\n", color.YellowString("Cannot read the configured endpoints:"), err)
+		return map[string][]string{}
+	}
+	return keysPerDomain
+}
+
+func priorityName(priority transaction.Priority) string {
+	if priority == transaction.TransactionPriorityHigh {
+		return "high"
+	}
+	return "normal"
+}
+
+func printRetryQueueJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+var retryQueueTestKeysPerDomain = map[string][]string{
+	"https://app.datadoghq.com": {"apiKey1", "apiKey2"},
+	"https://app.datadoghq.eu":  {"apiKey3"},
+}
+
+func newRetryQueueTestTransaction(apiKey string) *transaction.HTTPTransaction {
+	t := transaction.NewHTTPTransaction()
+	t.Domain = "https://app.datadoghq.com"
+	t.Endpoint.Name = "series"
+	t.Endpoint.Route = "/api/v1/series?api_key=" + apiKey
+	t.Headers.Set("DD-Api-Key", apiKey)
+	t.Headers.Set("Content-Encoding", "deflate")
+	payload := []byte("payload")
+	t.Payload = &payload
+	return t
+}
+
+func TestReplaceAPIKeys(t *testing.T) {
+	tr := newRetryQueueTestTransaction("apiKey2")
+	replaceAPIKeys(tr, retryQueueTestKeysPerDomain, "replayKey")
+
+	assert.Equal(t, "/api/v1/series?api_key=replayKey", tr.Endpoint.Route)
+	assert.Equal(t, "replayKey", tr.Headers.Get("DD-Api-Key"))
+	assert.Equal(t, "deflate", tr.Headers.Get("Content-Encoding"))
+}
+
+func TestReplaceAPIKeysStrip(t *testing.T) {
+	tr := newRetryQueueTestTransaction("apiKey3")
+	tr.Endpoint.Route = "/api/v1/series?api_key=apiKey3&other=value"
+	replaceAPIKeys(tr, retryQueueTestKeysPerDomain, "")
+
+	assert.Equal(t, "/api/v1/series?other=value", tr.Endpoint.Route)
+	_, found := tr.Headers["Dd-Api-Key"]
+	assert.False(t, found)
+	assert.Equal(t, "deflate", tr.Headers.Get("Content-Encoding"))
+}
+
+func TestRemoveEmptyAPIKeyParam(t *testing.T) {
+	assert.Equal(t, "/api/v1/series", removeEmptyAPIKeyParam("/api/v1/series?api_key="))
+	assert.Equal(t, "/api/v1/series?api_key=key", removeEmptyAPIKeyParam("/api/v1/series?api_key=key"))
+	assert.Equal(t, "/api/v1/series", removeEmptyAPIKeyParam("/api/v1/series"))
+}
+
+func TestReplayTransaction(t *testing.T) {
+	var received *http.Request
+	var receivedPayload []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedPayload, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	tr := newRetryQueueTestTransaction("apiKey1")
+	replaceAPIKeys(tr, retryQueueTestKeysPerDomain, "")
+	require.NoError(t, replayTransaction(ts.Client(), tr, ts.URL))
+
+	require.NotNil(t, received)
+	assert.Equal(t, "/api/v1/series", received.URL.Path)
+	assert.Empty(t, received.URL.Query().Get("api_key"))
+	assert.Empty(t, received.Header.Get("DD-Api-Key"))
+	assert.Equal(t, "payload", string(receivedPayload))
+}
+
+func TestReplayTransactionError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	tr := newRetryQueueTestTransaction("apiKey1")
+	assert.Error(t, replayTransaction(ts.Client(), tr, ts.URL))
+}
+
+func TestSelectRetryFiles(t *testing.T) {
+	mockConfig := config.Mock()
+	storagePath := t.TempDir()
+	mockConfig.Set("forwarder_storage_path", storagePath)
+	defer mockConfig.Set("forwarder_storage_path", "")
+
+	// no retry queue yet
+	files, err := selectRetryFiles(nil)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	folder := filepath.Join(storagePath, "core", "domain_folder")
+	require.NoError(t, os.MkdirAll(folder, 0755))
+	retryFile := filepath.Join(folder, "1.retry")
+	require.NoError(t, ioutil.WriteFile(retryFile, []byte("content"), 0600))
+
+	files, err = selectRetryFiles(nil)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, retryFile, files[0].Path)
+
+	files, err = selectRetryFiles([]string{retryFile})
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	_, err = selectRetryFiles([]string{filepath.Join(folder, "unknown.retry")})
+	assert.Error(t, err)
+}
+
+func TestRetryQueueReplayInvalidURL(t *testing.T) {
+	retryQueueReplayTo = "not a url"
+	defer func() { retryQueueReplayTo = "" }()
+
+	assert.Error(t, retryQueueReplay(nil))
+}
@@ -0,0 +1,318 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	model "github.com/DataDog/agent-payload/process"
+	"github.com/DataDog/datadog-agent/pkg/network/encoding"
+	"github.com/fatih/color"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputProtobuf = "protobuf"
+
+	// shortContainerIDLen is the length of the container IDs shown in the table, like in the docker CLI
+	shortContainerIDLen = 12
+)
+
+var connectionsArgs = struct {
+	pid       int32
+	port      int32
+	container string
+	watch     bool
+	interval  time.Duration
+	output    string
+}{}
+
+func init() {
+	SysprobeCmd.AddCommand(connectionsCommand)
+
+	connectionsCommand.Flags().Int32Var(&connectionsArgs.pid, "pid", 0, "only show the connections of the process having this PID")
+	connectionsCommand.Flags().Int32Var(&connectionsArgs.port, "port", 0, "only show the connections having this local or remote port")
+	connectionsCommand.Flags().StringVar(&connectionsArgs.container, "container", "", "only show the connections of the containers of which the ID starts with this prefix")
+	connectionsCommand.Flags().BoolVarP(&connectionsArgs.watch, "watch", "w", false, "refresh the connections until interrupted")
+	connectionsCommand.Flags().DurationVar(&connectionsArgs.interval, "interval", 2*time.Second, "refresh interval of --watch")
+	connectionsCommand.Flags().StringVarP(&connectionsArgs.output, "output", "o", outputTable, "output format: table, json or protobuf")
+}
+
+var connectionsCommand = &cobra.Command{
+	Use:   "connections",
+	Short: "Print the network connections tracked by a running system-probe",
+	Long: `Print the network connections tracked by a running system-probe, with their processes, containers,
+traffic, DNS names and HTTP stats. The traffic and the stats of a connection are the ones seen since the previous
+refresh with --watch, and since the connection started otherwise.`,
+	Args: cobra.NoArgs,
+	RunE: runConnections,
+}
+
+// processInfo holds the attributes shown in the table of the process owning a connection
+type processInfo struct {
+	name        string
+	containerID string
+}
+
+// processResolver returns the attributes of the process having the given PID
+type processResolver func(pid int32) processInfo
+
+// connectionsFilter selects the connections to show. Its zero value selects all of them.
+type connectionsFilter struct {
+	pid       int32
+	port      int32
+	container string
+}
+
+func (f connectionsFilter) match(c *model.Connection, p processInfo) bool {
+	if f.pid != 0 && c.Pid != f.pid {
+		return false
+	}
+	if f.port != 0 && c.Laddr.Port != f.port && c.Raddr.Port != f.port {
+		return false
+	}
+	if f.container != "" && !strings.HasPrefix(p.containerID, f.container) {
+		return false
+	}
+	return true
+}
+
+func runConnections(_ *cobra.Command, _ []string) error {
+	contentType := encoding.ContentTypeProtobuf
+	switch connectionsArgs.output {
+	case outputTable, outputProtobuf:
+	case outputJSON:
+		contentType = encoding.ContentTypeJSON
+	default:
+		return fmt.Errorf("unknown output format %q, it should be table, json or protobuf", connectionsArgs.output)
+	}
+	if connectionsArgs.watch && connectionsArgs.output == outputProtobuf {
+		return fmt.Errorf("--watch can not be used with the protobuf output")
+	}
+
+	c, err := getSystemProbeClient()
+	if err != nil {
+		return err
+	}
+
+	// a client ID of its own gives the command the traffic since its previous query
+	clientID := fmt.Sprintf("system-probe-cli-%d", os.Getpid())
+	resolve := newProcessResolver()
+	filter := connectionsFilter{
+		pid:       connectionsArgs.pid,
+		port:      connectionsArgs.port,
+		container: connectionsArgs.container,
+	}
+
+	// the colors are only written to the table
+	var out io.Writer = os.Stdout
+	if connectionsArgs.output == outputTable {
+		out = color.Output
+	}
+	refresh := func() error {
+		conns, err := getConnections(c, clientID, contentType)
+		if err != nil {
+			return err
+		}
+		conns.Conns = filterConnections(conns.Conns, filter, resolve)
+		return writeConnections(out, connectionsArgs.output, conns, resolve)
+	}
+
+	if !connectionsArgs.watch {
+		return refresh()
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(connectionsArgs.interval)
+	defer ticker.Stop()
+	for {
+		if connectionsArgs.output == outputTable {
+			// clear the terminal
+			fmt.Print("\033[H\033[2J")
+			fmt.Fprintf(color.Output, "Every %s: system-probe connections, %s\n\n", connectionsArgs.interval, time.Now().Format(time.RFC3339))
+		}
+		if err := refresh(); err != nil {
+			return err
+		}
+
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// getConnections queries the /connections endpoint of the system-probe
+func getConnections(c *http.Client, clientID, contentType string) (*model.Connections, error) {
+	req, err := http.NewRequest("GET", "http://localhost/connections?client_id="+clientID, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", contentType)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not reach %s: %v \nMake sure the %s is running and that its network module is enabled", targetProcessName, err, targetProcessName)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("connections request failed: status code %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return encoding.GetUnmarshaler(resp.Header.Get("Content-type")).Unmarshal(body)
+}
+
+// filterConnections returns the connections selected by filter, sorted by PID and by local address
+func filterConnections(conns []*model.Connection, filter connectionsFilter, resolve processResolver) []*model.Connection {
+	selected := make([]*model.Connection, 0, len(conns))
+	for _, c := range conns {
+		if filter.match(c, processOf(c, resolve)) {
+			selected = append(selected, c)
+		}
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Pid != selected[j].Pid {
+			return selected[i].Pid < selected[j].Pid
+		}
+		return formatAddr(selected[i].Laddr) < formatAddr(selected[j].Laddr)
+	})
+	return selected
+}
+
+// writeConnections writes the connections with the given output format
+func writeConnections(w io.Writer, output string, conns *model.Connections, resolve processResolver) error {
+	switch output {
+	case outputJSON:
+		marshaler := jsonpb.Marshaler{EmitDefaults: true}
+		if err := marshaler.Marshal(w, conns); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	case outputProtobuf:
+		buf, err := proto.Marshal(conns)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, color.BlueString("PID\tPROCESS\tCONTAINER\tPROTO\tLOCAL\tREMOTE\tDIRECTION\tSENT\tRECEIVED\tRETRANSMITS\tDNS\tHTTP"))
+	for _, c := range conns.Conns {
+		p := processOf(c, resolve)
+		container := p.containerID
+		if len(container) > shortContainerIDLen {
+			container = container[:shortContainerIDLen]
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			c.Pid,
+			orDash(p.name),
+			orDash(container),
+			c.Type,
+			formatAddr(c.Laddr),
+			formatAddr(c.Raddr),
+			c.Direction,
+			c.LastBytesSent,
+			c.LastBytesReceived,
+			c.LastRetransmits,
+			orDash(strings.Join(dnsNames(conns, c), ",")),
+			orDash(formatHTTPStats(c.HttpAggregations)),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d connections\n", len(conns.Conns))
+	return err
+}
+
+// processOf returns the attributes of the process of a connection, using the container ID
+// of the connection when it is known
+func processOf(c *model.Connection, resolve processResolver) processInfo {
+	p := resolve(c.Pid)
+	if id := c.Laddr.ContainerId; id != "" {
+		p.containerID = id
+	}
+	return p
+}
+
+func formatAddr(addr *model.Addr) string {
+	if addr == nil {
+		return "-"
+	}
+	return net.JoinHostPort(addr.Ip, strconv.Itoa(int(addr.Port)))
+}
+
+// dnsNames returns the names resolving to the remote address of a connection
+func dnsNames(conns *model.Connections, c *model.Connection) []string {
+	if entry, ok := conns.Dns[c.Raddr.Ip]; ok && entry != nil {
+		return entry.Names
+	}
+	return nil
+}
+
+// formatHTTPStats summarizes the encoded HTTP aggregations of a connection as the number of requests,
+// followed by the number of responses with a 4xx or 5xx status
+func formatHTTPStats(aggregations []byte) string {
+	if len(aggregations) == 0 {
+		return ""
+	}
+	var stats model.HTTPAggregations
+	if err := proto.Unmarshal(aggregations, &stats); err != nil {
+		return "?"
+	}
+
+	var requests, failures uint32
+	for _, endpoint := range stats.EndpointAggregations {
+		for i, data := range endpoint.StatsByResponseStatus {
+			if data == nil {
+				continue
+			}
+			requests += data.Count
+			// the stats are indexed by status class: 1xx, 2xx, 3xx, 4xx and 5xx
+			if i >= 3 {
+				failures += data.Count
+			}
+		}
+	}
+	if requests == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d req (%d err)", requests, failures)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
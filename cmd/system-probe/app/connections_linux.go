@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build linux
+
+package app
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// newProcessResolver returns a processResolver reading the processes from procfs
+func newProcessResolver() processResolver {
+	resolver := network.NewProcessInfoResolver(util.HostProc(), nil)
+	return func(pid int32) processInfo {
+		info := resolver.Resolve(uint32(pid), time.Now())
+		if info == nil {
+			return processInfo{}
+		}
+		return processInfo{name: info.Name, containerID: info.ContainerID}
+	}
+}
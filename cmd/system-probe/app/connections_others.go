@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build !linux
+
+package app
+
+// newProcessResolver returns a processResolver which knows no process, since their attributes are only read on Linux
+func newProcessResolver() processResolver {
+	return func(int32) processInfo {
+		return processInfo{}
+	}
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	model "github.com/DataDog/agent-payload/process"
+	"github.com/DataDog/datadog-agent/pkg/network/encoding"
+	"github.com/fatih/color"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testProcessResolver(pid int32) processInfo {
+	switch pid {
+	case 100:
+		return processInfo{name: "nginx", containerID: "3f2c1e4d5b6a7c8d9e0f"}
+	case 200:
+		return processInfo{name: "curl"}
+	}
+	return processInfo{}
+}
+
+func testConnections(t *testing.T) *model.Connections {
+	httpAggregations, err := proto.Marshal(&model.HTTPAggregations{
+		EndpointAggregations: []*model.HTTPStats{
+			{
+				Path: "/",
+				StatsByResponseStatus: []*model.HTTPStats_Data{
+					{}, {Count: 3}, {}, {Count: 1}, {Count: 1},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return &model.Connections{
+		Conns: []*model.Connection{
+			{
+				Pid:               200,
+				Laddr:             &model.Addr{Ip: "10.0.0.1", Port: 40000},
+				Raddr:             &model.Addr{Ip: "93.184.216.34", Port: 443},
+				Type:              model.ConnectionType_tcp,
+				Direction:         model.ConnectionDirection_outgoing,
+				LastBytesSent:     1200,
+				LastBytesReceived: 5400,
+				LastRetransmits:   2,
+			},
+			{
+				Pid:              100,
+				Laddr:            &model.Addr{Ip: "10.0.0.1", Port: 80},
+				Raddr:            &model.Addr{Ip: "10.0.0.2", Port: 50000},
+				Type:             model.ConnectionType_tcp,
+				Direction:        model.ConnectionDirection_incoming,
+				HttpAggregations: httpAggregations,
+			},
+			{
+				Pid:       300,
+				Laddr:     &model.Addr{Ip: "10.0.0.1", Port: 50001},
+				Raddr:     &model.Addr{Ip: "10.0.0.3", Port: 53},
+				Type:      model.ConnectionType_udp,
+				Direction: model.ConnectionDirection_outgoing,
+			},
+		},
+		Dns: map[string]*model.DNSEntry{
+			"93.184.216.34": {Names: []string{"example.com"}},
+		},
+	}
+}
+
+func TestFilterConnections(t *testing.T) {
+	pids := func(conns []*model.Connection) []int32 {
+		var pids []int32
+		for _, c := range conns {
+			pids = append(pids, c.Pid)
+		}
+		return pids
+	}
+
+	for name, tt := range map[string]struct {
+		filter   connectionsFilter
+		expected []int32
+	}{
+		"none":                {connectionsFilter{}, []int32{100, 200, 300}},
+		"pid":                 {connectionsFilter{pid: 200}, []int32{200}},
+		"local port":          {connectionsFilter{port: 80}, []int32{100}},
+		"remote port":         {connectionsFilter{port: 53}, []int32{300}},
+		"container":           {connectionsFilter{container: "3f2c1e"}, []int32{100}},
+		"unknown container":   {connectionsFilter{container: "abcdef"}, nil},
+		"pid and remote port": {connectionsFilter{pid: 200, port: 53}, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conns := testConnections(t)
+			assert.Equal(t, tt.expected, pids(filterConnections(conns.Conns, tt.filter, testProcessResolver)))
+		})
+	}
+}
+
+func TestWriteConnectionsTable(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	conns := testConnections(t)
+	conns.Conns = filterConnections(conns.Conns, connectionsFilter{}, testProcessResolver)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeConnections(&buf, outputTable, conns, testProcessResolver))
+	assert.Equal(t, ""+
+		"PID  PROCESS  CONTAINER     PROTO  LOCAL           REMOTE             DIRECTION  SENT  RECEIVED  RETRANSMITS  DNS          HTTP\n"+
+		"100  nginx    3f2c1e4d5b6a  tcp    10.0.0.1:80     10.0.0.2:50000     incoming   0     0         0            -            5 req (2 err)\n"+
+		"200  curl     -             tcp    10.0.0.1:40000  93.184.216.34:443  outgoing   1200  5400      2            example.com  -\n"+
+		"300  -        -             udp    10.0.0.1:50001  10.0.0.3:53        outgoing   0     0         0            -            -\n"+
+		"3 connections\n",
+		buf.String())
+}
+
+func TestWriteConnectionsEncoded(t *testing.T) {
+	for output, contentType := range map[string]string{
+		outputJSON:     encoding.ContentTypeJSON,
+		outputProtobuf: encoding.ContentTypeProtobuf,
+	} {
+		t.Run(output, func(t *testing.T) {
+			conns := testConnections(t)
+			conns.Conns = filterConnections(conns.Conns, connectionsFilter{pid: 200}, testProcessResolver)
+
+			var buf bytes.Buffer
+			require.NoError(t, writeConnections(&buf, output, conns, testProcessResolver))
+			result, err := encoding.GetUnmarshaler(contentType).Unmarshal(buf.Bytes())
+			require.NoError(t, err)
+			require.Len(t, result.Conns, 1)
+			assert.Equal(t, uint64(1200), result.Conns[0].LastBytesSent)
+			assert.Equal(t, []string{"example.com"}, result.Dns["93.184.216.34"].Names)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+// +build linux
+
+package modules
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// newProcessInfoFunc returns the function reading the attributes of the processes, whose DNS failures
+// are grouped by container
+func newProcessInfoFunc() func(pid uint32) *network.ProcessInfo {
+	resolver := network.NewProcessInfoResolver(util.HostProc(), nil)
+	return func(pid uint32) *network.ProcessInfo {
+		return resolver.Resolve(pid, time.Now())
+	}
+}
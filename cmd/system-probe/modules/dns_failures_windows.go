@@ -0,0 +1,13 @@
+// +build windows
+
+package modules
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network"
+)
+
+// newProcessInfoFunc returns nil since the process attributes are not read on Windows: the DNS failures
+// are grouped by process
+func newProcessInfoFunc() func(pid uint32) *network.ProcessInfo {
+	return nil
+}
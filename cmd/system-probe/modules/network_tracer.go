@@ -18,6 +18,8 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/system-probe/utils"
 	"github.com/DataDog/datadog-agent/pkg/network"
 	networkconfig "github.com/DataDog/datadog-agent/pkg/network/config"
+	dbdebugging "github.com/DataDog/datadog-agent/pkg/network/database/debugging"
+	dnsdebugging "github.com/DataDog/datadog-agent/pkg/network/dns/debugging"
 	"github.com/DataDog/datadog-agent/pkg/network/encoding"
 	"github.com/DataDog/datadog-agent/pkg/network/http/debugging"
 	"github.com/DataDog/datadog-agent/pkg/network/tracer"
@@ -44,7 +46,11 @@ var NetworkTracer = module.Factory{
 		log.Infof("Creating tracer for: %s", filepath.Base(os.Args[0]))
 
 		t, err := tracer.NewTracer(ncfg)
-		return &networkTracer{tracer: t}, err
+		return &networkTracer{
+			tracer:            t,
+			resolveProcess:    newProcessInfoFunc(),
+			topFailingDomains: ncfg.DNSTopFailingDomains,
+		}, err
 	},
 }
 
@@ -53,6 +59,10 @@ var _ module.Module = &networkTracer{}
 type networkTracer struct {
 	tracer       *tracer.Tracer
 	restartTimer *time.Timer
+
+	// used by the network_dns check
+	resolveProcess    func(pid uint32) *network.ProcessInfo
+	topFailingDomains int
 }
 
 func (nt *networkTracer) GetStats() map[string]interface{} {
@@ -120,6 +130,41 @@ func (nt *networkTracer) Register(httpMux *module.Router) error {
 		utils.WriteAsJSON(w, debugging.HTTP(cs.HTTP, cs.DNS))
 	})
 
+	httpMux.HandleFunc("/debug/database_monitoring", func(w http.ResponseWriter, req *http.Request) {
+		id := getClientID(req)
+		cs, err := nt.tracer.GetActiveConnections(id)
+		if err != nil {
+			log.Errorf("unable to retrieve connections: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		utils.WriteAsJSON(w, dbdebugging.Database(cs.Database, cs.DNS))
+	})
+
+	httpMux.HandleFunc("/debug/dns_latencies", func(w http.ResponseWriter, req *http.Request) {
+		id := getClientID(req)
+		cs, err := nt.tracer.GetActiveConnections(id)
+		if err != nil {
+			log.Errorf("unable to retrieve connections: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		utils.WriteAsJSON(w, dnsdebugging.DNSLatencies(cs.DNSStats))
+	})
+
+	httpMux.HandleFunc("/check/network_dns", func(w http.ResponseWriter, req *http.Request) {
+		snapshot, err := nt.tracer.GetDNSSnapshot()
+		if err != nil {
+			log.Errorf("unable to retrieve DNS stats: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		utils.WriteAsJSON(w, network.TopDNSFailures(snapshot, nt.resolveProcess, nt.topFailingDomains))
+	})
+
 	// /debug/ebpf_maps as default will dump all registered maps/perfmaps
 	// an optional ?maps= argument could be pass with a list of map name : ?maps=map1,map2,map3
 	httpMux.HandleFunc("/debug/ebpf_maps", func(w http.ResponseWriter, req *http.Request) {
@@ -20,6 +20,9 @@ type SerieSignature struct {
 	nameSuffix string
 }
 
+// maxSampleAge is the maximum age, in seconds, of the client-supplied timestamp of a sample
+const maxSampleAge = 3600
+
 // TimeSampler aggregates metrics by buckets of 'interval' seconds
 type TimeSampler struct {
 	interval                    int64
@@ -52,11 +55,33 @@ func (s *TimeSampler) isBucketStillOpen(bucketStartTimestamp, timestamp int64) b
 	return bucketStartTimestamp+s.interval > timestamp
 }
 
+// sampleTimestamp returns the timestamp used to bucket the metricSample: the
+// client-supplied timestamp when there is one in the past, the arrival
+// timestamp otherwise. It returns false when the client-supplied timestamp is
+// older than maxSampleAge, as such samples would create an unbounded number of
+// old buckets.
+func sampleTimestamp(metricSample *metrics.MetricSample, timestamp float64) (float64, bool) {
+	if metricSample.Timestamp <= 0 || metricSample.Timestamp >= timestamp {
+		return timestamp, true
+	}
+	if metricSample.Timestamp < timestamp-maxSampleAge {
+		return 0, false
+	}
+	return metricSample.Timestamp, true
+}
+
 // Add the metricSample to the correct bucket
 func (s *TimeSampler) addSample(metricSample *metrics.MetricSample, timestamp float64) {
-	// Keep track of the context
+	sampleTs, ok := sampleTimestamp(metricSample, timestamp)
+	if !ok {
+		log.Debugf("Dropping sample '%s' on host '%s' and tags '%s': its timestamp %.0f is more than %ds in the past", metricSample.Name, metricSample.Host, metricSample.Tags, metricSample.Timestamp, maxSampleAge)
+		return
+	}
+
+	// Keep track of the context. Contexts are always tracked with the arrival
+	// timestamp so that a late sample does not expire a context still in use.
 	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
-	bucketStart := s.calculateBucketStart(timestamp)
+	bucketStart := s.calculateBucketStart(sampleTs)
 
 	switch metricSample.Mtype {
 	case metrics.DistributionType:
@@ -74,7 +99,7 @@ func (s *TimeSampler) addSample(metricSample *metrics.MetricSample, timestamp fl
 		}
 
 		// Add sample to bucket
-		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, s.interval, nil); err != nil {
+		if err := bucketMetrics.AddSample(contextKey, metricSample, sampleTs, s.interval, nil); err != nil {
 			log.Debugf("Ignoring sample '%s' on host '%s' and tags '%s': %s", metricSample.Name, metricSample.Host, metricSample.Tags, err)
 		}
 	}
@@ -102,22 +127,31 @@ func (s *TimeSampler) flushSeries(cutoffTime int64) metrics.Series {
 	// Map to hold the expired contexts that will need to be deleted after the flush so that we stop sending zeros
 	counterContextsToDelete := map[ckey.ContextKey]struct{}{}
 
-	if len(s.metricsByTimestamp) > 0 {
-		for bucketTimestamp, contextMetrics := range s.metricsByTimestamp {
-			// disregard when the timestamp is too recent
-			if s.isBucketStillOpen(bucketTimestamp, cutoffTime) {
-				continue
-			}
+	// Buckets older than the last cutoff were recreated by late timestamped samples: their counters were already sent
+	hasCurrentBuckets := false
+	for bucketTimestamp, contextMetrics := range s.metricsByTimestamp {
+		lateBucket := bucketTimestamp < s.lastCutOffTime
+		if !lateBucket {
+			hasCurrentBuckets = true
+		}
+
+		// disregard when the timestamp is too recent
+		if s.isBucketStillOpen(bucketTimestamp, cutoffTime) {
+			continue
+		}
 
-			// Add a 0 sample to all the counters that are not expired.
-			// It is ok to add 0 samples to a counter that was already sampled for real in the bucket, since it won't change its value
+		// Add a 0 sample to all the counters that are not expired.
+		// It is ok to add 0 samples to a counter that was already sampled for real in the bucket, since it won't change its value
+		if !lateBucket {
 			s.countersSampleZeroValue(bucketTimestamp, contextMetrics, counterContextsToDelete)
+		}
 
-			rawSeries = append(rawSeries, s.flushContextMetrics(bucketTimestamp, contextMetrics)...)
+		rawSeries = append(rawSeries, s.flushContextMetrics(bucketTimestamp, contextMetrics)...)
 
-			delete(s.metricsByTimestamp, bucketTimestamp)
-		}
-	} else if s.lastCutOffTime+s.interval <= cutoffTime {
+		delete(s.metricsByTimestamp, bucketTimestamp)
+	}
+
+	if !hasCurrentBuckets && s.lastCutOffTime+s.interval <= cutoffTime {
 		// Even if there is no metric in this flush, recreate empty counters,
 		// but only if we've passed an interval since the last flush
 
@@ -65,6 +65,124 @@ func TestBucketSampling(t *testing.T) {
 	}
 }
 
+func TestBucketSamplingWithTimestamp(t *testing.T) {
+	sampler := NewTimeSampler(10)
+
+	mSample := metrics.MetricSample{
+		Name:       "my.metric.name",
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"foo", "bar"},
+		SampleRate: 1,
+	}
+	pastSample := mSample
+	pastSample.Value = 2
+	pastSample.Timestamp = 12315.0
+	futureSample := mSample
+	futureSample.Value = 3
+	futureSample.Timestamp = 12395.0
+
+	sampler.addSample(&mSample, 12345.0)
+	// late sample, goes to the bucket of its own timestamp
+	sampler.addSample(&pastSample, 12346.0)
+	// timestamps in the future are ignored
+	sampler.addSample(&futureSample, 12365.0)
+
+	series, _ := sampler.flush(12360.0)
+
+	expectedSerie := &metrics.Serie{
+		Name:       "my.metric.name",
+		Tags:       []string{"foo", "bar"},
+		Points:     []metrics.Point{{Ts: 12310.0, Value: 2}, {Ts: 12340.0, Value: 1}},
+		MType:      metrics.APIGaugeType,
+		Interval:   10,
+		NameSuffix: "",
+	}
+
+	assert.Equal(t, 1, len(sampler.metricsByTimestamp))
+	if assert.Equal(t, 1, len(series)) {
+		sort.Slice(series[0].Points, func(i, j int) bool { return series[0].Points[i].Ts < series[0].Points[j].Ts })
+		metrics.AssertSerieEqual(t, expectedSerie, series[0])
+	}
+}
+
+func TestBucketSamplingWithOldTimestamp(t *testing.T) {
+	sampler := NewTimeSampler(10)
+
+	mSample := metrics.MetricSample{
+		Name:       "my.metric.name",
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"foo", "bar"},
+		SampleRate: 1,
+	}
+	oldSample := mSample
+	oldSample.Value = 2
+	oldSample.Timestamp = 12345.0 - maxSampleAge - 1
+	limitSample := mSample
+	limitSample.Value = 3
+	limitSample.Timestamp = 12345.0 - maxSampleAge
+
+	sampler.addSample(&mSample, 12345.0)
+	// too old, dropped
+	sampler.addSample(&oldSample, 12345.0)
+	// at the limit, kept
+	sampler.addSample(&limitSample, 12345.0)
+
+	assert.Equal(t, 2, len(sampler.metricsByTimestamp))
+	series, _ := sampler.flush(12360.0)
+
+	expectedSerie := &metrics.Serie{
+		Name:       "my.metric.name",
+		Tags:       []string{"foo", "bar"},
+		Points:     []metrics.Point{{Ts: 12345.0 - maxSampleAge - 5, Value: 3}, {Ts: 12340.0, Value: 1}},
+		MType:      metrics.APIGaugeType,
+		Interval:   10,
+		NameSuffix: "",
+	}
+
+	if assert.Equal(t, 1, len(series)) {
+		sort.Slice(series[0].Points, func(i, j int) bool { return series[0].Points[i].Ts < series[0].Points[j].Ts })
+		metrics.AssertSerieEqual(t, expectedSerie, series[0])
+	}
+}
+
+func TestCounterLateSampleAfterFlush(t *testing.T) {
+	sampler := NewTimeSampler(10)
+
+	counter := metrics.MetricSample{
+		Name:       "my.counter",
+		Value:      1,
+		Mtype:      metrics.CounterType,
+		Tags:       []string{"foo"},
+		SampleRate: 1,
+	}
+	sampler.addSample(&counter, 12345.0)
+	series, _ := sampler.flush(12360.0)
+	require.Len(t, series, 1)
+
+	// a late sample of another metric recreates the already flushed bucket
+	lateSample := metrics.MetricSample{
+		Name:       "my.late.gauge",
+		Value:      2,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"bar"},
+		SampleRate: 1,
+		Timestamp:  12342.0,
+	}
+	sampler.addSample(&lateSample, 12365.0)
+	series, _ = sampler.flush(12370.0)
+
+	// the counter is only zero-filled in the new bucket, not at the timestamp of the late sample
+	var counterPoints []metrics.Point
+	for _, serie := range series {
+		if serie.Name == "my.counter" {
+			counterPoints = append(counterPoints, serie.Points...)
+		}
+	}
+	assert.Equal(t, []metrics.Point{{Ts: 12360.0, Value: 0}}, counterPoints)
+}
+
 func TestContextSampling(t *testing.T) {
 	sampler := NewTimeSampler(10)
 
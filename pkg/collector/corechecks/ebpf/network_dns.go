@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// FIXME: we require the `cgo` build tag because of this dep relationship:
+// github.com/DataDog/datadog-agent/pkg/process/net depends on `github.com/DataDog/agent-payload/process`,
+// which has a hard dependency on `github.com/DataDog/zstd_0`, which requires CGO.
+// Should be removed once `github.com/DataDog/agent-payload/process` can be imported with CGO disabled.
+// +build cgo
+// +build linux
+
+package ebpf
+
+import (
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	dd_config "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/network"
+	process_net "github.com/DataDog/datadog-agent/pkg/process/net"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	networkDNSCheckName = "network_dns"
+)
+
+// NetworkDNSConfig is the config of the network DNS check
+type NetworkDNSConfig struct {
+	CollectDNSFailures bool `yaml:"collect_dns_failures"`
+}
+
+// NetworkDNSCheck grabs the DNS failures and response times of the domains of which the
+// resolutions failed the most, for each process or container
+type NetworkDNSCheck struct {
+	core.CheckBase
+	instance *NetworkDNSConfig
+}
+
+func init() {
+	core.RegisterCheck(networkDNSCheckName, NetworkDNSFactory)
+}
+
+// NetworkDNSFactory is exported for integration testing
+func NetworkDNSFactory() check.Check {
+	return &NetworkDNSCheck{
+		CheckBase: core.NewCheckBase(networkDNSCheckName),
+		instance:  &NetworkDNSConfig{},
+	}
+}
+
+// Parse parses the check configuration
+func (c *NetworkDNSConfig) Parse(data []byte) error {
+	// default values
+	c.CollectDNSFailures = true
+
+	return yaml.Unmarshal(data, c)
+}
+
+// Configure parses the check configuration and init the check
+func (n *NetworkDNSCheck) Configure(config, initConfig integration.Data, source string) error {
+	// TODO: Remove that hard-code and put it somewhere else
+	process_net.SetSystemProbePath(dd_config.Datadog.GetString("system_probe_config.sysprobe_socket"))
+
+	err := n.CommonConfigure(config, source)
+	if err != nil {
+		return err
+	}
+
+	return n.instance.Parse(config)
+}
+
+// Run executes the check
+func (n *NetworkDNSCheck) Run() error {
+	if !n.instance.CollectDNSFailures {
+		return nil
+	}
+
+	sysProbeUtil, err := process_net.GetRemoteSystemProbeUtil()
+	if err != nil {
+		return err
+	}
+
+	data, err := sysProbeUtil.GetCheck(networkDNSCheckName)
+	if err != nil {
+		return err
+	}
+
+	sender, err := aggregator.GetSender(n.ID())
+	if err != nil {
+		return err
+	}
+
+	failures, ok := data.([]network.DNSFailures)
+	if !ok {
+		return log.Errorf("Raw data has incorrect type")
+	}
+
+	for _, f := range failures {
+		entityID := containers.BuildTaggerEntityName(f.ContainerID)
+		var tags []string
+		if entityID != "" {
+			tags, err = tagger.Tag(entityID, tagger.ChecksCardinality)
+			if err != nil {
+				log.Errorf("Error collecting tags for container %s: %s", f.ContainerID, err)
+			}
+		}
+		if f.ProcessName != "" {
+			tags = append(tags, "process_name:"+f.ProcessName)
+		}
+
+		for _, d := range f.Domains {
+			domainTags := append(append([]string{}, tags...), "domain:"+d.Domain)
+			sender.Count("network.dns.failures", float64(d.Failures), "", domainTags)
+			sender.Count("network.dns.timeouts", float64(d.Timeouts), "", domainTags)
+
+			for _, r := range d.Responses {
+				rcodeTags := append(append([]string{}, domainTags...), "rcode:"+strings.ToLower(r.Rcode))
+				sender.Count("network.dns.responses", float64(r.Count), "", rcodeTags)
+				// the latencies are in nanoseconds
+				sender.Gauge("network.dns.response_time.p50", r.LatencyP50/1e9, "", rcodeTags)
+				sender.Gauge("network.dns.response_time.p95", r.LatencyP95/1e9, "", rcodeTags)
+				sender.Gauge("network.dns.response_time.p99", r.LatencyP99/1e9, "", rcodeTags)
+			}
+		}
+	}
+
+	sender.Commit()
+	return nil
+}
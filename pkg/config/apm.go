@@ -26,9 +26,15 @@ func setupAPM(config Config) {
 	config.SetKnown("apm_config.obfuscation.sql_exec_plan_normalize.obfuscate_sql_values")
 	config.SetKnown("apm_config.obfuscation.http.remove_query_string")
 	config.SetKnown("apm_config.obfuscation.http.remove_paths_with_digits")
+	config.SetKnown("apm_config.obfuscation.sql.table_names")
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.enabled")
+	config.SetKnown("apm_config.obfuscation.kafka.enabled")
+	config.SetKnown("apm_config.obfuscation.kafka.scrub_keys")
+	config.SetKnown("apm_config.obfuscation.grpc.enabled")
+	config.SetKnown("apm_config.obfuscation.grpc.scrub_keys")
 	config.SetKnown("apm_config.filter_tags.require")
 	config.SetKnown("apm_config.filter_tags.reject")
 	config.SetKnown("apm_config.extra_sample_rate")
@@ -79,9 +85,25 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")
 	config.BindEnv("apm_config.receiver_socket", "DD_APM_RECEIVER_SOCKET")
 	config.BindEnv("apm_config.windows_pipe_name", "DD_APM_WINDOWS_PIPE_NAME")
+	config.BindEnv("apm_config.extra_aggregators", "DD_APM_EXTRA_AGGREGATORS")
+	config.BindEnv("apm_config.extra_aggregators_max_cardinality", "DD_APM_EXTRA_AGGREGATORS_MAX_CARDINALITY")
+	config.BindEnv("apm_config.prometheus_stats.enabled", "DD_APM_PROMETHEUS_STATS_ENABLED")
+	config.BindEnv("apm_config.prometheus_stats.max_series", "DD_APM_PROMETHEUS_STATS_MAX_SERIES")
+	config.BindEnv("apm_config.zipkin_receiver.enabled", "DD_APM_ZIPKIN_RECEIVER_ENABLED")
+	config.BindEnv("apm_config.jaeger_receiver.enabled", "DD_APM_JAEGER_RECEIVER_ENABLED")
+	config.BindEnv("apm_config.capture_path", "DD_APM_CAPTURE_PATH")
+	config.BindEnv("apm_config.spool.path", "DD_APM_SPOOL_PATH")
+	config.BindEnv("apm_config.spool.traces_max_size_in_bytes", "DD_APM_SPOOL_TRACES_MAX_SIZE_IN_BYTES")
+	config.BindEnv("apm_config.spool.stats_max_size_in_bytes", "DD_APM_SPOOL_STATS_MAX_SIZE_IN_BYTES")
+	config.BindEnv("apm_config.spool.max_disk_ratio", "DD_APM_SPOOL_MAX_DISK_RATIO")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait_seconds", "DD_APM_TAIL_SAMPLING_DECISION_WAIT_SECONDS")
+	config.BindEnv("apm_config.tail_sampling.max_spans", "DD_APM_TAIL_SAMPLING_MAX_SPANS")
+	config.BindEnv("apm_config.tail_sampling.rules", "DD_APM_TAIL_SAMPLING_RULES")
 	config.BindEnv("apm_config.sync_flushing", "DD_APM_SYNC_FLUSHING")
 	config.BindEnv("apm_config.filter_tags.require", "DD_APM_FILTER_TAGS_REQUIRE")
 	config.BindEnv("apm_config.filter_tags.reject", "DD_APM_FILTER_TAGS_REJECT")
+	config.BindEnv("apm_config.filter_tags.rules", "DD_APM_FILTER_TAGS_RULES")
 	config.BindEnv("apm_config.internal_profiling.enabled", "DD_APM_INTERNAL_PROFILING_ENABLED")
 	config.BindEnv("apm_config.debugger_dd_url", "DD_APM_DEBUGGER_DD_URL")
 
@@ -102,6 +124,14 @@ func setupAPM(config Config) {
 		return strings.Split(in, " ")
 	})
 
+	config.SetEnvKeyTransformer("apm_config.filter_tags.rules", func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.filter_tags.rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
 	config.SetEnvKeyTransformer("apm_config.replace_tags", func(in string) interface{} {
 		var out []map[string]string
 		if err := json.Unmarshal([]byte(in), &out); err != nil {
@@ -110,6 +140,14 @@ func setupAPM(config Config) {
 		return out
 	})
 
+	config.SetEnvKeyTransformer("apm_config.tail_sampling.rules", func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.tail_sampling.rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
 	config.SetEnvKeyTransformer("apm_config.analyzed_spans", func(in string) interface{} {
 		out, err := parseAnalyzedSpans(in)
 		if err != nil {
@@ -367,6 +367,7 @@ func InitConfig(config Config) {
 	// Warning: do not change the two following values. Your payloads will get dropped by Datadog's intake.
 	config.BindEnvAndSetDefault("serializer_max_payload_size", 2*megaByte+megaByte/2)
 	config.BindEnvAndSetDefault("serializer_max_uncompressed_payload_size", 4*megaByte)
+	config.BindEnvAndSetDefault("serializer_compressor_kind", "") // empty means the compression method selected at build time
 
 	config.BindEnvAndSetDefault("use_v2_api.events", false)
 	config.BindEnvAndSetDefault("use_v2_api.service_checks", false)
@@ -386,6 +387,7 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_apikey_validation_interval", DefaultAPIKeyValidationInterval) // in minutes
 	config.BindEnvAndSetDefault("forwarder_num_workers", 1)
 	config.BindEnvAndSetDefault("forwarder_stop_timeout", 2)
+	config.BindEnvAndSetDefault("forwarder_compression_kind_per_domain", map[string]string{})
 	// Forwarder retry settings
 	config.BindEnvAndSetDefault("forwarder_backoff_factor", 2)
 	config.BindEnvAndSetDefault("forwarder_backoff_base", 2)
@@ -399,6 +401,7 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_flush_to_disk_mem_ratio", 0.5)
 	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0) // 0 means disabled. This is a BETA feature.
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80) // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
+	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes_per_endpoint", map[string]int64{})
 
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
@@ -752,6 +755,16 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("logs_config.aggregation_timeout", 1000)
 	// Time in seconds
 	config.BindEnvAndSetDefault("logs_config.file_scan_period", 10.0)
+	// Store on disk the payloads which can not be sent during intake outages
+	config.BindEnvAndSetDefault("logs_config.disk_spool.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.disk_spool.path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_spool.max_size_in_bytes", 100*1024*1024)
+	// Receive OTLP log records, over gRPC and HTTP. A port of 0 disables the protocol.
+	config.BindEnvAndSetDefault("logs_config.otlp.bind_host", "localhost")
+	config.BindEnvAndSetDefault("logs_config.otlp.grpc_port", 0)
+	config.BindEnvAndSetDefault("logs_config.otlp.http_port", 0)
+	// Keys of the resource attributes of the OTLP log records added as tags, besides the ones mapped to Datadog tags.
+	config.BindEnvAndSetDefault("logs_config.otlp.resource_attributes_as_tags", []string{})
 
 	// The cardinality of tags to send for checks and dogstatsd respectively.
 	// Choices are: low, orchestrator, high.
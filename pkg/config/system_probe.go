@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 const (
@@ -105,6 +108,7 @@ func InitSystemProbeConfig(cfg Config) {
 	cfg.BindEnvAndSetDefault(join(spNS, "collect_dns_domains"), false, "DD_COLLECT_DNS_DOMAINS")
 	cfg.BindEnvAndSetDefault(join(spNS, "max_dns_stats"), 20000)
 	cfg.BindEnvAndSetDefault(join(spNS, "dns_timeout_in_s"), 15)
+	cfg.BindEnvAndSetDefault(join(spNS, "dns_top_failing_domains"), 10)
 
 	cfg.BindEnvAndSetDefault(join(spNS, "enable_conntrack"), true)
 	cfg.BindEnvAndSetDefault(join(spNS, "conntrack_max_state_size"), 65536*2)
@@ -115,10 +119,24 @@ func InitSystemProbeConfig(cfg Config) {
 
 	cfg.BindEnvAndSetDefault(join(spNS, "source_excludes"), map[string][]string{})
 	cfg.BindEnvAndSetDefault(join(spNS, "dest_excludes"), map[string][]string{})
+	cfg.BindEnvAndSetDefault(join(spNS, "process_excludes"), []map[string]interface{}{}, "DD_SYSTEM_PROBE_PROCESS_EXCLUDES")
+	cfg.SetEnvKeyTransformer(join(spNS, "process_excludes"), func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`%q can not be parsed: %v`, join(spNS, "process_excludes"), err)
+		}
+		return out
+	})
 
 	// network_config namespace only
 	cfg.BindEnv(join(netNS, "enable_http_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTP_MONITORING")
 	cfg.BindEnv(join(netNS, "enable_https_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTPS_MONITORING")
+	cfg.BindEnvAndSetDefault(join(netNS, "enable_http_monitoring_fallback"), false, "DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTP_MONITORING_FALLBACK")
+	cfg.BindEnvAndSetDefault(join(netNS, "http_ports"), []string{"80", "8080"}, "DD_SYSTEM_PROBE_NETWORK_HTTP_PORTS")
+	cfg.BindEnvAndSetDefault(join(netNS, "enable_postgres_monitoring"), false, "DD_SYSTEM_PROBE_NETWORK_ENABLE_POSTGRES_MONITORING")
+	cfg.BindEnvAndSetDefault(join(netNS, "postgres_ports"), []string{"5432"}, "DD_SYSTEM_PROBE_NETWORK_POSTGRES_PORTS")
+	cfg.BindEnvAndSetDefault(join(netNS, "enable_redis_monitoring"), false, "DD_SYSTEM_PROBE_NETWORK_ENABLE_REDIS_MONITORING")
+	cfg.BindEnvAndSetDefault(join(netNS, "redis_ports"), []string{"6379"}, "DD_SYSTEM_PROBE_NETWORK_REDIS_PORTS")
 	cfg.BindEnvAndSetDefault(join(netNS, "enable_gateway_lookup"), false, "DD_SYSTEM_PROBE_NETWORK_ENABLE_GATEWAY_LOOKUP")
 
 	// list of DNS query types to be recorded
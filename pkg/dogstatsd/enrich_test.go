@@ -68,6 +68,20 @@ func parseAndEnrichEventMessage(message []byte, defaultHostname string) (*metric
 	return enrichEvent(parsed, defaultHostname, "", true), nil
 }
 
+func TestConvertParseTimestamp(t *testing.T) {
+	parsed, err := parseAndEnrichMultipleMetricMessage([]byte("daemon:666:777|g|T1657100430"), "", nil, "default-hostname")
+
+	assert.NoError(t, err)
+	require.Len(t, parsed, 2)
+	assert.Equal(t, 1657100430.0, parsed[0].Timestamp)
+	assert.Equal(t, 1657100430.0, parsed[1].Timestamp)
+
+	single, err := parseAndEnrichSingleMetricMessage([]byte("daemon:666|c"), "", nil, "default-hostname")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, single.Timestamp)
+}
+
 func TestConvertParseMultiple(t *testing.T) {
 	for metricSymbol, metricType := range symbolToType {
 
@@ -853,11 +867,31 @@ func TestConvertEntityOriginDetectionTagsError(t *testing.T) {
 	assert.InEpsilon(t, 1.0, parsed.SampleRate, epsilon)
 }
 
+func TestConvertContainerIDField(t *testing.T) {
+	parsed, err := parseAndEnrichSingleMetricMessage([]byte("daemon:666|g|#sometag1:somevalue1|c:my-container"), "", nil, "default-hostname")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", parsed.Name)
+	assert.Equal(t, []string{"sometag1:somevalue1"}, parsed.Tags)
+	assert.Equal(t, "container_id://my-container", parsed.OriginID)
+	assert.Equal(t, "", parsed.K8sOriginID)
+
+	event, err := parseAndEnrichEventMessage([]byte("_e{10,9}:test title|test text|c:my-container"), "default-hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "container_id://my-container", event.OriginID)
+
+	serviceCheck, err := parseAndEnrichServiceCheckMessage([]byte("_sc|agent.up|0|c:my-container|m:this is fine"), "default-hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "container_id://my-container", serviceCheck.OriginID)
+	assert.Equal(t, "this is fine", serviceCheck.Message)
+}
+
 func TestEnrichTags(t *testing.T) {
 	type args struct {
 		tags                       []string
 		defaultHostname            string
 		originTags                 string
+		containerID                string
 		entityIDPrecendenceEnabled bool
 	}
 	tests := []struct {
@@ -1022,10 +1056,53 @@ func TestEnrichTags(t *testing.T) {
 			wantedK8sOrigin:   "kubernetes_pod_uid://42",
 			wantedCardinality: "",
 		},
+		{
+			name: "containerID present, no origin tags, should return the container entity",
+			args: args{
+				tags:                       []string{"env:prod"},
+				defaultHostname:            "foo",
+				containerID:                "my-container",
+				entityIDPrecendenceEnabled: true,
+			},
+			wantedTags:        []string{"env:prod"},
+			wantedHost:        "foo",
+			wantedOrigin:      "container_id://my-container",
+			wantedK8sOrigin:   "",
+			wantedCardinality: "",
+		},
+		{
+			name: "containerID and origin tags present, should return origin tags",
+			args: args{
+				tags:                       []string{"env:prod"},
+				defaultHostname:            "foo",
+				originTags:                 "originID",
+				containerID:                "my-container",
+				entityIDPrecendenceEnabled: true,
+			},
+			wantedTags:        []string{"env:prod"},
+			wantedHost:        "foo",
+			wantedOrigin:      "originID",
+			wantedK8sOrigin:   "",
+			wantedCardinality: "",
+		},
+		{
+			name: "containerID and entityId present, should not return the container entity",
+			args: args{
+				tags:                       []string{"env:prod", fmt.Sprintf("%s%s", entityIDTagPrefix, "my-id")},
+				defaultHostname:            "foo",
+				containerID:                "my-container",
+				entityIDPrecendenceEnabled: true,
+			},
+			wantedTags:        []string{"env:prod"},
+			wantedHost:        "foo",
+			wantedOrigin:      "",
+			wantedK8sOrigin:   "kubernetes_pod_uid://my-id",
+			wantedCardinality: "",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tags, host, origin, k8sOrigin, cardinality := extractTagsMetadata(tt.args.tags, tt.args.defaultHostname, tt.args.originTags, tt.args.entityIDPrecendenceEnabled)
+			tags, host, origin, k8sOrigin, cardinality := extractTagsMetadata(tt.args.tags, tt.args.defaultHostname, tt.args.originTags, tt.args.containerID, tt.args.entityIDPrecendenceEnabled)
 			assert.Equal(t, tt.wantedTags, tags)
 			assert.Equal(t, tt.wantedHost, host)
 			assert.Equal(t, tt.wantedOrigin, origin)
@@ -24,6 +24,10 @@ var (
 	fieldSeparator = []byte("|")
 	colonSeparator = []byte(":")
 	commaSeparator = []byte(",")
+
+	// containerIDFieldPrefix is the prefix of the optional field carrying the
+	// ID of the container that sent the message, common to all message types.
+	containerIDFieldPrefix = []byte("c:")
 )
 
 // parser parses dogstatsd messages
@@ -126,7 +130,9 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 	}
 
 	sampleRate := 1.0
+	var timestamp int64
 	var tags []string
+	var containerID string
 	var optionalField []byte
 	for message != nil {
 		optionalField, message = nextField(message)
@@ -137,20 +143,39 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 			if err != nil {
 				return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd sample rate %q", optionalField)
 			}
+		} else if bytes.HasPrefix(optionalField, timestampFieldPrefix) {
+			timestamp, err = parseMetricSampleTimestamp(optionalField[1:])
+			if err != nil {
+				return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd timestamp %q", optionalField)
+			}
+		} else if bytes.HasPrefix(optionalField, containerIDFieldPrefix) {
+			containerID = p.extractContainerID(optionalField)
 		}
 	}
 
 	return dogstatsdMetricSample{
-		name:       p.interner.LoadOrStore(name),
-		value:      value,
-		values:     values,
-		setValue:   string(setValue),
-		metricType: metricType,
-		sampleRate: sampleRate,
-		tags:       tags,
+		name:        p.interner.LoadOrStore(name),
+		value:       value,
+		values:      values,
+		setValue:    string(setValue),
+		metricType:  metricType,
+		sampleRate:  sampleRate,
+		tags:        tags,
+		timestamp:   timestamp,
+		containerID: containerID,
 	}, nil
 }
 
+// extractContainerID returns the container ID carried by a `c:` field, or
+// an empty string if the field is empty.
+func (p *parser) extractContainerID(rawContainerIDField []byte) string {
+	containerID := rawContainerIDField[len(containerIDFieldPrefix):]
+	if len(containerID) == 0 {
+		return ""
+	}
+	return p.interner.LoadOrStore(containerID)
+}
+
 // parseFloat64List parses a list of float64 separated by colonSeparator.
 func (p *parser) parseFloat64List(rawFloats []byte) ([]float64, error) {
 	var value float64
@@ -33,6 +33,7 @@ type dogstatsdEvent struct {
 	sourceType     string
 	alertType      alertType
 	tags           []string
+	containerID    string
 }
 
 type eventHeader struct {
@@ -156,6 +157,8 @@ func (p *parser) applyEventOptionalField(event dogstatsdEvent, optionalField []b
 		newEvent.alertType, err = parseEventAlertType(optionalField[len(eventAlertTypePrefix):])
 	case bytes.HasPrefix(optionalField, eventTagsPrefix):
 		newEvent.tags = p.parseTags(optionalField[len(eventTagsPrefix):])
+	case bytes.HasPrefix(optionalField, containerIDFieldPrefix):
+		newEvent.containerID = p.extractContainerID(optionalField)
 	}
 	if err != nil {
 		return event, err
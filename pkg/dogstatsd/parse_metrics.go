@@ -26,6 +26,7 @@ var (
 
 	tagsFieldPrefix       = []byte("#")
 	sampleRateFieldPrefix = []byte("@")
+	timestampFieldPrefix  = []byte("T")
 )
 
 type dogstatsdMetricSample struct {
@@ -39,6 +40,12 @@ type dogstatsdMetricSample struct {
 	metricType metricType
 	sampleRate float64
 	tags       []string
+	// timestamp is the client-supplied unix timestamp of the sample, in
+	// seconds. 0 means the sample is stamped with its arrival time.
+	timestamp int64
+	// containerID is the ID of the container that sent the sample, as
+	// provided by the client
+	containerID string
 }
 
 // sanity checks a given message against the metric sample format
@@ -47,7 +54,7 @@ func hasMetricSampleFormat(message []byte) bool {
 		return false
 	}
 	separatorCount := bytes.Count(message, fieldSeparator)
-	if separatorCount < 1 || separatorCount > 3 {
+	if separatorCount < 1 || separatorCount > 5 {
 		return false
 	}
 	return true
@@ -87,3 +94,14 @@ func parseMetricSampleMetricType(rawMetricType []byte) (metricType, error) {
 func parseMetricSampleSampleRate(rawSampleRate []byte) (float64, error) {
 	return parseFloat64(rawSampleRate)
 }
+
+func parseMetricSampleTimestamp(rawTimestamp []byte) (int64, error) {
+	timestamp, err := parseInt64(rawTimestamp)
+	if err != nil {
+		return 0, err
+	}
+	if timestamp <= 0 {
+		return 0, fmt.Errorf("invalid timestamp: %d", timestamp)
+	}
+	return timestamp, nil
+}
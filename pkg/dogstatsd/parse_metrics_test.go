@@ -241,6 +241,39 @@ func TestParseGaugeWithSampleRate(t *testing.T) {
 	assert.InEpsilon(t, 0.21, sample.sampleRate, epsilon)
 }
 
+func TestParseGaugeWithTimestamp(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:666|g|@0.21|#sometag:someval|T1657100430"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 666.0, sample.value, epsilon)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, []string{"sometag:someval"}, sample.tags)
+	assert.InEpsilon(t, 0.21, sample.sampleRate, epsilon)
+	assert.Equal(t, int64(1657100430), sample.timestamp)
+
+	sample, err = parseMetricSample([]byte("daemon:666|g"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), sample.timestamp)
+}
+
+func TestParseGaugeWithContainerID(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:666|g|@0.21|#sometag:someval|T1657100430|c:1a2b3c4d"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 666.0, sample.value, epsilon)
+	assert.Equal(t, []string{"sometag:someval"}, sample.tags)
+	assert.Equal(t, int64(1657100430), sample.timestamp)
+	assert.Equal(t, "1a2b3c4d", sample.containerID)
+
+	sample, err = parseMetricSample([]byte("daemon:666|g|c:"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", sample.containerID)
+}
+
 func TestParseGaugeWithPoundOnly(t *testing.T) {
 	sample, err := parseMetricSample([]byte("daemon:666|g|#"))
 
@@ -297,6 +330,13 @@ func TestParseMetricError(t *testing.T) {
 	_, err = parseMetricSample([]byte("daemon:666|unknown"))
 	assert.Error(t, err)
 
+	// invalid timestamp
+	_, err = parseMetricSample([]byte("daemon:666|g|Tabc"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T-1"))
+	assert.Error(t, err)
+
 	// invalid sample rate
 	_, err = parseMetricSample([]byte("daemon:666|g|@abc"))
 	assert.Error(t, err)
@@ -18,12 +18,13 @@ const (
 )
 
 type dogstatsdServiceCheck struct {
-	name      string
-	status    serviceCheckStatus
-	timestamp int64
-	hostname  string
-	message   string
-	tags      []string
+	name        string
+	status      serviceCheckStatus
+	timestamp   int64
+	hostname    string
+	message     string
+	tags        []string
+	containerID string
 }
 
 var (
@@ -90,6 +91,8 @@ func (p *parser) applyServiceCheckOptionalField(serviceCheck dogstatsdServiceChe
 		newServiceCheck.tags = p.parseTags(optionalField[len(serviceCheckTagsPrefix):])
 	case bytes.HasPrefix(optionalField, serviceCheckMessagePrefix):
 		newServiceCheck.message = string(optionalField[len(serviceCheckMessagePrefix):])
+	case bytes.HasPrefix(optionalField, containerIDFieldPrefix):
+		newServiceCheck.containerID = p.extractContainerID(optionalField)
 	}
 	if err != nil {
 		return serviceCheck, err
@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
@@ -34,6 +35,8 @@ const (
 	versionHTTPHeaderKey      = "DD-Agent-Version"
 	useragentHTTPHeaderKey    = "User-Agent"
 	arbitraryTagHTTPHeaderKey = "Allow-Arbitrary-Tag-Value"
+
+	contentEncodingHTTPHeaderKey = "Content-Encoding"
 )
 
 // The amount of time the forwarder will wait to receive process-like response payloads before giving up
@@ -99,6 +102,8 @@ type Options struct {
 	EnabledFeatures                Features
 	APIKeyValidationInterval       time.Duration
 	KeysPerDomain                  map[string][]string
+	CompressionKindPerDomain       map[string]string
+	MaxPayloadSize                 int
 	ConnectionResetInterval        time.Duration
 	CompletionHandler              transaction.HTTPCompletionHandler
 }
@@ -141,6 +146,8 @@ func NewOptions(keysPerDomain map[string][]string) *Options {
 		RetryQueuePayloadsTotalMaxSize: retryQueuePayloadsTotalMaxSize,
 		APIKeyValidationInterval:       time.Duration(validationInterval) * time.Minute,
 		KeysPerDomain:                  keysPerDomain,
+		CompressionKindPerDomain:       config.Datadog.GetStringMapString("forwarder_compression_kind_per_domain"),
+		MaxPayloadSize:                 config.Datadog.GetInt("serializer_max_payload_size"),
 		ConnectionResetInterval:        time.Duration(config.Datadog.GetInt("forwarder_connection_reset_interval")) * time.Second,
 	}
 
@@ -176,6 +183,8 @@ type DefaultForwarder struct {
 
 	domainForwarders map[string]*domainForwarder
 	keysPerDomains   map[string][]string
+	codecPerDomain   map[string]compression.Codec
+	maxPayloadSize   int
 	healthChecker    *forwarderHealth
 	internalState    uint32
 	m                sync.Mutex // To control Start/Stop races
@@ -189,6 +198,8 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 		NumberOfWorkers:  options.NumberOfWorkers,
 		domainForwarders: map[string]*domainForwarder{},
 		keysPerDomains:   map[string][]string{},
+		codecPerDomain:   map[string]compression.Codec{},
+		maxPayloadSize:   options.MaxPayloadSize,
 		internalState:    Stopped,
 		healthChecker: &forwarderHealth{
 			keysPerDomains:        options.KeysPerDomain,
@@ -204,14 +215,10 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	if storageMaxSize == 0 {
 		log.Infof("Retry queue storage on disk is disabled")
 	} else if agentFolder := getAgentFolder(options); agentFolder != "" {
-		storagePath := config.Datadog.GetString("forwarder_storage_path")
-		if storagePath == "" {
-			storagePath = path.Join(config.Datadog.GetString("run_path"), "transactions_to_retry")
-		}
+		storagePath := getRetryQueueStoragePath(agentFolder)
 		outdatedFileInDays := config.Datadog.GetInt("forwarder_outdated_file_in_days")
 		var err error
 
-		storagePath = path.Join(storagePath, agentFolder)
 		optionalRemovalPolicy, err = retry.NewFileRemovalPolicy(storagePath, outdatedFileInDays, retry.FileRemovalPolicyTelemetry{})
 		if err != nil {
 			log.Errorf("Error when initializing the removal policy: %v", err)
@@ -231,6 +238,7 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 
 	for domain, keys := range options.KeysPerDomain {
+		compressionKind := options.CompressionKindPerDomain[domain]
 		domain, _ := config.AddAgentVersionToDomain(domain, "app")
 		if keys == nil || len(keys) == 0 {
 			log.Errorf("No API keys for domain '%s', dropping domain ", domain)
@@ -254,6 +262,14 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				keys)
 
 			f.keysPerDomains[domain] = keys
+			if compressionKind != "" {
+				codec, err := compression.GetCodec(compressionKind)
+				if err != nil {
+					log.Errorf("Cannot use the compression codec %q for domain '%s', payloads will be sent as is: %v", compressionKind, domain, err)
+				} else {
+					f.codecPerDomain[domain] = codec
+				}
+			}
 			f.domainForwarders[domain] = newDomainForwarder(
 				domain,
 				transactionContainer,
@@ -281,6 +297,15 @@ func getAgentFolder(options *Options) string {
 	return ""
 }
 
+// getRetryQueueStoragePath returns the folder where the forwarder of agentFolder stores its retry files.
+func getRetryQueueStoragePath(agentFolder string) string {
+	storagePath := config.Datadog.GetString("forwarder_storage_path")
+	if storagePath == "" {
+		storagePath = path.Join(config.Datadog.GetString("run_path"), "transactions_to_retry")
+	}
+	return path.Join(storagePath, agentFolder)
+}
+
 // Start initialize and runs the forwarder.
 func (f *DefaultForwarder) Start() error {
 	// Lock so we can't stop a Forwarder while is starting
@@ -374,9 +399,11 @@ func (f *DefaultForwarder) createHTTPTransactions(endpoint transaction.Endpoint,
 func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.Endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header, priority transaction.Priority, storableOnDisk bool) []*transaction.HTTPTransaction {
 	transactions := make([]*transaction.HTTPTransaction, 0, len(payloads)*len(f.keysPerDomains))
 	allowArbitraryTags := config.Datadog.GetBool("allow_arbitrary_tags")
+	payloadContentEncoding := extra.Get(contentEncodingHTTPHeaderKey)
 
 	for _, payload := range payloads {
 		for domain, apiKeys := range f.keysPerDomains {
+			domainPayload, contentEncoding := f.payloadForDomain(domain, payload, payloadContentEncoding)
 			for _, apiKey := range apiKeys {
 				t := transaction.NewHTTPTransaction()
 				t.Domain = domain
@@ -384,7 +411,7 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 				if apiKeyInQueryString {
 					t.Endpoint.Route = fmt.Sprintf("%s?api_key=%s", endpoint.Route, apiKey)
 				}
-				t.Payload = payload
+				t.Payload = domainPayload
 				t.Priority = priority
 				t.StorableOnDisk = storableOnDisk
 				t.Headers.Set(apiHTTPHeaderKey, apiKey)
@@ -406,6 +433,13 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 				for key := range extra {
 					t.Headers.Set(key, extra.Get(key))
 				}
+				if contentEncoding != payloadContentEncoding {
+					if contentEncoding == "" {
+						t.Headers.Del(contentEncodingHTTPHeaderKey)
+					} else {
+						t.Headers.Set(contentEncodingHTTPHeaderKey, contentEncoding)
+					}
+				}
 				transactions = append(transactions, t)
 			}
 		}
@@ -413,6 +447,36 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 	return transactions
 }
 
+// payloadForDomain returns the payload to send to the given domain along with its
+// content encoding. Compressed payloads are re-compressed when the domain is configured
+// to use a different compression codec, unless the result would be larger than the
+// maximum payload size. Other payloads are returned as is.
+func (f *DefaultForwarder) payloadForDomain(domain string, payload *[]byte, contentEncoding string) (*[]byte, string) {
+	codec, ok := f.codecPerDomain[domain]
+	if !ok || contentEncoding == "" || codec.ContentEncoding() == contentEncoding {
+		return payload, contentEncoding
+	}
+
+	payloadCodec, err := compression.GetCodecForContentEncoding(contentEncoding)
+	if err != nil {
+		log.Warnf("Cannot re-compress payload for domain '%s', sending it as is: %v", domain, err)
+		return payload, contentEncoding
+	}
+
+	transcoded, err := compression.Transcode(payloadCodec, codec, *payload)
+	if err != nil {
+		log.Warnf("Cannot re-compress payload for domain '%s', sending it as is: %v", domain, err)
+		return payload, contentEncoding
+	}
+	// The payloads were split by the serializer to fit in the intake size limit once
+	// compressed with its codec, a weaker codec (e.g. none) can exceed it.
+	if f.maxPayloadSize > 0 && len(transcoded) > f.maxPayloadSize {
+		log.Debugf("Payload for domain '%s' exceeds %d bytes once re-compressed with the %q codec, sending it as is", domain, f.maxPayloadSize, codec.Name())
+		return payload, contentEncoding
+	}
+	return &transcoded, codec.ContentEncoding()
+}
+
 func (f *DefaultForwarder) sendHTTPTransactions(transactions []*transaction.HTTPTransaction) error {
 	if atomic.LoadUint32(&f.internalState) == Stopped {
 		return fmt.Errorf("the forwarder is not started")
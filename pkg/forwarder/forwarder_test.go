@@ -6,6 +6,7 @@
 package forwarder
 
 import (
+	"bytes"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
 
@@ -202,6 +204,76 @@ func TestCreateHTTPTransactionsWithMultipleDomains(t *testing.T) {
 	assert.Equal(t, txBar[0].Endpoint.Route, "/api/foo?api_key=api-key-3")
 }
 
+func TestCreateHTTPTransactionsWithCompressionPerDomain(t *testing.T) {
+	zlibCodec, err := compression.GetCodec(compression.ZlibKind)
+	require.NoError(t, err)
+
+	options := NewOptions(keysWithMultipleDomains)
+	options.CompressionKindPerDomain = map[string]string{"datadog.bar": compression.NoneKind}
+	forwarder := NewDefaultForwarder(options)
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	p1 := []byte("A payload")
+
+	// uncompressed payloads are sent as is
+	transactions := forwarder.createHTTPTransactions(endpoint, Payloads{&p1}, false, make(http.Header))
+	require.Len(t, transactions, 3)
+	for _, tx := range transactions {
+		assert.Equal(t, p1, *tx.Payload)
+		assert.Equal(t, "", tx.Headers.Get("Content-Encoding"))
+	}
+
+	compressed, err := zlibCodec.Compress(nil, p1)
+	require.NoError(t, err)
+	headers := make(http.Header)
+	headers.Set("Content-Encoding", zlibCodec.ContentEncoding())
+
+	transactions = forwarder.createHTTPTransactions(endpoint, Payloads{&compressed}, false, headers)
+	require.Len(t, transactions, 3)
+	for _, tx := range transactions {
+		if tx.Domain == "datadog.bar" {
+			assert.Equal(t, p1, *tx.Payload)
+			assert.Equal(t, "", tx.Headers.Get("Content-Encoding"))
+		} else {
+			assert.Equal(t, compressed, *tx.Payload)
+			assert.Equal(t, zlibCodec.ContentEncoding(), tx.Headers.Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestCreateHTTPTransactionsWithCompressionPerDomainMaxPayloadSize(t *testing.T) {
+	zlibCodec, err := compression.GetCodec(compression.ZlibKind)
+	require.NoError(t, err)
+
+	options := NewOptions(keysWithMultipleDomains)
+	options.CompressionKindPerDomain = map[string]string{"datadog.bar": compression.NoneKind}
+	options.MaxPayloadSize = 100
+	forwarder := NewDefaultForwarder(options)
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+
+	headers := make(http.Header)
+	headers.Set("Content-Encoding", zlibCodec.ContentEncoding())
+	small, err := zlibCodec.Compress(nil, bytes.Repeat([]byte("A"), 50))
+	require.NoError(t, err)
+	// the large payload compresses well below the limit but exceeds it uncompressed
+	large, err := zlibCodec.Compress(nil, bytes.Repeat([]byte("A"), 1000))
+	require.NoError(t, err)
+	require.True(t, len(large) < options.MaxPayloadSize)
+
+	transactions := forwarder.createHTTPTransactions(endpoint, Payloads{&small, &large}, false, headers)
+	require.Len(t, transactions, 6)
+	for _, tx := range transactions {
+		if tx.Domain != "datadog.bar" {
+			continue
+		}
+		if len(*tx.Payload) == 50 {
+			assert.Equal(t, "", tx.Headers.Get("Content-Encoding"))
+		} else {
+			assert.Equal(t, large, *tx.Payload)
+			assert.Equal(t, zlibCodec.ContentEncoding(), tx.Headers.Get("Content-Encoding"))
+		}
+	}
+}
+
 func TestArbitraryTagsHTTPHeader(t *testing.T) {
 	mockConfig := config.Mock()
 	mockConfig.Set("allow_arbitrary_tags", true)
@@ -12,10 +12,11 @@ import (
 )
 
 type diskUsageLimit struct {
-	diskPath       string
-	maxSizeInBytes int64
-	disk           diskUsageRetriever
-	maxDiskRatio   float64
+	diskPath              string
+	maxSizeInBytes        int64
+	maxSizeInBytesPerKind map[string]int64
+	disk                  diskUsageRetriever
+	maxDiskRatio          float64
 }
 
 type diskUsageRetriever interface {
@@ -26,12 +27,14 @@ func newDiskUsageLimit(
 	diskPath string,
 	disk diskUsageRetriever,
 	maxSizeInBytes int64,
+	maxSizeInBytesPerKind map[string]int64,
 	maxDiskRatio float64) *diskUsageLimit {
 	return &diskUsageLimit{
-		diskPath:       diskPath,
-		maxSizeInBytes: maxSizeInBytes,
-		disk:           disk,
-		maxDiskRatio:   maxDiskRatio,
+		diskPath:              diskPath,
+		maxSizeInBytes:        maxSizeInBytes,
+		maxSizeInBytesPerKind: maxSizeInBytesPerKind,
+		disk:                  disk,
+		maxDiskRatio:          maxDiskRatio,
 	}
 }
 
@@ -50,6 +53,12 @@ func (s *diskUsageLimit) getMaxSizeInBytes() int64 {
 	return s.maxSizeInBytes
 }
 
+// getMaxSizeInBytesForKind returns the quota of a kind of transactions, if any.
+func (s *diskUsageLimit) getMaxSizeInBytesForKind(kind string) (int64, bool) {
+	maxSizeInBytes, found := s.maxSizeInBytesPerKind[kind]
+	return maxSizeInBytes, found
+}
+
 func minInt64(v1, v2 int64) int64 {
 	if v1 < v2 {
 		return v1
@@ -12,22 +12,44 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
-	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/hashicorp/go-multierror"
 )
 
 const retryTransactionsExtension = ".retry"
 const retryFileFormat = "2006_01_02__15_04_05_"
 
+// Reasons why transactions are dropped from the retry queue.
+const (
+	dropReasonMemoryLimit   = "memory_limit"
+	dropReasonPayloadTooBig = "payload_too_big"
+	dropReasonKindQuota     = "endpoint_quota"
+	dropReasonDiskLimit     = "disk_limit"
+)
+
+// retryFile is a file of the on-disk retry queue. All the transactions of a file share
+// the same kind (their endpoint name) and the same priority, which are stored in the
+// file name with the number of transactions.
+type retryFile struct {
+	path              string
+	sizeInBytes       int64
+	kind              string
+	priority          transaction.Priority
+	transactionsCount int
+}
+
 type onDiskRetryQueue struct {
 	serializer         *HTTPTransactionsSerializer
 	storagePath        string
 	diskUsageLimit     *diskUsageLimit
-	filenames          []string
+	files              []retryFile
 	currentSizeInBytes int64
+	sizeInBytesPerKind map[string]int64
 	telemetry          onDiskRetryQueueTelemetry
 }
 
@@ -42,10 +64,11 @@ func newOnDiskRetryQueue(
 	}
 
 	storage := &onDiskRetryQueue{
-		serializer:     serializer,
-		storagePath:    storagePath,
-		diskUsageLimit: diskUsageLimit,
-		telemetry:      telemetry,
+		serializer:         serializer,
+		storagePath:        storagePath,
+		diskUsageLimit:     diskUsageLimit,
+		sizeInBytesPerKind: make(map[string]int64),
+		telemetry:          telemetry,
 	}
 
 	if err := storage.reloadExistingRetryFiles(); err != nil {
@@ -60,9 +83,20 @@ func newOnDiskRetryQueue(
 }
 
 // Serialize serializes transactions to the file system.
+// One file is created for each kind and priority of transactions.
 func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) error {
 	s.telemetry.addSerializeCount()
 
+	var errs error
+	for _, group := range groupByKindAndPriority(transactions) {
+		if err := s.serializeGroup(group); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (s *onDiskRetryQueue) serializeGroup(transactions []transaction.Transaction) error {
 	// Reset the serializer in case some transactions were serialized
 	// but `GetBytesAndReset` was not called because of an error.
 	_, _ = s.serializer.GetBytesAndReset()
@@ -78,13 +112,18 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 		return err
 	}
 	bufferSize := int64(len(bytes))
+	retryFile := retryFile{
+		sizeInBytes:       bufferSize,
+		kind:              transactions[0].GetEndpointName(),
+		priority:          transactions[0].GetPriority(),
+		transactionsCount: len(transactions),
+	}
 
-	if err := s.makeRoomFor(bufferSize); err != nil {
+	if err := s.makeRoomFor(retryFile); err != nil {
 		return err
 	}
 
-	filename := time.Now().UTC().Format(retryFileFormat)
-	file, err := ioutil.TempFile(s.storagePath, filename+"*"+retryTransactionsExtension)
+	file, err := ioutil.TempFile(s.storagePath, retryFile.filenamePattern(time.Now()))
 	if err != nil {
 		return err
 	}
@@ -95,8 +134,8 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 	}
 	defer file.Close()
 
-	s.currentSizeInBytes += bufferSize
-	s.filenames = append(s.filenames, file.Name())
+	retryFile.path = file.Name()
+	s.addFile(retryFile)
 	s.telemetry.setFileSize(bufferSize)
 	s.telemetry.setCurrentSizeInBytes(s.getCurrentSizeInBytes())
 	s.telemetry.setFilesCount(s.getFilesCount())
@@ -105,12 +144,12 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 
 // Deserialize deserializes a transactions from the file system.
 func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
-	if len(s.filenames) == 0 {
+	if len(s.files) == 0 {
 		return nil, nil
 	}
 	s.telemetry.addDeserializeCount()
-	index := len(s.filenames) - 1
-	path := s.filenames[index]
+	index := len(s.files) - 1
+	path := s.files[index].path
 	bytes, err := ioutil.ReadFile(path)
 
 	// Remove the file even in case of a read failure.
@@ -135,7 +174,7 @@ func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
 
 // GetFileCount returns the current files count.
 func (s *onDiskRetryQueue) getFilesCount() int {
-	return len(s.filenames)
+	return len(s.files)
 }
 
 // getCurrentSizeInBytes returns the current disk space used.
@@ -143,81 +182,184 @@ func (s *onDiskRetryQueue) getCurrentSizeInBytes() int64 {
 	return s.currentSizeInBytes
 }
 
-func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
+// makeRoomFor removes files until there is enough room for the new file, according to
+// the quota of its kind and to the disk usage limit. The files with the lowest priority
+// are removed first, from the oldest to the newest, and files with a priority higher than
+// the new file are never removed: the new file is dropped instead.
+func (s *onDiskRetryQueue) makeRoomFor(file retryFile) error {
 	maxSizeInBytes := s.diskUsageLimit.getMaxSizeInBytes()
-	if bufferSize > maxSizeInBytes {
-		return fmt.Errorf("The payload is too big. Current:%v Maximum:%v", bufferSize, maxSizeInBytes)
+	if file.sizeInBytes > maxSizeInBytes {
+		s.telemetry.addTransactionsDroppedCount(file, dropReasonPayloadTooBig)
+		return fmt.Errorf("The payload is too big. Current:%v Maximum:%v", file.sizeInBytes, maxSizeInBytes)
+	}
+
+	if maxKindSizeInBytes, found := s.diskUsageLimit.getMaxSizeInBytesForKind(file.kind); found {
+		if file.sizeInBytes > maxKindSizeInBytes {
+			s.telemetry.addTransactionsDroppedCount(file, dropReasonPayloadTooBig)
+			return fmt.Errorf("The payload is too big for the quota of %s. Current:%v Maximum:%v", file.kind, file.sizeInBytes, maxKindSizeInBytes)
+		}
+		sameKindAndPriorityOrLower := func(f retryFile) bool { return f.kind == file.kind && f.priority <= file.priority }
+		for s.sizeInBytesPerKind[file.kind]+file.sizeInBytes > maxKindSizeInBytes {
+			index := s.getFileIndexToEvict(sameKindAndPriorityOrLower)
+			if index < 0 {
+				s.telemetry.addTransactionsDroppedCount(file, dropReasonKindQuota)
+				return fmt.Errorf("Maximum disk space for retry transactions of %s is reached by transactions with a higher priority", file.kind)
+			}
+			log.Infof("Maximum disk space for retry transactions of %s is reached. Removing %s", file.kind, s.files[index].path)
+			if err := s.evictFileAt(index, dropReasonKindQuota); err != nil {
+				return err
+			}
+		}
 	}
 
 	maxStorageInBytes, err := s.diskUsageLimit.computeAvailableSpace(s.currentSizeInBytes)
 	if err != nil {
 		return err
 	}
-	for len(s.filenames) > 0 && s.currentSizeInBytes+bufferSize > maxStorageInBytes {
-		index := 0
-		filename := s.filenames[index]
-		log.Infof("Maximum disk space for retry transactions is reached. Removing %s", filename)
-		if err := s.removeFileAt(index); err != nil {
+	samePriorityOrLower := func(f retryFile) bool { return f.priority <= file.priority }
+	for len(s.files) > 0 && s.currentSizeInBytes+file.sizeInBytes > maxStorageInBytes {
+		index := s.getFileIndexToEvict(samePriorityOrLower)
+		if index < 0 {
+			s.telemetry.addTransactionsDroppedCount(file, dropReasonDiskLimit)
+			return fmt.Errorf("Maximum disk space for retry transactions is reached by transactions with a higher priority than %s", file.kind)
+		}
+		log.Infof("Maximum disk space for retry transactions is reached. Removing %s", s.files[index].path)
+		if err := s.evictFileAt(index, dropReasonDiskLimit); err != nil {
 			return err
 		}
-		s.telemetry.addFilesRemovedCount()
 	}
 
 	return nil
 }
 
-func (s *onDiskRetryQueue) removeFileAt(index int) error {
-	filename := s.filenames[index]
-
-	// Remove the file from s.filenames also in case of error to not
-	// fail on the next call.
-	s.filenames = append(s.filenames[:index], s.filenames[index+1:]...)
-
-	size, err := util.GetFileSize(filename)
-	if err != nil {
-		return err
+// getFileIndexToEvict returns the index of the oldest file with the lowest priority
+// among the files matching canEvict, or -1 if there is none.
+func (s *onDiskRetryQueue) getFileIndexToEvict(canEvict func(retryFile) bool) int {
+	index := -1
+	for i, file := range s.files {
+		if canEvict(file) && (index < 0 || file.priority < s.files[index].priority) {
+			index = i
+		}
 	}
+	return index
+}
 
-	if err := os.Remove(filename); err != nil {
+func (s *onDiskRetryQueue) evictFileAt(index int, reason string) error {
+	file := s.files[index]
+	if err := s.removeFileAt(index); err != nil {
 		return err
 	}
-
-	s.currentSizeInBytes -= size
+	s.telemetry.addFilesRemovedCount()
+	s.telemetry.addTransactionsDroppedCount(file, reason)
 	return nil
 }
 
+func (s *onDiskRetryQueue) addFile(file retryFile) {
+	s.files = append(s.files, file)
+	s.currentSizeInBytes += file.sizeInBytes
+	s.sizeInBytesPerKind[file.kind] += file.sizeInBytes
+}
+
+func (s *onDiskRetryQueue) removeFileAt(index int) error {
+	file := s.files[index]
+
+	// Remove the file from s.files also in case of error to not
+	// fail on the next call.
+	s.files = append(s.files[:index], s.files[index+1:]...)
+	s.currentSizeInBytes -= file.sizeInBytes
+	s.sizeInBytesPerKind[file.kind] -= file.sizeInBytes
+
+	return os.Remove(file.path)
+}
+
 func (s *onDiskRetryQueue) reloadExistingRetryFiles() error {
-	files, sizeInBytes, err := s.getExistingRetryFiles()
+	files, err := s.getExistingRetryFiles()
 	if err != nil {
 		return err
 	}
-	s.currentSizeInBytes = sizeInBytes
 
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].ModTime().Before(files[j].ModTime())
 	})
-	var filenames []string
 	for _, file := range files {
 		fullPath := path.Join(s.storagePath, file.Name())
-		filenames = append(filenames, fullPath)
+		s.addFile(parseRetryFilename(fullPath, file.Size()))
 	}
-	s.telemetry.setReloadedRetryFilesCount(len(filenames))
-	s.filenames = append(s.filenames, filenames...)
+	s.telemetry.setReloadedRetryFilesCount(len(files))
 	return nil
 }
 
-func (s *onDiskRetryQueue) getExistingRetryFiles() ([]os.FileInfo, int64, error) {
+func (s *onDiskRetryQueue) getExistingRetryFiles() ([]os.FileInfo, error) {
 	entries, err := ioutil.ReadDir(s.storagePath)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	var files []os.FileInfo
-	currentSizeInBytes := int64(0)
 	for _, entry := range entries {
 		if entry.Mode().IsRegular() && filepath.Ext(entry.Name()) == retryTransactionsExtension {
-			currentSizeInBytes += entry.Size()
 			files = append(files, entry)
 		}
 	}
-	return files, currentSizeInBytes, nil
+	return files, nil
+}
+
+// filenamePattern returns the pattern of the file name, for `ioutil.TempFile`:
+// `<time>_<priority>-<transactions count>-<kind>-<random>.retry`
+func (f retryFile) filenamePattern(now time.Time) string {
+	return fmt.Sprintf("%s%d-%d-%s-*%s",
+		now.UTC().Format(retryFileFormat),
+		f.priority,
+		f.transactionsCount,
+		f.kind,
+		retryTransactionsExtension)
+}
+
+// parseRetryFilename reads the kind, the priority and the transactions count of a retry file
+// from its name. The files written by previous versions of the Agent only contain the time
+// and are considered as an unknown kind with a normal priority.
+func parseRetryFilename(fullPath string, sizeInBytes int64) retryFile {
+	file := retryFile{path: fullPath, sizeInBytes: sizeInBytes}
+
+	name := strings.TrimSuffix(filepath.Base(fullPath), retryTransactionsExtension)
+	if len(name) <= len(retryFileFormat) {
+		return file
+	}
+	fields := strings.SplitN(name[len(retryFileFormat):], "-", 4)
+	if len(fields) != 4 {
+		return file
+	}
+	priority, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return file
+	}
+	transactionsCount, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return file
+	}
+
+	file.priority = transaction.Priority(priority)
+	file.transactionsCount = transactionsCount
+	file.kind = fields[2]
+	return file
+}
+
+// groupByKindAndPriority splits transactions by kind and priority, keeping their order.
+func groupByKindAndPriority(transactions []transaction.Transaction) [][]transaction.Transaction {
+	type groupKey struct {
+		kind     string
+		priority transaction.Priority
+	}
+	var groups [][]transaction.Transaction
+	indexes := make(map[groupKey]int)
+	for _, t := range transactions {
+		key := groupKey{kind: t.GetEndpointName(), priority: t.GetPriority()}
+		index, found := indexes[key]
+		if !found {
+			index = len(groups)
+			indexes[key] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], t)
+	}
+	return groups
 }
@@ -90,13 +90,146 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
-func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
+func TestOnDiskRetryQueueKindQuota(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	q := newTestOnDiskRetryQueue(a, path, 1000)
+	a.NoError(q.Serialize(createTransactionsOfKind("metadata", transaction.TransactionPriorityNormal, "1")))
+	fileSize := q.getCurrentSizeInBytes()
+	q.diskUsageLimit.maxSizeInBytesPerKind = map[string]int64{"metadata": fileSize * 3 / 2}
+
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityNormal, "2")))
+	a.NoError(q.Serialize(createTransactionsOfKind("metadata", transaction.TransactionPriorityNormal, "3")))
+	a.Equal([]string{"series", "metadata"}, getKindsFromFiles(q))
+
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"3"}, getEndpointsFromTransactions(transactions))
+	transactions, err = q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"2"}, getEndpointsFromTransactions(transactions))
+	a.Equal(0, q.getFilesCount())
+}
+
+func TestOnDiskRetryQueuePriorityEviction(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	q := newTestOnDiskRetryQueue(a, path, 1000)
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityHigh, "high1")))
+	q.diskUsageLimit.maxSizeInBytes = 3 * q.getCurrentSizeInBytes()
+
+	a.NoError(q.Serialize(createTransactionsOfKind("metadata", transaction.TransactionPriorityNormal, "norm1")))
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityHigh, "high2")))
+
+	// The oldest transactions with the lowest priority are dropped first.
+	a.NoError(q.Serialize(createTransactionsOfKind("metadata", transaction.TransactionPriorityNormal, "norm2")))
+	a.Equal([]string{"high1", "high2", "norm2"}, getRoutesFromFiles(a, q))
+
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityHigh, "high3")))
+	a.Equal([]string{"high1", "high2", "high3"}, getRoutesFromFiles(a, q))
+
+	// Transactions with a higher priority are never dropped for transactions with a lower priority.
+	a.Error(q.Serialize(createTransactionsOfKind("metadata", transaction.TransactionPriorityNormal, "norm3")))
+	a.Equal([]string{"high1", "high2", "high3"}, getRoutesFromFiles(a, q))
+}
+
+func TestOnDiskRetryQueueKindQuotaPriorityEviction(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	q := newTestOnDiskRetryQueue(a, path, 1000)
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityHigh, "high1")))
+	q.diskUsageLimit.maxSizeInBytesPerKind = map[string]int64{"series": 3 * q.getCurrentSizeInBytes()}
+
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityNormal, "norm1")))
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityHigh, "high2")))
+
+	// The oldest transactions of the kind with the lowest priority are dropped first.
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityNormal, "norm2")))
+	a.Equal([]string{"high1", "high2", "norm2"}, getRoutesFromFiles(a, q))
+
+	a.NoError(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityHigh, "high3")))
+	a.Equal([]string{"high1", "high2", "high3"}, getRoutesFromFiles(a, q))
+
+	// Transactions with a higher priority are never dropped for transactions with a lower priority.
+	a.Error(q.Serialize(createTransactionsOfKind("series", transaction.TransactionPriorityNormal, "norm3")))
+	a.Equal([]string{"high1", "high2", "high3"}, getRoutesFromFiles(a, q))
+}
+
+func TestOnDiskRetryQueueReloadKindAndPriority(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	q := newTestOnDiskRetryQueue(a, path, 1000)
+	transactions := createTransactionsOfKind("series_v2", transaction.TransactionPriorityHigh, "1", "2")
+	transactions = append(transactions, createTransactionsOfKind("metadata_v2", transaction.TransactionPriorityNormal, "3")...)
+	a.NoError(q.Serialize(transactions))
+	a.Equal(2, q.getFilesCount())
+
+	newQueue := newTestOnDiskRetryQueue(a, path, 1000)
+	a.ElementsMatch(q.files, newQueue.files)
+	a.Equal(q.sizeInBytesPerKind, newQueue.sizeInBytesPerKind)
+	for _, file := range newQueue.files {
+		if file.kind == "series_v2" {
+			a.Equal(transaction.TransactionPriorityHigh, file.priority)
+			a.Equal(2, file.transactionsCount)
+		} else {
+			a.Equal("metadata_v2", file.kind)
+			a.Equal(transaction.TransactionPriorityNormal, file.priority)
+			a.Equal(1, file.transactionsCount)
+		}
+	}
+}
+
+func TestParseRetryFilenameFromPreviousVersion(t *testing.T) {
+	file := parseRetryFilename("/tmp/2021_06_01__10_00_00_123456.retry", 10)
+	assert.Equal(t, retryFile{path: "/tmp/2021_06_01__10_00_00_123456.retry", sizeInBytes: 10}, file)
+}
+
+func createTransactionsOfKind(kind string, priority transaction.Priority, routes ...string) []transaction.Transaction {
+	transactions := createHTTPTransactionCollectionTests(routes...)
+	for _, t := range transactions {
+		httpTransaction := t.(*transaction.HTTPTransaction)
+		httpTransaction.Endpoint.Name = kind
+		httpTransaction.Priority = priority
+	}
+	return transactions
+}
+
+func getKindsFromFiles(q *onDiskRetryQueue) []string {
+	var kinds []string
+	for _, file := range q.files {
+		kinds = append(kinds, file.kind)
+	}
+	return kinds
+}
+
+func getRoutesFromFiles(a *assert.Assertions, q *onDiskRetryQueue) []string {
+	var routes []string
+	for _, file := range q.files {
+		transactions, _, err := ReadRetryFile(file.path, domainName, nil)
+		a.NoError(err)
+		routes = append(routes, getEndpointsFromTransactions(transactions)...)
+	}
+	return routes
+}
+
+// createHTTPTransactionCollectionTests creates transactions of the same kind, identified by their route,
+// as transactions of different kinds are stored in different files.
+func createHTTPTransactionCollectionTests(routes ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
-	for _, d := range endpoints {
+	for _, r := range routes {
 		t := transaction.NewHTTPTransaction()
 		t.Domain = domainName
-		t.Endpoint.Name = d
+		t.Endpoint.Name = "endpoint"
+		t.Endpoint.Route = r
 		transactions = append(transactions, t)
 	}
 	return transactions
@@ -112,7 +245,7 @@ func getEndpointsFromTransactions(transactions []transaction.Transaction) []stri
 	var endpoints []string
 	for _, t := range transactions {
 		httpTransaction := t.(*transaction.HTTPTransaction)
-		endpoints = append(endpoints, httpTransaction.Endpoint.Name)
+		endpoints = append(endpoints, httpTransaction.Endpoint.Route)
 	}
 	return endpoints
 }
@@ -124,7 +257,7 @@ func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes i
 			Available: 10000,
 			Total:     10000,
 		}}
-	diskUsageLimit := newDiskUsageLimit("", disk, maxSizeInBytes, 1)
+	diskUsageLimit := newDiskUsageLimit("", disk, maxSizeInBytes, nil, 1)
 	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(domainName, nil), path, diskUsageLimit, telemetry)
 	a.NoError(err)
 	return storage
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+// RetryFile describes a file of the on-disk retry queue.
+type RetryFile struct {
+	Path         string
+	DomainFolder string
+	SizeInBytes  int64
+	ModTime      time.Time
+}
+
+// TransactionInfo describes a transaction stored in a retry file.
+// The API keys are replaced by placeholders.
+type TransactionInfo struct {
+	EndpointName    string
+	Route           string
+	ContentEncoding string
+	PayloadSize     int
+	ErrorCount      int
+	CreatedAt       time.Time
+	Retryable       bool
+	Priority        transaction.Priority
+}
+
+// ListRetryFiles returns the retry files stored in the domain folders of rootPath,
+// from the oldest to the newest.
+func ListRetryFiles(rootPath string) ([]RetryFile, error) {
+	domains, err := ioutil.ReadDir(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []RetryFile
+	for _, domain := range domains {
+		if !domain.Mode().IsDir() {
+			continue
+		}
+		folderPath := path.Join(rootPath, domain.Name())
+		entries, err := ioutil.ReadDir(folderPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() && filepath.Ext(entry.Name()) == retryTransactionsExtension {
+				files = append(files, RetryFile{
+					Path:         path.Join(folderPath, entry.Name()),
+					DomainFolder: domain.Name(),
+					SizeInBytes:  entry.Size(),
+					ModTime:      entry.ModTime(),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime.Before(files[j].ModTime)
+	})
+	return files, nil
+}
+
+// InspectRetryFile returns a description of the transactions stored in a retry file
+// without restoring their API keys.
+func InspectRetryFile(filePath string) ([]TransactionInfo, error) {
+	bytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := HttpTransactionProtoCollection{}
+	if err := proto.Unmarshal(bytes, &collection); err != nil {
+		return nil, err
+	}
+
+	infos := make([]TransactionInfo, 0, len(collection.Values))
+	for _, tr := range collection.Values {
+		info := TransactionInfo{
+			PayloadSize: len(tr.Payload),
+			ErrorCount:  int(tr.ErrorCount),
+			CreatedAt:   time.Unix(tr.CreatedAt, 0),
+			Retryable:   tr.Retryable,
+		}
+		if tr.Endpoint != nil {
+			info.EndpointName = tr.Endpoint.Name
+			info.Route = maskAPIKeyPlaceholders(tr.Endpoint.Route)
+		}
+		if values, ok := tr.Headers["Content-Encoding"]; ok && len(values.Values) > 0 {
+			info.ContentEncoding = values.Values[0]
+		}
+		// An unknown priority is reported as a normal one, like when deserializing.
+		info.Priority, _ = fromTransactionPriorityProto(tr.Priority)
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// ReadRetryFile deserializes the transactions stored in a retry file. apiKeys must
+// be the API keys of the domain the file was stored for, the transactions whose API
+// keys cannot be restored are skipped and counted in the returned error count.
+func ReadRetryFile(filePath string, domain string, apiKeys []string) ([]transaction.Transaction, int, error) {
+	bytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return NewHTTPTransactionsSerializer(domain, apiKeys).Deserialize(bytes)
+}
+
+// maskAPIKeyPlaceholders makes the API key placeholders readable, for instance
+// as `<api_key_0>`.
+func maskAPIKeyPlaceholders(str string) string {
+	str = strings.ReplaceAll(str, placeHolderPrefix, "<api_key_")
+	return strings.ReplaceAll(str, squareChar, ">")
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAndInspectRetryFiles(t *testing.T) {
+	a := assert.New(t)
+	root, _ := createTmpFolder(a)
+	defer os.RemoveAll(root)
+
+	apiKeys := []string{"apiKey1"}
+	folder, err := DomainFolderName(domainName)
+	a.NoError(err)
+	folderPath := path.Join(root, folder)
+	a.NoError(os.MkdirAll(folderPath, 0755))
+
+	tr := transaction.NewHTTPTransaction()
+	tr.Domain = domainName
+	tr.Endpoint.Name = "series"
+	tr.Endpoint.Route = "/api/v1/series?api_key=apiKey1"
+	tr.Headers.Set("Content-Encoding", "deflate")
+	payload := []byte("payload")
+	tr.Payload = &payload
+	tr.ErrorCount = 2
+
+	serializer := NewHTTPTransactionsSerializer(domainName, apiKeys)
+	a.NoError(serializer.Add(tr))
+	bytes, err := serializer.GetBytesAndReset()
+	a.NoError(err)
+	filePath := path.Join(folderPath, "1"+retryTransactionsExtension)
+	a.NoError(ioutil.WriteFile(filePath, bytes, 0600))
+	a.NoError(ioutil.WriteFile(path.Join(folderPath, "ignored.txt"), bytes, 0600))
+
+	files, err := ListRetryFiles(root)
+	a.NoError(err)
+	a.Len(files, 1)
+	a.Equal(filePath, files[0].Path)
+	a.Equal(folder, files[0].DomainFolder)
+	a.Equal(int64(len(bytes)), files[0].SizeInBytes)
+
+	infos, err := InspectRetryFile(filePath)
+	a.NoError(err)
+	a.Len(infos, 1)
+	a.Equal("series", infos[0].EndpointName)
+	a.Equal("/api/v1/series?api_key=<api_key_0>", infos[0].Route)
+	a.Equal("deflate", infos[0].ContentEncoding)
+	a.Equal(len(payload), infos[0].PayloadSize)
+	a.Equal(2, infos[0].ErrorCount)
+
+	transactions, errorCount, err := ReadRetryFile(filePath, domainName, apiKeys)
+	a.NoError(err)
+	a.Equal(0, errorCount)
+	a.Len(transactions, 1)
+	a.Equal("/api/v1/series?api_key=apiKey1", transactions[0].(*transaction.HTTPTransaction).Endpoint.Route)
+
+	_, errorCount, err = ReadRetryFile(filePath, domainName, nil)
+	a.NoError(err)
+	a.Equal(1, errorCount)
+}
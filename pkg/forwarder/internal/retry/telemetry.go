@@ -62,6 +62,7 @@ var (
 	currentMemSizeInBytesTelemetry    *gaugeExpvar
 	transactionsCountTelemetry        *gaugeExpvar
 	transactionsDroppedCountTelemetry *counterExpvar
+	droppedTransactionsTelemetry      *counterExpvar
 	errorsCountTelemetry              *counterExpvar
 
 	fileStorageExpvar                       = expvar.Map{}
@@ -123,6 +124,12 @@ func init() {
 		domainTag,
 		"The number of transactions dropped because the retry queue is full",
 		&transactionContainerExpvar)
+	droppedTransactionsTelemetry = newCounterExpvar(
+		"transaction_container",
+		"dropped_transactions_count",
+		[]string{"domain", "endpoint", "priority", "reason"},
+		"The number of transactions dropped from the retry queue in memory or on disk, by endpoint, priority and reason",
+		&transactionContainerExpvar)
 	errorsCountTelemetry = newCounterExpvar(
 		"transaction_container",
 		"errors_count",
@@ -229,6 +236,12 @@ func (t TransactionRetryQueueTelemetry) addTransactionsDroppedCount(count int) {
 	transactionsDroppedCountTelemetry.add(float64(count), t.domainName)
 }
 
+func (t TransactionRetryQueueTelemetry) addDroppedTransactions(transactions []transaction.Transaction, reason string) {
+	for _, tr := range transactions {
+		droppedTransactionsTelemetry.add(1, t.domainName, tr.GetEndpointName(), priorityTag(tr.GetPriority()), reason)
+	}
+}
+
 func (t TransactionRetryQueueTelemetry) incErrorsCount() {
 	errorsCountTelemetry.add(1, t.domainName)
 }
@@ -271,6 +284,10 @@ func (t onDiskRetryQueueTelemetry) addFilesRemovedCount() {
 	filesRemovedCountTelemetry.add(1, t.domainName)
 }
 
+func (t onDiskRetryQueueTelemetry) addTransactionsDroppedCount(file retryFile, reason string) {
+	droppedTransactionsTelemetry.add(float64(file.transactionsCount), t.domainName, file.kind, priorityTag(file.priority), reason)
+}
+
 func (t onDiskRetryQueueTelemetry) addDeserializeErrorsCount(count int) {
 	deserializeErrorsCountTelemetry.add(float64(count), t.domainName)
 }
@@ -279,6 +296,13 @@ func (t onDiskRetryQueueTelemetry) addDeserializeTransactionsCount(count int) {
 	deserializeTransactionsCountTelemetry.add(float64(count), t.domainName)
 }
 
+func priorityTag(priority transaction.Priority) string {
+	if priority == transaction.TransactionPriorityHigh {
+		return "high"
+	}
+	return "normal"
+}
+
 func toCamelCase(s string) string {
 	parts := strings.Split(s, "_")
 	var camelCase string
@@ -7,6 +7,7 @@ package retry
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
@@ -56,7 +57,7 @@ func BuildTransactionRetryQueue(
 		serializer := NewHTTPTransactionsSerializer(domain, apiKeys)
 		diskRatio := config.Datadog.GetFloat64("forwarder_storage_max_disk_ratio")
 
-		diskUsageLimit := newDiskUsageLimit(optionalDomainFolderPath, filesystem.NewDisk(), storageMaxSize, diskRatio)
+		diskUsageLimit := newDiskUsageLimit(optionalDomainFolderPath, filesystem.NewDisk(), storageMaxSize, getStorageMaxSizePerEndpoint(), diskRatio)
 		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, diskUsageLimit, newOnDiskRetryQueueTelemetry(domain))
 
 		// If the storage on disk cannot be used, log the error and continue.
@@ -74,6 +75,20 @@ func BuildTransactionRetryQueue(
 		NewTransactionRetryQueueTelemetry(domain))
 }
 
+// getStorageMaxSizePerEndpoint returns the disk quotas of the endpoints from `forwarder_storage_max_size_in_bytes_per_endpoint`.
+func getStorageMaxSizePerEndpoint() map[string]int64 {
+	maxSizePerEndpoint := make(map[string]int64)
+	for endpoint, value := range config.Datadog.GetStringMapString("forwarder_storage_max_size_in_bytes_per_endpoint") {
+		maxSize, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || maxSize <= 0 {
+			log.Errorf("Invalid value for the retry queue disk quota of the endpoint %s: %v", endpoint, value)
+			continue
+		}
+		maxSizePerEndpoint[endpoint] = maxSize
+	}
+	return maxSizePerEndpoint
+}
+
 // NewTransactionRetryQueue creates a new instance of NewTransactionRetryQueue
 func NewTransactionRetryQueue(
 	dropPrioritySorter TransactionPrioritySorter,
@@ -126,6 +141,7 @@ func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
 		transactions := tc.extractTransactionsFromMemory(payloadSizeInBytesToDrop)
 		inMemTransactionDroppedCount = len(transactions)
 		tc.telemetry.addTransactionsDroppedCount(inMemTransactionDroppedCount)
+		tc.telemetry.addDroppedTransactions(transactions, dropReasonMemoryLimit)
 	}
 
 	tc.transactions = append(tc.transactions, t)
@@ -3,6 +3,7 @@ package retry
 import (
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
 	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
 	"github.com/stretchr/testify/assert"
@@ -96,6 +97,19 @@ func TestTransactionRetryQueueZeroMaxMemSizeInBytes(t *testing.T) {
 	a.Equal(1, inMemTrDropped)
 }
 
+func TestGetStorageMaxSizePerEndpoint(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_storage_max_size_in_bytes_per_endpoint", map[string]interface{}{
+		"series_v2":   100,
+		"metadata_v2": "200",
+		"sketches_v2": "invalid",
+		"intake":      -1,
+	})
+	defer mockConfig.Set("forwarder_storage_max_size_in_bytes_per_endpoint", map[string]int64{})
+
+	assert.Equal(t, map[string]int64{"series_v2": 100, "metadata_v2": 200}, getStorageMaxSizePerEndpoint())
+}
+
 func createTransactionWithPayloadSize(payloadSize int) *transaction.HTTPTransaction {
 	tr := transaction.NewHTTPTransaction()
 	payload := make([]byte, payloadSize)
@@ -130,7 +144,7 @@ func newOnDiskRetryQueueTest(a *assert.Assertions) (*onDiskRetryQueue, func()) {
 			Available: 10000,
 			Total:     10000,
 		}}
-	diskUsageLimit := newDiskUsageLimit("", disk, 1000, 1)
+	diskUsageLimit := newDiskUsageLimit("", disk, 1000, nil, 1)
 	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer("", nil), path, diskUsageLimit, newOnDiskRetryQueueTelemetry("domain"))
 	a.NoError(err)
 	return q, clean
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+// RetryFile describes a file of the on-disk retry queue.
+type RetryFile = retry.RetryFile
+
+// StoredTransactionInfo describes a transaction stored in a retry file.
+type StoredTransactionInfo = retry.TransactionInfo
+
+// RetryQueueStoragePath returns the folder where the core agent forwarder stores its retry files.
+func RetryQueueStoragePath() string {
+	return getRetryQueueStoragePath(getAgentFolder(&Options{EnabledFeatures: CoreFeatures}))
+}
+
+// ListRetryFiles returns the retry files of the core agent forwarder, from the oldest to the newest.
+func ListRetryFiles() ([]RetryFile, error) {
+	return retry.ListRetryFiles(RetryQueueStoragePath())
+}
+
+// InspectRetryFile returns a description of the transactions stored in a retry file.
+// The API keys of the transactions are not restored.
+func InspectRetryFile(path string) ([]StoredTransactionInfo, error) {
+	return retry.InspectRetryFile(path)
+}
+
+// RetryFileDomains maps the domain folders of the retry queue to the domains of keysPerDomain.
+func RetryFileDomains(keysPerDomain map[string][]string) map[string]string {
+	domains := make(map[string]string, len(keysPerDomain))
+	for domain := range keysPerDomain {
+		versionedDomain, err := config.AddAgentVersionToDomain(domain, "app")
+		if err != nil {
+			continue
+		}
+		if folder, err := retry.DomainFolderName(versionedDomain); err == nil {
+			domains[folder] = domain
+		}
+	}
+	return domains
+}
+
+// ReadRetryFile deserializes the transactions stored in a retry file, restoring their API keys
+// from keysPerDomain. It also returns the number of transactions which could not be restored.
+func ReadRetryFile(file RetryFile, keysPerDomain map[string][]string) ([]*transaction.HTTPTransaction, int, error) {
+	domain, found := RetryFileDomains(keysPerDomain)[file.DomainFolder]
+	if !found {
+		return nil, 0, fmt.Errorf("the retry file %s does not belong to a configured domain", file.Path)
+	}
+	versionedDomain, _ := config.AddAgentVersionToDomain(domain, "app")
+
+	transactions, errorCount, err := retry.ReadRetryFile(file.Path, versionedDomain, keysPerDomain[domain])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	httpTransactions := make([]*transaction.HTTPTransaction, 0, len(transactions))
+	for _, t := range transactions {
+		if httpTransaction, ok := t.(*transaction.HTTPTransaction); ok {
+			httpTransactions = append(httpTransactions, httpTransaction)
+		} else {
+			errorCount++
+		}
+	}
+	return httpTransactions, errorCount, nil
+}
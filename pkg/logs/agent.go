@@ -25,6 +25,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/input/journald"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/kubernetes"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/listener"
+	"github.com/DataDog/datadog-agent/pkg/logs/input/otlp"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/traps"
 	"github.com/DataDog/datadog-agent/pkg/logs/input/windowsevent"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
@@ -100,6 +101,9 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 		journald.NewLauncher(sources, pipelineProvider, auditor),
 		windowsevent.NewLauncher(sources, pipelineProvider),
 		traps.NewLauncher(sources, pipelineProvider),
+		otlp.NewLauncher(sources, pipelineProvider, coreConfig.Datadog.GetString("logs_config.otlp.bind_host"),
+			coreConfig.Datadog.GetInt("logs_config.otlp.grpc_port"), coreConfig.Datadog.GetInt("logs_config.otlp.http_port"),
+			coreConfig.Datadog.GetStringSlice("logs_config.otlp.resource_attributes_as_tags")),
 	}
 
 	// Only try to start the container launchers if Docker or Kubernetes is available
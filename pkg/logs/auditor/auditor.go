@@ -34,6 +34,7 @@ const registryAPIVersion = 2
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetLastUpdated(identifier string) time.Time
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -145,6 +146,17 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetLastUpdated returns the last time the offset of a given identifier was committed,
+// returns the zero time if it does not exist.
+func (a *RegistryAuditor) GetLastUpdated(identifier string) time.Time {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return time.Time{}
+	}
+	return entry.LastUpdated
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
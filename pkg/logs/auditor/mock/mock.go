@@ -5,10 +5,14 @@
 
 package mock
 
+import "time"
+
 // Registry does nothing
 type Registry struct {
 	offset      string
+	offsets     map[string]string
 	tailingMode string
+	lastUpdated time.Time
 }
 
 // NewRegistry returns a new registry.
@@ -16,16 +20,27 @@ func NewRegistry() *Registry {
 	return &Registry{}
 }
 
-// GetOffset returns the offset.
+// GetOffset returns the offset set for the identifier, or the default offset.
 func (r *Registry) GetOffset(identifier string) string {
+	if offset, found := r.offsets[identifier]; found {
+		return offset
+	}
 	return r.offset
 }
 
-// SetOffset sets the offset.
+// SetOffset sets the default offset.
 func (r *Registry) SetOffset(offset string) {
 	r.offset = offset
 }
 
+// SetOffsetForIdentifier sets the offset of a given identifier.
+func (r *Registry) SetOffsetForIdentifier(identifier string, offset string) {
+	if r.offsets == nil {
+		r.offsets = make(map[string]string)
+	}
+	r.offsets[identifier] = offset
+}
+
 // GetTailingMode returns the tailing mode.
 func (r *Registry) GetTailingMode(identifier string) string {
 	return r.tailingMode
@@ -35,3 +50,13 @@ func (r *Registry) GetTailingMode(identifier string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetLastUpdated returns the last update time.
+func (r *Registry) GetLastUpdated(identifier string) time.Time {
+	return r.lastUpdated
+}
+
+// SetLastUpdated sets the last update time.
+func (r *Registry) SetLastUpdated(lastUpdated time.Time) {
+	r.lastUpdated = lastUpdated
+}
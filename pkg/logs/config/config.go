@@ -23,6 +23,9 @@ const ContainerCollectAll = "container_collect_all"
 // SnmpTraps is the name of the integration that collects logs from SNMP traps received by the Agent
 const SnmpTraps = "snmp_traps"
 
+// OTLPLogs is the name of the integration that collects the log records received over OTLP
+const OTLPLogs = "otlp"
+
 // logs-intake endpoint prefix.
 const (
 	tcpEndpointPrefix            = "agent-intake.logs."
@@ -83,6 +86,16 @@ func SNMPTrapsSource() *LogSource {
 	return nil
 }
 
+// OTLPSource returns a source to collect the log records received over OTLP, if a port is set.
+func OTLPSource() *LogSource {
+	if coreConfig.Datadog.GetInt("logs_config.otlp.grpc_port") != 0 || coreConfig.Datadog.GetInt("logs_config.otlp.http_port") != 0 {
+		return NewLogSource(OTLPLogs, &LogsConfig{
+			Type: OTLPType,
+		})
+	}
+	return nil
+}
+
 // GlobalProcessingRules returns the global processing rules to apply to all logs.
 func GlobalProcessingRules() ([]*ProcessingRule, error) {
 	var rules []*ProcessingRule
@@ -20,6 +20,7 @@ const (
 	WindowsEventType  = "windows_event"
 	SnmpTrapsType     = "snmp_traps"
 	StringChannelType = "string_channel"
+	OTLPType          = "otlp"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -39,6 +40,8 @@ type LogsConfig struct {
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
+	// ReadRotatedArchives enables reading the compressed files rotated while the agent was not tailing the file.
+	ReadRotatedArchives bool `mapstructure:"read_rotated_archives" json:"read_rotated_archives"` // File
 
 	IncludeUnits  []string `mapstructure:"include_units" json:"include_units"`   // Journald
 	ExcludeUnits  []string `mapstructure:"exclude_units" json:"exclude_units"`   // Journald
@@ -63,6 +66,7 @@ type LogsConfig struct {
 	SourceCategory  string
 	Tags            []string
 	ProcessingRules []*ProcessingRule `mapstructure:"log_processing_rules" json:"log_processing_rules"`
+	ParsingRules    []*ParsingRule    `mapstructure:"log_parsing_rules" json:"log_parsing_rules"`
 
 	AutoMultiLine               bool    `mapstructure:"auto_multi_line_detection" json:"auto_multi_line_detection"`
 	AutoMultiLineSampleSize     int     `mapstructure:"auto_multi_line_sample_size" json:"auto_multi_line_sample_size"`
@@ -135,7 +139,15 @@ func (c *LogsConfig) Validate() error {
 	if err != nil {
 		return err
 	}
-	return CompileProcessingRules(c.ProcessingRules)
+	err = CompileProcessingRules(c.ProcessingRules)
+	if err != nil {
+		return err
+	}
+	err = ValidateParsingRules(c.ParsingRules)
+	if err != nil {
+		return err
+	}
+	return CompileParsingRules(c.ParsingRules)
 }
 
 func (c *LogsConfig) validateTailingMode() error {
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// LineSampler decides which log lines are kept by the `sample` and `rate_limit`
+// processing rules. It is safe to use from several pipelines.
+type LineSampler struct {
+	sampleRate        int
+	maxLinesPerSecond int
+
+	mu          sync.Mutex
+	count       int
+	windowStart time.Time
+	now         func() time.Time
+}
+
+// NewLineSampler returns a LineSampler keeping 1 log line out of sampleRate when sampleRate
+// is positive, and at most maxLinesPerSecond log lines per second when maxLinesPerSecond is positive.
+func NewLineSampler(sampleRate int, maxLinesPerSecond int) *LineSampler {
+	return &LineSampler{
+		sampleRate:        sampleRate,
+		maxLinesPerSecond: maxLinesPerSecond,
+		now:               time.Now,
+	}
+}
+
+// Keep returns true if the next log line must be kept.
+func (s *LineSampler) Keep() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sampleRate > 0 {
+		keep := s.count == 0
+		s.count = (s.count + 1) % s.sampleRate
+		return keep
+	}
+
+	now := s.now()
+	if now.Sub(s.windowStart) >= time.Second {
+		s.windowStart = now
+		s.count = 0
+	}
+	if s.count >= s.maxLinesPerSecond {
+		return false
+	}
+	s.count++
+	return true
+}
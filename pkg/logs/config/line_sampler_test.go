@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineSamplerSampleRate(t *testing.T) {
+	sampler := NewLineSampler(3, 0)
+
+	var kept []bool
+	for i := 0; i < 7; i++ {
+		kept = append(kept, sampler.Keep())
+	}
+	assert.Equal(t, []bool{true, false, false, true, false, false, true}, kept)
+}
+
+func TestLineSamplerRateLimit(t *testing.T) {
+	now := time.Now()
+	sampler := NewLineSampler(0, 2)
+	sampler.now = func() time.Time { return now }
+
+	assert.True(t, sampler.Keep())
+	assert.True(t, sampler.Keep())
+	assert.False(t, sampler.Keep())
+
+	now = now.Add(500 * time.Millisecond)
+	assert.False(t, sampler.Keep())
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, sampler.Keep())
+	assert.True(t, sampler.Keep())
+	assert.False(t, sampler.Keep())
+}
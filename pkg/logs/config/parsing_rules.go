@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Types a parsed value can be converted to
+const (
+	ParsedStringType = "string"
+	ParsedIntType    = "int"
+	ParsedFloatType  = "float"
+)
+
+// grokPatterns are the patterns which can be referenced with `%{NAME}` in a parsing rule.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`,
+	"IP":                `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `%{IP}|%{HOSTNAME}`,
+	"USER":              `[a-zA-Z0-9._-]+`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `\w{3} +\d{1,2} \d{2}:\d{2}:\d{2}`,
+}
+
+// grokReferenceRegex matches `%{PATTERN}`, `%{PATTERN:attribute}` and `%{PATTERN:attribute:type}`.
+var grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// namedGroupRegex matches the named capture groups written in a parsing rule.
+var namedGroupRegex = regexp.MustCompile(`\(\?P<(\w+)>`)
+
+// maxGrokExpansionDepth limits the expansion of patterns referencing other patterns.
+const maxGrokExpansionDepth = 10
+
+// grokGroupPrefix prefixes the names of the capture groups generated for grok references,
+// it is reserved so that they cannot clash with the capture groups named in the rules.
+const grokGroupPrefix = "__grok"
+
+// ParsingRule defines a grok-style pattern, or a regular expression with named capture groups,
+// used to extract attributes from log lines.
+type ParsingRule struct {
+	Name    string
+	Pattern string
+	// TODO: should be moved out
+	Regex  *regexp.Regexp
+	Fields []ParsedField
+}
+
+// ParsedField is the attribute of a capture group of a parsing rule.
+// Fields are indexed by capture group, the name of unnamed groups is empty.
+type ParsedField struct {
+	Name string
+	// Path is the dot-separated attribute name split, for nested attributes.
+	Path []string
+	Type string
+}
+
+// ValidateParsingRules validates the rules and raises an error if one is misconfigured.
+// Each parsing rule must have a valid name and a valid pattern that compiles.
+func ValidateParsingRules(rules []*ParsingRule) error {
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("all parsing rules must have a name")
+		}
+		if rule.Pattern == "" {
+			return fmt.Errorf("no pattern provided for parsing rule: %s", rule.Name)
+		}
+		if _, _, err := compileGrokPattern(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %s for parsing rule %s: %v", rule.Pattern, rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// CompileParsingRules compiles all parsing rule patterns.
+func CompileParsingRules(rules []*ParsingRule) error {
+	for _, rule := range rules {
+		re, fields, err := compileGrokPattern(rule.Pattern)
+		if err != nil {
+			return err
+		}
+		rule.Regex = re
+		rule.Fields = fields
+	}
+	return nil
+}
+
+// compileGrokPattern expands the grok references of pattern and compiles it.
+func compileGrokPattern(pattern string) (*regexp.Regexp, []ParsedField, error) {
+	for _, submatches := range namedGroupRegex.FindAllStringSubmatch(pattern, -1) {
+		if strings.HasPrefix(submatches[1], grokGroupPrefix) {
+			return nil, nil, fmt.Errorf("the capture group name %s is reserved, names cannot start with %s", submatches[1], grokGroupPrefix)
+		}
+	}
+
+	var grokFields []ParsedField
+	expanded, err := expandGrokPattern(pattern, 0, &grokFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := make([]ParsedField, len(re.SubexpNames()))
+	for i, name := range re.SubexpNames() {
+		var field ParsedField
+		if index, ok := grokFieldIndex(name); ok {
+			field = grokFields[index]
+		} else if name != "" {
+			field = ParsedField{Name: name, Type: ParsedStringType}
+		} else {
+			continue
+		}
+		field.Path = strings.Split(field.Name, ".")
+		fields[i] = field
+	}
+	return re, fields, nil
+}
+
+// expandGrokPattern replaces the grok references of pattern by their regular expression. Named
+// references become capture groups named `__grok<index>` as attribute names can contain dots.
+func expandGrokPattern(pattern string, depth int, fields *[]ParsedField) (string, error) {
+	if depth > maxGrokExpansionDepth {
+		return "", fmt.Errorf("too many nested grok patterns")
+	}
+
+	var expandErr error
+	expanded := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(reference string) string {
+		submatches := grokReferenceRegex.FindStringSubmatch(reference)
+		patternName, attributeName, attributeType := submatches[1], submatches[2], submatches[3]
+
+		grokPattern, found := grokPatterns[patternName]
+		if !found {
+			expandErr = fmt.Errorf("unknown grok pattern %s", patternName)
+			return reference
+		}
+		subpattern, err := expandGrokPattern(grokPattern, depth+1, fields)
+		if err != nil {
+			expandErr = err
+			return reference
+		}
+
+		if attributeName == "" {
+			return "(?:" + subpattern + ")"
+		}
+		switch attributeType {
+		case "":
+			attributeType = ParsedStringType
+		case ParsedStringType, ParsedIntType, ParsedFloatType:
+		default:
+			expandErr = fmt.Errorf("unknown type %s for the attribute %s", attributeType, attributeName)
+			return reference
+		}
+		*fields = append(*fields, ParsedField{Name: attributeName, Type: attributeType})
+		return fmt.Sprintf("(?P<%s%d>%s)", grokGroupPrefix, len(*fields)-1, subpattern)
+	})
+	return expanded, expandErr
+}
+
+func grokFieldIndex(groupName string) (int, bool) {
+	if !strings.HasPrefix(groupName, grokGroupPrefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(groupName[len(grokGroupPrefix):])
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileParsingRules(t *testing.T) {
+	rules := []*ParsingRule{{Name: "test", Pattern: `%{LOGLEVEL:level} \[%{DATA}\] %{INT:http.status:int} (?P<rest>.*)`}}
+	assert.NoError(t, ValidateParsingRules(rules))
+	assert.NoError(t, CompileParsingRules(rules))
+
+	rule := rules[0]
+	assert.True(t, rule.Regex.MatchString("WARN [main] 500 done"))
+	assert.Equal(t, []ParsedField{
+		{},
+		{Name: "level", Path: []string{"level"}, Type: ParsedStringType},
+		{Name: "http.status", Path: []string{"http", "status"}, Type: ParsedIntType},
+		{Name: "rest", Path: []string{"rest"}, Type: ParsedStringType},
+	}, rule.Fields)
+}
+
+func TestValidateParsingRules(t *testing.T) {
+	invalidRules := []*ParsingRule{
+		{Pattern: "%{WORD:word}"},
+		{Name: "no pattern"},
+		{Name: "unknown pattern", Pattern: "%{UNKNOWN:word}"},
+		{Name: "unknown type", Pattern: "%{WORD:word:bool}"},
+		{Name: "invalid regex", Pattern: "(?=abf)"},
+		{Name: "reserved group name", Pattern: "%{WORD:word} (?P<__grok0>.*)"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateParsingRules([]*ParsingRule{rule}), rule.Name)
+	}
+}
+
+func TestCompileParsingRulesGroupNamedLikeGrok(t *testing.T) {
+	rules := []*ParsingRule{{Name: "test", Pattern: `%{WORD:word} (?P<grok0>\w+) (?P<grok5>\w+)`}}
+	assert.NoError(t, CompileParsingRules(rules))
+
+	assert.Equal(t, []ParsedField{
+		{},
+		{Name: "word", Path: []string{"word"}, Type: ParsedStringType},
+		{Name: "grok0", Path: []string{"grok0"}, Type: ParsedStringType},
+		{Name: "grok5", Path: []string{"grok5"}, Type: ParsedStringType},
+	}, rules[0].Fields)
+}
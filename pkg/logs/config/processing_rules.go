@@ -8,6 +8,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // Processing rule types
@@ -16,6 +17,20 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+
+	// The following rules parse JSON log lines and act on the field at `path`.
+	JSONDropField      = "json_drop_field"
+	JSONRenameField    = "json_rename_field"
+	JSONMaskField      = "json_mask_field"
+	JSONFieldToTag     = "json_field_to_tag"
+	JSONFieldToService = "json_field_to_service"
+	JSONFieldToStatus  = "json_field_to_status"
+	JSONExcludeAtMatch = "json_exclude_at_match"
+
+	// The following rules drop a part of the log lines matching `pattern`, or of all
+	// the log lines when no pattern is set.
+	Sample    = "sample"
+	RateLimit = "rate_limit"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,9 +40,27 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Path is the dot-separated path of the JSON field the rule applies to, e.g. `http.url`.
+	Path string
+	// Target is the new name of the field for `json_rename_field`, or the tag name for `json_field_to_tag`.
+	Target string
+	// SampleRate is the rate of the `sample` rule, 1 log line out of SampleRate is kept.
+	SampleRate int `mapstructure:"sample_rate" json:"sample_rate"`
+	// MaxLinesPerSecond is the maximum number of log lines per second kept by the `rate_limit` rule.
+	MaxLinesPerSecond int `mapstructure:"max_lines_per_second" json:"max_lines_per_second"`
+	// KeepPattern matches the log lines always kept by the `sample` and `rate_limit` rules, e.g. errors.
+	KeepPattern string `mapstructure:"keep_pattern" json:"keep_pattern"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	FieldPath   []string
+	KeepRegex   *regexp.Regexp
+	Sampler     *LineSampler
+}
+
+// IsJSONRule returns true if the rule parses JSON log lines.
+func (r *ProcessingRule) IsJSONRule() bool {
+	return strings.HasPrefix(r.Type, "json_")
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
@@ -41,17 +74,48 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 			return fmt.Errorf("all processing rules must have a name")
 		}
 
+		patternRequired := true
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, JSONExcludeAtMatch:
 			break
+		case JSONDropField, JSONMaskField, JSONFieldToTag, JSONFieldToService, JSONFieldToStatus:
+			patternRequired = false
+		case JSONRenameField:
+			patternRequired = false
+			if rule.Target == "" {
+				return fmt.Errorf("no target provided for processing rule: %s", rule.Name)
+			}
+		case Sample:
+			patternRequired = false
+			if rule.SampleRate < 1 {
+				return fmt.Errorf("sample_rate must be greater than 0 for processing rule: %s", rule.Name)
+			}
+		case RateLimit:
+			patternRequired = false
+			if rule.MaxLinesPerSecond < 1 {
+				return fmt.Errorf("max_lines_per_second must be greater than 0 for processing rule: %s", rule.Name)
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
 			return fmt.Errorf("type %s is not supported for processing rule `%s`", rule.Type, rule.Name)
 		}
 
+		if rule.IsJSONRule() && rule.Path == "" {
+			return fmt.Errorf("no path provided for processing rule: %s", rule.Name)
+		}
+
+		if rule.KeepPattern != "" {
+			if _, err := regexp.Compile(rule.KeepPattern); err != nil {
+				return fmt.Errorf("invalid keep pattern %s for processing rule: %s", rule.KeepPattern, rule.Name)
+			}
+		}
+
 		if rule.Pattern == "" {
-			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+			if patternRequired {
+				return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+			}
+			continue
 		}
 		_, err := regexp.Compile(rule.Pattern)
 		if err != nil {
@@ -64,14 +128,35 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.IsJSONRule() {
+			rule.FieldPath = strings.Split(rule.Path, ".")
+		}
+		switch rule.Type {
+		case Sample:
+			rule.Sampler = NewLineSampler(rule.SampleRate, 0)
+		case RateLimit:
+			rule.Sampler = NewLineSampler(0, rule.MaxLinesPerSecond)
+		}
+		if rule.KeepPattern != "" {
+			re, err := regexp.Compile(rule.KeepPattern)
+			if err != nil {
+				return err
+			}
+			rule.KeepRegex = re
+		}
+		if rule.Pattern == "" && (rule.IsJSONRule() || rule.Sampler != nil) {
+			// The pattern is optional for these rules
+			rule.Placeholder = []byte(rule.ReplacePlaceholder)
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, JSONExcludeAtMatch, Sample, RateLimit:
 			rule.Regex = re
-		case MaskSequences:
+		case MaskSequences, JSONMaskField:
 			rule.Regex = re
 			rule.Placeholder = []byte(rule.ReplacePlaceholder)
 		case MultiLine:
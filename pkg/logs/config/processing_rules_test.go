@@ -33,3 +33,29 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateJSONRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "drop", Type: JSONDropField, Path: "user.password"},
+		{Name: "rename", Type: JSONRenameField, Path: "msg", Target: "message"},
+		{Name: "exclude", Type: JSONExcludeAtMatch, Path: "level", Pattern: "debug"},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.Equal(t, []string{"user", "password"}, validRules[0].FieldPath)
+	assert.Nil(t, validRules[0].Regex)
+	assert.True(t, validRules[2].Regex.MatchString("debug"))
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no sample rate", Type: Sample},
+		{Name: "no max lines per second", Type: RateLimit},
+		{Name: "invalid keep pattern", Type: Sample, SampleRate: 2, KeepPattern: "(?=abf)"},
+		{Name: "no path", Type: JSONDropField},
+		{Name: "no target", Type: JSONRenameField, Path: "msg"},
+		{Name: "no pattern", Type: JSONExcludeAtMatch, Path: "level"},
+		{Name: "invalid pattern", Type: JSONMaskField, Path: "level", Pattern: "(?=abf)"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -7,6 +7,7 @@ package diagnostic
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -148,7 +149,14 @@ func formatMessage(m *message.Message, redactedMsg []byte) string {
 		ts = m.Timestamp
 	}
 
-	return fmt.Sprintf("Integration Name: %s | Type: %s | Status: %s | Timestamp: %s | Hostname: %s | Service: %s | Source: %s | Tags: %s | Message: %s\n",
+	var attributes string
+	if len(m.ParsedAttributes) > 0 {
+		if data, err := json.Marshal(m.ParsedAttributes); err == nil {
+			attributes = " | Attributes: " + string(data)
+		}
+	}
+
+	return fmt.Sprintf("Integration Name: %s | Type: %s | Status: %s | Timestamp: %s | Hostname: %s | Service: %s | Source: %s | Tags: %s | Message: %s%s\n",
 		m.Origin.LogSource.Name,
 		m.Origin.LogSource.Config.Type,
 		m.GetStatus(),
@@ -157,5 +165,6 @@ func formatMessage(m *message.Message, redactedMsg []byte) string {
 		m.Origin.Service(),
 		m.Origin.Source(),
 		m.Origin.TagsToString(),
-		string(redactedMsg))
+		string(redactedMsg),
+		attributes)
 }
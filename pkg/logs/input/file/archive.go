@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/tag"
+)
+
+// archiveReaders returns a decompressing reader for each supported extension of the files
+// compressed by log rotation tools. zstd archives are only supported by builds with the zstd tag.
+var archiveReaders = map[string]func(io.Reader) (io.ReadCloser, error){
+	".gz": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+}
+
+// isArchive returns true if path is a compressed file the ArchiveReader can read.
+func isArchive(path string) bool {
+	_, supported := archiveReaders[filepath.Ext(path)]
+	return supported
+}
+
+// rotatedArchives returns the paths of the compressed files rotated from path,
+// for instance `app.log.1.gz` or `app.log-20210101.zst`, modified after since, the oldest first.
+func rotatedArchives(path string, since time.Time) []string {
+	matches, err := filepath.Glob(path + "*")
+	if err != nil {
+		return nil
+	}
+
+	type archive struct {
+		path    string
+		modTime time.Time
+	}
+	var archives []archive
+	for _, match := range matches {
+		// the character following path separates it from the rotation suffix,
+		// path itself is skipped when it is an archive
+		if len(match) <= len(path) || !isArchive(match) || !strings.ContainsAny(match[len(path):len(path)+1], ".-") {
+			continue
+		}
+		info, err := os.Stat(match)
+		if err != nil || !info.Mode().IsRegular() || !info.ModTime().After(since) {
+			continue
+		}
+		archives = append(archives, archive{path: match, modTime: info.ModTime()})
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].modTime.Before(archives[j].modTime)
+	})
+
+	paths := make([]string, 0, len(archives))
+	for _, archive := range archives {
+		paths = append(paths, archive.path)
+	}
+	return paths
+}
+
+// errArchiveReadStopped is returned when the read of an archive is interrupted.
+var errArchiveReadStopped = errors.New("archive read stopped")
+
+// ArchiveReader reads once the content of a compressed rotated file and sends its
+// log messages to an output channel. The decompressed offset is tracked by the auditor
+// so that an archive partially read is resumed after a restart.
+type ArchiveReader struct {
+	path          string
+	file          *File
+	decodedOffset int64
+
+	outputChan  chan *message.Message
+	decoder     *decoder.Decoder
+	tagProvider tag.Provider
+	tags        []string
+}
+
+// NewArchiveReader returns a reader for the archive at path, rotated from file.
+func NewArchiveReader(outputChan chan *message.Message, file *File, path string, decoder *decoder.Decoder) *ArchiveReader {
+	var tagProvider tag.Provider
+	if file.Source.Config.Identifier != "" {
+		tagProvider = tag.NewProvider(containers.BuildTaggerEntityName(file.Source.Config.Identifier))
+	} else {
+		tagProvider = tag.NewLocalProvider([]string{})
+	}
+
+	return &ArchiveReader{
+		path:        path,
+		file:        file,
+		outputChan:  outputChan,
+		decoder:     decoder,
+		tagProvider: tagProvider,
+		tags:        buildFileTags(file),
+	}
+}
+
+// Identifier returns the identifier of the archive in the registry.
+func (r *ArchiveReader) Identifier() string {
+	return fmt.Sprintf("file:%s", r.path)
+}
+
+// Read sends the messages of the archive, from the given decompressed offset,
+// and returns once they have all been sent to the output channel or stop is closed.
+func (r *ArchiveReader) Read(offset int64, stop <-chan struct{}) error {
+	newReader, supported := archiveReaders[filepath.Ext(r.path)]
+	if !supported {
+		return fmt.Errorf("unsupported archive format: %s", r.path)
+	}
+
+	f, err := openFile(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := newReader(f)
+	if err != nil {
+		return fmt.Errorf("could not decompress %s: %v", r.path, err)
+	}
+	defer reader.Close()
+
+	skipped, err := io.CopyN(ioutil.Discard, reader, offset)
+	if err == io.EOF {
+		// the archive has already been read
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not decompress %s: %v", r.path, err)
+	}
+	r.decodedOffset = skipped
+
+	var readErr error
+	done := make(chan struct{})
+	go func() {
+		r.forwardMessages()
+		close(done)
+	}()
+	r.decoder.Start()
+
+read:
+	for {
+		select {
+		case <-stop:
+			readErr = errArchiveReadStopped
+			break read
+		default:
+		}
+
+		inBuf := make([]byte, 4096)
+		n, err := reader.Read(inBuf)
+		if n > 0 {
+			r.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+			r.recordBytes(int64(n))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			readErr = fmt.Errorf("could not decompress %s: %v", r.path, err)
+			break
+		}
+	}
+
+	// wait for the decoder to be flushed
+	r.decoder.Stop()
+	<-done
+	log.Infof("Read archive %s rotated from %s, from offset %d to offset %d", r.path, r.file.Path, offset, r.decodedOffset)
+	return readErr
+}
+
+// forwardMessages forwards the decoded messages of the archive to the output channel.
+func (r *ArchiveReader) forwardMessages() {
+	for output := range r.decoder.OutputChan {
+		r.decodedOffset += int64(output.RawDataLen)
+		origin := message.NewOrigin(r.file.Source)
+		origin.Identifier = r.Identifier()
+		origin.Offset = strconv.FormatInt(r.decodedOffset, 10)
+		origin.SetTags(append(r.tags, r.tagProvider.GetTags()...))
+		// Ignore empty lines once the registry offset is updated
+		if len(output.Content) == 0 {
+			continue
+		}
+		r.outputChan <- message.NewMessage(output.Content, origin, output.Status, output.IngestionTimestamp)
+	}
+}
+
+func (r *ArchiveReader) recordBytes(n int64) {
+	r.file.Source.BytesRead.Add(n)
+	if r.file.Source.ParentSource != nil {
+		r.file.Source.ParentSource.BytesRead.Add(n)
+	}
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build !windows
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func writeGzipArchive(t *testing.T, path string, content string, modTime time.Time) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func readArchive(t *testing.T, path string, offset int64) []*message.Message {
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: "app.log"})
+	outputChan := make(chan *message.Message, 10)
+	reader := NewArchiveReader(outputChan, NewFile("app.log", source, false), path, NewDecoderFromSource(source))
+	require.NoError(t, reader.Read(offset, make(chan struct{})))
+	close(outputChan)
+
+	var messages []*message.Message
+	for msg := range outputChan {
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestRotatedArchives(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	now := time.Now()
+	path := filepath.Join(testDir, "app.log")
+	writeGzipArchive(t, path+".1.gz", "", now.Add(-1*time.Minute))
+	writeGzipArchive(t, path+"-20210101.gz", "", now.Add(-2*time.Minute))
+	writeGzipArchive(t, path+".3.gz", "", now.Add(-2*time.Hour))
+	writeGzipArchive(t, filepath.Join(testDir, "other.log.1.gz"), "", now)
+	require.NoError(t, ioutil.WriteFile(path+".2", nil, 0644))
+
+	assert.Equal(t, []string{path + "-20210101.gz", path + ".1.gz"}, rotatedArchives(path, now.Add(-1*time.Hour)))
+	assert.Equal(t, []string{path + ".3.gz", path + "-20210101.gz", path + ".1.gz"}, rotatedArchives(path, time.Time{}))
+	assert.Empty(t, rotatedArchives(path, now))
+}
+
+func TestRotatedArchivesOfArchive(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	// the tailed file is itself an archive, it is not one of its rotated archives
+	path := filepath.Join(testDir, "app.log.gz")
+	writeGzipArchive(t, path, "", time.Now())
+	writeGzipArchive(t, path+".1.gz", "", time.Now())
+
+	assert.Equal(t, []string{path + ".1.gz"}, rotatedArchives(path, time.Time{}))
+}
+
+func TestArchiveReaderReadsGzip(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.1.gz")
+	writeGzipArchive(t, path, "hello\nworld\n", time.Now())
+
+	messages := readArchive(t, path, 0)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "hello", string(messages[0].Content))
+	assert.Equal(t, "world", string(messages[1].Content))
+	assert.Equal(t, "file:"+path, messages[1].Origin.Identifier)
+	assert.Equal(t, "12", messages[1].Origin.Offset)
+	assert.Contains(t, messages[1].Origin.Tags(), "filename:app.log")
+}
+
+func TestArchiveReaderResumesFromOffset(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.1.gz")
+	writeGzipArchive(t, path, "hello\nworld\n", time.Now())
+
+	messages := readArchive(t, path, 6)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "world", string(messages[0].Content))
+	assert.Equal(t, "12", messages[0].Origin.Offset)
+
+	// the archive has already been read
+	assert.Empty(t, readArchive(t, path, 12))
+	assert.Empty(t, readArchive(t, path, 42))
+}
+
+func TestArchiveReaderStop(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.1.gz")
+	writeGzipArchive(t, path, "hello\nworld\n", time.Now())
+
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: "app.log"})
+	outputChan := make(chan *message.Message, 10)
+	reader := NewArchiveReader(outputChan, NewFile("app.log", source, false), path, NewDecoderFromSource(source))
+	stop := make(chan struct{})
+	close(stop)
+	assert.Equal(t, errArchiveReadStopped, reader.Read(0, stop))
+	assert.Empty(t, outputChan)
+}
@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build zstd
+
+package file
+
+import (
+	"io"
+
+	"github.com/DataDog/zstd"
+)
+
+func init() {
+	// the zstd library relies on cgo, it is only available in builds with the zstd tag
+	archiveReaders[".zst"] = func(r io.Reader) (io.ReadCloser, error) {
+		return zstd.NewReader(r), nil
+	}
+}
@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build zstd,!windows
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeZstdArchive(t *testing.T, path string, content string, modTime time.Time) {
+	data, err := zstd.Compress(nil, []byte(content))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestRotatedZstdArchives(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	now := time.Now()
+	path := filepath.Join(testDir, "app.log")
+	writeGzipArchive(t, path+".1.gz", "", now.Add(-1*time.Minute))
+	writeZstdArchive(t, path+"-20210101.zst", "", now.Add(-2*time.Minute))
+
+	assert.Equal(t, []string{path + "-20210101.zst", path + ".1.gz"}, rotatedArchives(path, time.Time{}))
+}
+
+func TestArchiveReaderReadsZstd(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-archive-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	path := filepath.Join(testDir, "app.log.1.zst")
+	writeZstdArchive(t, path, "hello\nworld\n", time.Now())
+
+	messages := readArchive(t, path, 0)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "hello", string(messages[0].Content))
+	assert.Equal(t, "world", string(messages[1].Content))
+}
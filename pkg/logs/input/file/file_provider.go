@@ -179,6 +179,10 @@ func (p *Provider) searchFiles(pattern string, source *config.LogSource) ([]*Fil
 	}
 
 	for _, path := range paths {
+		// rotated archives are read once before tailing the file they were rotated from
+		if source.Config.ReadRotatedArchives && isArchive(path) {
+			continue
+		}
 		if excludedPaths[path] == 0 {
 			files = append(files, NewFile(path, source, true))
 		}
@@ -109,6 +109,25 @@ func (suite *ProviderTestSuite) TestFilesToTailReturnsAllFilesFromDirectory() {
 	)
 }
 
+func (suite *ProviderTestSuite) TestFilesToTailSkipsRotatedArchives() {
+	archivePath := fmt.Sprintf("%s/2/2.log.1.gz", suite.testDir)
+	_, err := os.Create(archivePath)
+	suite.Nil(err)
+
+	path := fmt.Sprintf("%s/2/*", suite.testDir)
+	fileProvider := NewProvider(suite.filesLimit)
+	logSources := suite.newLogSources(path)
+	config.CreateSources(logSources)
+	suite.Equal(3, len(fileProvider.FilesToTail(logSources)))
+
+	logSources[0].Config.ReadRotatedArchives = true
+	files := fileProvider.FilesToTail(logSources)
+	suite.Equal(2, len(files))
+	for _, file := range files {
+		suite.NotEqual(archivePath, file.Path)
+	}
+}
+
 func (suite *ProviderTestSuite) TestCollectFilesWildcardFlag() {
 	// with wildcard
 
@@ -6,10 +6,13 @@
 package file
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -51,6 +54,13 @@ type Scanner struct {
 	// Feature flag defaulting to false, use `logs_config.validate_pod_container_id`.
 	validatePodContainerID bool
 	scanPeriod             time.Duration
+	// pendingTailers are the tailers waiting for the rotated archives of their file to be read,
+	// which happens in the background. archivesRead receives them once the archives have been read,
+	// archivesStop interrupts the reads and archivesWg waits for them to return
+	pendingTailers map[string]*Tailer
+	archivesRead   chan *Tailer
+	archivesStop   chan struct{}
+	archivesWg     sync.WaitGroup
 }
 
 // NewScanner returns a new scanner.
@@ -68,6 +78,9 @@ func NewScanner(sources *config.LogSources, tailingLimit int, pipelineProvider p
 		stop:                   make(chan struct{}),
 		validatePodContainerID: validatePodContainerID,
 		scanPeriod:             scanPeriod,
+		pendingTailers:         make(map[string]*Tailer),
+		archivesRead:           make(chan *Tailer),
+		archivesStop:           make(chan struct{}),
 	}
 }
 
@@ -80,6 +93,10 @@ func (s *Scanner) Start() {
 // this call returns only when all the tailers are stopped
 func (s *Scanner) Stop() {
 	s.stop <- struct{}{}
+	close(s.archivesStop)
+	s.archivesWg.Wait()
+	// the scanner may be started again
+	s.archivesStop = make(chan struct{})
 	s.cleanup()
 }
 
@@ -93,6 +110,8 @@ func (s *Scanner) run() {
 			s.addSource(source)
 		case source := <-s.removedSources:
 			s.removeSource(source)
+		case tailer := <-s.archivesRead:
+			s.startPendingTailer(tailer)
 		case <-scanTicker.C:
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
@@ -110,6 +129,10 @@ func (s *Scanner) cleanup() {
 		stopper.Add(tailer)
 		delete(s.tailers, tailer.file.GetScanKey())
 	}
+	// the pending tailers were never started
+	for key := range s.pendingTailers {
+		delete(s.pendingTailers, key)
+	}
 	stopper.Stop()
 }
 
@@ -120,7 +143,7 @@ func (s *Scanner) cleanup() {
 func (s *Scanner) scan() {
 	files := s.fileProvider.FilesToTail(s.activeSources)
 	filesTailed := make(map[string]bool)
-	tailersLen := len(s.tailers)
+	tailersLen := len(s.tailers) + len(s.pendingTailers)
 
 	for _, file := range files {
 		// We're using generated key here: in case this file has been found while
@@ -133,6 +156,11 @@ func (s *Scanner) scan() {
 		// when a tailer for a dead container is still tailing the file, and another
 		// tailer is tailing the file for the new container).
 		tailerKey := file.GetScanKey()
+		if _, isPending := s.pendingTailers[tailerKey]; isPending {
+			// the tailer will start once the rotated archives of the file have been read
+			filesTailed[tailerKey] = true
+			continue
+		}
 		tailer, isTailed := s.tailers[tailerKey]
 		if isTailed && atomic.LoadInt32(&tailer.shouldStop) != 0 {
 			// skip this tailer as it must be stopped
@@ -206,12 +234,15 @@ func (s *Scanner) launchTailers(source *config.LogSource) {
 		return
 	}
 	for _, file := range files {
-		if len(s.tailers) >= s.tailingLimit {
+		if len(s.tailers)+len(s.pendingTailers) >= s.tailingLimit {
 			return
 		}
 		if _, isTailed := s.tailers[file.GetScanKey()]; isTailed {
 			continue
 		}
+		if _, isPending := s.pendingTailers[file.GetScanKey()]; isPending {
+			continue
+		}
 
 		mode, _ := config.TailingModeFromString(source.Config.TailingMode)
 
@@ -262,6 +293,15 @@ func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
 
+	if file.Source.Config.ReadRotatedArchives && (mode == config.Beginning || mode == config.End) {
+		if s.readRotatedArchives(tailer, offset, whence) {
+			// the file has been rotated since it was last tailed, its new content is read from the beginning
+			// by startPendingTailer, once the archives have been read
+			s.pendingTailers[file.GetScanKey()] = tailer
+			return true
+		}
+	}
+
 	log.Infof("Starting a new tailer for: %s (offset: %d, whence: %d) for tailer key %s", file.Path, offset, whence, file.GetScanKey())
 	err = tailer.Start(offset, whence)
 	if err != nil {
@@ -273,6 +313,81 @@ func (s *Scanner) startNewTailer(file *File, m config.TailingMode) bool {
 	return true
 }
 
+// startPendingTailer starts the tailer of which the rotated archives have been read,
+// from the beginning of its file.
+func (s *Scanner) startPendingTailer(tailer *Tailer) {
+	key := tailer.file.GetScanKey()
+	if s.pendingTailers[key] != tailer {
+		return
+	}
+	delete(s.pendingTailers, key)
+
+	log.Infof("Starting a new tailer for: %s (offset: 0, whence: %d) for tailer key %s", tailer.file.Path, io.SeekStart, key)
+	if err := tailer.Start(0, io.SeekStart); err != nil {
+		log.Warn(err)
+		return
+	}
+	s.tailers[key] = tailer
+}
+
+// readRotatedArchives reads once the compressed files rotated from the file of tailer since it was
+// last tailed, the oldest first, and returns true if there was any. The oldest archive holds the content
+// of the file which was tailed, it is read from the offset registered for the file. The archives are
+// decompressed in the background so that the scanner is not blocked, the tailer is sent to archivesRead
+// once they have all been read, so that the file is only tailed after them.
+func (s *Scanner) readRotatedArchives(tailer *Tailer, offset int64, whence int) bool {
+	file := tailer.file
+	lastUpdated := s.registry.GetLastUpdated(tailer.Identifier())
+	if lastUpdated.IsZero() {
+		// the file was never tailed, there is nothing to recover
+		return false
+	}
+
+	archives := rotatedArchives(file.Path, lastUpdated)
+	if len(archives) == 0 {
+		return false
+	}
+
+	readers := make([]*ArchiveReader, 0, len(archives))
+	offsets := make([]int64, 0, len(archives))
+	for i, path := range archives {
+		// the archives are sent to the pipeline of the tailer to keep the messages ordered
+		reader := NewArchiveReader(tailer.outputChan, file, path, NewDecoderFromSource(file.Source))
+
+		var archiveOffset int64
+		if i == 0 && whence == io.SeekStart {
+			archiveOffset = offset
+		}
+		if value := s.registry.GetOffset(reader.Identifier()); value != "" {
+			if registeredOffset, err := strconv.ParseInt(value, 10, 64); err == nil {
+				archiveOffset = registeredOffset
+			}
+		}
+		readers = append(readers, reader)
+		offsets = append(offsets, archiveOffset)
+	}
+
+	stop := s.archivesStop
+	s.archivesWg.Add(1)
+	go func() {
+		defer s.archivesWg.Done()
+		for i, reader := range readers {
+			log.Infof("Reading rotated archive %s of %s (offset: %d)", reader.path, file.Path, offsets[i])
+			err := reader.Read(offsets[i], stop)
+			if err == errArchiveReadStopped {
+				return
+			} else if err != nil {
+				log.Warnf("Could not read rotated archive %s: %v", reader.path, err)
+			}
+		}
+		select {
+		case s.archivesRead <- tailer:
+		case <-stop:
+		}
+	}()
+	return true
+}
+
 // shouldIgnore resolves symlinks in /var/log/containers in order to use that redirection
 // to validate that we will be reading a file for the correct container.
 func (s *Scanner) shouldIgnore(file *File) bool {
@@ -344,6 +344,48 @@ func TestScannerTailFromTheBeginning(t *testing.T) {
 	}
 }
 
+func TestScannerReadsRotatedArchives(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "log-scanner-test-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(testDir)
+	path := fmt.Sprintf("%s/test.log", testDir)
+
+	// the file was tailed up to the end of its first line, then rotated twice while the agent was stopped,
+	// and the agent was stopped again while reading the first line of the newest archive
+	registry := auditor.NewRegistry()
+	registry.SetOffsetForIdentifier("file:"+path, "5")
+	registry.SetOffsetForIdentifier("file:"+path+".1.gz", "5")
+	registry.SetLastUpdated(time.Now().Add(-1 * time.Hour))
+	writeGzipArchive(t, path+".2.gz", "Once\nUpon\n", time.Now().Add(-30*time.Minute))
+	writeGzipArchive(t, path+".1.gz", "Skip\nA\n", time.Now())
+	err = ioutil.WriteFile(path, []byte("Time\n"), 0644)
+	assert.Nil(t, err)
+
+	openFilesLimit := 3
+	sleepDuration := 20 * time.Millisecond
+	pipelineProvider := mock.NewMockProvider()
+	sources := config.NewLogSources()
+	scanner := NewScanner(sources, openFilesLimit, pipelineProvider, registry, sleepDuration, false, 10*time.Second)
+	source := config.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, ReadRotatedArchives: true})
+
+	scanner.Start()
+	defer scanner.Stop()
+	sources.AddSource(source)
+
+	// the archives are read the oldest first, and the file is only tailed once they have been read
+	outputChan := pipelineProvider.NextPipelineChan()
+	var contents []string
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-outputChan:
+			contents = append(contents, string(msg.Content))
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timeout waiting for the log messages", "received: %v", contents)
+		}
+	}
+	assert.Equal(t, []string{"Upon", "A", "Time"}, contents)
+}
+
 func TestScannerScanWithTooManyFiles(t *testing.T) {
 	var err error
 	var path string
@@ -444,3 +486,11 @@ func TestContainerIDInContainerLogFile(t *testing.T) {
 func getScanKey(path string, source *config.LogSource) string {
 	return NewFile(path, source, false).GetScanKey()
 }
+
+func TestScannerRestart(t *testing.T) {
+	scanner := NewScanner(config.NewLogSources(), 3, mock.NewMockProvider(), auditor.NewRegistry(), 20*time.Millisecond, false, 10*time.Second)
+	for i := 0; i < 2; i++ {
+		scanner.Start()
+		scanner.Stop()
+	}
+}
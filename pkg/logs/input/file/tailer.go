@@ -181,9 +181,14 @@ func (t *Tailer) readForever() {
 
 // buildTailerTags groups the file tag, directory (if wildcard path) and user tags
 func (t *Tailer) buildTailerTags() []string {
-	tags := []string{fmt.Sprintf("filename:%s", filepath.Base(t.file.Path))}
-	if t.file.IsWildcardPath {
-		tags = append(tags, fmt.Sprintf("dirname:%s", filepath.Dir(t.file.Path)))
+	return buildFileTags(t.file)
+}
+
+// buildFileTags returns the file tag, and the directory tag if the file was found with a wildcard path
+func buildFileTags(file *File) []string {
+	tags := []string{fmt.Sprintf("filename:%s", filepath.Base(file.Path))}
+	if file.IsWildcardPath {
+		tags = append(tags, fmt.Sprintf("dirname:%s", filepath.Dir(file.Path)))
 	}
 	return tags
 }
@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Launcher starts a receiver of OTLP log records for the OTLP source.
+type Launcher struct {
+	pipelineProvider pipeline.Provider
+	sources          chan *config.LogSource
+	bindHost         string
+	grpcPort         int
+	httpPort         int
+	tagKeys          []string
+	receiver         *Receiver
+	stop             chan struct{}
+}
+
+// NewLauncher returns an initialized Launcher, of which the receiver listens on bindHost for gRPC
+// requests on grpcPort and for HTTP requests on httpPort. A port of 0 disables the protocol. The resource
+// attributes whose key is in tagKeys are added as tags.
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider, bindHost string, grpcPort, httpPort int, tagKeys []string) *Launcher {
+	return &Launcher{
+		pipelineProvider: pipelineProvider,
+		sources:          sources.GetAddedForType(config.OTLPType),
+		bindHost:         bindHost,
+		grpcPort:         grpcPort,
+		httpPort:         httpPort,
+		tagKeys:          tagKeys,
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.sources:
+			if l.receiver != nil {
+				log.Warnf("An OTLP logs receiver is already running, ignoring source %s", source.Name)
+				continue
+			}
+			l.receiver = NewReceiver(source, l.pipelineProvider.NextPipelineChan(), l.bindHost, l.grpcPort, l.httpPort, l.tagKeys)
+			if err := l.receiver.Start(); err != nil {
+				log.Errorf("Can't start the OTLP logs receiver: %v", err)
+				source.Status.Error(err)
+				l.receiver = nil
+				continue
+			}
+			source.Status.Success()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the receiver, waiting for the requests in progress to be processed.
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	if l.receiver != nil {
+		l.receiver.Stop()
+		l.receiver = nil
+	}
+}
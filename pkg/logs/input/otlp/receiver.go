@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/otlpgrpc"
+	"go.opentelemetry.io/collector/model/pdata"
+	"google.golang.org/grpc"
+)
+
+const (
+	// logsPath is the path of the HTTP endpoint receiving the log records.
+	logsPath = "/v1/logs"
+
+	// maxRequestBytes is the maximum size of the body of the HTTP requests, once uncompressed.
+	maxRequestBytes = 10 * 1024 * 1024
+
+	// shutdownTimeout is the maximum time given to the requests in progress when stopping.
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	protobufUnmarshaler = otlp.NewProtobufLogsUnmarshaler()
+	jsonUnmarshaler     = otlp.NewJSONLogsUnmarshaler()
+)
+
+// Receiver receives OTLP log records over gRPC and HTTP and sends them as messages to a pipeline.
+type Receiver struct {
+	source     *config.LogSource
+	outputChan chan *message.Message
+	bindHost   string
+	grpcPort   int
+	httpPort   int
+	tagKeys    []string // keys of the resource attributes added as tags
+
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	wg         sync.WaitGroup
+	done       chan struct{} // closed when stopping, to unblock the requests waiting for the pipeline
+}
+
+// NewReceiver returns a new Receiver sending the log records of the source to outputChan. The resource
+// attributes whose key is in tagKeys are added as tags.
+func NewReceiver(source *config.LogSource, outputChan chan *message.Message, bindHost string, grpcPort, httpPort int, tagKeys []string) *Receiver {
+	return &Receiver{
+		source:     source,
+		outputChan: outputChan,
+		bindHost:   bindHost,
+		grpcPort:   grpcPort,
+		httpPort:   httpPort,
+		tagKeys:    tagKeys,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts listening for the log records. It returns an error if a port can not be listened on.
+func (r *Receiver) Start() error {
+	var grpcListener, httpListener net.Listener
+	var err error
+	if r.grpcPort != 0 {
+		if grpcListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", r.bindHost, r.grpcPort)); err != nil {
+			return err
+		}
+	}
+	if r.httpPort != 0 {
+		if httpListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", r.bindHost, r.httpPort)); err != nil {
+			if grpcListener != nil {
+				grpcListener.Close()
+			}
+			return err
+		}
+	}
+	if grpcListener != nil {
+		r.grpcServer = grpc.NewServer()
+		otlpgrpc.RegisterLogsServer(r.grpcServer, r)
+		r.serve("gRPC", grpcListener, r.grpcServer.Serve)
+	}
+	if httpListener != nil {
+		mux := http.NewServeMux()
+		mux.Handle(logsPath, r)
+		r.httpServer = &http.Server{Handler: mux}
+		r.serve("HTTP", httpListener, r.httpServer.Serve)
+	}
+	return nil
+}
+
+func (r *Receiver) serve(protocol string, ln net.Listener, serve func(net.Listener) error) {
+	log.Infof("Listening for OTLP log records over %s on %s", protocol, ln.Addr())
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := serve(ln); err != nil && err != http.ErrServerClosed && err != grpc.ErrServerStopped {
+			log.Errorf("Error serving OTLP log records over %s: %v", protocol, err)
+		}
+	}()
+}
+
+// Stop stops listening, giving some time to the requests in progress to complete.
+func (r *Receiver) Stop() {
+	if r.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := r.httpServer.Shutdown(ctx); err != nil {
+			log.Debugf("Error stopping the OTLP logs HTTP server: %v", err)
+		}
+		cancel()
+	}
+	if r.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			r.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			r.grpcServer.Stop()
+		}
+	}
+	close(r.done)
+	r.wg.Wait()
+}
+
+// Export implements otlpgrpc.LogsServer.
+func (r *Receiver) Export(ctx context.Context, req otlpgrpc.LogsRequest) (otlpgrpc.LogsResponse, error) {
+	if err := r.process(ctx, req.Logs()); err != nil {
+		return otlpgrpc.LogsResponse{}, err
+	}
+	return otlpgrpc.NewLogsResponse(), nil
+}
+
+// ServeHTTP receives the log records sent to the HTTP endpoint, encoded in protobuf or JSON.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gzipr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gzipr.Close()
+		body = gzipr
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxRequestBytes+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxRequestBytes {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.source.BytesRead.Add(int64(len(data)))
+
+	var logs pdata.Logs
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/json":
+		logs, err = jsonUnmarshaler.UnmarshalLogs(data)
+	case "application/x-protobuf", "":
+		logs, err = protobufUnmarshaler.UnmarshalLogs(data)
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := r.process(req.Context(), logs); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	// an empty ExportLogsServiceResponse
+	if contentType == "application/json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// process sends the log records to the pipeline, blocking while it is full.
+func (r *Receiver) process(ctx context.Context, logs pdata.Logs) error {
+	for _, msg := range toMessages(r.source, logs, r.tagKeys, time.Now()) {
+		select {
+		case r.outputChan <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.done:
+			return fmt.Errorf("the OTLP logs receiver is stopped")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/otlpgrpc"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// freePort returns a TCP port which is not in use.
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func newTestReceiver(t *testing.T) (*Receiver, chan *message.Message) {
+	out := make(chan *message.Message, 10)
+	source := config.NewLogSource(config.OTLPLogs, &config.LogsConfig{Type: config.OTLPType})
+	r := NewReceiver(source, out, "localhost", freePort(t), freePort(t), nil)
+	require.NoError(t, r.Start())
+	return r, out
+}
+
+func receive(t *testing.T, out chan *message.Message) *message.Message {
+	select {
+	case msg := <-out:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+		return nil
+	}
+}
+
+func TestReceiverHTTP(t *testing.T) {
+	r, out := newTestReceiver(t)
+	defer r.Stop()
+	url := fmt.Sprintf("http://localhost:%d%s", r.httpPort, logsPath)
+
+	pbPayload, err := otlp.NewProtobufLogsMarshaler().MarshalLogs(testLogs(time.Now()))
+	require.NoError(t, err)
+	jsonPayload, err := otlp.NewJSONLogsMarshaler().MarshalLogs(testLogs(time.Now()))
+	require.NoError(t, err)
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write(pbPayload)
+	gw.Close()
+
+	for name, tt := range map[string]struct {
+		contentType string
+		encoding    string
+		body        []byte
+	}{
+		"protobuf": {contentType: "application/x-protobuf", body: pbPayload},
+		"json":     {contentType: "application/json", body: jsonPayload},
+		"gzip":     {contentType: "application/x-protobuf", encoding: "gzip", body: gzipped.Bytes()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", url, bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Contains(t, string(receive(t, out).Content), `"message":"payment failed"`)
+			assert.Equal(t, "payment retried", string(receive(t, out).Content))
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		resp, err := http.Post(url, "application/x-protobuf", bytes.NewReader([]byte("not protobuf")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Post(url, "text/plain", bytes.NewReader(pbPayload))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		assert.Len(t, out, 0)
+	})
+}
+
+func TestReceiverGRPC(t *testing.T) {
+	r, out := newTestReceiver(t)
+	defer r.Stop()
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", r.grpcPort), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+	req := otlpgrpc.NewLogsRequest()
+	req.SetLogs(testLogs(time.Now()))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = otlpgrpc.NewLogsClient(conn).Export(ctx, req)
+	require.NoError(t, err)
+
+	msg := receive(t, out)
+	assert.Contains(t, string(msg.Content), `"message":"payment failed"`)
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "payment retried", string(receive(t, out).Content))
+}
+
+func TestReceiverStopUnblocksRequests(t *testing.T) {
+	source := config.NewLogSource(config.OTLPLogs, &config.LogsConfig{Type: config.OTLPType})
+	r := NewReceiver(source, make(chan *message.Message), "localhost", 0, 0, nil)
+	require.NoError(t, r.Start())
+	errc := make(chan error)
+	go func() { errc <- r.process(context.Background(), testLogs(time.Now())) }()
+	r.Stop()
+	select {
+	case err := <-errc:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("request still blocked")
+	}
+}
@@ -0,0 +1,148 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/otlp/model/attributes"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"go.opentelemetry.io/collector/model/pdata"
+	conventions "go.opentelemetry.io/collector/model/semconv/v1.5.0"
+)
+
+// otlpSource is the source of the log records.
+const otlpSource = "otlp"
+
+// Attributes added to the log records.
+const (
+	messageAttribute        = "message"
+	traceIDAttribute        = "dd.trace_id"
+	spanIDAttribute         = "dd.span_id"
+	otelTraceIDAttribute    = "otel.trace_id"
+	otelSpanIDAttribute     = "otel.span_id"
+	severityTextAttribute   = "otel.severity_text"
+	severityNumberAttribute = "otel.severity_number"
+)
+
+// toMessages converts the log records of logs into messages of the source. The content of the
+// message of a record is its body or, when it has attributes, severity or a trace context, a JSON
+// object holding its body as "message" along with them, so that the processing rules apply to
+// them too. The Datadog tags of the attributes of its resource, and the attributes whose key is
+// in tagKeys, are added as tags.
+func toMessages(source *config.LogSource, logs pdata.Logs, tagKeys []string, now time.Time) []*message.Message {
+	var msgs []*message.Message
+	rls := logs.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		res := rl.Resource().Attributes()
+		tags := resourceTags(res, tagKeys)
+		service := ""
+		if v, ok := res.Get(conventions.AttributeServiceName); ok {
+			service = v.AsString()
+		}
+		ills := rl.InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			records := ills.At(j).Logs()
+			for k := 0; k < records.Len(); k++ {
+				origin := message.NewOrigin(source)
+				origin.SetTags(tags)
+				origin.SetService(service)
+				origin.SetSource(otlpSource)
+				msgs = append(msgs, toMessage(records.At(k), origin, now))
+			}
+		}
+	}
+	return msgs
+}
+
+// toMessage converts the log record lr into a message of the given origin.
+func toMessage(lr pdata.LogRecord, origin *message.Origin, now time.Time) *message.Message {
+	attrs := lr.Attributes().AsRaw()
+	if traceID := lr.TraceID(); !traceID.IsEmpty() {
+		b := traceID.Bytes()
+		// Datadog trace IDs are the lower 64 bits of the OpenTelemetry ones
+		attrs[traceIDAttribute] = strconv.FormatUint(binary.BigEndian.Uint64(b[8:]), 10)
+		attrs[otelTraceIDAttribute] = traceID.HexString()
+	}
+	if spanID := lr.SpanID(); !spanID.IsEmpty() {
+		b := spanID.Bytes()
+		attrs[spanIDAttribute] = strconv.FormatUint(binary.BigEndian.Uint64(b[:]), 10)
+		attrs[otelSpanIDAttribute] = spanID.HexString()
+	}
+	if text := lr.SeverityText(); text != "" {
+		attrs[severityTextAttribute] = text
+	}
+	if number := lr.SeverityNumber(); number != pdata.SeverityNumberUNDEFINED {
+		attrs[severityNumberAttribute] = int64(number)
+	}
+	content := []byte(lr.Body().AsString())
+	if len(attrs) > 0 {
+		attrs[messageAttribute] = lr.Body().AsString()
+		if b, err := json.Marshal(attrs); err == nil {
+			content = b
+		} else {
+			log.Debugf("Can't encode the attributes of an OTLP log record, sending its body only: %v", err)
+		}
+	}
+	msg := message.NewMessage(content, origin, toStatus(lr.SeverityNumber(), lr.SeverityText()), now.UnixNano())
+	msg.Timestamp = now.UTC()
+	if ts := lr.Timestamp(); ts != 0 {
+		msg.Timestamp = ts.AsTime().UTC()
+	}
+	return msg
+}
+
+// toStatus returns the status of a log record having the given severity. The severity text is
+// only used when the severity number is not set.
+// See https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/logs/data-model.md#field-severitynumber
+func toStatus(number pdata.SeverityNumber, text string) string {
+	switch {
+	case number >= pdata.SeverityNumberFATAL:
+		return message.StatusCritical
+	case number >= pdata.SeverityNumberERROR:
+		return message.StatusError
+	case number >= pdata.SeverityNumberWARN:
+		return message.StatusWarning
+	case number >= pdata.SeverityNumberINFO:
+		return message.StatusInfo
+	case number >= pdata.SeverityNumberTRACE:
+		return message.StatusDebug
+	}
+	switch strings.ToLower(text) {
+	case "fatal", "critical":
+		return message.StatusCritical
+	case "error":
+		return message.StatusError
+	case "warn", "warning":
+		return message.StatusWarning
+	case "debug", "trace":
+		return message.StatusDebug
+	}
+	return message.StatusInfo
+}
+
+// resourceTags returns the tags of the resource attributes attrs: the Datadog tags of the attributes
+// following the semantic conventions, such as "env", and a "key:value" tag per attribute whose key
+// is in keys.
+func resourceTags(attrs pdata.AttributeMap, keys []string) []string {
+	tags := attributes.TagsFromAttributes(attrs)
+	for _, k := range keys {
+		if v, ok := attrs.Get(k); ok {
+			if s := v.AsString(); s != "" {
+				tags = append(tags, k+":"+s)
+			}
+		}
+	}
+	return tags
+}
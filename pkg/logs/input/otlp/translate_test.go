@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/model/pdata"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+)
+
+// testLogs returns logs holding a record with a trace context and a record without.
+func testLogs(ts time.Time) pdata.Logs {
+	logs := pdata.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().InsertString("service.name", "checkout")
+	rl.Resource().Attributes().InsertString("deployment.environment", "prod")
+	rl.Resource().Attributes().InsertInt("shard", 3)
+	records := rl.InstrumentationLibraryLogs().AppendEmpty().Logs()
+
+	lr := records.AppendEmpty()
+	lr.Body().SetStringVal("payment failed")
+	lr.SetTimestamp(pdata.NewTimestampFromTime(ts))
+	lr.SetSeverityNumber(pdata.SeverityNumberERROR2)
+	lr.SetSeverityText("ERROR")
+	lr.SetTraceID(pdata.NewTraceID([16]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}))
+	lr.SetSpanID(pdata.NewSpanID([8]byte{0, 0, 0, 0, 0, 0, 0, 3}))
+	lr.Attributes().InsertString("user.id", "42")
+	lr.Attributes().InsertInt("attempt", 2)
+
+	lr = records.AppendEmpty()
+	lr.Body().SetStringVal("payment retried")
+	return logs
+}
+
+func TestToMessages(t *testing.T) {
+	now := time.Now()
+	ts := now.Add(-time.Minute)
+	source := config.NewLogSource(config.OTLPLogs, &config.LogsConfig{Type: config.OTLPType})
+	msgs := toMessages(source, testLogs(ts), []string{"shard", "unknown"}, now)
+	if !assert.Len(t, msgs, 2) {
+		return
+	}
+
+	msg := msgs[0]
+	var content map[string]interface{}
+	assert.NoError(t, json.Unmarshal(msg.Content, &content))
+	assert.Equal(t, map[string]interface{}{
+		"message":              "payment failed",
+		"user.id":              "42",
+		"attempt":              float64(2),
+		"dd.trace_id":          "2",
+		"dd.span_id":           "3",
+		"otel.trace_id":        "00000000000000010000000000000002",
+		"otel.span_id":         "0000000000000003",
+		"otel.severity_text":   "ERROR",
+		"otel.severity_number": float64(pdata.SeverityNumberERROR2),
+	}, content)
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, ts.UnixNano(), msg.Timestamp.UnixNano())
+	assert.Equal(t, now.UnixNano(), msg.IngestionTimestamp)
+	assert.Equal(t, "checkout", msg.Origin.Service())
+	assert.Equal(t, "otlp", msg.Origin.Source())
+	assert.ElementsMatch(t, []string{
+		"shard:3",
+		"service:checkout",
+		"env:prod",
+	}, msg.Origin.Tags())
+
+	msg = msgs[1]
+	assert.Equal(t, "payment retried", string(msg.Content))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	assert.Equal(t, now.UnixNano(), msg.Timestamp.UnixNano())
+	assert.Equal(t, msgs[0].Origin.Tags(), msg.Origin.Tags())
+}
+
+func TestToMessagesProcessingRules(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Type: config.MaskSequences, Name: "mask_user", Pattern: `"user.id":"\d+"`, ReplacePlaceholder: `"user.id":"[masked]"`},
+		{Type: config.ExcludeAtMatch, Name: "exclude_retries", Pattern: "retried"},
+	}
+	assert.NoError(t, config.CompileProcessingRules(rules))
+	source := config.NewLogSource(config.OTLPLogs, &config.LogsConfig{Type: config.OTLPType, ProcessingRules: rules})
+	in, out := make(chan *message.Message, 2), make(chan *message.Message, 2)
+	p := processor.New(in, out, nil, processor.JSONEncoder, &diagnostic.NoopMessageReceiver{})
+	p.Start()
+	defer p.Stop()
+	for _, msg := range toMessages(source, testLogs(time.Now()), nil, time.Now()) {
+		in <- msg
+	}
+
+	select {
+	case msg := <-out:
+		// the attributes are masked, and the records are excluded, like the rest of the logs
+		assert.Contains(t, string(msg.Content), `user.id\":\"[masked]\"`)
+		assert.NotContains(t, string(msg.Content), "42")
+		assert.Contains(t, string(msg.Content), "payment failed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+	select {
+	case msg := <-out:
+		t.Fatalf("unexpected message: %s", msg.Content)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	for _, tt := range []struct {
+		number pdata.SeverityNumber
+		text   string
+		status string
+	}{
+		{pdata.SeverityNumberTRACE2, "", message.StatusDebug},
+		{pdata.SeverityNumberDEBUG, "", message.StatusDebug},
+		{pdata.SeverityNumberINFO4, "error", message.StatusInfo},
+		{pdata.SeverityNumberWARN, "", message.StatusWarning},
+		{pdata.SeverityNumberERROR3, "", message.StatusError},
+		{pdata.SeverityNumberFATAL, "", message.StatusCritical},
+		{pdata.SeverityNumberUNDEFINED, "Warning", message.StatusWarning},
+		{pdata.SeverityNumberUNDEFINED, "FATAL", message.StatusCritical},
+		{pdata.SeverityNumberUNDEFINED, "notice", message.StatusInfo},
+		{pdata.SeverityNumberUNDEFINED, "", message.StatusInfo},
+	} {
+		assert.Equal(t, tt.status, toStatus(tt.number, tt.text), "%d %q", tt.number, tt.text)
+	}
+}
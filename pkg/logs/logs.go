@@ -139,6 +139,12 @@ func start(getAC func() *autodiscovery.AutoConfig, serverless bool, logsChan cha
 		sources.AddSource(source)
 	}
 
+	// add OTLP source collecting the log records received over OTLP if enabled.
+	if source := config.OTLPSource(); source != nil {
+		log.Debug("Adding OTLP source to the Logs Agent")
+		sources.AddSource(source)
+	}
+
 	// adds the source collecting logs from all containers if enabled,
 	// but ensure that it is enabled after the AutoConfig initialization
 	if source := config.ContainerCollectAllSource(); source != nil {
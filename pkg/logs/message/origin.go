@@ -88,6 +88,12 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags adds tags to the tags of the origin.
+func (o *Origin) AddTags(tags ...string) {
+	// Copy the tags as the slice can be shared by several origins
+	o.tags = append(o.tags[:len(o.tags):len(o.tags)], tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
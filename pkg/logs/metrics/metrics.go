@@ -40,6 +40,11 @@ var (
 	// TlmLogsDropped is the total number of logs dropped per Destination
 	TlmLogsDropped = telemetry.NewCounter("logs", "dropped",
 		[]string{"destination"}, "Total number of logs dropped per Destination")
+	// LogsSampledOut is the total number of logs dropped by the sample and rate_limit processing rules
+	LogsSampledOut = expvar.Int{}
+	// TlmLogsSampledOut is the total number of logs dropped by the sample and rate_limit processing rules
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out",
+		[]string{"rule_type", "rule_name"}, "Total number of logs dropped by the sample and rate_limit processing rules")
 	// BytesSent is the total number of sent bytes before encoding if any
 	BytesSent = expvar.Int{}
 	// TlmBytesSent is the total number of sent bytes before encoding if any
@@ -56,6 +61,16 @@ var (
 	// TlmSenderLatency a histogram of http sender latency (ms)
 	TlmSenderLatency = telemetry.NewHistogram("logs", "sender_latency",
 		nil, "Histogram of http sender latency in ms", []float64{10, 25, 50, 75, 100, 250, 500, 1000, 10000})
+	// SpoolSizeInBytes is the size of the payloads stored in the disk spool
+	SpoolSizeInBytes = expvar.Int{}
+	// TlmSpoolSizeInBytes is the size of the payloads stored in the disk spool
+	TlmSpoolSizeInBytes = telemetry.NewGauge("logs", "spool_size_bytes",
+		nil, "Size of the payloads stored in the disk spool")
+	// SpoolPayloads is the number of payloads stored in the disk spool
+	SpoolPayloads = expvar.Int{}
+	// TlmSpoolPayloads is the number of payloads stored in the disk spool
+	TlmSpoolPayloads = telemetry.NewGauge("logs", "spool_payloads",
+		nil, "Number of payloads stored in the disk spool")
 	// TODO: Add LogsCollected for the total number of collected logs.
 
 )
@@ -67,7 +82,10 @@ func init() {
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
 	LogsExpvars.Set("BytesSent", &BytesSent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
+	LogsExpvars.Set("SpoolSizeInBytes", &SpoolSizeInBytes)
+	LogsExpvars.Set("SpoolPayloads", &SpoolPayloads)
 }
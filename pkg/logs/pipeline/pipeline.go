@@ -25,8 +25,8 @@ type Pipeline struct {
 	sender    *sender.Sender
 }
 
-// NewPipeline returns a new Pipeline
-func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diagnosticMessageReceiver diagnostic.MessageReceiver, serverless bool) *Pipeline {
+// NewPipeline returns a new Pipeline, spool can be nil
+func NewPipeline(outputChan chan *message.Message, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, diagnosticMessageReceiver diagnostic.MessageReceiver, serverless bool, spool *sender.DiskSpool) *Pipeline {
 	var destinations *client.Destinations
 	if endpoints.UseHTTP {
 		main := http.NewDestination(endpoints.Main, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend)
@@ -52,7 +52,7 @@ func NewPipeline(outputChan chan *message.Message, processingRules []*config.Pro
 	} else {
 		strategy = sender.StreamStrategy
 	}
-	sender := sender.NewSender(senderChan, outputChan, destinations, strategy)
+	sender := sender.NewSenderWithSpool(senderChan, outputChan, destinations, strategy, spool)
 
 	var encoder processor.Encoder
 	if serverless {
@@ -7,15 +7,20 @@ package pipeline
 
 import (
 	"context"
+	"path/filepath"
 	"sync/atomic"
 
+	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 )
 
 // Provider provides message channels
@@ -39,6 +44,7 @@ type provider struct {
 	pipelines            []*Pipeline
 	currentPipelineIndex int32
 	destinationsContext  *client.DestinationsContext
+	spool                *sender.DiskSpool
 
 	serverless bool
 }
@@ -70,9 +76,10 @@ func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessa
 func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
+	p.spool = p.newSpool()
 
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, p.spool)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
@@ -86,10 +93,35 @@ func (p *provider) Stop() {
 		stopper.Add(pipeline)
 	}
 	stopper.Stop()
+	// the spool is stopped once the senders can not store payloads anymore
+	if p.spool != nil {
+		p.spool.Stop()
+		p.spool = nil
+	}
 	p.pipelines = p.pipelines[:0]
 	p.outputChan = nil
 }
 
+// newSpool returns the disk spool shared by the senders of the pipelines,
+// or nil if it is disabled. The spool is only supported with HTTP endpoints.
+func (p *provider) newSpool() *sender.DiskSpool {
+	if p.serverless || !p.endpoints.UseHTTP || !coreConfig.Datadog.GetBool("logs_config.disk_spool.enabled") {
+		return nil
+	}
+
+	path := coreConfig.Datadog.GetString("logs_config.disk_spool.path")
+	if path == "" {
+		path = filepath.Join(coreConfig.Datadog.GetString("logs_config.run_path"), "spool")
+	}
+	spool, err := sender.NewDiskSpool(path, coreConfig.Datadog.GetInt64("logs_config.disk_spool.max_size_in_bytes"))
+	if err != nil {
+		log.Warnf("Could not create the logs disk spool in %s, the payloads are kept in memory during outages: %v", path, err)
+		return nil
+	}
+	spool.Start(http.NewDestination(p.endpoints.Main, http.JSONContentType, p.destinationsContext, 0))
+	return spool
+}
+
 // NextPipelineChan returns the next pipeline input channel
 func (p *provider) NextPipelineChan() chan *message.Message {
 	pipelinesLen := len(p.pipelines)
@@ -221,6 +221,29 @@ func TestJsonEncoder(t *testing.T) {
 	assert.NotEmpty(t, log.Timestamp)
 }
 
+func TestJsonEncoderWithParsedAttributes(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{Service: "Service"})
+	msg := newMessage([]byte("message"), source, message.StatusError)
+	msg.ParsedAttributes = map[string]interface{}{
+		"http":    map[string]interface{}{"status_code": int64(200)},
+		"service": "overridden",
+	}
+
+	jsonMessage, err := JSONEncoder.Encode(msg, []byte("redacted"))
+	assert.Nil(t, err)
+
+	log := map[string]interface{}{}
+	err = json.Unmarshal(jsonMessage, &log)
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]interface{}{"status_code": float64(200)}, log["http"])
+	assert.Equal(t, "Service", log["service"])
+	assert.Equal(t, "redacted", log["message"])
+	assert.Equal(t, message.StatusError, log["status"])
+	assert.NotEmpty(t, log["timestamp"])
+	assert.NotEmpty(t, log["hostname"])
+}
+
 func TestEncoderToValidUTF8(t *testing.T) {
 	assert.Equal(t, "a�z", toValidUtf8([]byte("a\xfez")))
 	assert.Equal(t, "a��z", toValidUtf8([]byte("a\xc0\xafz")))
@@ -37,7 +37,7 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 	if !msg.Timestamp.IsZero() {
 		ts = msg.Timestamp
 	}
-	return json.Marshal(jsonPayload{
+	payload := jsonPayload{
 		Message:   toValidUtf8(redactedMsg),
 		Status:    msg.GetStatus(),
 		Timestamp: ts.UnixNano() / nanoToMillis,
@@ -45,5 +45,26 @@ func (j *jsonEncoder) Encode(msg *message.Message, redactedMsg []byte) ([]byte,
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
 		Tags:      msg.Origin.TagsToString(),
-	})
+	}
+	if len(msg.ParsedAttributes) > 0 {
+		return json.Marshal(withParsedAttributes(payload, msg.ParsedAttributes))
+	}
+	return json.Marshal(payload)
+}
+
+// withParsedAttributes merges the parsed attributes with the payload,
+// the attributes of the payload take precedence.
+func withParsedAttributes(payload jsonPayload, attributes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(attributes)+7)
+	for name, value := range attributes {
+		merged[name] = value
+	}
+	merged["message"] = payload.Message
+	merged["status"] = payload.Status
+	merged["timestamp"] = payload.Timestamp
+	merged["hostname"] = payload.Hostname
+	merged["service"] = payload.Service
+	merged["ddsource"] = payload.Source
+	merged["ddtags"] = payload.Tags
+	return merged
 }
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// statusAliases maps the values of a status field to a message status.
+var statusAliases = map[string]string{
+	message.StatusEmergency: message.StatusEmergency,
+	"emerg":                 message.StatusEmergency,
+	message.StatusAlert:     message.StatusAlert,
+	message.StatusCritical:  message.StatusCritical,
+	"crit":                  message.StatusCritical,
+	"fatal":                 message.StatusCritical,
+	message.StatusError:     message.StatusError,
+	"err":                   message.StatusError,
+	message.StatusWarning:   message.StatusWarning,
+	"warning":               message.StatusWarning,
+	message.StatusNotice:    message.StatusNotice,
+	message.StatusInfo:      message.StatusInfo,
+	"information":           message.StatusInfo,
+	message.StatusDebug:     message.StatusDebug,
+	"trace":                 message.StatusDebug,
+}
+
+// jsonContent holds the content of a message parsed as a JSON object,
+// the content is parsed once for consecutive JSON processing rules.
+type jsonContent struct {
+	fields   map[string]interface{}
+	parsed   bool
+	modified bool
+}
+
+// parse parses content if it was not already done, and returns false if content is not a JSON object.
+func (c *jsonContent) parse(content []byte) bool {
+	if !c.parsed {
+		c.parsed = true
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		var fields map[string]interface{}
+		if err := decoder.Decode(&fields); err == nil && !decoder.More() {
+			c.fields = fields
+		}
+	}
+	return c.fields != nil
+}
+
+// flush returns the content updated by the JSON processing rules, and resets the parsed content.
+// The fields of the updated content are sorted by name.
+func (c *jsonContent) flush(content []byte) []byte {
+	defer func() { *c = jsonContent{} }()
+	if !c.modified {
+		return content
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(c.fields); err != nil {
+		return content
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// lookup returns the object holding the field at path, and the name of the field in this object.
+func (c *jsonContent) lookup(path []string) (map[string]interface{}, string, bool) {
+	fields := c.fields
+	for _, name := range path[:len(path)-1] {
+		next, ok := fields[name].(map[string]interface{})
+		if !ok {
+			return nil, "", false
+		}
+		fields = next
+	}
+	name := path[len(path)-1]
+	_, found := fields[name]
+	return fields, name, found
+}
+
+// applyJSONRule applies a JSON processing rule to the parsed content of msg,
+// and returns false if the message must be excluded.
+func applyJSONRule(rule *config.ProcessingRule, content *jsonContent, msg *message.Message) bool {
+	fields, name, found := content.lookup(rule.FieldPath)
+	if !found {
+		return true
+	}
+	value := fields[name]
+
+	switch rule.Type {
+	case config.JSONExcludeAtMatch:
+		if str, ok := fieldValueToString(value); ok && rule.Regex.MatchString(str) {
+			return false
+		}
+	case config.JSONDropField:
+		delete(fields, name)
+		content.modified = true
+	case config.JSONRenameField:
+		delete(fields, name)
+		fields[rule.Target] = value
+		content.modified = true
+	case config.JSONMaskField:
+		if rule.Regex == nil {
+			fields[name] = rule.ReplacePlaceholder
+			content.modified = true
+		} else if str, ok := value.(string); ok {
+			fields[name] = rule.Regex.ReplaceAllString(str, rule.ReplacePlaceholder)
+			content.modified = true
+		}
+	case config.JSONFieldToTag:
+		if str, ok := fieldValueToString(value); ok {
+			tagName := rule.Target
+			if tagName == "" {
+				tagName = rule.Path
+			}
+			msg.Origin.AddTags(tagName + ":" + str)
+		}
+	case config.JSONFieldToService:
+		if str, ok := fieldValueToString(value); ok && str != "" {
+			msg.Origin.SetService(str)
+		}
+	case config.JSONFieldToStatus:
+		if str, ok := fieldValueToString(value); ok {
+			if status, exists := statusAliases[strings.ToLower(str)]; exists {
+				msg.SetStatus(status)
+			}
+		}
+	}
+	return true
+}
+
+// fieldValueToString returns the string representation of a scalar JSON value.
+func fieldValueToString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+)
+
+// applyParsingRules extracts attributes from content with the first parsing rule matching it,
+// and returns nil if no rule matches.
+func applyParsingRules(rules []*config.ParsingRule, content []byte) map[string]interface{} {
+	for _, rule := range rules {
+		submatches := rule.Regex.FindSubmatchIndex(content)
+		if submatches == nil {
+			continue
+		}
+
+		attributes := make(map[string]interface{})
+		for i, field := range rule.Fields {
+			start, end := submatches[2*i], submatches[2*i+1]
+			if field.Name == "" || start < 0 {
+				continue
+			}
+			setAttribute(attributes, field.Path, parseValue(string(content[start:end]), field.Type))
+		}
+		return attributes
+	}
+	return nil
+}
+
+// parseValue converts value to the type of its field, and keeps it as a string if it cannot.
+func parseValue(value string, fieldType string) interface{} {
+	switch fieldType {
+	case config.ParsedIntType:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case config.ParsedFloatType:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// setAttribute sets the nested attribute at path.
+func setAttribute(attributes map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		next, ok := attributes[name].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			attributes[name] = next
+		}
+		attributes = next
+	}
+	attributes[path[len(path)-1]] = value
+}
@@ -90,6 +90,9 @@ func (p *Processor) processMessage(msg *message.Message) {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
+		// Parse the redacted content so that masked sequences are not extracted
+		msg.ParsedAttributes = applyParsingRules(msg.Origin.LogSource.Config.ParsingRules, redactedMsg)
+
 		p.diagnosticMessageReceiver.HandleMessage(*msg, redactedMsg)
 
 		// Encode the message to its final format
@@ -107,8 +110,18 @@ func (p *Processor) processMessage(msg *message.Message) {
 // and a copy of the message with some fields redacted, depending on config
 func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 	content := msg.Content
+	var parsedContent jsonContent
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
 	for _, rule := range rules {
+		if rule.IsJSONRule() {
+			// Lines which are not JSON objects are left untouched by the JSON rules
+			if parsedContent.parse(content) && !applyJSONRule(rule, &parsedContent, msg) {
+				return false, nil
+			}
+			continue
+		}
+
+		content = parsedContent.flush(content)
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			if rule.Regex.Match(content) {
@@ -120,7 +133,26 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.Sample, config.RateLimit:
+			if shouldSampleOut(rule, content) {
+				metrics.LogsSampledOut.Add(1)
+				metrics.TlmLogsSampledOut.Inc(rule.Type, rule.Name)
+				return false, nil
+			}
 		}
 	}
-	return true, content
+	return true, parsedContent.flush(content)
+}
+
+// shouldSampleOut returns true if the content is dropped by a sample or rate_limit rule.
+// The rule only applies to the content matching its pattern, if any, and never drops
+// the content matching its keep pattern.
+func shouldSampleOut(rule *config.ProcessingRule, content []byte) bool {
+	if rule.Regex != nil && !rule.Regex.Match(content) {
+		return false
+	}
+	if rule.KeepRegex != nil && rule.KeepRegex.Match(content) {
+		return false
+	}
+	return !rule.Sampler.Keep()
 }
@@ -10,7 +10,9 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -138,6 +140,182 @@ func TestTruncate(t *testing.T) {
 	assert.Equal(t, []byte("hello"), redactedMessage)
 }
 
+func TestJSONRules(t *testing.T) {
+	tests := []struct {
+		name            string
+		rule            *config.ProcessingRule
+		content         string
+		shouldProcess   bool
+		expectedContent string
+	}{
+		{
+			name:            "drop a nested field",
+			rule:            &config.ProcessingRule{Type: config.JSONDropField, Path: "user.password"},
+			content:         `{"message":"login","user":{"name":"foo","password":"bar"}}`,
+			shouldProcess:   true,
+			expectedContent: `{"message":"login","user":{"name":"foo"}}`,
+		},
+		{
+			name:            "rename a field",
+			rule:            &config.ProcessingRule{Type: config.JSONRenameField, Path: "msg", Target: "message"},
+			content:         `{"msg":"hello","count":10}`,
+			shouldProcess:   true,
+			expectedContent: `{"count":10,"message":"hello"}`,
+		},
+		{
+			name:            "mask a field",
+			rule:            &config.ProcessingRule{Type: config.JSONMaskField, Path: "token", ReplacePlaceholder: "[redacted]"},
+			content:         `{"token":"abcdef","message":"token abcdef"}`,
+			shouldProcess:   true,
+			expectedContent: `{"message":"token abcdef","token":"[redacted]"}`,
+		},
+		{
+			name:            "mask a sequence in a field",
+			rule:            &config.ProcessingRule{Type: config.JSONMaskField, Path: "http.url", Pattern: "api_key=[a-z0-9]+", ReplacePlaceholder: "api_key=<hidden>"},
+			content:         `{"http":{"url":"/v1?api_key=abc123"},"message":"api_key=abc123"}`,
+			shouldProcess:   true,
+			expectedContent: `{"http":{"url":"/v1?api_key=<hidden>"},"message":"api_key=abc123"}`,
+		},
+		{
+			name:            "exclude by field value",
+			rule:            &config.ProcessingRule{Type: config.JSONExcludeAtMatch, Path: "level", Pattern: "^debug$"},
+			content:         `{"level":"debug","message":"hello"}`,
+			shouldProcess:   false,
+			expectedContent: "",
+		},
+		{
+			name:            "keep other field values",
+			rule:            &config.ProcessingRule{Type: config.JSONExcludeAtMatch, Path: "level", Pattern: "^debug$"},
+			content:         `{"level":"info","message":"hello"}`,
+			shouldProcess:   true,
+			expectedContent: `{"level":"info","message":"hello"}`,
+		},
+		{
+			name:            "ignore missing fields",
+			rule:            &config.ProcessingRule{Type: config.JSONDropField, Path: "user.password"},
+			content:         `{"user":"foo"}`,
+			shouldProcess:   true,
+			expectedContent: `{"user":"foo"}`,
+		},
+		{
+			name:            "ignore lines which are not JSON objects",
+			rule:            &config.ProcessingRule{Type: config.JSONDropField, Path: "user"},
+			content:         `user=foo {"user":"foo"}`,
+			shouldProcess:   true,
+			expectedContent: `user=foo {"user":"foo"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.rule.Name = "test"
+			assert.NoError(t, config.ValidateProcessingRules([]*config.ProcessingRule{test.rule}))
+			assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{test.rule}))
+			p := &Processor{processingRules: []*config.ProcessingRule{test.rule}}
+			source := config.NewLogSource("", &config.LogsConfig{})
+
+			shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte(test.content), source, ""))
+			assert.Equal(t, test.shouldProcess, shouldProcess)
+			assert.Equal(t, test.expectedContent, string(redactedMessage))
+		})
+	}
+}
+
+func TestJSONRulesPromoteFields(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Name: "tag", Type: config.JSONFieldToTag, Path: "user.id", Target: "user_id"},
+		{Name: "service", Type: config.JSONFieldToService, Path: "app"},
+		{Name: "status", Type: config.JSONFieldToStatus, Path: "level"},
+		{Name: "mask", Type: config.MaskSequences, Pattern: "secret", ReplacePlaceholder: "[masked]"},
+		{Name: "drop", Type: config.JSONDropField, Path: "app"},
+	}
+	assert.NoError(t, config.CompileProcessingRules(rules))
+	p := &Processor{processingRules: rules}
+	source := config.NewLogSource("", &config.LogsConfig{Tags: []string{"env:prod"}})
+	msg := newMessage([]byte(`{"user":{"id":42},"app":"shop","level":"WARNING","message":"secret"}`), source, "")
+
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, `{"level":"WARNING","message":"[masked]","user":{"id":42}}`, string(redactedMessage))
+	assert.Equal(t, []string{"user_id:42", "env:prod"}, msg.Origin.Tags())
+	assert.Equal(t, "shop", msg.Origin.Service())
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+}
+
+func TestParsingRules(t *testing.T) {
+	rules := []*config.ParsingRule{
+		{Name: "access", Pattern: `^%{IPORHOST:network.client.ip} %{WORD:http.method} %{URIPATHPARAM:http.url} %{INT:http.status_code:int} %{NUMBER:duration:float}`},
+		{Name: "level", Pattern: `^(?P<level>[A-Z]+): (?P<msg>.*)$`},
+	}
+	assert.NoError(t, config.ValidateParsingRules(rules))
+	assert.NoError(t, config.CompileParsingRules(rules))
+
+	attributes := applyParsingRules(rules, []byte("10.0.0.1 GET /api/v1/users?id=1 404 0.25 extra"))
+	assert.Equal(t, map[string]interface{}{
+		"network":  map[string]interface{}{"client": map[string]interface{}{"ip": "10.0.0.1"}},
+		"http":     map[string]interface{}{"method": "GET", "url": "/api/v1/users?id=1", "status_code": int64(404)},
+		"duration": 0.25,
+	}, attributes)
+
+	attributes = applyParsingRules(rules, []byte("ERROR: something failed"))
+	assert.Equal(t, map[string]interface{}{"level": "ERROR", "msg": "something failed"}, attributes)
+
+	assert.Nil(t, applyParsingRules(rules, []byte("not matching")))
+}
+
+func TestProcessMessageWithParsingRules(t *testing.T) {
+	rules := []*config.ParsingRule{{Name: "user", Pattern: `user=%{NOTSPACE:user} token=%{NOTSPACE:token}`}}
+	assert.NoError(t, config.CompileParsingRules(rules))
+	source := config.NewLogSource("", &config.LogsConfig{
+		ProcessingRules: []*config.ProcessingRule{newProcessingRule(config.MaskSequences, "[masked]", "secret")},
+		ParsingRules:    rules,
+	})
+
+	outputChan := make(chan *message.Message, 1)
+	p := New(nil, outputChan, nil, JSONEncoder, &diagnostic.NoopMessageReceiver{})
+	p.processMessage(newMessage([]byte("user=foo token=secret"), source, ""))
+	msg := <-outputChan
+
+	// The redacted content is parsed
+	assert.Equal(t, map[string]interface{}{"user": "foo", "token": "[masked]"}, msg.ParsedAttributes)
+	assert.Contains(t, string(msg.Content), `"user":"foo"`)
+}
+
+func TestSampleRule(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.Sample, Name: "sample", SampleRate: 2, Pattern: "noisy", KeepPattern: "(?i)error"}
+	assert.NoError(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	p := &Processor{processingRules: []*config.ProcessingRule{rule}}
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	sampledOut := metrics.LogsSampledOut.Value()
+	var kept []string
+	for _, content := range []string{"noisy 1", "noisy 2", "other", "noisy ERROR", "noisy 3", "noisy 4"} {
+		if shouldProcess, _ := p.applyRedactingRules(newMessage([]byte(content), source, "")); shouldProcess {
+			kept = append(kept, content)
+		}
+	}
+	assert.Equal(t, []string{"noisy 1", "other", "noisy ERROR", "noisy 3"}, kept)
+	assert.Equal(t, sampledOut+2, metrics.LogsSampledOut.Value())
+}
+
+func TestRateLimitRule(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "rate_limit", MaxLinesPerSecond: 2}
+	assert.NoError(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	p := &Processor{}
+	source := config.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+
+	keptCount := 0
+	for i := 0; i < 5; i++ {
+		if shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("hello"), source, "")); shouldProcess {
+			keptCount++
+		}
+	}
+	// The test does not last one second
+	assert.Equal(t, 2, keptCount)
+}
+
 func newProcessingRule(ruleType, replacePlaceholder, pattern string) *config.ProcessingRule {
 	return &config.ProcessingRule{
 		Type:               ruleType,
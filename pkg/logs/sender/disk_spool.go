@@ -0,0 +1,255 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+const (
+	spoolFileExtension    = ".spool"
+	spoolTmpFileExtension = ".tmp"
+)
+
+// defaultSpoolRetryInterval is the time the spool waits before replaying a payload again
+// after a failure, the destination applies its own backoff on top of it.
+const defaultSpoolRetryInterval = 1 * time.Second
+
+// spoolFile is a payload stored in the spool.
+type spoolFile struct {
+	path        string
+	sizeInBytes int64
+}
+
+// DiskSpool stores on disk the payloads the senders could not send because the intake is unreachable,
+// and replays them in order once the connectivity returns. The spool is shared by the senders of all
+// the pipelines and its size on disk is bounded.
+type DiskSpool struct {
+	path           string
+	maxSizeInBytes int64
+	retryInterval  time.Duration
+
+	mu          sync.Mutex
+	files       []spoolFile
+	sizeInBytes int64
+	sequence    uint64
+
+	destination client.Destination
+	wakeUp      chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewDiskSpool returns a new spool storing its payloads in path,
+// the payloads stored by a previous run of the agent are replayed first.
+func NewDiskSpool(path string, maxSizeInBytes int64) (*DiskSpool, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &DiskSpool{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+		retryInterval:  defaultSpoolRetryInterval,
+		wakeUp:         make(chan struct{}, 1),
+	}
+	if err := s.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start starts replaying the stored payloads to destination.
+func (s *DiskSpool) Start(destination client.Destination) {
+	s.destination = destination
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run()
+}
+
+// Stop stops replaying the stored payloads, they are kept on disk for the next run.
+func (s *DiskSpool) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Store writes payload to disk, and returns false if the spool is full or the payload could not be written.
+func (s *DiskSpool) Store(payload []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizeInBytes := int64(len(payload))
+	if s.sizeInBytes+sizeInBytes > s.maxSizeInBytes {
+		return false
+	}
+
+	s.sequence++
+	path := filepath.Join(s.path, fmt.Sprintf("%020d%s", s.sequence, spoolFileExtension))
+	// the payload is renamed once fully written so that a crash can not leave a partial payload
+	if err := ioutil.WriteFile(path+spoolTmpFileExtension, payload, 0600); err != nil {
+		log.Warnf("Could not write payload to the logs disk spool: %v", err)
+		return false
+	}
+	if err := os.Rename(path+spoolTmpFileExtension, path); err != nil {
+		log.Warnf("Could not write payload to the logs disk spool: %v", err)
+		_ = os.Remove(path + spoolTmpFileExtension)
+		return false
+	}
+
+	s.files = append(s.files, spoolFile{path: path, sizeInBytes: sizeInBytes})
+	s.sizeInBytes += sizeInBytes
+	s.updateMetrics()
+
+	select {
+	case s.wakeUp <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// IsEmpty returns true if there is no payload to replay.
+func (s *DiskSpool) IsEmpty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files) == 0
+}
+
+// SizeInBytes returns the size of the stored payloads.
+func (s *DiskSpool) SizeInBytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sizeInBytes
+}
+
+// run replays the stored payloads, the oldest first, until the spool is stopped.
+func (s *DiskSpool) run() {
+	defer close(s.done)
+	for {
+		file, found := s.oldest()
+		if !found {
+			select {
+			case <-s.wakeUp:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		if !s.replay(file) {
+			select {
+			case <-time.After(s.retryInterval):
+			case <-s.stop:
+				return
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+// replay sends the payload stored in file, and returns false if it must be retried.
+func (s *DiskSpool) replay(file spoolFile) bool {
+	payload, err := ioutil.ReadFile(file.path)
+	if err != nil {
+		log.Warnf("Could not read payload from the logs disk spool, dropping it: %v", err)
+		s.remove(file)
+		return true
+	}
+
+	err = s.destination.Send(payload)
+	if err != nil {
+		if _, ok := err.(*client.RetryableError); ok || shouldStopSending(err) {
+			return false
+		}
+		log.Warnf("Could not send payload from the logs disk spool, dropping it: %v", err)
+	}
+	s.remove(file)
+	return true
+}
+
+// oldest returns the oldest stored payload.
+func (s *DiskSpool) oldest() (spoolFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.files) == 0 {
+		return spoolFile{}, false
+	}
+	return s.files[0], true
+}
+
+// remove removes file, the oldest stored payload.
+func (s *DiskSpool) remove(file spoolFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove payload from the logs disk spool: %v", err)
+	}
+	s.files = s.files[1:]
+	s.sizeInBytes -= file.sizeInBytes
+	s.updateMetrics()
+}
+
+// reloadExistingFiles loads the payloads stored by a previous run of the agent.
+func (s *DiskSpool) reloadExistingFiles() error {
+	entries, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(s.path, entry.Name())
+		switch filepath.Ext(entry.Name()) {
+		case spoolTmpFileExtension:
+			// a payload partially written before a crash
+			_ = os.Remove(path)
+		case spoolFileExtension:
+			sequence, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), spoolFileExtension), 10, 64)
+			if err != nil {
+				continue
+			}
+			if sequence > s.sequence {
+				s.sequence = sequence
+			}
+			s.files = append(s.files, spoolFile{path: path, sizeInBytes: entry.Size()})
+			s.sizeInBytes += entry.Size()
+		}
+	}
+	// the file names are zero-padded sequence numbers
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].path < s.files[j].path
+	})
+	if len(s.files) > 0 {
+		log.Infof("Found %d payloads (%d bytes) in the logs disk spool %s", len(s.files), s.sizeInBytes, s.path)
+	}
+	s.updateMetrics()
+	return nil
+}
+
+func (s *DiskSpool) updateMetrics() {
+	metrics.SpoolSizeInBytes.Set(s.sizeInBytes)
+	metrics.TlmSpoolSizeInBytes.Set(float64(s.sizeInBytes))
+	metrics.SpoolPayloads.Set(int64(len(s.files)))
+	metrics.TlmSpoolPayloads.Set(float64(len(s.files)))
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+)
+
+// fakeDestination records the payloads it receives and fails with err when it is set.
+type fakeDestination struct {
+	sync.Mutex
+	err      error
+	payloads []string
+}
+
+func (d *fakeDestination) Send(payload []byte) error {
+	d.Lock()
+	defer d.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.payloads = append(d.payloads, string(payload))
+	return nil
+}
+
+func (d *fakeDestination) SendAsync(payload []byte) {}
+
+func (d *fakeDestination) setError(err error) {
+	d.Lock()
+	defer d.Unlock()
+	d.err = err
+}
+
+func (d *fakeDestination) getPayloads() []string {
+	d.Lock()
+	defer d.Unlock()
+	return append([]string(nil), d.payloads...)
+}
+
+func newTestDiskSpool(t *testing.T, maxSizeInBytes int64) (*DiskSpool, string) {
+	path, err := ioutil.TempDir("", "logs-spool-test-")
+	require.NoError(t, err)
+	spool, err := NewDiskSpool(path, maxSizeInBytes)
+	require.NoError(t, err)
+	spool.retryInterval = 10 * time.Millisecond
+	return spool, path
+}
+
+func spoolFilesCount(t *testing.T, path string) int {
+	entries, err := ioutil.ReadDir(path)
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestDiskSpoolReplaysInOrder(t *testing.T) {
+	spool, path := newTestDiskSpool(t, 100)
+	defer os.RemoveAll(path)
+
+	destination := &fakeDestination{err: client.NewRetryableError(errors.New("intake unreachable"))}
+	spool.Start(destination)
+	defer spool.Stop()
+
+	assert.True(t, spool.IsEmpty())
+	for _, payload := range []string{"first", "second", "third"} {
+		assert.True(t, spool.Store([]byte(payload)))
+	}
+	assert.False(t, spool.IsEmpty())
+	assert.Equal(t, int64(16), spool.SizeInBytes())
+	assert.Equal(t, 3, spoolFilesCount(t, path))
+
+	destination.setError(nil)
+	assert.Eventually(t, spool.IsEmpty, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"first", "second", "third"}, destination.getPayloads())
+	assert.Equal(t, int64(0), spool.SizeInBytes())
+	assert.Equal(t, 0, spoolFilesCount(t, path))
+}
+
+func TestDiskSpoolMaxSize(t *testing.T) {
+	spool, path := newTestDiskSpool(t, 10)
+	defer os.RemoveAll(path)
+
+	assert.True(t, spool.Store([]byte("12345")))
+	assert.True(t, spool.Store([]byte("12345")))
+	assert.False(t, spool.Store([]byte("1")))
+	assert.Equal(t, int64(10), spool.SizeInBytes())
+	assert.Equal(t, 2, spoolFilesCount(t, path))
+}
+
+func TestDiskSpoolDropsNonRetryablePayloads(t *testing.T) {
+	spool, path := newTestDiskSpool(t, 100)
+	defer os.RemoveAll(path)
+
+	destination := &fakeDestination{err: errors.New("client error")}
+	assert.True(t, spool.Store([]byte("payload")))
+	spool.Start(destination)
+	defer spool.Stop()
+
+	assert.Eventually(t, spool.IsEmpty, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, destination.getPayloads())
+}
+
+func TestDiskSpoolReloadsExistingFiles(t *testing.T) {
+	spool, path := newTestDiskSpool(t, 100)
+	defer os.RemoveAll(path)
+
+	assert.True(t, spool.Store([]byte("first")))
+	assert.True(t, spool.Store([]byte("second")))
+	// a payload partially written before a crash
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "00000000000000000003.spool.tmp"), []byte("partial"), 0600))
+
+	spool, err := NewDiskSpool(path, 100)
+	require.NoError(t, err)
+	spool.retryInterval = 10 * time.Millisecond
+	assert.Equal(t, int64(11), spool.SizeInBytes())
+	assert.Equal(t, 2, spoolFilesCount(t, path))
+	assert.True(t, spool.Store([]byte("third")))
+
+	destination := &fakeDestination{}
+	spool.Start(destination)
+	defer spool.Stop()
+
+	assert.Eventually(t, spool.IsEmpty, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"first", "second", "third"}, destination.getPayloads())
+}
@@ -26,16 +26,24 @@ type Sender struct {
 	outputChan   chan *message.Message
 	destinations *client.Destinations
 	strategy     Strategy
+	spool        *DiskSpool
 	done         chan struct{}
 }
 
 // NewSender returns a new sender.
 func NewSender(inputChan chan *message.Message, outputChan chan *message.Message, destinations *client.Destinations, strategy Strategy) *Sender {
+	return NewSenderWithSpool(inputChan, outputChan, destinations, strategy, nil)
+}
+
+// NewSenderWithSpool returns a new sender storing in spool the payloads it can not send
+// to the main destination, spool can be nil.
+func NewSenderWithSpool(inputChan chan *message.Message, outputChan chan *message.Message, destinations *client.Destinations, strategy Strategy, spool *DiskSpool) *Sender {
 	return &Sender{
 		inputChan:    inputChan,
 		outputChan:   outputChan,
 		destinations: destinations,
 		strategy:     strategy,
+		spool:        spool,
 		done:         make(chan struct{}),
 	}
 }
@@ -67,13 +75,23 @@ func (s *Sender) run() {
 // send sends a payload to multiple destinations,
 // it will forever retry for the main destination unless the error is not retryable
 // and only try once for additionnal destinations.
+// When the sender has a spool, the payloads which can not be sent are stored on disk and
+// considered as sent, so that the pipeline is not blocked. As the spool is replayed in order,
+// the payloads are stored as long as it is not empty. When the spool is full, the sender
+// retries forever as without spool.
 func (s *Sender) send(payload []byte) error {
 	for {
+		if s.spool != nil && !s.spool.IsEmpty() && s.spool.Store(payload) {
+			break
+		}
 		err := s.destinations.Main.Send(payload)
 		if err != nil {
 			metrics.DestinationErrors.Add(1)
 			metrics.TlmDestinationErrors.Inc()
 			if _, ok := err.(*client.RetryableError); ok {
+				if s.spool != nil && s.spool.Store(payload) {
+					break
+				}
 				// could not send the payload because of a client issue,
 				// let's retry
 				continue
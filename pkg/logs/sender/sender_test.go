@@ -6,7 +6,10 @@
 package sender
 
 import (
+	"errors"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -87,3 +90,40 @@ func TestSenderNotBlockedByAdditional(t *testing.T) {
 	sender.Stop()
 	destinationsCtx.Stop()
 }
+
+func TestSenderStoresPayloadsInSpool(t *testing.T) {
+	spool, path := newTestDiskSpool(t, 1024)
+	defer os.RemoveAll(path)
+
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	input := make(chan *message.Message, 1)
+	output := make(chan *message.Message, 1)
+
+	mainDestination := &fakeDestination{err: client.NewRetryableError(errors.New("intake unreachable"))}
+	destinations := client.NewDestinations(mainDestination, nil)
+
+	sender := NewSenderWithSpool(input, output, destinations, StreamStrategy, spool)
+	sender.Start()
+
+	// the message is forwarded to the auditor once its payload is stored on disk
+	expectedMessage := newMessage([]byte("fake line"), source, "")
+	input <- expectedMessage
+	message, ok := <-output
+	assert.True(t, ok)
+	assert.Equal(t, message, expectedMessage)
+	assert.False(t, spool.IsEmpty())
+
+	// the next payloads are stored as long as the spool is not empty to keep them in order
+	mainDestination.setError(nil)
+	input <- newMessage([]byte("fake line 2"), source, "")
+	<-output
+	assert.Empty(t, mainDestination.getPayloads())
+
+	spool.Start(mainDestination)
+	assert.Eventually(t, spool.IsEmpty, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"fake line", "fake line 2"}, mainDestination.getPayloads())
+
+	sender.Stop()
+	spool.Stop()
+}
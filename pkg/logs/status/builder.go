@@ -207,5 +207,9 @@ func (b *Builder) getMetricsStatus() map[string]int64 {
 	metrics["LogsSent"] = b.logsExpVars.Get("LogsSent").(*expvar.Int).Value()
 	metrics["BytesSent"] = b.logsExpVars.Get("BytesSent").(*expvar.Int).Value()
 	metrics["EncodedBytesSent"] = b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value()
+	if spoolSize := b.logsExpVars.Get("SpoolSizeInBytes").(*expvar.Int).Value(); spoolSize > 0 {
+		metrics["SpoolSizeInBytes"] = spoolSize
+		metrics["SpoolPayloads"] = b.logsExpVars.Get("SpoolPayloads").(*expvar.Int).Value()
+	}
 	return metrics
 }
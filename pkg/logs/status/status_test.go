@@ -92,13 +92,13 @@ func TestStatusDeduplicateErrorsAndWarnings(t *testing.T) {
 func TestMetrics(t *testing.T) {
 	defer Clear()
 	Clear()
-	var expected = `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "", "IsRunning": false, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "SenderLatency": 0, "Warnings": ""}`
+	var expected = `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "", "IsRunning": false, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSampledOut": 0, "LogsSent": 0, "SenderLatency": 0, "SpoolPayloads": 0, "SpoolSizeInBytes": 0, "Warnings": ""}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 
 	initStatus()
 	AddGlobalWarning("bar", "Unique Warning")
 	AddGlobalError("bar", "I am an error")
-	expected = `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "I am an error", "IsRunning": true, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "SenderLatency": 0, "Warnings": "Unique Warning"}`
+	expected = `{"BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "I am an error", "IsRunning": true, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSampledOut": 0, "LogsSent": 0, "SenderLatency": 0, "SpoolPayloads": 0, "SpoolSizeInBytes": 0, "Warnings": "Unique Warning"}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 }
 
@@ -122,6 +122,15 @@ func TestStatusMetrics(t *testing.T) {
 	assert.Equal(t, int64(3), status.StatusMetrics["LogsSent"])
 	assert.Equal(t, int64(42), status.StatusMetrics["BytesSent"])
 	assert.Equal(t, int64(21), status.StatusMetrics["EncodedBytesSent"])
+	assert.NotContains(t, status.StatusMetrics, "SpoolSizeInBytes")
+
+	metrics.SpoolSizeInBytes.Set(1024)
+	metrics.SpoolPayloads.Set(2)
+	defer metrics.SpoolSizeInBytes.Set(0)
+	defer metrics.SpoolPayloads.Set(0)
+	status = Get()
+	assert.Equal(t, int64(1024), status.StatusMetrics["SpoolSizeInBytes"])
+	assert.Equal(t, int64(2), status.StatusMetrics["SpoolPayloads"])
 
 	metrics.LogsProcessed.Set(math.MaxInt64)
 	metrics.LogsProcessed.Add(1)
@@ -163,7 +163,7 @@ func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferC
 		bufferContext.CompressorInput.Reset()
 		bufferContext.CompressorOutput.Reset()
 
-		compressor, err = stream.NewCompressor(bufferContext.CompressorInput, bufferContext.CompressorOutput, []byte{}, footer, []byte{})
+		compressor, err = stream.NewCompressorWithCodec(bufferContext.CompressorInput, bufferContext.CompressorOutput, []byte{}, footer, []byte{}, bufferContext.Codec)
 		if err != nil {
 			return err
 		}
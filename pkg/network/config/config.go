@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
@@ -60,6 +61,10 @@ type Config struct {
 	// These stats objects get flushed on every client request (default 30s check interval)
 	MaxDNSStats int
 
+	// DNSTopFailingDomains is the number of domains of which the DNS resolutions failed the most reported
+	// for each process or container by the network_dns check
+	DNSTopFailingDomains int
+
 	// EnableHTTPMonitoring specifies whether the tracer should monitor HTTP traffic
 	EnableHTTPMonitoring bool
 
@@ -67,6 +72,25 @@ type Config struct {
 	// Supported libraries: OpenSSL
 	EnableHTTPSMonitoring bool
 
+	// EnableHTTPMonitoringFallback specifies whether the HTTP traffic should be decoded from captured packets
+	// in userspace when the HTTP eBPF programs can not be loaded
+	EnableHTTPMonitoringFallback bool
+
+	// HTTPPorts are the ports of the HTTP servers monitored by the packet capture fallback
+	HTTPPorts []uint16
+
+	// EnablePostgresMonitoring specifies whether the tracer should monitor the Postgres traffic
+	EnablePostgresMonitoring bool
+
+	// PostgresPorts are the ports of the Postgres servers
+	PostgresPorts []uint16
+
+	// EnableRedisMonitoring specifies whether the tracer should monitor the Redis traffic
+	EnableRedisMonitoring bool
+
+	// RedisPorts are the ports of the Redis servers
+	RedisPorts []uint16
+
 	// UDPConnTimeout determines the length of traffic inactivity between two
 	// (IP, port)-pairs before declaring a UDP connection as inactive. This is
 	// set to /proc/sys/net/netfilter/nf_conntrack_udp_timeout on Linux by
@@ -102,6 +126,10 @@ type Config struct {
 	// get flushed on every client request (default 30s check interval)
 	MaxHTTPStatsBuffered int
 
+	// MaxDatabaseStatsBuffered represents the maximum number of database stats we'll buffer in memory. These stats
+	// get flushed on every client request (default 30s check interval)
+	MaxDatabaseStatsBuffered int
+
 	// MaxConnectionsStateBuffered represents the maximum number of state objects that we'll store in memory. These state objects store
 	// the stats for a connection so we can accurately determine traffic change between client requests.
 	MaxConnectionsStateBuffered int
@@ -139,6 +167,9 @@ type Config struct {
 	// ExcludedDestinationConnections is a map of destination connections to blacklist
 	ExcludedDestinationConnections map[string][]string
 
+	// ExcludedProcesses holds the rules excluding the connections of the matching processes
+	ExcludedProcesses []ProcessExcludeRule
+
 	// OffsetGuessThreshold is the size of the byte threshold we will iterate over when guessing offsets
 	OffsetGuessThreshold uint64
 
@@ -155,6 +186,22 @@ type Config struct {
 	RecordedQueryTypes []string
 }
 
+// ProcessExcludeRule excludes the connections of the processes matching all the criteria it sets.
+type ProcessExcludeRule struct {
+	// ProcessName is a regular expression matched against the name of the process
+	ProcessName string `mapstructure:"process_name"`
+	// Cmdline is a regular expression matched against the command line of the process
+	Cmdline string `mapstructure:"cmdline"`
+	// PIDNamespace is the inode number of the PID namespace of the process
+	PIDNamespace uint32 `mapstructure:"pid_namespace"`
+	// ContainerID is the ID of the container of the process
+	ContainerID string `mapstructure:"container_id"`
+	// ContainerTags are "key:value" tags which the container of the process must all have
+	ContainerTags []string `mapstructure:"container_tags"`
+	// Cgroup is a regular expression matched against the cgroup paths of the process
+	Cgroup string `mapstructure:"cgroup"`
+}
+
 func join(pieces ...string) string {
 	return strings.Join(pieces, ".")
 }
@@ -188,17 +235,26 @@ func New() *Config {
 		MaxConnectionsStateBuffered:  cfg.GetInt(join(spNS, "max_connection_state_buffered")),
 		ClientStateExpiry:            2 * time.Minute,
 
-		DNSInspection:       !cfg.GetBool(join(spNS, "disable_dns_inspection")),
-		CollectDNSStats:     cfg.GetBool(join(spNS, "collect_dns_stats")),
-		CollectLocalDNS:     cfg.GetBool(join(spNS, "collect_local_dns")),
-		CollectDNSDomains:   cfg.GetBool(join(spNS, "collect_dns_domains")),
-		MaxDNSStats:         cfg.GetInt(join(spNS, "max_dns_stats")),
-		MaxDNSStatsBuffered: 75000,
-		DNSTimeout:          time.Duration(cfg.GetInt(join(spNS, "dns_timeout_in_s"))) * time.Second,
-
-		EnableHTTPMonitoring:  cfg.GetBool(join(netNS, "enable_http_monitoring")),
-		EnableHTTPSMonitoring: cfg.GetBool(join(netNS, "enable_https_monitoring")),
-		MaxHTTPStatsBuffered:  100000,
+		DNSInspection:        !cfg.GetBool(join(spNS, "disable_dns_inspection")),
+		CollectDNSStats:      cfg.GetBool(join(spNS, "collect_dns_stats")),
+		CollectLocalDNS:      cfg.GetBool(join(spNS, "collect_local_dns")),
+		CollectDNSDomains:    cfg.GetBool(join(spNS, "collect_dns_domains")),
+		MaxDNSStats:          cfg.GetInt(join(spNS, "max_dns_stats")),
+		MaxDNSStatsBuffered:  75000,
+		DNSTimeout:           time.Duration(cfg.GetInt(join(spNS, "dns_timeout_in_s"))) * time.Second,
+		DNSTopFailingDomains: cfg.GetInt(join(spNS, "dns_top_failing_domains")),
+
+		EnableHTTPMonitoring:         cfg.GetBool(join(netNS, "enable_http_monitoring")),
+		EnableHTTPSMonitoring:        cfg.GetBool(join(netNS, "enable_https_monitoring")),
+		EnableHTTPMonitoringFallback: cfg.GetBool(join(netNS, "enable_http_monitoring_fallback")),
+		HTTPPorts:                    getPorts(cfg, join(netNS, "http_ports")),
+		MaxHTTPStatsBuffered:         100000,
+
+		EnablePostgresMonitoring: cfg.GetBool(join(netNS, "enable_postgres_monitoring")),
+		PostgresPorts:            getPorts(cfg, join(netNS, "postgres_ports")),
+		EnableRedisMonitoring:    cfg.GetBool(join(netNS, "enable_redis_monitoring")),
+		RedisPorts:               getPorts(cfg, join(netNS, "redis_ports")),
+		MaxDatabaseStatsBuffered: 100000,
 
 		EnableConntrack:              cfg.GetBool(join(spNS, "enable_conntrack")),
 		ConntrackMaxStateSize:        cfg.GetInt(join(spNS, "conntrack_max_state_size")),
@@ -215,6 +271,12 @@ func New() *Config {
 		RecordedQueryTypes: cfg.GetStringSlice(join(netNS, "dns_recorded_query_types")),
 	}
 
+	if k := join(spNS, "process_excludes"); cfg.IsSet(k) {
+		if err := cfg.UnmarshalKey(k, &c.ExcludedProcesses); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"process_name\":\"regex\",\"cmdline\":\"regex\",\"pid_namespace\":4026531836,\"container_id\":\"id\",\"container_tags\":[\"key:value\"],\"cgroup\":\"regex\"}]', error: %v", k, err)
+		}
+	}
+
 	if c.OffsetGuessThreshold > maxOffsetThreshold {
 		log.Warn("offset_guess_threshold exceeds maximum of 3000. Setting it to the default of 400")
 		c.OffsetGuessThreshold = defaultOffsetThreshold
@@ -248,3 +310,17 @@ func New() *Config {
 
 	return c
 }
+
+// getPorts returns the ports listed under key, ignoring the invalid ones
+func getPorts(cfg ddconfig.Config, key string) []uint16 {
+	var ports []uint16
+	for _, p := range cfg.GetStringSlice(key) {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || port == 0 {
+			log.Errorf("invalid port %q in %q", p, key)
+			continue
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports
+}
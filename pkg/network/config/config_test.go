@@ -230,3 +230,158 @@ func TestSettingMaxDNSStats(t *testing.T) {
 		assert.Equal(t, 10000, cfg.MaxDNSStats)
 	})
 }
+
+func TestSettingDNSTopFailingDomains(t *testing.T) {
+	newConfig()
+	defer restoreGlobalConfig()
+
+	t.Run("via YAML", func(t *testing.T) {
+		_, err := sysconfig.New("./testdata/TestDDAgentConfigYamlAndSystemProbeConfig-EnableDNSDomains.yaml")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.Equal(t, 5, cfg.DNSTopFailingDomains)
+	})
+
+	t.Run("via ENV variable", func(t *testing.T) {
+		newConfig()
+		os.Unsetenv("DD_SYSTEM_PROBE_CONFIG_DNS_TOP_FAILING_DOMAINS")
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.Equal(t, 10, cfg.DNSTopFailingDomains) // default value
+
+		newConfig()
+		os.Setenv("DD_SYSTEM_PROBE_CONFIG_DNS_TOP_FAILING_DOMAINS", "20")
+		defer os.Unsetenv("DD_SYSTEM_PROBE_CONFIG_DNS_TOP_FAILING_DOMAINS")
+		_, err = sysconfig.New("")
+		require.NoError(t, err)
+		cfg = New()
+
+		assert.Equal(t, 20, cfg.DNSTopFailingDomains)
+	})
+}
+
+func TestProcessExcludes(t *testing.T) {
+	expected := []ProcessExcludeRule{
+		{ProcessName: "^envoy$"},
+		{Cmdline: "healthcheck", PIDNamespace: 4026531836},
+		{ContainerID: "3f2c1e4d5b6a", ContainerTags: []string{"kube_container_name:istio-proxy"}, Cgroup: "^/kubepods/"},
+	}
+
+	t.Run("via YAML", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		assert.Empty(t, New().ExcludedProcesses)
+
+		newConfig()
+		_, err = sysconfig.New("./testdata/TestDDAgentConfigYamlAndSystemProbeConfig-ProcessExcludes.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, expected, New().ExcludedProcesses)
+	})
+
+	t.Run("via ENV variable", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		os.Setenv("DD_SYSTEM_PROBE_PROCESS_EXCLUDES", `[{"process_name":"^envoy$"},{"cmdline":"healthcheck","pid_namespace":4026531836},{"container_id":"3f2c1e4d5b6a","container_tags":["kube_container_name:istio-proxy"],"cgroup":"^/kubepods/"}]`)
+		defer os.Unsetenv("DD_SYSTEM_PROBE_PROCESS_EXCLUDES")
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		assert.Equal(t, expected, New().ExcludedProcesses)
+	})
+}
+
+func TestEnableDatabaseMonitoring(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.False(t, cfg.EnablePostgresMonitoring)
+		assert.Equal(t, []uint16{5432}, cfg.PostgresPorts)
+		assert.False(t, cfg.EnableRedisMonitoring)
+		assert.Equal(t, []uint16{6379}, cfg.RedisPorts)
+	})
+
+	t.Run("via YAML", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		_, err := sysconfig.New("./testdata/TestDDAgentConfigYamlAndSystemProbeConfig-EnableDatabases.yaml")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.True(t, cfg.EnablePostgresMonitoring)
+		assert.Equal(t, []uint16{5432, 5433}, cfg.PostgresPorts)
+		assert.True(t, cfg.EnableRedisMonitoring)
+		assert.Equal(t, []uint16{6380}, cfg.RedisPorts)
+	})
+
+	t.Run("via ENV variable", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		os.Setenv("DD_SYSTEM_PROBE_NETWORK_ENABLE_REDIS_MONITORING", "true")
+		defer os.Unsetenv("DD_SYSTEM_PROBE_NETWORK_ENABLE_REDIS_MONITORING")
+		os.Setenv("DD_SYSTEM_PROBE_NETWORK_REDIS_PORTS", "6379 6380")
+		defer os.Unsetenv("DD_SYSTEM_PROBE_NETWORK_REDIS_PORTS")
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.False(t, cfg.EnablePostgresMonitoring)
+		assert.True(t, cfg.EnableRedisMonitoring)
+		assert.Equal(t, []uint16{6379, 6380}, cfg.RedisPorts)
+	})
+}
+
+func TestEnableHTTPMonitoringFallback(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.False(t, cfg.EnableHTTPMonitoringFallback)
+		assert.Equal(t, []uint16{80, 8080}, cfg.HTTPPorts)
+	})
+
+	t.Run("via YAML", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		_, err := sysconfig.New("./testdata/TestDDAgentConfigYamlAndSystemProbeConfig-EnableHTTPMonitoringFallback.yaml")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.True(t, cfg.EnableHTTPMonitoring)
+		assert.True(t, cfg.EnableHTTPMonitoringFallback)
+		assert.Equal(t, []uint16{8000}, cfg.HTTPPorts)
+	})
+
+	t.Run("via ENV variable", func(t *testing.T) {
+		newConfig()
+		defer restoreGlobalConfig()
+
+		os.Setenv("DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTP_MONITORING_FALLBACK", "true")
+		defer os.Unsetenv("DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTP_MONITORING_FALLBACK")
+		os.Setenv("DD_SYSTEM_PROBE_NETWORK_HTTP_PORTS", "80 8000")
+		defer os.Unsetenv("DD_SYSTEM_PROBE_NETWORK_HTTP_PORTS")
+		_, err := sysconfig.New("")
+		require.NoError(t, err)
+		cfg := New()
+
+		assert.True(t, cfg.EnableHTTPMonitoringFallback)
+		assert.Equal(t, []uint16{80, 8000}, cfg.HTTPPorts)
+	})
+}
@@ -0,0 +1,95 @@
+package debugging
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/database"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/sketches-go/ddsketch"
+)
+
+// OperationSummary represents a (debug-friendly) aggregated view of the database operations
+// matching a (client, server, protocol, command) tuple
+type OperationSummary struct {
+	Client   Address
+	Server   Address
+	DNS      string
+	Protocol string
+	Command  string
+	Success  Stats
+	Failure  Stats
+}
+
+// Address represents represents a IP:Port
+type Address struct {
+	IP   string
+	Port uint16
+}
+
+// Stats consolidates the count and latency information of the successful or of the failed operations
+type Stats struct {
+	Count              int
+	FirstLatencySample float64
+	LatencyP50         float64
+}
+
+// Database returns a debug-friendly representation of map[database.Key]database.RequestStats
+func Database(stats map[database.Key]database.RequestStats, dns map[util.Address][]string) []OperationSummary {
+	all := make([]OperationSummary, 0, len(stats))
+	for k, v := range stats {
+		clientAddr := formatIP(k.SrcIPLow, k.SrcIPHigh)
+		serverAddr := formatIP(k.DstIPLow, k.DstIPHigh)
+
+		all = append(all, OperationSummary{
+			Client: Address{
+				IP:   clientAddr.String(),
+				Port: k.SrcPort,
+			},
+			Server: Address{
+				IP:   serverAddr.String(),
+				Port: k.DstPort,
+			},
+			DNS:      getDNS(dns, serverAddr),
+			Protocol: k.Protocol.String(),
+			Command:  k.Command,
+			Success:  formatStats(v.Success),
+			Failure:  formatStats(v.Failure),
+		})
+	}
+
+	return all
+}
+
+func formatStats(stats latency.Stats) Stats {
+	return Stats{
+		Count:              stats.Count,
+		FirstLatencySample: stats.FirstLatencySample,
+		LatencyP50:         getSketchQuantile(stats.Latencies, 0.5),
+	}
+}
+
+func formatIP(low, high uint64) util.Address {
+	// like for HTTP, the keys hold no socket family information, so the address is
+	// assumed to be IPv6 only if higher order bits are set
+	if high > 0 || (low>>32) > 0 {
+		return util.V6Address(low, high)
+	}
+
+	return util.V4Address(uint32(low))
+}
+
+func getDNS(dns map[util.Address][]string, addr util.Address) string {
+	if names := dns[addr]; len(names) > 0 {
+		return names[0]
+	}
+
+	return ""
+}
+
+func getSketchQuantile(sketch *ddsketch.DDSketch, quantile float64) float64 {
+	if sketch == nil {
+		return 0.0
+	}
+
+	val, _ := sketch.GetValueAtQuantile(quantile)
+	return val
+}
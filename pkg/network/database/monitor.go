@@ -0,0 +1,106 @@
+// +build linux_bpf
+
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+)
+
+// Monitor is responsible for:
+// * Capturing the TCP streams of the connections to the database servers;
+// * Decoding the Postgres and Redis operations of these streams;
+// * Aggregating the latency and the outcome of the operations;
+type Monitor struct {
+	capture    *tcpstream.Capture
+	statkeeper *statKeeper
+	telemetry  *telemetry
+
+	// termination
+	mux     sync.Mutex
+	stopped bool
+}
+
+// NewMonitor returns a new Monitor instance
+func NewMonitor(c *config.Config) (*Monitor, error) {
+	protocols := make(map[uint16]Protocol)
+	if c.EnablePostgresMonitoring {
+		for _, port := range c.PostgresPorts {
+			protocols[port] = ProtocolPostgres
+		}
+	}
+	if c.EnableRedisMonitoring {
+		for _, port := range c.RedisPorts {
+			protocols[port] = ProtocolRedis
+		}
+	}
+	if len(protocols) == 0 {
+		return nil, fmt.Errorf("no database server port to monitor")
+	}
+
+	ports := make([]uint16, 0, len(protocols))
+	for port := range protocols {
+		ports = append(ports, port)
+	}
+
+	telemetry := newTelemetry()
+	statkeeper := newStatKeeper(c.MaxDatabaseStatsBuffered, telemetry)
+	tracker := newFlowTracker(protocols, decoders, statkeeper.Process, telemetry)
+	capture, err := tcpstream.NewCapture(c.ProcRoot, ports, tracker)
+	if err != nil {
+		return nil, fmt.Errorf("error enabling database traffic inspection: %s", err)
+	}
+
+	return &Monitor{
+		capture:    capture,
+		statkeeper: statkeeper,
+		telemetry:  telemetry,
+	}, nil
+}
+
+// Start consuming the database traffic
+func (m *Monitor) Start() {
+	if m == nil {
+		return
+	}
+
+	m.capture.Start()
+}
+
+// GetDatabaseStats returns a map of database stats stored in the following format:
+// [source, dest tuple, protocol, command] -> RequestStats object
+func (m *Monitor) GetDatabaseStats() map[Key]RequestStats {
+	if m == nil {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stopped {
+		return nil
+	}
+
+	delta := m.telemetry.reset()
+	delta.report()
+
+	return m.statkeeper.GetAndResetAllStats()
+}
+
+// Stop database monitoring
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stopped {
+		return
+	}
+
+	m.capture.Stop()
+	m.stopped = true
+}
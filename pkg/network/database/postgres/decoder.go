@@ -0,0 +1,335 @@
+// Package postgres decodes the transactions of the Postgres frontend/backend protocol from the
+// bytes exchanged over a connection.
+// See https://www.postgresql.org/docs/current/protocol-message-formats.html
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBodyCapture is the number of bytes of the body of the messages kept to find their command
+	maxBodyCapture = 256
+
+	// maxPending is the maximum number of transactions waiting for their response
+	maxPending = 128
+
+	// maxStatements is the maximum number of prepared statements remembered per connection
+	maxStatements = 128
+
+	// maxMessageLength is the maximum length of a message. Postgres refuses messages over 1GB.
+	maxMessageLength = 1 << 30
+
+	// maxCommandLength is the maximum length of a command
+	maxCommandLength = 32
+
+	// Codes of the messages having no type, sent by the client when opening a connection
+	protocolVersion3Code = 196608
+	cancelRequestCode    = 80877102
+	sslRequestCode       = 80877103
+	gssEncRequestCode    = 80877104
+)
+
+const (
+	// UnknownCommand is the command of the transactions of which the query could not be found
+	UnknownCommand = "UNKNOWN"
+	// FunctionCallCommand is the command of the function calls
+	FunctionCallCommand = "FUNCTION CALL"
+)
+
+var (
+	// ErrEncrypted is returned when the connection is encrypted and can not be decoded
+	ErrEncrypted = errors.New("the postgres connection is encrypted")
+
+	// The types of the messages sent by the client and by the server
+	clientMessageTypes = messageTypes("BCDEFHPQSXcdfp")
+	serverMessageTypes = messageTypes("123ACDEGHIKNRSTVWZcdnstv")
+)
+
+// TransactionHandler is called with the command, the outcome and the latency of the completed transactions
+type TransactionHandler func(command string, failed bool, latency time.Duration)
+
+// Decoder decodes the transactions of a Postgres connection. A transaction is a simple query, or the
+// messages of the extended query protocol up to a Sync message, and completes when the server is ready
+// for the next query. It fails when the server sends an error response.
+type Decoder struct {
+	handler TransactionHandler
+	client  messageReader
+	server  messageReader
+
+	started      bool // whether the client sent data
+	sslRequested bool // whether the server is expected to answer an encryption request with a single byte
+	pending      []transaction
+	batch        *transaction      // extended query protocol messages not followed by a Sync yet
+	statements   map[string]string // prepared statement name => command
+}
+
+type transaction struct {
+	command string
+	start   time.Time
+	failed  bool
+}
+
+// NewDecoder returns a Decoder calling handler for each completed transaction
+func NewDecoder(handler TransactionHandler) *Decoder {
+	return &Decoder{
+		handler:    handler,
+		client:     messageReader{types: clientMessageTypes},
+		server:     messageReader{types: serverMessageTypes},
+		statements: make(map[string]string),
+	}
+}
+
+// Write decodes the data sent by the client or by the server at ts. It returns an error if the data
+// can not be decoded, in which case the decoder should not be used anymore.
+func (d *Decoder) Write(fromClient bool, data []byte, ts time.Time) error {
+	if fromClient {
+		if !d.started {
+			d.started = true
+			// the connection starts with a message having no type if it was captured from its beginning
+			d.client.untyped = isUntypedMessage(data)
+		}
+		return d.client.read(data, func(msgType byte, body []byte) error {
+			return d.clientMessage(msgType, body, ts)
+		})
+	}
+
+	if d.sslRequested && len(data) > 0 {
+		// the server accepts ('S' or 'G') or refuses ('N') the encryption with a single byte
+		d.sslRequested = false
+		if data[0] != 'N' {
+			return ErrEncrypted
+		}
+		d.client.untyped = true // a startup message follows
+		data = data[1:]
+	}
+	return d.server.read(data, func(msgType byte, body []byte) error {
+		return d.serverMessage(msgType, ts)
+	})
+}
+
+func (d *Decoder) clientMessage(msgType byte, body []byte, ts time.Time) error {
+	switch msgType {
+	case 0: // untyped message
+		if len(body) >= 4 {
+			if code := binary.BigEndian.Uint32(body); code == sslRequestCode || code == gssEncRequestCode {
+				d.sslRequested = true
+			}
+		}
+	case 'Q': // Query
+		query, _ := cstring(body)
+		return d.push(transaction{command: command(query), start: ts})
+	case 'P': // Parse
+		name, rest := cstring(body)
+		query, _ := cstring(rest)
+		cmd := command(query)
+		if _, ok := d.statements[name]; ok || len(d.statements) < maxStatements {
+			d.statements[name] = cmd
+		}
+		d.extend(cmd, ts)
+	case 'B': // Bind
+		_, rest := cstring(body)
+		statement, _ := cstring(rest)
+		d.extend(d.statements[statement], ts)
+	case 'C', 'D', 'E', 'H': // Close, Describe, Execute, Flush
+		d.extend("", ts)
+	case 'S': // Sync
+		d.extend("", ts)
+		batch := d.batch
+		d.batch = nil
+		if batch.command == "" {
+			batch.command = UnknownCommand
+		}
+		return d.push(*batch)
+	case 'F': // FunctionCall
+		return d.push(transaction{command: FunctionCallCommand, start: ts})
+	}
+	return nil
+}
+
+func (d *Decoder) serverMessage(msgType byte, ts time.Time) error {
+	if len(d.pending) == 0 {
+		// messages of the startup phase, or of the commands sent before the capture started
+		return nil
+	}
+	switch msgType {
+	case 'E': // ErrorResponse
+		d.pending[0].failed = true
+	case 'Z': // ReadyForQuery
+		tx := d.pending[0]
+		d.pending = d.pending[1:]
+		d.handler(tx.command, tx.failed, ts.Sub(tx.start))
+	}
+	return nil
+}
+
+// extend adds a message of the extended query protocol to the current batch
+func (d *Decoder) extend(cmd string, ts time.Time) {
+	if d.batch == nil {
+		d.batch = &transaction{start: ts}
+	}
+	if d.batch.command == "" {
+		d.batch.command = cmd
+	}
+}
+
+func (d *Decoder) push(tx transaction) error {
+	if len(d.pending) >= maxPending {
+		return fmt.Errorf("more than %d postgres transactions are waiting for a response", maxPending)
+	}
+	d.pending = append(d.pending, tx)
+	return nil
+}
+
+// messageReader splits a stream into messages
+type messageReader struct {
+	types   *[256]bool
+	untyped bool // whether the next message has no type
+
+	header    [5]byte
+	headerLen int
+	msgType   byte
+	remaining int // bytes of the body left to read
+	body      []byte
+}
+
+func (r *messageReader) read(data []byte, onMessage func(msgType byte, body []byte) error) error {
+	for len(data) > 0 {
+		if r.remaining == 0 {
+			// reading the header: the type, if any, and the length of the message including itself
+			size := 5
+			if r.untyped {
+				size = 4
+			}
+			n := copy(r.header[r.headerLen:size], data)
+			r.headerLen += n
+			data = data[n:]
+			if r.headerLen < size {
+				return nil
+			}
+			r.headerLen = 0
+			r.msgType = 0
+			length := binary.BigEndian.Uint32(r.header[size-4 : size])
+			if !r.untyped {
+				r.msgType = r.header[0]
+				if !r.types[r.msgType] {
+					return fmt.Errorf("invalid postgres message type %q", r.msgType)
+				}
+			}
+			r.untyped = false
+			if length < 4 || length > maxMessageLength {
+				return fmt.Errorf("invalid postgres message length %d", length)
+			}
+			r.remaining = int(length) - 4
+			r.body = r.body[:0]
+			if r.remaining == 0 {
+				if err := onMessage(r.msgType, nil); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		n := r.remaining
+		if n > len(data) {
+			n = len(data)
+		}
+		if capture := maxBodyCapture - len(r.body); capture > 0 {
+			if capture > n {
+				capture = n
+			}
+			r.body = append(r.body, data[:capture]...)
+		}
+		data = data[n:]
+		r.remaining -= n
+		if r.remaining == 0 {
+			if err := onMessage(r.msgType, r.body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isUntypedMessage returns whether data starts with a startup, cancel or encryption request message
+func isUntypedMessage(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	length := binary.BigEndian.Uint32(data)
+	switch binary.BigEndian.Uint32(data[4:]) {
+	case protocolVersion3Code:
+		return length <= 10000
+	case cancelRequestCode:
+		return length == 16
+	case sslRequestCode, gssEncRequestCode:
+		return length == 8
+	}
+	return false
+}
+
+// cstring returns the null-terminated string at the start of b, and the bytes following it
+func cstring(b []byte) (string, []byte) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return string(b), nil
+	}
+	return string(b[:i]), b[i+1:]
+}
+
+// command returns the command of a query: its first keyword, such as "SELECT"
+func command(query string) string {
+	for {
+		query = trimSpace(query)
+		switch {
+		case len(query) >= 2 && query[:2] == "--":
+			i := strings.IndexByte(query, '\n')
+			if i < 0 {
+				return UnknownCommand
+			}
+			query = query[i+1:]
+			continue
+		case len(query) >= 2 && query[:2] == "/*":
+			i := strings.Index(query, "*/")
+			if i < 0 {
+				return UnknownCommand
+			}
+			query = query[i+2:]
+			continue
+		}
+		break
+	}
+
+	end := 0
+	for end < len(query) && end < maxCommandLength && isLetter(query[end]) {
+		end++
+	}
+	if end == 0 || (end < len(query) && isLetter(query[end])) {
+		return UnknownCommand
+	}
+	return strings.ToUpper(query[:end])
+}
+
+func trimSpace(s string) string {
+	for len(s) > 0 && (s[0] == ' ' || s[0] == '\t' || s[0] == '\n' || s[0] == '\r' || s[0] == '(') {
+		s = s[1:]
+	}
+	return s
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func messageTypes(types string) *[256]bool {
+	var t [256]bool
+	for i := 0; i < len(types); i++ {
+		t[types[i]] = true
+	}
+	return &t
+}
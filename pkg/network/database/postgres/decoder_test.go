@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transactionResult struct {
+	command string
+	failed  bool
+	latency time.Duration
+}
+
+func newTestDecoder() (*Decoder, *[]transactionResult) {
+	var results []transactionResult
+	d := NewDecoder(func(command string, failed bool, latency time.Duration) {
+		results = append(results, transactionResult{command, failed, latency})
+	})
+	return d, &results
+}
+
+func message(msgType byte, body ...[]byte) []byte {
+	var b []byte
+	for _, part := range body {
+		b = append(b, part...)
+	}
+	msg := []byte{msgType, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[1:], uint32(len(b)+4))
+	return append(msg, b...)
+}
+
+func untypedMessage(code uint32, body []byte) []byte {
+	msg := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(msg, uint32(8+len(body)))
+	binary.BigEndian.PutUint32(msg[4:], code)
+	return append(msg, body...)
+}
+
+func cstr(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func concat(messages ...[]byte) []byte {
+	var b []byte
+	for _, m := range messages {
+		b = append(b, m...)
+	}
+	return b
+}
+
+var (
+	t0 = time.Unix(1600000000, 0)
+
+	readyForQuery   = message('Z', []byte{'I'})
+	commandComplete = message('C', cstr("SELECT 1"))
+	errorResponse   = message('E', []byte{'S'}, cstr("ERROR"), []byte{'C'}, cstr("42P01"), []byte{0})
+)
+
+func TestSimpleQuery(t *testing.T) {
+	d, results := newTestDecoder()
+
+	startup := untypedMessage(protocolVersion3Code, concat(cstr("user"), cstr("postgres"), []byte{0}))
+	require.NoError(t, d.Write(true, startup, t0))
+	// authentication and parameters
+	require.NoError(t, d.Write(false, concat(
+		message('R', []byte{0, 0, 0, 0}),
+		message('S', cstr("server_version"), cstr("13.3")),
+		message('K', []byte{0, 0, 0, 1, 0, 0, 0, 2}),
+		readyForQuery,
+	), t0))
+	assert.Empty(t, *results)
+
+	require.NoError(t, d.Write(true, message('Q', cstr("select * from users")), t0))
+	require.NoError(t, d.Write(false, concat(
+		message('T', []byte{0, 0}),
+		message('D', []byte{0, 0}),
+		commandComplete,
+		readyForQuery,
+	), t0.Add(3*time.Millisecond)))
+
+	require.NoError(t, d.Write(true, message('Q', cstr("  /* comment */ -- comment\n (INSERT INTO missing VALUES (1))")), t0))
+	require.NoError(t, d.Write(false, concat(errorResponse, readyForQuery), t0.Add(time.Millisecond)))
+
+	assert.Equal(t, []transactionResult{
+		{"SELECT", false, 3 * time.Millisecond},
+		{"INSERT", true, time.Millisecond},
+	}, *results)
+}
+
+func TestExtendedQuery(t *testing.T) {
+	d, results := newTestDecoder()
+
+	parse := message('P', cstr("stmt1"), cstr("UPDATE users SET name = $1"), []byte{0, 0})
+	bind := func(statement string) []byte {
+		return message('B', cstr(""), cstr(statement), []byte{0, 0, 0, 0, 0, 0})
+	}
+	execute := message('E', cstr(""), []byte{0, 0, 0, 0})
+	sync := message('S')
+
+	require.NoError(t, d.Write(true, concat(parse, bind("stmt1"), execute, sync), t0))
+	require.NoError(t, d.Write(false, concat(
+		message('1'),
+		message('2'),
+		message('C', cstr("UPDATE 1")),
+		readyForQuery,
+	), t0.Add(2*time.Millisecond)))
+
+	// the prepared statement is reused
+	require.NoError(t, d.Write(true, concat(bind("stmt1"), execute, sync), t0))
+	require.NoError(t, d.Write(false, concat(message('2'), errorResponse, readyForQuery), t0.Add(time.Millisecond)))
+
+	// unknown prepared statement
+	require.NoError(t, d.Write(true, concat(bind("stmt2"), execute, sync), t0))
+	require.NoError(t, d.Write(false, concat(message('2'), message('C', cstr("SELECT 1")), readyForQuery), t0))
+
+	assert.Equal(t, []transactionResult{
+		{"UPDATE", false, 2 * time.Millisecond},
+		{"UPDATE", true, time.Millisecond},
+		{UnknownCommand, false, 0},
+	}, *results)
+}
+
+func TestPipelinedQueries(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, message('Q', cstr("SELECT 1")), t0))
+	require.NoError(t, d.Write(true, message('Q', cstr("DELETE FROM users")), t0.Add(time.Millisecond)))
+	require.NoError(t, d.Write(false, concat(commandComplete, readyForQuery), t0.Add(2*time.Millisecond)))
+	require.NoError(t, d.Write(false, concat(errorResponse, readyForQuery), t0.Add(4*time.Millisecond)))
+
+	assert.Equal(t, []transactionResult{
+		{"SELECT", false, 2 * time.Millisecond},
+		{"DELETE", true, 3 * time.Millisecond},
+	}, *results)
+}
+
+func TestSplitMessages(t *testing.T) {
+	d, results := newTestDecoder()
+
+	query := message('Q', cstr("SELECT * FROM users WHERE id = 1"))
+	for i := range query {
+		require.NoError(t, d.Write(true, query[i:i+1], t0))
+	}
+
+	response := concat(
+		message('T', []byte{0, 0}),
+		message('D', make([]byte, 1000)),
+		commandComplete,
+		readyForQuery,
+	)
+	require.NoError(t, d.Write(false, response[:3], t0))
+	require.NoError(t, d.Write(false, response[3:500], t0))
+	assert.Empty(t, *results)
+	require.NoError(t, d.Write(false, response[500:], t0.Add(time.Millisecond)))
+
+	assert.Equal(t, []transactionResult{{"SELECT", false, time.Millisecond}}, *results)
+}
+
+func TestLongQuery(t *testing.T) {
+	d, results := newTestDecoder()
+
+	query := make([]byte, 10000)
+	copy(query, "WITH t AS (SELECT 1) ")
+	for i := 21; i < len(query); i++ {
+		query[i] = 'x'
+	}
+	require.NoError(t, d.Write(true, message('Q', query, []byte{0}), t0))
+	require.NoError(t, d.Write(false, concat(commandComplete, readyForQuery), t0))
+
+	assert.Equal(t, []transactionResult{{"WITH", false, 0}}, *results)
+}
+
+func TestSSLRequest(t *testing.T) {
+	t.Run("refused", func(t *testing.T) {
+		d, results := newTestDecoder()
+
+		require.NoError(t, d.Write(true, untypedMessage(sslRequestCode, nil), t0))
+		require.NoError(t, d.Write(false, []byte{'N'}, t0))
+		require.NoError(t, d.Write(true, untypedMessage(protocolVersion3Code, concat(cstr("user"), cstr("postgres"), []byte{0})), t0))
+		require.NoError(t, d.Write(false, concat(message('R', []byte{0, 0, 0, 0}), readyForQuery), t0))
+		require.NoError(t, d.Write(true, message('Q', cstr("SELECT 1")), t0))
+		require.NoError(t, d.Write(false, concat(commandComplete, readyForQuery), t0))
+
+		assert.Equal(t, []transactionResult{{"SELECT", false, 0}}, *results)
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		d, _ := newTestDecoder()
+
+		require.NoError(t, d.Write(true, untypedMessage(sslRequestCode, nil), t0))
+		assert.Equal(t, ErrEncrypted, d.Write(false, []byte{'S'}, t0))
+	})
+}
+
+func TestInvalidStream(t *testing.T) {
+	d, _ := newTestDecoder()
+	assert.Error(t, d.Write(true, []byte("GET / HTTP/1.1\r\n\r\n"), t0))
+
+	d, _ = newTestDecoder()
+	require.NoError(t, d.Write(true, message('Q', cstr("SELECT 1")), t0))
+	assert.Error(t, d.Write(false, []byte{'Z', 0, 0, 0, 1}, t0))
+}
+
+func TestTooManyPendingTransactions(t *testing.T) {
+	d, _ := newTestDecoder()
+
+	query := message('Q', cstr("SELECT 1"))
+	for i := 0; i < maxPending; i++ {
+		require.NoError(t, d.Write(true, query, t0))
+	}
+	assert.Error(t, d.Write(true, query, t0))
+}
+
+func TestCommand(t *testing.T) {
+	for query, expected := range map[string]string{
+		"select 1":                            "SELECT",
+		"\n\tBEGIN":                           "BEGIN",
+		"((SELECT 1))":                        "SELECT",
+		"-- comment\nCOMMIT":                  "COMMIT",
+		"/* comment */update t set a=1":       "UPDATE",
+		"":                                    UnknownCommand,
+		"123":                                 UnknownCommand,
+		"/* unterminated":                     UnknownCommand,
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHI": UnknownCommand,
+	} {
+		assert.Equal(t, expected, command(query), query)
+	}
+}
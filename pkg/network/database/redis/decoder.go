@@ -0,0 +1,269 @@
+// Package redis decodes the commands and the replies of the Redis serialization protocol (RESP2 and RESP3)
+// from the bytes exchanged over a connection.
+// See https://redis.io/topics/protocol
+package redis
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLineLength is the number of bytes of a line kept to decode it
+	maxLineLength = 512
+
+	// maxPending is the maximum number of commands waiting for their reply
+	maxPending = 1024
+
+	// maxDepth is the maximum depth of the nested aggregates
+	maxDepth = 32
+
+	// maxBulkLength is the maximum length of a bulk string. Redis refuses bulk strings over 512MB.
+	maxBulkLength = 512 * 1024 * 1024
+
+	// maxAggregateLength is the maximum number of elements of an aggregate
+	maxAggregateLength = 1 << 24
+
+	// maxCommandLength is the maximum length of a command
+	maxCommandLength = 32
+)
+
+// UnknownCommand is the command of the requests of which the command could not be decoded
+const UnknownCommand = "UNKNOWN"
+
+// TransactionHandler is called with the command, the outcome and the latency of the completed commands
+type TransactionHandler func(command string, failed bool, latency time.Duration)
+
+// Decoder decodes the commands of a Redis connection. Since the commands can be pipelined, the replies
+// are matched to the commands in order. A command fails when its reply is an error.
+type Decoder struct {
+	handler TransactionHandler
+	client  valueReader
+	server  valueReader
+	pending []command
+}
+
+type command struct {
+	name  string
+	start time.Time
+}
+
+// NewDecoder returns a Decoder calling handler for each completed command
+func NewDecoder(handler TransactionHandler) *Decoder {
+	return &Decoder{
+		handler: handler,
+		client:  valueReader{captureCommand: true},
+	}
+}
+
+// Write decodes the data sent by the client or by the server at ts. It returns an error if the data
+// can not be decoded, in which case the decoder should not be used anymore.
+func (d *Decoder) Write(fromClient bool, data []byte, ts time.Time) error {
+	if fromClient {
+		return d.client.read(data, func(_ byte, name string) error {
+			if len(d.pending) >= maxPending {
+				return fmt.Errorf("more than %d redis commands are waiting for a reply", maxPending)
+			}
+			d.pending = append(d.pending, command{name: commandName(name), start: ts})
+			return nil
+		})
+	}
+
+	return d.server.read(data, func(valueType byte, _ string) error {
+		// push messages are not replies, and replies with no command are
+		// sent to subscribed clients or to commands sent before the capture started
+		if valueType == '>' || len(d.pending) == 0 {
+			return nil
+		}
+		cmd := d.pending[0]
+		d.pending = d.pending[1:]
+		d.handler(cmd.name, valueType == '-' || valueType == '!', ts.Sub(cmd.start))
+		return nil
+	})
+}
+
+// valueReader splits a stream into RESP values
+type valueReader struct {
+	// captureCommand is set when reading the client stream: the first element of the top-level
+	// arrays, which is the command, is captured, and inline commands are allowed
+	captureCommand bool
+
+	line      []byte
+	skip      int // bytes of the bulk string being read left to skip, including the trailing CRLF
+	capture   int // bytes of the bulk string being read left to capture
+	captured  []byte
+	topType   byte
+	aggregate []aggregate // aggregates being read, from the top-level one
+}
+
+type aggregate struct {
+	left      int // elements left to read
+	size      int
+	attribute bool // attributes precede the value they describe, and are not values themselves
+}
+
+func (r *valueReader) read(data []byte, onValue func(valueType byte, command string) error) error {
+	for len(data) > 0 {
+		if r.skip > 0 {
+			n := r.skip
+			if n > len(data) {
+				n = len(data)
+			}
+			if c := r.capture; c > 0 {
+				if c > n {
+					c = n
+				}
+				r.captured = append(r.captured, data[:c]...)
+				r.capture -= c
+			}
+			data = data[n:]
+			r.skip -= n
+			if r.skip == 0 {
+				if err := r.endValue(onValue); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			r.appendLine(data)
+			return nil
+		}
+		r.appendLine(data[:i])
+		data = data[i+1:]
+		line := bytes.TrimSuffix(r.line, []byte{'\r'})
+		r.line = r.line[:0]
+		if err := r.readLine(line, onValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *valueReader) appendLine(b []byte) {
+	if n := maxLineLength - len(r.line); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		r.line = append(r.line, b[:n]...)
+	}
+}
+
+func (r *valueReader) readLine(line []byte, onValue func(valueType byte, command string) error) error {
+	if len(line) == 0 {
+		if len(r.aggregate) == 0 {
+			// empty inline commands are ignored
+			return nil
+		}
+		return fmt.Errorf("empty redis line")
+	}
+
+	valueType := line[0]
+	if len(r.aggregate) == 0 {
+		r.topType = valueType
+		r.captured = r.captured[:0]
+	}
+	switch valueType {
+	case '+', '-', ':', '_', ',', '#', '(':
+		return r.endValue(onValue)
+	case '$', '!', '=':
+		n, err := parseLength(line[1:], maxBulkLength)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return r.endValue(onValue)
+		}
+		r.skip = n + 2
+		if r.captureCommand && len(r.aggregate) == 1 && r.aggregate[0].left == r.aggregate[0].size {
+			r.capture = n
+			if r.capture > maxCommandLength+1 {
+				r.capture = maxCommandLength + 1
+			}
+		}
+		return nil
+	case '*', '~', '>', '%', '|':
+		n, err := parseLength(line[1:], maxAggregateLength)
+		if err != nil {
+			return err
+		}
+		if valueType == '%' || valueType == '|' {
+			n *= 2
+		}
+		if n <= 0 {
+			if valueType == '|' {
+				// empty attributes describe the value following them
+				if len(r.aggregate) == 0 {
+					r.topType = 0
+				}
+				return nil
+			}
+			return r.endValue(onValue)
+		}
+		if len(r.aggregate) >= maxDepth {
+			return fmt.Errorf("redis aggregates nested over %d levels", maxDepth)
+		}
+		r.aggregate = append(r.aggregate, aggregate{left: n, size: n, attribute: valueType == '|'})
+		return nil
+	}
+
+	if len(r.aggregate) > 0 || !r.captureCommand {
+		return fmt.Errorf("invalid redis value type %q", valueType)
+	}
+	// inline command, of which the first word is the command
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		line = line[:i]
+	}
+	r.topType = 0
+	return onValue(0, string(line))
+}
+
+// endValue is called when a value is read, completing the aggregates of which it is the last element
+func (r *valueReader) endValue(onValue func(valueType byte, command string) error) error {
+	for len(r.aggregate) > 0 {
+		last := &r.aggregate[len(r.aggregate)-1]
+		last.left--
+		if last.left > 0 {
+			return nil
+		}
+		r.aggregate = r.aggregate[:len(r.aggregate)-1]
+		if last.attribute {
+			// the value described by the attribute follows
+			if len(r.aggregate) == 0 {
+				r.topType = 0
+			}
+			return nil
+		}
+	}
+	if r.topType == '|' || r.topType == 0 {
+		return nil
+	}
+	return onValue(r.topType, string(r.captured))
+}
+
+func parseLength(b []byte, max int) (int, error) {
+	n, err := strconv.Atoi(string(b))
+	if err != nil || n < -1 || n > max {
+		return 0, fmt.Errorf("invalid redis length %q", b)
+	}
+	return n, nil
+}
+
+// commandName returns the normalized name of a command
+func commandName(name string) string {
+	if name == "" || len(name) > maxCommandLength {
+		return UnknownCommand
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '.' && c != '_' && c != '-' {
+			return UnknownCommand
+		}
+	}
+	return strings.ToUpper(name)
+}
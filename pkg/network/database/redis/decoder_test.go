@@ -0,0 +1,169 @@
+package redis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type commandResult struct {
+	command string
+	failed  bool
+	latency time.Duration
+}
+
+func newTestDecoder() (*Decoder, *[]commandResult) {
+	var results []commandResult
+	d := NewDecoder(func(command string, failed bool, latency time.Duration) {
+		results = append(results, commandResult{command, failed, latency})
+	})
+	return d, &results
+}
+
+var t0 = time.Unix(1600000000, 0)
+
+func TestCommands(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, []byte("*3\r\n$3\r\nset\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("+OK\r\n"), t0.Add(time.Millisecond)))
+
+	require.NoError(t, d.Write(true, []byte("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("$5\r\nvalue\r\n"), t0.Add(2*time.Millisecond)))
+
+	require.NoError(t, d.Write(true, []byte("*2\r\n$4\r\nINCR\r\n$3\r\nkey\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("-ERR value is not an integer or out of range\r\n"), t0.Add(3*time.Millisecond)))
+
+	require.NoError(t, d.Write(true, []byte("*2\r\n$4\r\nLLEN\r\n$4\r\nlist\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte(":2\r\n"), t0))
+
+	assert.Equal(t, []commandResult{
+		{"SET", false, time.Millisecond},
+		{"GET", false, 2 * time.Millisecond},
+		{"INCR", true, 3 * time.Millisecond},
+		{"LLEN", false, 0},
+	}, *results)
+}
+
+func TestAggregateReplies(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, []byte("*2\r\n$6\r\nLRANGE\r\n$4\r\nlist\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("*3\r\n$1\r\na\r\n*2\r\n:1\r\n$-1\r\n*0\r\n"), t0))
+
+	// RESP3 map, with an attribute
+	require.NoError(t, d.Write(true, []byte("*1\r\n$5\r\nHELLO\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("|1\r\n+ttl\r\n:3600\r\n%2\r\n+server\r\n+redis\r\n+proto\r\n:3\r\n"), t0))
+
+	// null array
+	require.NoError(t, d.Write(true, []byte("*2\r\n$5\r\nBLPOP\r\n$4\r\nlist\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("*-1\r\n"), t0))
+
+	// RESP3 blob error
+	require.NoError(t, d.Write(true, []byte("*1\r\n$4\r\nPING\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("!21\r\nSYNTAX invalid syntax\r\n"), t0))
+
+	assert.Equal(t, []commandResult{
+		{"LRANGE", false, 0},
+		{"HELLO", false, 0},
+		{"BLPOP", false, 0},
+		{"PING", true, 0},
+	}, *results)
+}
+
+func TestPipelinedCommands(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, []byte("*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$1\r\na\r\n*1\r\n$5\r\nMULTI\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("+PONG\r\n$-1\r\n"), t0.Add(time.Millisecond)))
+	require.NoError(t, d.Write(false, []byte("+OK\r\n"), t0.Add(2*time.Millisecond)))
+
+	assert.Equal(t, []commandResult{
+		{"PING", false, time.Millisecond},
+		{"GET", false, time.Millisecond},
+		{"MULTI", false, 2 * time.Millisecond},
+	}, *results)
+}
+
+func TestSplitValues(t *testing.T) {
+	d, results := newTestDecoder()
+
+	value := strings.Repeat("x", 100000)
+	command := []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$100000\r\n" + value + "\r\n")
+	for i := 0; i < len(command); i += 7 {
+		end := i + 7
+		if end > len(command) {
+			end = len(command)
+		}
+		require.NoError(t, d.Write(true, command[i:end], t0))
+	}
+
+	require.NoError(t, d.Write(true, []byte("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"), t0))
+	reply := []byte("+OK\r\n$100000\r\n" + value + "\r\n")
+	require.NoError(t, d.Write(false, reply[:3], t0))
+	require.NoError(t, d.Write(false, reply[3:50000], t0.Add(time.Millisecond)))
+	require.NoError(t, d.Write(false, reply[50000:], t0.Add(2*time.Millisecond)))
+
+	assert.Equal(t, []commandResult{
+		{"SET", false, time.Millisecond},
+		{"GET", false, 2 * time.Millisecond},
+	}, *results)
+}
+
+func TestInlineCommands(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, []byte("ping\r\n\r\nexists key\n"), t0))
+	require.NoError(t, d.Write(false, []byte("+PONG\r\n:1\r\n"), t0))
+
+	assert.Equal(t, []commandResult{
+		{"PING", false, 0},
+		{"EXISTS", false, 0},
+	}, *results)
+}
+
+func TestPushMessages(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, []byte("*2\r\n$9\r\nSUBSCRIBE\r\n$7\r\nchannel\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte(">3\r\n$9\r\nsubscribe\r\n$7\r\nchannel\r\n:1\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte(">3\r\n$7\r\nmessage\r\n$7\r\nchannel\r\n$5\r\nhello\r\n"), t0))
+	assert.Empty(t, *results)
+
+	// the replies with no command are ignored
+	d, results = newTestDecoder()
+	require.NoError(t, d.Write(false, []byte("*3\r\n$7\r\nmessage\r\n$7\r\nchannel\r\n$5\r\nhello\r\n"), t0))
+	assert.Empty(t, *results)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	d, results := newTestDecoder()
+
+	require.NoError(t, d.Write(true, []byte("*1\r\n$40\r\n"+strings.Repeat("A", 40)+"\r\n"), t0))
+	require.NoError(t, d.Write(true, []byte("*1\r\n$4\r\nGE\x00T\r\n"), t0))
+	require.NoError(t, d.Write(true, []byte("*0\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("-ERR unknown command\r\n-ERR unknown command\r\n-ERR unknown command\r\n"), t0))
+
+	assert.Equal(t, []commandResult{
+		{UnknownCommand, true, 0},
+		{UnknownCommand, true, 0},
+		{UnknownCommand, true, 0},
+	}, *results)
+}
+
+func TestInvalidStream(t *testing.T) {
+	d, _ := newTestDecoder()
+	assert.Error(t, d.Write(true, []byte("*1\r\n?4\r\n"), t0))
+
+	d, _ = newTestDecoder()
+	assert.Error(t, d.Write(true, []byte("*x\r\n"), t0))
+
+	d, _ = newTestDecoder()
+	assert.Error(t, d.Write(false, []byte("HTTP/1.1 200 OK\r\n"), t0))
+
+	d, _ = newTestDecoder()
+	assert.Error(t, d.Write(true, []byte(strings.Repeat("*1\r\n", maxDepth+1)), t0))
+}
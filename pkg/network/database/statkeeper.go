@@ -0,0 +1,50 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type statKeeper struct {
+	mux        sync.Mutex
+	stats      map[Key]RequestStats
+	maxEntries int
+	telemetry  *telemetry
+}
+
+func newStatKeeper(maxEntries int, telemetry *telemetry) *statKeeper {
+	return &statKeeper{
+		stats:      make(map[Key]RequestStats),
+		maxEntries: maxEntries,
+		telemetry:  telemetry,
+	}
+}
+
+// Process adds a completed operation to the stats
+func (s *statKeeper) Process(key Key, failed bool, latency time.Duration) {
+	s.telemetry.addOperation(failed)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	stats, ok := s.stats[key]
+	if !ok && len(s.stats) >= s.maxEntries {
+		atomic.AddInt64(&s.telemetry.dropped, 1)
+		return
+	}
+
+	stats.AddRequest(failed, float64(latency.Nanoseconds()))
+	s.stats[key] = stats
+	atomic.StoreInt64(&s.telemetry.aggregations, int64(len(s.stats)))
+}
+
+// GetAndResetAllStats returns the stats gathered since the previous call
+func (s *statKeeper) GetAndResetAllStats() map[Key]RequestStats {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	ret := s.stats // No deep copy needed since `s.stats` gets reset
+	s.stats = make(map[Key]RequestStats)
+	return ret
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// Protocol is the type used to represent the database protocols
+type Protocol int
+
+const (
+	// ProtocolUnknown represents an unknown protocol
+	ProtocolUnknown Protocol = iota
+	// ProtocolPostgres represents the Postgres wire protocol
+	ProtocolPostgres
+	// ProtocolRedis represents the Redis serialization protocol (RESP)
+	ProtocolRedis
+)
+
+// String returns a string representing the protocol
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolPostgres:
+		return "postgres"
+	case ProtocolRedis:
+		return "redis"
+	default:
+		return "unknown"
+	}
+}
+
+// Key is an identifier for a group of database operations
+type Key struct {
+	SrcIPHigh uint64
+	SrcIPLow  uint64
+	SrcPort   uint16
+
+	DstIPHigh uint64
+	DstIPLow  uint64
+	DstPort   uint16
+
+	Protocol Protocol
+	// Command is the command of the operations, such as "SELECT" for Postgres or "GET" for Redis
+	Command string
+}
+
+// NewKey generates a new Key. Like the HTTP keys, the source is the client and the destination the server.
+func NewKey(saddr, daddr util.Address, sport, dport uint16, protocol Protocol, command string) Key {
+	saddrl, saddrh := util.ToLowHigh(saddr)
+	daddrl, daddrh := util.ToLowHigh(daddr)
+	return Key{
+		SrcIPHigh: saddrh,
+		SrcIPLow:  saddrl,
+		SrcPort:   sport,
+		DstIPHigh: daddrh,
+		DstIPLow:  daddrl,
+		DstPort:   dport,
+		Protocol:  protocol,
+		Command:   command,
+	}
+}
+
+// ConnectionKey returns the key without the protocol and the command, identifying the connection
+func (k Key) ConnectionKey() Key {
+	k.Protocol = ProtocolUnknown
+	k.Command = ""
+	return k
+}
+
+// RequestStats stores the stats of the successful and of the failed operations of a group
+type RequestStats struct {
+	Success latency.Stats
+	Failure latency.Stats
+}
+
+// AddRequest adds an operation having the given latency in nanoseconds to the stats
+func (r *RequestStats) AddRequest(failed bool, latency float64) {
+	if failed {
+		r.Failure.Add(latency)
+	} else {
+		r.Success.Add(latency)
+	}
+}
+
+// CombineWith merges the data in 2 RequestStats objects
+// newStats is kept as it is, while the method receiver gets mutated
+func (r *RequestStats) CombineWith(newStats RequestStats) {
+	r.Success.CombineWith(&newStats.Success)
+	r.Failure.CombineWith(&newStats.Failure)
+}
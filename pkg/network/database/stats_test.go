@@ -0,0 +1,63 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddRequest(t *testing.T) {
+	var stats RequestStats
+	stats.AddRequest(false, 10.0)
+	assert.Equal(t, 1, stats.Success.Count)
+	assert.Nil(t, stats.Success.Latencies)
+	assert.Equal(t, 10.0, stats.Success.FirstLatencySample)
+
+	stats.AddRequest(false, 15.0)
+	stats.AddRequest(false, 20.0)
+	stats.AddRequest(true, 5.0)
+
+	assert.Equal(t, 3, stats.Success.Count)
+	assert.Equal(t, 3.0, stats.Success.Latencies.GetCount())
+	verifyQuantile(t, stats.Success.Latencies, 0.0, 10.0)
+	verifyQuantile(t, stats.Success.Latencies, 1.0, 20.0)
+
+	assert.Equal(t, 1, stats.Failure.Count)
+	assert.Nil(t, stats.Failure.Latencies)
+	assert.Equal(t, 5.0, stats.Failure.FirstLatencySample)
+}
+
+func TestCombineWith(t *testing.T) {
+	var stats, single, multiple RequestStats
+	single.AddRequest(false, 10.0)
+	multiple.AddRequest(false, 20.0)
+	multiple.AddRequest(false, 30.0)
+	multiple.AddRequest(true, 40.0)
+
+	stats.CombineWith(single)
+	assert.Equal(t, 1, stats.Success.Count)
+	assert.Nil(t, stats.Success.Latencies)
+
+	stats.CombineWith(multiple)
+	assert.Equal(t, 3, stats.Success.Count)
+	assert.Equal(t, 3.0, stats.Success.Latencies.GetCount())
+	verifyQuantile(t, stats.Success.Latencies, 0.0, 10.0)
+	verifyQuantile(t, stats.Success.Latencies, 1.0, 30.0)
+	assert.Equal(t, 1, stats.Failure.Count)
+	assert.Equal(t, 40.0, stats.Failure.FirstLatencySample)
+
+	// empty stats don't change anything
+	stats.CombineWith(RequestStats{})
+	assert.Equal(t, 3, stats.Success.Count)
+	assert.Equal(t, 1, stats.Failure.Count)
+}
+
+func verifyQuantile(t *testing.T, sketch *ddsketch.DDSketch, q float64, expectedValue float64) {
+	val, err := sketch.GetValueAtQuantile(q)
+	assert.Nil(t, err)
+
+	acceptableError := expectedValue * sketch.IndexMapping.RelativeAccuracy()
+	assert.True(t, val >= expectedValue-acceptableError)
+	assert.True(t, val <= expectedValue+acceptableError)
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/database/postgres"
+	"github.com/DataDog/datadog-agent/pkg/network/database/redis"
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+)
+
+// newDecoderFunc returns a decoder for a connection, calling handler for each completed operation
+type newDecoderFunc func(handler func(command string, failed bool, latency time.Duration)) tcpstream.Decoder
+
+// decoders are the decoders of the supported protocols
+var decoders = map[Protocol]newDecoderFunc{
+	ProtocolPostgres: func(handler func(command string, failed bool, latency time.Duration)) tcpstream.Decoder {
+		return postgres.NewDecoder(handler)
+	},
+	ProtocolRedis: func(handler func(command string, failed bool, latency time.Duration)) tcpstream.Decoder {
+		return redis.NewDecoder(handler)
+	},
+}
+
+// newFlowTracker returns a tcpstream.Tracker decoding the connections to the database servers with the
+// decoder of their protocol, and calling onOperation for each completed operation
+func newFlowTracker(
+	protocols map[uint16]Protocol,
+	newDecoders map[Protocol]newDecoderFunc,
+	onOperation func(key Key, failed bool, latency time.Duration),
+	telemetry *telemetry,
+) *tcpstream.Tracker {
+	isServerPort := func(port uint16) bool {
+		_, ok := protocols[port]
+		return ok
+	}
+
+	newDecoder := func(c tcpstream.Connection) tcpstream.Decoder {
+		protocol := protocols[c.ServerPort]
+		key := NewKey(c.Client, c.Server, c.ClientPort, c.ServerPort, protocol, "")
+		return newDecoders[protocol](func(command string, failed bool, latency time.Duration) {
+			k := key
+			k.Command = command
+			onOperation(k, failed, latency)
+		})
+	}
+
+	return tcpstream.NewTracker(isServerPort, newDecoder, &telemetry.stream)
+}
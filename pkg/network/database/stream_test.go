@@ -0,0 +1,144 @@
+package database
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	clientIP = net.ParseIP("10.0.0.1")
+	serverIP = net.ParseIP("10.0.0.2")
+	t0       = time.Unix(1600000000, 0)
+
+	testProtocols = map[uint16]Protocol{5432: ProtocolPostgres, 6379: ProtocolRedis}
+)
+
+// testConnection feeds the packets of a connection to the tracker returned by newFlowTracker
+type testConnection struct {
+	t          *testing.T
+	parser     *tcpstream.Parser
+	tracker    *tcpstream.Tracker
+	statkeeper *statKeeper
+	telemetry  *telemetry
+
+	client, server         net.IP
+	clientPort, serverPort uint16
+	clientSeq, serverSeq   uint32
+}
+
+func newTestConnection(t *testing.T, serverPort uint16) *testConnection {
+	telemetry := newTelemetry()
+	statkeeper := newStatKeeper(1000, telemetry)
+	return &testConnection{
+		t:          t,
+		parser:     tcpstream.NewParser(layers.LayerTypeEthernet),
+		tracker:    newFlowTracker(testProtocols, decoders, statkeeper.Process, telemetry),
+		statkeeper: statkeeper,
+		telemetry:  telemetry,
+		client:     clientIP,
+		server:     serverIP,
+		clientPort: 40000,
+		serverPort: serverPort,
+		clientSeq:  1000,
+		serverSeq:  5000,
+	}
+}
+
+func (c *testConnection) send(fromClient bool, tcp layers.TCP, payload []byte, ts time.Time) {
+	src, dst := c.server, c.client
+	tcp.SrcPort, tcp.DstPort = layers.TCPPort(c.serverPort), layers.TCPPort(c.clientPort)
+	seq := &c.serverSeq
+	if fromClient {
+		src, dst = c.client, c.server
+		tcp.SrcPort, tcp.DstPort = tcp.DstPort, tcp.SrcPort
+		seq = &c.clientSeq
+	}
+	tcp.Seq = *seq
+	tcp.ACK = true
+	tcp.Window = 1024
+	*seq += uint32(len(payload))
+	if tcp.SYN || tcp.FIN {
+		*seq++
+	}
+
+	c.process(src, dst, &tcp, payload, ts)
+}
+
+func (c *testConnection) process(src, dst net.IP, tcp *layers.TCP, payload []byte, ts time.Time) {
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: src, DstIP: dst}
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 1},
+		DstMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 2},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	require.NoError(c.t, tcp.SetNetworkLayerForChecksum(ip))
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(c.t, gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)))
+
+	var s tcpstream.Segment
+	ok, err := c.parser.Parse(buf.Bytes(), ts, &s)
+	require.NoError(c.t, err)
+	require.True(c.t, ok)
+	c.tracker.Process(&s)
+}
+
+func (c *testConnection) handshake() {
+	c.send(true, layers.TCP{SYN: true}, nil, t0)
+	c.send(false, layers.TCP{SYN: true}, nil, t0)
+}
+
+func (c *testConnection) key(command string) Key {
+	protocol := testProtocols[c.serverPort]
+	return NewKey(util.AddressFromNetIP(c.client), util.AddressFromNetIP(c.server), c.clientPort, c.serverPort, protocol, command)
+}
+
+func TestPostgresFlow(t *testing.T) {
+	c := newTestConnection(t, 5432)
+	c.handshake()
+
+	query := []byte("Q\x00\x00\x00\x0dSELECT 1\x00")
+	response := []byte("C\x00\x00\x00\x0dSELECT 1\x00Z\x00\x00\x00\x05I")
+	c.send(true, layers.TCP{PSH: true}, query, t0)
+	c.send(false, layers.TCP{PSH: true}, response, t0.Add(time.Millisecond))
+
+	stats := c.statkeeper.GetAndResetAllStats()
+	require.Len(t, stats, 1)
+	s := stats[c.key("SELECT")]
+	assert.Equal(t, 1, s.Success.Count)
+	assert.Equal(t, float64(time.Millisecond), s.Success.FirstLatencySample)
+	assert.Equal(t, 0, s.Failure.Count)
+}
+
+func TestRedisFlowCapturedMidStream(t *testing.T) {
+	c := newTestConnection(t, 6379)
+	c.clientSeq, c.serverSeq = 123456, 654321
+
+	// the reply to a command sent before the capture started is ignored
+	c.send(false, layers.TCP{PSH: true}, []byte("$100\r\n"), t0)
+
+	c.send(true, layers.TCP{PSH: true}, []byte("*2\r\n$3\r\nGET\r\n$1\r\na\r\n"), t0)
+	c.send(false, layers.TCP{PSH: true}, []byte("-ERR wrong type\r\n"), t0.Add(2*time.Millisecond))
+
+	stats := c.statkeeper.GetAndResetAllStats()
+	require.Len(t, stats, 1)
+	s := stats[c.key("GET")]
+	assert.Equal(t, 1, s.Failure.Count)
+	assert.Equal(t, float64(2*time.Millisecond), s.Failure.FirstLatencySample)
+}
+
+func TestDecodingErrorTelemetry(t *testing.T) {
+	c := newTestConnection(t, 5432)
+	c.handshake()
+
+	c.send(true, layers.TCP{PSH: true}, []byte("GET / HTTP/1.1\r\n\r\n"), t0)
+	assert.Equal(t, int64(1), c.telemetry.stream.DecodingErrors)
+}
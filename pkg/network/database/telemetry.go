@@ -0,0 +1,69 @@
+package database
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+type telemetry struct {
+	then    int64
+	elapsed int64
+
+	operations   int64
+	failures     int64
+	dropped      int64 // this happens when statKeeper reaches capacity
+	aggregations int64
+
+	stream tcpstream.Telemetry
+}
+
+func newTelemetry() *telemetry {
+	return &telemetry{
+		then: time.Now().Unix(),
+	}
+}
+
+func (t *telemetry) addOperation(failed bool) {
+	atomic.AddInt64(&t.operations, 1)
+	if failed {
+		atomic.AddInt64(&t.failures, 1)
+	}
+}
+
+func (t *telemetry) reset() telemetry {
+	now := time.Now()
+	then := atomic.SwapInt64(&t.then, now.Unix())
+
+	return telemetry{
+		operations:   atomic.SwapInt64(&t.operations, 0),
+		failures:     atomic.SwapInt64(&t.failures, 0),
+		dropped:      atomic.SwapInt64(&t.dropped, 0),
+		aggregations: atomic.SwapInt64(&t.aggregations, 0),
+		stream:       t.stream.Reset(),
+		elapsed:      now.Unix() - then,
+	}
+}
+
+func (t *telemetry) report() {
+	elapsed := float64(t.elapsed)
+	if elapsed == 0 {
+		elapsed = 1
+	}
+
+	log.Debugf(
+		"database stats summary: operations_processed=%d(%.2f/s) operations_failed=%d(%.2f/s) operations_dropped=%d(%.2f/s) decoding_errors=%d stream_gaps=%d flows_dropped=%d aggregations=%d",
+		t.operations,
+		float64(t.operations)/elapsed,
+		t.failures,
+		float64(t.failures)/elapsed,
+		t.dropped,
+		float64(t.dropped)/elapsed,
+		t.stream.DecodingErrors,
+		t.stream.StreamGaps,
+		t.stream.FlowsDropped,
+		t.aggregations,
+	)
+}
@@ -0,0 +1,90 @@
+package debugging
+
+import (
+	"sort"
+	"syscall"
+
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+)
+
+// DomainSummary represents a (debug-friendly) aggregated view of the DNS responses
+// matching a (client, server, domain) tuple
+type DomainSummary struct {
+	Client   Address
+	Server   string
+	Protocol string
+	Domain   string
+	ByRcode  map[uint32]Stats
+}
+
+// Address represents represents a IP:Port
+type Address struct {
+	IP   string
+	Port uint16
+}
+
+// Stats consolidates the count and latency information of the responses having the same response code
+type Stats struct {
+	Count              int
+	FirstLatencySample float64
+	LatencyP50         float64
+}
+
+// DNSLatencies returns a debug-friendly representation of the DNS response latencies of dns.StatsByKeyByNameByType.
+// The latencies of the different query types of a domain are combined.
+func DNSLatencies(stats dns.StatsByKeyByNameByType) []DomainSummary {
+	all := make([]DomainSummary, 0, len(stats))
+	for k, statsByDomain := range stats {
+		for domain, statsByQtype := range statsByDomain {
+			latencies := make(map[uint32]*latency.Stats)
+			for _, s := range statsByQtype {
+				for rcode, l := range s.LatenciesByRcode {
+					if _, ok := latencies[rcode]; !ok {
+						latencies[rcode] = &latency.Stats{}
+					}
+					latencies[rcode].CombineWith(l)
+				}
+			}
+			if len(latencies) == 0 {
+				continue
+			}
+
+			debug := DomainSummary{
+				Client: Address{
+					IP:   k.ClientIP.String(),
+					Port: k.ClientPort,
+				},
+				Server:   k.ServerIP.String(),
+				Protocol: formatProtocol(k.Protocol),
+				Domain:   domain.Get().(string),
+				ByRcode:  make(map[uint32]Stats, len(latencies)),
+			}
+			for rcode, l := range latencies {
+				debug.ByRcode[rcode] = formatStats(l)
+			}
+			all = append(all, debug)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Domain < all[j].Domain })
+	return all
+}
+
+func formatStats(stats *latency.Stats) Stats {
+	return Stats{
+		Count:              stats.Count,
+		FirstLatencySample: stats.FirstLatencySample,
+		LatencyP50:         stats.ValueAtQuantile(0.5),
+	}
+}
+
+func formatProtocol(protocol uint8) string {
+	switch protocol {
+	case syscall.IPPROTO_TCP:
+		return "tcp"
+	case syscall.IPPROTO_UDP:
+		return "udp"
+	}
+	return ""
+}
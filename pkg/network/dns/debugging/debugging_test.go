@@ -0,0 +1,62 @@
+package debugging
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go4.org/intern"
+)
+
+func latencies(values ...float64) *latency.Stats {
+	l := &latency.Stats{}
+	for _, v := range values {
+		l.Add(v)
+	}
+	return l
+}
+
+func TestDNSLatencies(t *testing.T) {
+	key := dns.Key{
+		ClientIP:   util.AddressFromString("10.1.1.1"),
+		ServerIP:   util.AddressFromString("8.8.8.8"),
+		ClientPort: 1000,
+		Protocol:   syscall.IPPROTO_UDP,
+	}
+	stats := dns.StatsByKeyByNameByType{
+		key: {
+			intern.GetByString("foo.com"): {
+				dns.TypeA: {
+					CountByRcode:     map[uint32]uint32{0: 2},
+					LatenciesByRcode: map[uint32]*latency.Stats{0: latencies(1e6, 3e6)},
+				},
+				dns.TypeAAAA: {
+					CountByRcode:     map[uint32]uint32{0: 1, 3: 1},
+					LatenciesByRcode: map[uint32]*latency.Stats{0: latencies(2e6), 3: latencies(5e6)},
+				},
+			},
+			// only timeouts
+			intern.GetByString("bar.com"): {
+				dns.TypeA: {Timeouts: 1, CountByRcode: map[uint32]uint32{}},
+			},
+		},
+	}
+
+	summaries := DNSLatencies(stats)
+	require.Len(t, summaries, 1)
+	summary := summaries[0]
+	assert.Equal(t, Address{IP: "10.1.1.1", Port: 1000}, summary.Client)
+	assert.Equal(t, "8.8.8.8", summary.Server)
+	assert.Equal(t, "udp", summary.Protocol)
+	assert.Equal(t, "foo.com", summary.Domain)
+
+	// the latencies of the query types are combined
+	require.Len(t, summary.ByRcode, 2)
+	assert.Equal(t, 3, summary.ByRcode[0].Count)
+	assert.InEpsilon(t, 2e6, summary.ByRcode[0].LatencyP50, 0.02)
+	assert.Equal(t, Stats{Count: 1, FirstLatencySample: 5e6, LatencyP50: 5e6}, summary.ByRcode[3])
+}
@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"go4.org/intern"
 )
@@ -120,7 +121,7 @@ func (d *dnsStatKeeper) ProcessPacketInfo(info dnsPacketInfo, ts time.Time) {
 	delete(d.state, sk)
 	d.deleteCount++
 
-	latency := microSecs(ts) - start.ts
+	elapsed := microSecs(ts) - start.ts
 
 	allStats, ok := d.stats[info.key]
 	if !ok {
@@ -141,18 +142,25 @@ func (d *dnsStatKeeper) ProcessPacketInfo(info dnsPacketInfo, ts time.Time) {
 			return
 		}
 		byqtype.CountByRcode = make(map[uint32]uint32)
+		byqtype.LatenciesByRcode = make(map[uint32]*latency.Stats)
 		d.numStats++
 	}
 
 	// Note: time.Duration in the agent version of go (1.12.9) does not have the Microseconds method.
-	if latency > uint64(d.expirationPeriod.Microseconds()) {
+	if elapsed > uint64(d.expirationPeriod.Microseconds()) {
 		byqtype.Timeouts++
 	} else {
 		byqtype.CountByRcode[uint32(info.rCode)]++
+		latencies, ok := byqtype.LatenciesByRcode[uint32(info.rCode)]
+		if !ok {
+			latencies = &latency.Stats{}
+			byqtype.LatenciesByRcode[uint32(info.rCode)] = latencies
+		}
+		latencies.Add(float64(elapsed * 1000))
 		if info.pktType == successfulResponse {
-			byqtype.SuccessLatencySum += latency
+			byqtype.SuccessLatencySum += elapsed
 		} else if info.pktType == failedResponse {
-			byqtype.FailureLatencySum += latency
+			byqtype.FailureLatencySum += elapsed
 		}
 	}
 	stats[start.qtype] = byqtype
@@ -197,6 +205,13 @@ func (d *dnsStatKeeper) Snapshot() StatsByKeyByNameByType {
 					rcodeCopy[rcode] = count
 				}
 				statsCopy.CountByRcode = rcodeCopy
+				// Copy LatenciesByRcode map
+				latenciesCopy := make(map[uint32]*latency.Stats)
+				for rcode, latencies := range statsCopy.LatenciesByRcode {
+					latenciesCopy[rcode] = &latency.Stats{}
+					latenciesCopy[rcode].CombineWith(latencies)
+				}
+				statsCopy.LatenciesByRcode = latenciesCopy
 				snapshot[key][domain][qtype] = statsCopy
 			}
 		}
@@ -232,6 +247,7 @@ func (d *dnsStatKeeper) removeExpiredStates(earliestTs time.Time) {
 			if !ok {
 				d.numStats++
 				stats.CountByRcode = make(map[uint32]uint32)
+				stats.LatenciesByRcode = make(map[uint32]*latency.Stats)
 			}
 			stats.Timeouts++
 			bytype[v.qtype] = stats
@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"go4.org/intern"
 )
@@ -102,6 +103,40 @@ func TestExpiredStateRemoval(t *testing.T) {
 	assert.Equal(t, uint32(1), stats[key][d][TypeA].Timeouts)
 }
 
+func TestLatenciesByRcode(t *testing.T) {
+	sk := newDNSStatkeeper(DNSTimeoutSecs*time.Second, 10000)
+	key := getSampleDNSKey()
+	var d = intern.GetByString("abc.com")
+	then := time.Now()
+
+	// two NXDOMAIN responses and one successful response
+	for id, delta := range []time.Duration{time.Millisecond, 3 * time.Millisecond, 2 * time.Millisecond} {
+		qPkt := dnsPacketInfo{transactionID: uint16(id), pktType: query, key: key, question: d, queryType: TypeA}
+		rPkt := dnsPacketInfo{transactionID: uint16(id), key: key, pktType: failedResponse, rCode: 3, queryType: TypeA}
+		if id == 2 {
+			rPkt.pktType = successfulResponse
+			rPkt.rCode = 0
+		}
+		sk.ProcessPacketInfo(qPkt, then)
+		sk.ProcessPacketInfo(rPkt, then.Add(delta))
+	}
+
+	stats := sk.Snapshot()
+	require.Contains(t, stats, key)
+	require.Contains(t, stats[key], d)
+	latencies := stats[key][d][TypeA].LatenciesByRcode
+	require.Len(t, latencies, 2)
+
+	require.Contains(t, latencies, uint32(3))
+	assert.Equal(t, 2, latencies[3].Count)
+	assert.InEpsilon(t, float64(3*time.Millisecond), latencies[3].ValueAtQuantile(1), latency.RelativeAccuracy)
+
+	require.Contains(t, latencies, uint32(0))
+	assert.Equal(t, 1, latencies[0].Count)
+	assert.Nil(t, latencies[0].Latencies)
+	assert.Equal(t, float64(2*time.Millisecond), latencies[0].FirstLatencySample)
+}
+
 func BenchmarkStats(b *testing.B) {
 	key := getSampleDNSKey()
 
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/google/gopacket/layers"
 	"go4.org/intern"
@@ -60,4 +61,7 @@ type Stats struct {
 	SuccessLatencySum uint64
 	FailureLatencySum uint64
 	CountByRcode      map[uint32]uint32
+	// LatenciesByRcode holds the latency distributions of the responses, by response code.
+	// The responses which timed out are not part of it.
+	LatenciesByRcode map[uint32]*latency.Stats
 }
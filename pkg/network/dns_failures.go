@@ -0,0 +1,192 @@
+package network
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+)
+
+// dnsRcodeNames are the names of the most common DNS response codes
+var dnsRcodeNames = map[uint32]string{
+	0: "NOERROR",
+	1: "FORMERR",
+	2: "SERVFAIL",
+	3: "NXDOMAIN",
+	4: "NOTIMP",
+	5: "REFUSED",
+}
+
+// DNSFailures holds the domains of which the DNS resolutions failed the most for a process,
+// or for the processes of a container
+type DNSFailures struct {
+	Pid         uint32              `json:"pid,omitempty"`
+	ProcessName string              `json:"process_name,omitempty"`
+	ContainerID string              `json:"container_id,omitempty"`
+	Domains     []DNSDomainFailures `json:"domains"`
+}
+
+// DNSDomainFailures holds the DNS responses to the queries of a domain
+type DNSDomainFailures struct {
+	Domain string `json:"domain"`
+	// Failures counts the timeouts and the responses having a response code other than NOERROR
+	Failures  uint32         `json:"failures"`
+	Timeouts  uint32         `json:"timeouts"`
+	Responses []DNSResponses `json:"responses"`
+}
+
+// DNSResponses holds the count and the latency percentiles (in nanoseconds) of the responses having
+// the same response code
+type DNSResponses struct {
+	Rcode      string  `json:"rcode"`
+	Count      uint32  `json:"count"`
+	LatencyP50 float64 `json:"latency_p50"`
+	LatencyP95 float64 `json:"latency_p95"`
+	LatencyP99 float64 `json:"latency_p99"`
+}
+
+// dnsFailuresGroup identifies the processes of which the DNS stats are grouped: the processes of a container,
+// or a process running outside of any container
+type dnsFailuresGroup struct {
+	pid         uint32
+	containerID string
+}
+
+type dnsDomainStats struct {
+	timeouts  uint32
+	counts    map[uint32]uint32
+	latencies map[uint32]*latency.Stats
+}
+
+// TopDNSFailures returns, for each process or container, the maxDomains domains of which the DNS resolutions
+// failed the most. resolve returns the attributes of the process having the given pid, or nil if they are
+// unknown; it may be nil, in which case the stats are grouped by process.
+func TopDNSFailures(snapshot DNSSnapshot, resolve func(pid uint32) *ProcessInfo, maxDomains int) []DNSFailures {
+	if len(snapshot.DNSStats) == 0 || maxDomains <= 0 {
+		return nil
+	}
+
+	processNames := make(map[dnsFailuresGroup]string)
+	domainsByGroup := make(map[dnsFailuresGroup]map[string]*dnsDomainStats)
+	for key, statsByDomain := range snapshot.DNSStats {
+		// The stats of the queries sent by an unknown process are ignored
+		pid, ok := snapshot.Pids[key]
+		if !ok {
+			continue
+		}
+
+		group := dnsFailuresGroup{pid: pid}
+		var info *ProcessInfo
+		if resolve != nil {
+			info = resolve(pid)
+		}
+		if info != nil && info.ContainerID != "" {
+			group = dnsFailuresGroup{containerID: info.ContainerID}
+		} else if info != nil {
+			processNames[group] = info.Name
+		}
+
+		domains, ok := domainsByGroup[group]
+		if !ok {
+			domains = make(map[string]*dnsDomainStats)
+			domainsByGroup[group] = domains
+		}
+		for domain, statsByQtype := range statsByDomain {
+			name := domain.Get().(string)
+			ds, ok := domains[name]
+			if !ok {
+				ds = &dnsDomainStats{
+					counts:    make(map[uint32]uint32),
+					latencies: make(map[uint32]*latency.Stats),
+				}
+				domains[name] = ds
+			}
+			for _, s := range statsByQtype {
+				ds.timeouts += s.Timeouts
+				for rcode, count := range s.CountByRcode {
+					ds.counts[rcode] += count
+				}
+				for rcode, l := range s.LatenciesByRcode {
+					if _, ok := ds.latencies[rcode]; !ok {
+						ds.latencies[rcode] = &latency.Stats{}
+					}
+					ds.latencies[rcode].CombineWith(l)
+				}
+			}
+		}
+	}
+
+	var failures []DNSFailures
+	for group, domains := range domainsByGroup {
+		top := topFailingDomains(domains, maxDomains)
+		if len(top) == 0 {
+			continue
+		}
+		failures = append(failures, DNSFailures{
+			Pid:         group.pid,
+			ProcessName: processNames[group],
+			ContainerID: group.containerID,
+			Domains:     top,
+		})
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].ContainerID != failures[j].ContainerID {
+			return failures[i].ContainerID < failures[j].ContainerID
+		}
+		return failures[i].Pid < failures[j].Pid
+	})
+	return failures
+}
+
+// topFailingDomains returns the maxDomains domains having the most failures, ignoring the domains without failure
+func topFailingDomains(domains map[string]*dnsDomainStats, maxDomains int) []DNSDomainFailures {
+	var top []DNSDomainFailures
+	for domain, ds := range domains {
+		failures := ds.timeouts
+		for rcode, count := range ds.counts {
+			if rcode != DNSResponseCodeNoError {
+				failures += count
+			}
+		}
+		if failures == 0 {
+			continue
+		}
+
+		responses := make([]DNSResponses, 0, len(ds.counts))
+		for rcode, count := range ds.counts {
+			r := DNSResponses{Rcode: dnsRcodeName(rcode), Count: count}
+			if l, ok := ds.latencies[rcode]; ok {
+				r.LatencyP50 = l.ValueAtQuantile(0.5)
+				r.LatencyP95 = l.ValueAtQuantile(0.95)
+				r.LatencyP99 = l.ValueAtQuantile(0.99)
+			}
+			responses = append(responses, r)
+		}
+		sort.Slice(responses, func(i, j int) bool { return responses[i].Rcode < responses[j].Rcode })
+
+		top = append(top, DNSDomainFailures{
+			Domain:    domain,
+			Failures:  failures,
+			Timeouts:  ds.timeouts,
+			Responses: responses,
+		})
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Failures != top[j].Failures {
+			return top[i].Failures > top[j].Failures
+		}
+		return top[i].Domain < top[j].Domain
+	})
+	if len(top) > maxDomains {
+		top = top[:maxDomains]
+	}
+	return top
+}
+
+func dnsRcodeName(rcode uint32) string {
+	if name, ok := dnsRcodeNames[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.FormatUint(uint64(rcode), 10)
+}
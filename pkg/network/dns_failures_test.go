@@ -0,0 +1,140 @@
+package network
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go4.org/intern"
+)
+
+func TestTopDNSFailures(t *testing.T) {
+	server := util.AddressFromString("8.8.8.8")
+	client := util.AddressFromString("10.1.1.1")
+	dnsKey := func(port uint16) dns.Key {
+		return dns.Key{ClientIP: client, ServerIP: server, ClientPort: port, Protocol: syscall.IPPROTO_UDP}
+	}
+	latencies := func(values ...float64) *latency.Stats {
+		l := &latency.Stats{}
+		for _, v := range values {
+			l.Add(v)
+		}
+		return l
+	}
+
+	stats := dns.StatsByKeyByNameByType{
+		dnsKey(1001): {
+			intern.GetByString("foo.com"): {
+				dns.TypeA: {
+					CountByRcode:     map[uint32]uint32{0: 1, 3: 2},
+					LatenciesByRcode: map[uint32]*latency.Stats{0: latencies(1e6), 3: latencies(2e6, 4e6)},
+				},
+			},
+			intern.GetByString("bar.com"): {
+				dns.TypeA: {Timeouts: 3, CountByRcode: map[uint32]uint32{}},
+			},
+		},
+		// the two processes of the container
+		dnsKey(1002): {
+			intern.GetByString("foo.com"): {
+				dns.TypeA: {
+					CountByRcode:     map[uint32]uint32{2: 1},
+					LatenciesByRcode: map[uint32]*latency.Stats{2: latencies(5e6)},
+				},
+			},
+		},
+		dnsKey(1003): {
+			intern.GetByString("foo.com"): {
+				dns.TypeAAAA: {
+					CountByRcode:     map[uint32]uint32{2: 1, 16: 1},
+					LatenciesByRcode: map[uint32]*latency.Stats{2: latencies(7e6), 16: latencies(1e6)},
+				},
+			},
+		},
+		// no failure
+		dnsKey(1004): {
+			intern.GetByString("baz.com"): {
+				dns.TypeA: {
+					CountByRcode:     map[uint32]uint32{0: 1},
+					LatenciesByRcode: map[uint32]*latency.Stats{0: latencies(1e6)},
+				},
+			},
+		},
+		// sent by an unknown process
+		dnsKey(1005): {
+			intern.GetByString("qux.com"): {
+				dns.TypeA: {Timeouts: 1, CountByRcode: map[uint32]uint32{}},
+			},
+		},
+	}
+	snapshot := DNSSnapshot{
+		DNSStats: stats,
+		Pids: map[dns.Key]uint32{
+			dnsKey(1001): 1,
+			dnsKey(1002): 2,
+			dnsKey(1003): 3,
+			dnsKey(1004): 4,
+		},
+	}
+	resolve := func(pid uint32) *ProcessInfo {
+		switch pid {
+		case 1:
+			return &ProcessInfo{Name: "curl"}
+		case 2, 3:
+			return &ProcessInfo{Name: "app", ContainerID: "3f2c1e4d5b6a"}
+		}
+		return nil
+	}
+
+	failures := TopDNSFailures(snapshot, resolve, 10)
+	require.Len(t, failures, 2)
+
+	process := failures[0]
+	assert.Equal(t, uint32(1), process.Pid)
+	assert.Equal(t, "curl", process.ProcessName)
+	assert.Empty(t, process.ContainerID)
+	require.Len(t, process.Domains, 2)
+	assert.Equal(t, DNSDomainFailures{Domain: "bar.com", Failures: 3, Timeouts: 3, Responses: []DNSResponses{}}, process.Domains[0])
+	foo := process.Domains[1]
+	assert.Equal(t, "foo.com", foo.Domain)
+	assert.Equal(t, uint32(2), foo.Failures)
+	require.Len(t, foo.Responses, 2)
+	assert.Equal(t, DNSResponses{Rcode: "NOERROR", Count: 1, LatencyP50: 1e6, LatencyP95: 1e6, LatencyP99: 1e6}, foo.Responses[0])
+	assert.Equal(t, "NXDOMAIN", foo.Responses[1].Rcode)
+	assert.Equal(t, uint32(2), foo.Responses[1].Count)
+	assert.InEpsilon(t, 2e6, foo.Responses[1].LatencyP50, latency.RelativeAccuracy)
+
+	container := failures[1]
+	assert.Equal(t, uint32(0), container.Pid)
+	assert.Equal(t, "3f2c1e4d5b6a", container.ContainerID)
+	require.Len(t, container.Domains, 1)
+	foo = container.Domains[0]
+	assert.Equal(t, uint32(3), foo.Failures)
+	require.Len(t, foo.Responses, 2)
+	assert.Equal(t, "RCODE16", foo.Responses[0].Rcode)
+	assert.Equal(t, "SERVFAIL", foo.Responses[1].Rcode)
+	assert.Equal(t, uint32(2), foo.Responses[1].Count)
+	assert.InEpsilon(t, 5e6, foo.Responses[1].LatencyP50, latency.RelativeAccuracy)
+
+	t.Run("top domains", func(t *testing.T) {
+		failures := TopDNSFailures(snapshot, resolve, 1)
+		require.Len(t, failures, 2)
+		require.Len(t, failures[0].Domains, 1)
+		assert.Equal(t, "bar.com", failures[0].Domains[0].Domain)
+	})
+
+	t.Run("without process resolution", func(t *testing.T) {
+		failures := TopDNSFailures(snapshot, nil, 10)
+		require.Len(t, failures, 3)
+		for i, pid := range []uint32{1, 2, 3} {
+			assert.Equal(t, pid, failures[i].Pid)
+			assert.Empty(t, failures[i].ProcessName)
+		}
+	})
+
+	assert.Nil(t, TopDNSFailures(DNSSnapshot{}, resolve, 10))
+}
@@ -68,6 +68,10 @@ func modelConnections(conns *network.Connections) *model.Connections {
 	ipc := make(ipCache, len(conns.Conns)/2)
 	dnsFormatter := newDNSFormatter(conns, ipc)
 
+	// TODO: encode the database aggregations of conns.Database next to the HTTP aggregations, and
+	// the DNS latencies of conns.DNSStats next to the other DNS stats, once the agent-payload model
+	// of the connections has fields for them. Until then, they are only served by the
+	// /debug/database_monitoring and /debug/dns_latencies endpoints of the system-probe.
 	for i, conn := range conns.Conns {
 		httpKey := httpKeyFromConn(conn)
 		httpAggregations := httpIndex[httpKey]
@@ -14,6 +14,7 @@ import (
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/afpacket"
 	"github.com/google/gopacket/layers"
+	"golang.org/x/net/bpf"
 )
 
 // AFPacketSource provides a RAW_SOCKET attached to an eBPF SOCKET_FILTER
@@ -31,7 +32,40 @@ type AFPacketSource struct {
 	dropped   int64
 }
 
+// NewPacketSource returns an AFPacketSource of which the packets are filtered by an eBPF SOCKET_FILTER
 func NewPacketSource(filter *manager.Probe) (*AFPacketSource, error) {
+	rawSocket, err := newTPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	// The underlying socket file descriptor is private, hence the use of reflection
+	socketFD := int(reflect.ValueOf(rawSocket).Elem().FieldByName("fd").Int())
+
+	// Point socket filter program to the RAW_SOCKET file descriptor
+	// Note the filter attachment itself is triggered by the ebpf.Manager
+	filter.SocketFD = socketFD
+
+	return newAFPacketSource(rawSocket, filter, socketFD), nil
+}
+
+// NewPacketSourceWithFilter returns an AFPacketSource of which the packets are filtered by a classic BPF program
+func NewPacketSourceWithFilter(filter []bpf.RawInstruction) (*AFPacketSource, error) {
+	rawSocket, err := newTPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rawSocket.SetBPF(filter); err != nil {
+		rawSocket.Close()
+		return nil, fmt.Errorf("error attaching filter to raw socket: %s", err)
+	}
+
+	socketFD := int(reflect.ValueOf(rawSocket).Elem().FieldByName("fd").Int())
+	return newAFPacketSource(rawSocket, nil, socketFD), nil
+}
+
+func newTPacket() (*afpacket.TPacket, error) {
 	rawSocket, err := afpacket.NewTPacket(
 		afpacket.OptPollTimeout(1*time.Second),
 		// This setup will require ~4Mb that is mmap'd into the process virtual space
@@ -43,14 +77,10 @@ func NewPacketSource(filter *manager.Probe) (*AFPacketSource, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error creating raw socket: %s", err)
 	}
+	return rawSocket, nil
+}
 
-	// The underlying socket file descriptor is private, hence the use of reflection
-	socketFD := int(reflect.ValueOf(rawSocket).Elem().FieldByName("fd").Int())
-
-	// Point socket filter program to the RAW_SOCKET file descriptor
-	// Note the filter attachment itself is triggered by the ebpf.Manager
-	filter.SocketFD = socketFD
-
+func newAFPacketSource(rawSocket *afpacket.TPacket, filter *manager.Probe, socketFD int) *AFPacketSource {
 	ps := &AFPacketSource{
 		TPacket:      rawSocket,
 		socketFilter: filter,
@@ -59,7 +89,7 @@ func NewPacketSource(filter *manager.Probe) (*AFPacketSource, error) {
 	}
 	go ps.pollStats()
 
-	return ps, nil
+	return ps
 }
 
 func (p *AFPacketSource) Stats() map[string]int64 {
@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"fmt"
+
+	"golang.org/x/net/bpf"
+)
+
+const (
+	// snapLen is the number of bytes of the packets accepted by TCPPortsFilter
+	snapLen = 262144
+
+	ethHeaderLen  = 14
+	ipv6HeaderLen = 40
+)
+
+// TCPPortsFilter returns a classic BPF program accepting the TCP packets, over IPv4 or IPv6 and
+// in Ethernet frames, of which the source or destination port is one of the given ports.
+// IPv4 fragments other than the first one are dropped, since they carry no TCP header.
+func TCPPortsFilter(ports []uint16) ([]bpf.RawInstruction, error) {
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no port to filter")
+	}
+
+	var p assembler
+	p.add(bpf.LoadAbsolute{Off: 12, Size: 2}) // ethertype
+	p.jumpIf(bpf.JumpEqual, 0x0800, "", "not_ipv4")
+
+	// IPv4
+	p.add(bpf.LoadAbsolute{Off: ethHeaderLen + 9, Size: 1}) // protocol
+	p.jumpIf(bpf.JumpEqual, 6, "", "drop")
+	p.add(bpf.LoadAbsolute{Off: ethHeaderLen + 6, Size: 2}) // flags and fragment offset
+	p.jumpIf(bpf.JumpBitsSet, 0x1fff, "drop", "")
+	p.add(bpf.LoadMemShift{Off: ethHeaderLen}) // X = IPv4 header length
+	p.add(bpf.LoadIndirect{Off: ethHeaderLen, Size: 2})
+	p.matchPorts(ports)
+	p.add(bpf.LoadIndirect{Off: ethHeaderLen + 2, Size: 2})
+	p.matchPorts(ports)
+	p.jump("drop")
+
+	// IPv6, of which extension headers are not supported
+	p.label("not_ipv4")
+	p.jumpIf(bpf.JumpEqual, 0x86dd, "", "drop")
+	p.add(bpf.LoadAbsolute{Off: ethHeaderLen + 6, Size: 1}) // next header
+	p.jumpIf(bpf.JumpEqual, 6, "", "drop")
+	p.add(bpf.LoadAbsolute{Off: ethHeaderLen + ipv6HeaderLen, Size: 2})
+	p.matchPorts(ports)
+	p.add(bpf.LoadAbsolute{Off: ethHeaderLen + ipv6HeaderLen + 2, Size: 2})
+	p.matchPorts(ports)
+
+	p.label("drop")
+	p.add(bpf.RetConstant{Val: 0})
+	p.label("accept")
+	p.add(bpf.RetConstant{Val: snapLen})
+
+	return p.assemble()
+}
+
+// assembler builds a BPF program of which the jumps target labels
+type assembler struct {
+	instructions []bpf.Instruction
+	targets      []jumpTargets
+	labels       map[string]int
+}
+
+// jumpTargets are the labels targeted by a jump instruction. An empty label targets the next instruction.
+type jumpTargets struct {
+	onTrue, onFalse string
+}
+
+func (a *assembler) add(i bpf.Instruction) {
+	a.instructions = append(a.instructions, i)
+	a.targets = append(a.targets, jumpTargets{})
+}
+
+func (a *assembler) jumpIf(cond bpf.JumpTest, val uint32, onTrue, onFalse string) {
+	a.instructions = append(a.instructions, bpf.JumpIf{Cond: cond, Val: val})
+	a.targets = append(a.targets, jumpTargets{onTrue: onTrue, onFalse: onFalse})
+}
+
+func (a *assembler) jump(label string) {
+	a.instructions = append(a.instructions, bpf.Jump{})
+	a.targets = append(a.targets, jumpTargets{onTrue: label})
+}
+
+func (a *assembler) label(name string) {
+	if a.labels == nil {
+		a.labels = make(map[string]int)
+	}
+	a.labels[name] = len(a.instructions)
+}
+
+// matchPorts accepts the packet if the loaded value is one of the ports
+func (a *assembler) matchPorts(ports []uint16) {
+	for _, port := range ports {
+		a.jumpIf(bpf.JumpEqual, uint32(port), "accept", "")
+	}
+}
+
+func (a *assembler) assemble() ([]bpf.RawInstruction, error) {
+	for i, insn := range a.instructions {
+		targets := a.targets[i]
+		switch insn := insn.(type) {
+		case bpf.JumpIf:
+			onTrue, err := a.skip(i, targets.onTrue, 0xff)
+			if err != nil {
+				return nil, err
+			}
+			onFalse, err := a.skip(i, targets.onFalse, 0xff)
+			if err != nil {
+				return nil, err
+			}
+			insn.SkipTrue, insn.SkipFalse = uint8(onTrue), uint8(onFalse)
+			a.instructions[i] = insn
+		case bpf.Jump:
+			skip, err := a.skip(i, targets.onTrue, 0xffffffff)
+			if err != nil {
+				return nil, err
+			}
+			insn.Skip = skip
+			a.instructions[i] = insn
+		}
+	}
+	return bpf.Assemble(a.instructions)
+}
+
+// skip returns the number of instructions to skip to jump from the instruction at index i to the label
+func (a *assembler) skip(i int, label string, max uint32) (uint32, error) {
+	if label == "" {
+		return 0, nil
+	}
+	target, ok := a.labels[label]
+	if !ok {
+		return 0, fmt.Errorf("unknown label %q", label)
+	}
+	skip := target - i - 1
+	if skip < 0 || uint32(skip) > max {
+		return 0, fmt.Errorf("jump to %q out of range, too many ports to filter", label)
+	}
+	return uint32(skip), nil
+}
@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/bpf"
+)
+
+func TestTCPPortsFilter(t *testing.T) {
+	raw, err := TCPPortsFilter([]uint16{5432, 6379})
+	require.NoError(t, err)
+	instructions, ok := bpf.Disassemble(raw)
+	require.True(t, ok)
+	vm, err := bpf.NewVM(instructions)
+	require.NoError(t, err)
+
+	accepted := func(packet []byte) bool {
+		n, err := vm.Run(packet)
+		require.NoError(t, err)
+		return n > 0
+	}
+
+	v4Src, v4Dst := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	v6Src, v6Dst := net.ParseIP("fd00::1"), net.ParseIP("fd00::2")
+
+	assert.True(t, accepted(tcpPacket(t, v4Src, v4Dst, 40000, 5432)))
+	assert.True(t, accepted(tcpPacket(t, v4Src, v4Dst, 6379, 40000)))
+	assert.False(t, accepted(tcpPacket(t, v4Src, v4Dst, 40000, 80)))
+	assert.True(t, accepted(tcpPacket(t, v6Src, v6Dst, 40000, 6379)))
+	assert.True(t, accepted(tcpPacket(t, v6Src, v6Dst, 5432, 40000)))
+	assert.False(t, accepted(tcpPacket(t, v6Src, v6Dst, 443, 40000)))
+	assert.False(t, accepted(udpPacket(t, v4Src, v4Dst, 40000, 5432)))
+
+	t.Run("ipv4 options", func(t *testing.T) {
+		ip := &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    v4Src,
+			DstIP:    v4Dst,
+			Options:  []layers.IPv4Option{{OptionType: 1}, {OptionType: 1}, {OptionType: 1}, {OptionType: 1}},
+		}
+		assert.True(t, accepted(serialize(t, ip, &layers.TCP{SrcPort: 40000, DstPort: 5432})))
+	})
+
+	t.Run("ipv4 fragment", func(t *testing.T) {
+		ip := &layers.IPv4{
+			Version:    4,
+			TTL:        64,
+			Protocol:   layers.IPProtocolTCP,
+			SrcIP:      v4Src,
+			DstIP:      v4Dst,
+			FragOffset: 100,
+		}
+		// the payload of the fragment looks like a TCP header matching the filter
+		payload := gopacket.Payload([]byte{0x9c, 0x40, 0x15, 0x38, 0, 0, 0, 0})
+		assert.False(t, accepted(serialize(t, ip, payload)))
+	})
+}
+
+func TestTCPPortsFilterErrors(t *testing.T) {
+	_, err := TCPPortsFilter(nil)
+	assert.Error(t, err)
+
+	ports := make([]uint16, 200)
+	for i := range ports {
+		ports[i] = uint16(i + 1)
+	}
+	_, err = TCPPortsFilter(ports)
+	assert.Error(t, err)
+}
+
+func tcpPacket(t *testing.T, src, dst net.IP, srcPort, dstPort uint16) []byte {
+	return serialize(t, ipLayer(src, dst, layers.IPProtocolTCP), &layers.TCP{
+		SrcPort: layers.TCPPort(srcPort),
+		DstPort: layers.TCPPort(dstPort),
+		ACK:     true,
+		Window:  1024,
+	})
+}
+
+func udpPacket(t *testing.T, src, dst net.IP, srcPort, dstPort uint16) []byte {
+	return serialize(t, ipLayer(src, dst, layers.IPProtocolUDP), &layers.UDP{
+		SrcPort: layers.UDPPort(srcPort),
+		DstPort: layers.UDPPort(dstPort),
+	})
+}
+
+func ipLayer(src, dst net.IP, protocol layers.IPProtocol) gopacket.SerializableLayer {
+	if src.To4() != nil {
+		return &layers.IPv4{Version: 4, TTL: 64, Protocol: protocol, SrcIP: src, DstIP: dst}
+	}
+	return &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: protocol, SrcIP: src, DstIP: dst}
+}
+
+func serialize(t *testing.T, ip gopacket.SerializableLayer, transport gopacket.SerializableLayer) []byte {
+	ethernetType := layers.EthernetTypeIPv4
+	if _, ok := ip.(*layers.IPv6); ok {
+		ethernetType = layers.EthernetTypeIPv6
+	}
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 1},
+		DstMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 2},
+		EthernetType: ethernetType,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, eth, ip, transport, gopacket.Payload([]byte("data"))))
+	return buf.Bytes()
+}
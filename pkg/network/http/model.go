@@ -4,6 +4,9 @@ package http
 
 import (
 	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
 )
 
 /*
@@ -32,6 +35,31 @@ func (k *httpBatchKey) Prepare(n httpNotification) {
 	k.page_num = C.uint(int(n.batch_idx) % HTTPBatchPages)
 }
 
+// newHTTPTX returns the httpTX of a transaction decoded from the TCP stream of a connection
+func newHTTPTX(c tcpstream.Connection, tx *transaction) httpTX {
+	var htx httpTX
+	saddrl, saddrh := util.ToLowHigh(c.Client)
+	daddrl, daddrh := util.ToLowHigh(c.Server)
+	htx.tup.saddr_h = C.__u64(saddrh)
+	htx.tup.saddr_l = C.__u64(saddrl)
+	htx.tup.sport = C.__u16(c.ClientPort)
+	htx.tup.daddr_h = C.__u64(daddrh)
+	htx.tup.daddr_l = C.__u64(daddrl)
+	htx.tup.dport = C.__u16(c.ServerPort)
+	htx.tup.metadata = C.CONN_TYPE_TCP
+	if len(c.Client.Bytes()) == 16 {
+		htx.tup.metadata |= C.CONN_V6
+	}
+	htx.request_method = C.__u8(tx.method)
+	htx.request_started = C.__u64(tx.requestStarted.UnixNano())
+	htx.response_status_code = C.__u16(tx.statusCode)
+	htx.response_last_seen = C.__u64(tx.responseLastSeen.UnixNano())
+	for i := 0; i < len(tx.requestFragment) && i < HTTPBufferSize; i++ {
+		htx.request_fragment[i] = C.char(tx.requestFragment[i])
+	}
+	return htx
+}
+
 // Path returns the URL from the request fragment captured in eBPF with
 // GET variables excluded.
 // Example:
@@ -5,8 +5,12 @@ package http
 import (
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPath(t *testing.T) {
@@ -29,6 +33,34 @@ func TestLatency(t *testing.T) {
 	assert.Equal(t, 999424.0, tx.RequestLatency())
 }
 
+func TestNewHTTPTX(t *testing.T) {
+	conn := tcpstream.Connection{
+		Client:     util.AddressFromString("1.1.1.1"),
+		Server:     util.AddressFromString("2.2.2.2"),
+		ClientPort: 1234,
+		ServerPort: 8080,
+	}
+	started := time.Unix(1600000000, 0)
+	tx := newHTTPTX(conn, &transaction{
+		method:           MethodGet,
+		requestFragment:  []byte("GET /foo/bar?var1=value HTTP/1.1\r\nHost: example.com"),
+		requestStarted:   started,
+		statusCode:       404,
+		responseLastSeen: started.Add(time.Millisecond),
+	})
+	assert.False(t, tx.Incomplete())
+	assert.Equal(t, 400, tx.StatusClass())
+
+	sk := newHTTPStatkeeper(1000, newTelemetry())
+	sk.Process([]httpTX{tx})
+	stats := sk.GetAndResetAllStats()
+	require.Len(t, stats, 1)
+	key := NewKey(conn.Client, conn.Server, conn.ClientPort, conn.ServerPort, "/foo/bar", MethodGet)
+	assert.Equal(t, 1, stats[key][3].Count)
+	// quantization brings it down
+	assert.Equal(t, 999424.0, stats[key][3].FirstLatencySample)
+}
+
 func BenchmarkPath(b *testing.B) {
 	tx := httpTX{
 		request_fragment: requestFragment(
@@ -0,0 +1,128 @@
+// +build linux_bpf
+
+package http
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/tcpstream"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// PacketMonitor is the alternative to Monitor for the kernels which can not load the HTTP eBPF programs.
+// It is responsible for:
+// * Capturing the TCP streams of the connections to the HTTP servers;
+// * Decoding the HTTP/1.x transactions of these streams;
+// * Aggregating the transactions with the same httpStatKeeper as Monitor;
+type PacketMonitor struct {
+	capture         *tcpstream.Capture
+	streamTelemetry *tcpstream.Telemetry
+	telemetry       *telemetry
+
+	// the statkeeper is shared by the packet processing goroutine and GetHTTPStats
+	statsMux   sync.Mutex
+	statkeeper *httpStatKeeper
+
+	// termination
+	mux     sync.Mutex
+	stopped bool
+}
+
+// NewPacketMonitor returns a new PacketMonitor instance
+func NewPacketMonitor(c *config.Config) (*PacketMonitor, error) {
+	if len(c.HTTPPorts) == 0 {
+		return nil, fmt.Errorf("no http server port to monitor")
+	}
+
+	ports := make(map[uint16]struct{}, len(c.HTTPPorts))
+	for _, port := range c.HTTPPorts {
+		ports[port] = struct{}{}
+	}
+
+	m := &PacketMonitor{
+		streamTelemetry: &tcpstream.Telemetry{},
+		telemetry:       newTelemetry(),
+	}
+	m.statkeeper = newHTTPStatkeeper(c.MaxHTTPStatsBuffered, m.telemetry)
+
+	isServerPort := func(port uint16) bool {
+		_, ok := ports[port]
+		return ok
+	}
+	newDecoder := func(conn tcpstream.Connection) tcpstream.Decoder {
+		return newStreamDecoder(HTTPBufferSize, func(tx *transaction) {
+			m.process(newHTTPTX(conn, tx))
+		})
+	}
+	tracker := tcpstream.NewTracker(isServerPort, newDecoder, m.streamTelemetry)
+
+	var err error
+	if m.capture, err = tcpstream.NewCapture(c.ProcRoot, c.HTTPPorts, tracker); err != nil {
+		return nil, fmt.Errorf("error enabling http traffic inspection: %s", err)
+	}
+	return m, nil
+}
+
+// Start consuming the HTTP traffic
+func (m *PacketMonitor) Start() {
+	if m == nil {
+		return
+	}
+
+	m.capture.Start()
+}
+
+// GetHTTPStats returns a map of HTTP stats stored in the following format:
+// [source, dest tuple, request path] -> RequestStats object
+func (m *PacketMonitor) GetHTTPStats() map[Key]RequestStats {
+	if m == nil {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stopped {
+		return nil
+	}
+
+	delta := m.telemetry.reset()
+	delta.report()
+	stream := m.streamTelemetry.Reset()
+	log.Debugf(
+		"http packet capture summary: decoding_errors=%d stream_gaps=%d flows_dropped=%d",
+		stream.DecodingErrors,
+		stream.StreamGaps,
+		stream.FlowsDropped,
+	)
+
+	m.statsMux.Lock()
+	defer m.statsMux.Unlock()
+	return m.statkeeper.GetAndResetAllStats()
+}
+
+// Stop HTTP monitoring
+func (m *PacketMonitor) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stopped {
+		return
+	}
+
+	m.capture.Stop()
+	m.stopped = true
+}
+
+func (m *PacketMonitor) process(tx httpTX) {
+	transactions := []httpTX{tx}
+	m.telemetry.aggregate(transactions, nil)
+
+	m.statsMux.Lock()
+	defer m.statsMux.Unlock()
+	m.statkeeper.Process(transactions)
+}
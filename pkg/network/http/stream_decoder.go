@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// statusLineSize is the size of the beginning of a status line holding the status code, as in "HTTP/1.1 200"
+const statusLineSize = 12
+
+var (
+	errNotHTTPResponse = errors.New("response is not an HTTP/1.x response")
+
+	// versionPrefix is the beginning of the status lines of the HTTP/1.x responses
+	versionPrefix = []byte("HTTP/1.")
+)
+
+// methodPrefixes are the beginnings of the requests of the supported methods
+var methodPrefixes = []struct {
+	prefix []byte
+	method Method
+}{
+	{[]byte("GET "), MethodGet},
+	{[]byte("POST "), MethodPost},
+	{[]byte("PUT "), MethodPut},
+	{[]byte("DELETE "), MethodDelete},
+	{[]byte("HEAD "), MethodHead},
+	{[]byte("OPTIONS "), MethodOptions},
+	{[]byte("PATCH "), MethodPatch},
+}
+
+// transaction is an HTTP transaction decoded from a TCP stream
+type transaction struct {
+	method           Method
+	requestFragment  []byte
+	requestStarted   time.Time
+	statusCode       uint16
+	responseLastSeen time.Time
+}
+
+// streamDecoder decodes the HTTP/1.x transactions of a TCP stream. It follows the eBPF program: a transaction
+// starts with a request, its response is the data sent by the server until the client sends the next request,
+// and the transaction is complete when the next request starts or when the stream ends.
+// Like with the eBPF program, the pipelined requests are counted as a single transaction.
+type streamDecoder struct {
+	fragmentSize int
+	handler      func(tx *transaction)
+
+	tx         *transaction
+	statusLine []byte
+}
+
+// newStreamDecoder returns a streamDecoder keeping the first fragmentSize bytes of the requests,
+// and calling handler with each completed transaction
+func newStreamDecoder(fragmentSize int, handler func(tx *transaction)) *streamDecoder {
+	return &streamDecoder{
+		fragmentSize: fragmentSize,
+		handler:      handler,
+	}
+}
+
+// Write decodes the data sent by the client or the server
+func (d *streamDecoder) Write(fromClient bool, data []byte, ts time.Time) error {
+	if fromClient {
+		d.writeRequest(data, ts)
+		return nil
+	}
+	return d.writeResponse(data, ts)
+}
+
+// Flush completes the pending transaction
+func (d *streamDecoder) Flush() {
+	if d.tx != nil && d.tx.statusCode != 0 {
+		d.handler(d.tx)
+	}
+	d.tx = nil
+	d.statusLine = d.statusLine[:0]
+}
+
+func (d *streamDecoder) writeRequest(data []byte, ts time.Time) {
+	if d.tx == nil || d.tx.statusCode != 0 {
+		method := requestMethod(data)
+		if method == MethodUnknown {
+			// the rest of a request body, or a request which is not supported
+			return
+		}
+
+		d.Flush()
+		d.tx = &transaction{
+			method:          method,
+			requestFragment: make([]byte, 0, d.fragmentSize),
+			requestStarted:  ts,
+		}
+	}
+
+	fragment := d.tx.requestFragment
+	if n := d.fragmentSize - len(fragment); n > 0 {
+		if n > len(data) {
+			n = len(data)
+		}
+		d.tx.requestFragment = append(fragment, data[:n]...)
+	}
+}
+
+func (d *streamDecoder) writeResponse(data []byte, ts time.Time) error {
+	if d.tx == nil {
+		// a response without request, such as the end of a response captured mid-stream
+		return nil
+	}
+	if d.tx.statusCode != 0 {
+		d.tx.responseLastSeen = ts
+		return nil
+	}
+
+	n := statusLineSize - len(d.statusLine)
+	if n > len(data) {
+		n = len(data)
+	}
+	d.statusLine = append(d.statusLine, data[:n]...)
+	prefix := versionPrefix
+	if len(d.statusLine) < len(prefix) {
+		prefix = prefix[:len(d.statusLine)]
+	}
+	if !bytes.HasPrefix(d.statusLine, prefix) {
+		return errNotHTTPResponse
+	}
+	if len(d.statusLine) < statusLineSize {
+		return nil
+	}
+
+	code, ok := statusCode(d.statusLine)
+	if !ok {
+		return errNotHTTPResponse
+	}
+	d.tx.statusCode = code
+	d.tx.responseLastSeen = ts
+	d.statusLine = d.statusLine[:0]
+	return nil
+}
+
+// requestMethod returns the method of the request starting with data
+func requestMethod(data []byte) Method {
+	for _, m := range methodPrefixes {
+		if bytes.HasPrefix(data, m.prefix) {
+			return m.method
+		}
+	}
+	return MethodUnknown
+}
+
+// statusCode returns the status code of a status line starting with "HTTP/1.x NNN"
+func statusCode(line []byte) (uint16, bool) {
+	if line[8] != ' ' {
+		return 0, false
+	}
+	var code uint16
+	for _, c := range line[9:statusLineSize] {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		code = code*10 + uint16(c-'0')
+	}
+	if code < 100 {
+		return 0, false
+	}
+	return code, true
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Unix(1600000000, 0)
+
+func newTestStreamDecoder() (*streamDecoder, *[]transaction) {
+	var txs []transaction
+	d := newStreamDecoder(25, func(tx *transaction) {
+		txs = append(txs, *tx)
+	})
+	return d, &txs
+}
+
+func TestStreamDecoderTransactions(t *testing.T) {
+	d, txs := newTestStreamDecoder()
+
+	require.NoError(t, d.Write(true, []byte("GET /foo?var=bar HTTP/1.1\r\nHost: example.com\r\n\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n"), t0.Add(time.Millisecond)))
+	require.NoError(t, d.Write(false, []byte("0123456789"), t0.Add(2*time.Millisecond)))
+	// the transaction is complete when the next request starts
+	assert.Empty(t, *txs)
+
+	require.NoError(t, d.Write(true, []byte("POST /bar HTTP/1.1\r\n"), t0.Add(5*time.Millisecond)))
+	require.NoError(t, d.Write(true, []byte("Content-Length: 4\r\n\r\nbody"), t0.Add(5*time.Millisecond)))
+	require.NoError(t, d.Write(false, []byte("HTTP/1.1 404 Not Found\r\n\r\n"), t0.Add(8*time.Millisecond)))
+	require.Len(t, *txs, 1)
+
+	// or when the stream ends
+	d.Flush()
+	assert.Equal(t, []transaction{
+		{
+			method:           MethodGet,
+			requestFragment:  []byte("GET /foo?var=bar HTTP/1.1"),
+			requestStarted:   t0,
+			statusCode:       200,
+			responseLastSeen: t0.Add(2 * time.Millisecond),
+		},
+		{
+			method:           MethodPost,
+			requestFragment:  []byte("POST /bar HTTP/1.1\r\nConte"),
+			requestStarted:   t0.Add(5 * time.Millisecond),
+			statusCode:       404,
+			responseLastSeen: t0.Add(8 * time.Millisecond),
+		},
+	}, *txs)
+}
+
+func TestStreamDecoderSplitStatusLine(t *testing.T) {
+	d, txs := newTestStreamDecoder()
+
+	require.NoError(t, d.Write(true, []byte("DELETE /item/1 HTTP/1.0\r\n\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("HTTP/1"), t0))
+	require.NoError(t, d.Write(false, []byte(".0 5"), t0))
+	require.NoError(t, d.Write(false, []byte("03 Service Unavailable\r\n\r\n"), t0.Add(time.Millisecond)))
+	d.Flush()
+
+	require.Len(t, *txs, 1)
+	assert.Equal(t, MethodDelete, (*txs)[0].method)
+	assert.Equal(t, uint16(503), (*txs)[0].statusCode)
+	assert.Equal(t, t0.Add(time.Millisecond), (*txs)[0].responseLastSeen)
+}
+
+func TestStreamDecoderIncompleteTransactions(t *testing.T) {
+	d, txs := newTestStreamDecoder()
+
+	// a response without request
+	require.NoError(t, d.Write(false, []byte("HTTP/1.1 200 OK\r\n\r\n"), t0))
+	// data which is not a request
+	require.NoError(t, d.Write(true, []byte("CONNECT example.com:443 HTTP/1.1\r\n\r\n"), t0))
+	require.NoError(t, d.Write(false, []byte("HTTP/1.1 200 OK\r\n\r\n"), t0))
+	// a request without response
+	require.NoError(t, d.Write(true, []byte("GET / HTTP/1.1\r\n\r\n"), t0))
+	d.Flush()
+
+	assert.Empty(t, *txs)
+}
+
+func TestStreamDecoderInvalidResponse(t *testing.T) {
+	d, _ := newTestStreamDecoder()
+	require.NoError(t, d.Write(true, []byte("GET / HTTP/1.1\r\n\r\n"), t0))
+	assert.Equal(t, errNotHTTPResponse, d.Write(false, []byte("HTTP/2"), t0))
+
+	d, _ = newTestStreamDecoder()
+	require.NoError(t, d.Write(true, []byte("GET / HTTP/1.1\r\n\r\n"), t0))
+	assert.Equal(t, errNotHTTPResponse, d.Write(false, []byte("HTTP/1.1 2xx OK\r\n"), t0))
+}
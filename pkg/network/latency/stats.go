@@ -0,0 +1,94 @@
+package latency
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/sketches-go/ddsketch"
+)
+
+// RelativeAccuracy defines the acceptable error in quantile values calculated by DDSketch.
+const RelativeAccuracy = 0.01
+
+// Stats stores the count and the latencies of a group of operations, such as the database
+// operations of a command or the DNS responses having the same response code
+type Stats struct {
+	// Like for HTTP, we keep our own count since DDSketch can discard values
+	Count     int
+	Latencies *ddsketch.DDSketch
+
+	// This field holds the value (in nanoseconds) of the first operation, to avoid
+	// creating sketches holding a single value
+	FirstLatencySample float64
+}
+
+// Add adds an operation having the given latency in nanoseconds to the stats
+func (s *Stats) Add(latency float64) {
+	s.Count++
+	if s.Count == 1 {
+		s.FirstLatencySample = latency
+		return
+	}
+
+	if s.Latencies == nil {
+		if err := s.initSketch(); err != nil {
+			return
+		}
+		// Add the deferred latency sample
+		if err := s.Latencies.Add(s.FirstLatencySample); err != nil {
+			log.Debugf("could not add latency to ddsketch: %v", err)
+		}
+	}
+
+	if err := s.Latencies.Add(latency); err != nil {
+		log.Debugf("could not add latency to ddsketch: %v", err)
+	}
+}
+
+// CombineWith merges the data in 2 Stats objects
+// other is kept as it is, while the method receiver gets mutated
+func (s *Stats) CombineWith(other *Stats) {
+	switch {
+	case other == nil || other.Count == 0:
+		return
+	case other.Count == 1:
+		s.Add(other.FirstLatencySample)
+		return
+	}
+
+	if s.Latencies == nil {
+		if err := s.initSketch(); err != nil {
+			return
+		}
+		if s.Count == 1 {
+			if err := s.Latencies.Add(s.FirstLatencySample); err != nil {
+				log.Debugf("could not add latency to ddsketch: %v", err)
+			}
+		}
+	}
+
+	s.Count += other.Count
+	if err := s.Latencies.MergeWith(other.Latencies); err != nil {
+		log.Debugf("error merging latencies: %v", err)
+	}
+}
+
+// ValueAtQuantile returns the latency (in nanoseconds) at the given quantile, or 0 if there is no latency
+func (s *Stats) ValueAtQuantile(quantile float64) float64 {
+	if s.Latencies == nil {
+		return s.FirstLatencySample
+	}
+
+	value, err := s.Latencies.GetValueAtQuantile(quantile)
+	if err != nil {
+		log.Debugf("could not compute latency quantile: %v", err)
+		return 0
+	}
+	return value
+}
+
+func (s *Stats) initSketch() (err error) {
+	s.Latencies, err = ddsketch.NewDefaultDDSketch(RelativeAccuracy)
+	if err != nil {
+		log.Debugf("error recording latency: could not create new ddsketch: %v", err)
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package latency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsAdd(t *testing.T) {
+	var stats Stats
+	stats.Add(10)
+	assert.Equal(t, 1, stats.Count)
+	assert.Nil(t, stats.Latencies)
+	assert.Equal(t, float64(10), stats.FirstLatencySample)
+
+	stats.Add(15)
+	stats.Add(20)
+	assert.Equal(t, 3, stats.Count)
+	require.NotNil(t, stats.Latencies)
+	assert.Equal(t, float64(3), stats.Latencies.GetCount())
+	assert.InEpsilon(t, float64(10), stats.ValueAtQuantile(0), RelativeAccuracy)
+	assert.InEpsilon(t, float64(20), stats.ValueAtQuantile(1), RelativeAccuracy)
+}
+
+func TestStatsCombineWith(t *testing.T) {
+	var single Stats
+	single.Add(10)
+
+	var multiple Stats
+	multiple.Add(20)
+	multiple.Add(30)
+
+	var combined Stats
+	combined.CombineWith(&single)
+	assert.Equal(t, 1, combined.Count)
+	assert.Nil(t, combined.Latencies)
+	assert.Equal(t, float64(10), combined.ValueAtQuantile(0.5))
+
+	combined.CombineWith(&multiple)
+	assert.Equal(t, 3, combined.Count)
+	require.NotNil(t, combined.Latencies)
+	assert.InEpsilon(t, float64(10), combined.ValueAtQuantile(0), RelativeAccuracy)
+	assert.InEpsilon(t, float64(30), combined.ValueAtQuantile(1), RelativeAccuracy)
+
+	// the combined stats are left untouched
+	assert.Equal(t, 2, multiple.Count)
+	assert.Equal(t, float64(2), multiple.Latencies.GetCount())
+
+	combined.CombineWith(nil)
+	combined.CombineWith(&Stats{})
+	assert.Equal(t, 3, combined.Count)
+}
+
+func TestStatsValueAtQuantileWithoutLatency(t *testing.T) {
+	var empty Stats
+	assert.Equal(t, float64(0), empty.ValueAtQuantile(0.5))
+}
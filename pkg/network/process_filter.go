@@ -0,0 +1,133 @@
+package network
+
+import (
+	"regexp"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ProcessFilter holds a user-defined rule excluding the connections of the processes matching all its criteria.
+// The criteria left unset match any process.
+type ProcessFilter struct {
+	Name          *regexp.Regexp
+	Cmdline       *regexp.Regexp
+	PIDNamespace  uint32
+	ContainerID   string
+	ContainerTags []string
+	Cgroup        *regexp.Regexp
+}
+
+// ProcessInfo holds the attributes of a process which the process filters match on
+type ProcessInfo struct {
+	Name          string
+	Cmdline       string
+	PIDNamespace  uint32
+	ContainerID   string
+	ContainerTags []string
+	Cgroups       []string // paths of the cgroups of the process
+}
+
+// ParseProcessFilters takes the user defined exclusion rules and returns a slice of ProcessFilters
+func ParseProcessFilters(rules []config.ProcessExcludeRule) (excludelist []*ProcessFilter) {
+	for i, rule := range rules {
+		filter := &ProcessFilter{
+			PIDNamespace:  rule.PIDNamespace,
+			ContainerID:   rule.ContainerID,
+			ContainerTags: rule.ContainerTags,
+		}
+		var err error
+		if filter.Name, err = compileProcessPattern(rule.ProcessName); err != nil {
+			log.Errorf("Given process filter %d will not be respected. Could not parse process_name: %s", i, err)
+			continue
+		}
+		if filter.Cmdline, err = compileProcessPattern(rule.Cmdline); err != nil {
+			log.Errorf("Given process filter %d will not be respected. Could not parse cmdline: %s", i, err)
+			continue
+		}
+		if filter.Cgroup, err = compileProcessPattern(rule.Cgroup); err != nil {
+			log.Errorf("Given process filter %d will not be respected. Could not parse cgroup: %s", i, err)
+			continue
+		}
+		if filter.Name == nil && filter.Cmdline == nil && filter.PIDNamespace == 0 && filter.ContainerID == "" &&
+			len(filter.ContainerTags) == 0 && filter.Cgroup == nil {
+			log.Errorf("Given process filter %d will not be respected. It would exclude the connections of all the processes", i)
+			continue
+		}
+		excludelist = append(excludelist, filter)
+	}
+	return excludelist
+}
+
+func compileProcessPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// ProcessFiltersNeedContainerTags returns whether any of the filters matches on the container tags
+func ProcessFiltersNeedContainerTags(filters []*ProcessFilter) bool {
+	for _, filter := range filters {
+		if len(filter.ContainerTags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExcludedProcess returns true if the connections of the given process should be excluded
+// by the tracer based on user defined filters
+func IsExcludedProcess(filters []*ProcessFilter, p *ProcessInfo) bool {
+	if p == nil {
+		return false
+	}
+	for _, filter := range filters {
+		if filter.matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ProcessFilter) matches(p *ProcessInfo) bool {
+	if f.Name != nil && !f.Name.MatchString(p.Name) {
+		return false
+	}
+	if f.Cmdline != nil && !f.Cmdline.MatchString(p.Cmdline) {
+		return false
+	}
+	if f.PIDNamespace != 0 && f.PIDNamespace != p.PIDNamespace {
+		return false
+	}
+	if f.ContainerID != "" && f.ContainerID != p.ContainerID {
+		return false
+	}
+	for _, tag := range f.ContainerTags {
+		if !containsString(p.ContainerTags, tag) {
+			return false
+		}
+	}
+	if f.Cgroup != nil {
+		matched := false
+		for _, cgroup := range p.Cgroups {
+			if f.Cgroup.MatchString(cgroup) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
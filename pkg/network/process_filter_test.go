@@ -0,0 +1,45 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var testProcessFilters = []config.ProcessExcludeRule{
+	{ProcessName: "^envoy$"},
+	{Cmdline: "--health-check", PIDNamespace: 4026532198},
+	{ContainerID: "3f2c1e4d5b6a"},
+	{ContainerTags: []string{"kube_namespace:linkerd", "kube_container_name:proxy"}},
+	{Cgroup: "^/system.slice/consul.service$"},
+	{ProcessName: "(invalid"}, // invalid config
+	{},                        // invalid config: would match every process
+}
+
+func TestParseProcessFilters(t *testing.T) {
+	filters := ParseProcessFilters(testProcessFilters)
+	assert.Len(t, filters, 5)
+	assert.True(t, ProcessFiltersNeedContainerTags(filters))
+	assert.False(t, ProcessFiltersNeedContainerTags(filters[:3]))
+
+	assert.True(t, IsExcludedProcess(filters, &ProcessInfo{Name: "envoy"}))
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{Name: "envoy-wrapper"}))
+	assert.False(t, IsExcludedProcess(filters, nil)) // process not found
+
+	// all the criteria of a rule must match
+	assert.True(t, IsExcludedProcess(filters, &ProcessInfo{Cmdline: "agent --health-check --port 8080", PIDNamespace: 4026532198}))
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{Cmdline: "agent --health-check --port 8080", PIDNamespace: 4026531836}))
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{Cmdline: "agent --port 8080", PIDNamespace: 4026532198}))
+
+	assert.True(t, IsExcludedProcess(filters, &ProcessInfo{ContainerID: "3f2c1e4d5b6a"}))
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{ContainerID: "3f2c1e4d5b6b"}))
+
+	assert.True(t, IsExcludedProcess(filters, &ProcessInfo{ContainerTags: []string{"kube_container_name:proxy", "image_name:linkerd-proxy", "kube_namespace:linkerd"}}))
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{ContainerTags: []string{"kube_container_name:proxy", "kube_namespace:default"}}))
+
+	assert.True(t, IsExcludedProcess(filters, &ProcessInfo{Cgroups: []string{"/", "/system.slice/consul.service"}}))
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{Cgroups: []string{"/system.slice/consul.service/child"}}))
+
+	assert.False(t, IsExcludedProcess(filters, &ProcessInfo{Name: "nginx", Cmdline: "nginx -g daemon off;"}))
+}
@@ -0,0 +1,124 @@
+// +build linux
+
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cgroups"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	maxProcessInfoCacheSize = 4096
+	processInfoTTL          = time.Minute
+)
+
+// ContainerTagsFunc returns the tags of the container having the given ID
+type ContainerTagsFunc func(containerID string) []string
+
+// ProcessInfoResolver reads the attributes of the processes owning the connections from procfs.
+// They are cached for a minute, so that the filters do not read procfs for every connection.
+type ProcessInfoResolver struct {
+	procRoot      string
+	containerTags ContainerTagsFunc
+
+	mux   sync.Mutex
+	cache *simplelru.LRU // pid => *processInfoEntry
+}
+
+type processInfoEntry struct {
+	info    *ProcessInfo // nil if the process could not be read
+	expires time.Time
+}
+
+// NewProcessInfoResolver returns a ProcessInfoResolver reading the processes from procRoot.
+// containerTags may be nil if the container tags are not needed.
+func NewProcessInfoResolver(procRoot string, containerTags ContainerTagsFunc) *ProcessInfoResolver {
+	cache, _ := simplelru.NewLRU(maxProcessInfoCacheSize, nil)
+	return &ProcessInfoResolver{
+		procRoot:      procRoot,
+		containerTags: containerTags,
+		cache:         cache,
+	}
+}
+
+// Resolve returns the attributes of the process having the given pid, or nil if they can not be read
+func (r *ProcessInfoResolver) Resolve(pid uint32, now time.Time) *ProcessInfo {
+	if pid == 0 {
+		return nil
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if v, ok := r.cache.Get(pid); ok {
+		if entry := v.(*processInfoEntry); now.Before(entry.expires) {
+			return entry.info
+		}
+	}
+	info := r.read(pid)
+	r.cache.Add(pid, &processInfoEntry{info: info, expires: now.Add(processInfoTTL)})
+	return info
+}
+
+func (r *ProcessInfoResolver) read(pid uint32) *ProcessInfo {
+	procDir := filepath.Join(r.procRoot, strconv.FormatUint(uint64(pid), 10))
+	comm, err := ioutil.ReadFile(filepath.Join(procDir, "comm"))
+	if err != nil {
+		// the process exited
+		return nil
+	}
+	info := &ProcessInfo{Name: strings.TrimSuffix(string(comm), "\n")}
+	if cmdline, err := ioutil.ReadFile(filepath.Join(procDir, "cmdline")); err == nil {
+		info.Cmdline = string(bytes.TrimSpace(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '})))
+	}
+	if ns, err := os.Readlink(filepath.Join(procDir, "ns", "pid")); err == nil {
+		info.PIDNamespace = parseNamespaceInode(ns)
+	}
+	if f, err := os.Open(filepath.Join(procDir, "cgroup")); err == nil {
+		info.Cgroups, info.ContainerID = parseProcCgroup(f)
+		f.Close()
+	}
+	if info.ContainerID != "" && r.containerTags != nil {
+		info.ContainerTags = r.containerTags(info.ContainerID)
+	}
+	return info
+}
+
+// parseNamespaceInode parses the inode number of a namespace link such as "pid:[4026531836]"
+func parseNamespaceInode(link string) uint32 {
+	start, end := strings.IndexByte(link, '['), strings.IndexByte(link, ']')
+	if start < 0 || end < start {
+		return 0
+	}
+	ino, err := strconv.ParseUint(link[start+1:end], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(ino)
+}
+
+// parseProcCgroup parses the cgroup paths of a /proc/$pid/cgroup file, of which the lines are
+// "hierarchy-ID:controller-list:cgroup-path", and the first container ID found in them
+func parseProcCgroup(r io.Reader) (paths []string, containerID string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		sp := strings.SplitN(scanner.Text(), ":", 3)
+		if len(sp) < 3 {
+			continue
+		}
+		paths = append(paths, sp[2])
+		if containerID == "" {
+			containerID, _ = cgroups.ContainerFilter(sp[2], filepath.Base(sp[2]))
+		}
+	}
+	return paths, containerID
+}
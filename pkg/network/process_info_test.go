@@ -0,0 +1,81 @@
+// +build linux
+
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcCgroup(t *testing.T) {
+	paths, containerID := parseProcCgroup(strings.NewReader(`12:memory:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
+11:cpu,cpuacct:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
+invalid line
+0::/
+`))
+	assert.Equal(t, []string{
+		"/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+		"/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+		"/",
+	}, paths)
+	assert.Equal(t, "47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e", containerID)
+
+	paths, containerID = parseProcCgroup(strings.NewReader("0::/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope\n"))
+	assert.Equal(t, []string{"/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope"}, paths)
+	assert.Equal(t, "47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e", containerID)
+
+	_, containerID = parseProcCgroup(strings.NewReader("0::/user.slice/user-1000.slice/session-2.scope\n"))
+	assert.Empty(t, containerID)
+}
+
+func TestParseNamespaceInode(t *testing.T) {
+	assert.Equal(t, uint32(4026531836), parseNamespaceInode("pid:[4026531836]"))
+	assert.Equal(t, uint32(0), parseNamespaceInode("pid:4026531836"))
+	assert.Equal(t, uint32(0), parseNamespaceInode("pid:[abc]"))
+}
+
+func TestProcessInfoResolver(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+
+	procDir := filepath.Join(procRoot, "42")
+	require.NoError(t, os.MkdirAll(filepath.Join(procDir, "ns"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procDir, "comm"), []byte("envoy\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procDir, "cmdline"), []byte("envoy\x00-c\x00/etc/envoy.yaml\x00"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procDir, "cgroup"), []byte("0::/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope\n"), 0644))
+	require.NoError(t, os.Symlink("pid:[4026532198]", filepath.Join(procDir, "ns", "pid")))
+
+	var tagLookups int
+	r := NewProcessInfoResolver(procRoot, func(containerID string) []string {
+		tagLookups++
+		return []string{"container_id:" + containerID}
+	})
+
+	now := time.Now()
+	assert.Nil(t, r.Resolve(0, now))
+	assert.Nil(t, r.Resolve(43, now))
+	expected := &ProcessInfo{
+		Name:          "envoy",
+		Cmdline:       "envoy -c /etc/envoy.yaml",
+		PIDNamespace:  4026532198,
+		ContainerID:   "47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e",
+		ContainerTags: []string{"container_id:47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e"},
+		Cgroups:       []string{"/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope"},
+	}
+	assert.Equal(t, expected, r.Resolve(42, now))
+
+	// the attributes are cached until they expire
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procDir, "comm"), []byte("nginx\n"), 0644))
+	assert.Equal(t, expected, r.Resolve(42, now.Add(processInfoTTL-time.Second)))
+	assert.Equal(t, 1, tagLookups)
+	assert.Equal(t, "nginx", r.Resolve(42, now.Add(processInfoTTL)).Name)
+	assert.Equal(t, 2, tagLookups)
+}
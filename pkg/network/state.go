@@ -4,8 +4,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	"github.com/DataDog/datadog-agent/pkg/network/http"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"go4.org/intern"
@@ -39,8 +41,13 @@ type State interface {
 		active, closed []ConnectionStats,
 		dns dns.StatsByKeyByNameByType,
 		http map[http.Key]http.RequestStats,
+		database map[database.Key]database.RequestStats,
 	) Delta
 
+	// GetDNSSnapshot returns the DNS stats stored since its previous call, without registering a client.
+	// The given closed connections and DNS stats are also stored for the clients, as the callers consume them.
+	GetDNSSnapshot(active, closed []ConnectionStats, dns dns.StatsByKeyByNameByType) DNSSnapshot
+
 	// RemoveClient stops tracking stateful data for a given client
 	RemoveClient(clientID string)
 
@@ -61,7 +68,15 @@ type State interface {
 type Delta struct {
 	BufferedData
 	HTTP     map[http.Key]http.RequestStats
+	Database map[database.Key]database.RequestStats
+	DNSStats dns.StatsByKeyByNameByType
+}
+
+// DNSSnapshot represents the DNS stats stored since the last call to State.GetDNSSnapshot
+type DNSSnapshot struct {
 	DNSStats dns.StatsByKeyByNameByType
+	// Pids maps the keys of the DNS stats to the pid of the process sending the DNS queries
+	Pids map[dns.Key]uint32
 }
 
 type telemetry struct {
@@ -71,6 +86,7 @@ type telemetry struct {
 	timeSyncCollisions int64
 	dnsStatsDropped    int64
 	httpStatsDropped   int64
+	dbStatsDropped     int64
 	dnsPidCollisions   int64
 }
 
@@ -92,6 +108,7 @@ type client struct {
 	// maps by dns key the domain (string) to stats structure
 	dnsStats       dns.StatsByKeyByNameByType
 	httpStatsDelta map[http.Key]http.RequestStats
+	dbStatsDelta   map[database.Key]database.RequestStats
 }
 
 type networkState struct {
@@ -101,6 +118,10 @@ type networkState struct {
 	clients   map[string]*client
 	telemetry telemetry
 
+	// dnsSnapshot holds the DNS stats returned by GetDNSSnapshot.
+	// It is nil until the first call, so that the DNS stats aren't kept when nothing requests them.
+	dnsSnapshot *DNSSnapshot
+
 	buf             []byte // Shared buffer
 	latestTimeEpoch uint64
 
@@ -110,10 +131,11 @@ type networkState struct {
 	maxClientStats int
 	maxDNSStats    int
 	maxHTTPStats   int
+	maxDBStats     int
 }
 
 // NewState creates a new network state
-func NewState(clientExpiry time.Duration, maxClosedConns, maxClientStats int, maxDNSStats int, maxHTTPStats int) State {
+func NewState(clientExpiry time.Duration, maxClosedConns, maxClientStats int, maxDNSStats int, maxHTTPStats int, maxDBStats int) State {
 	return &networkState{
 		clients:        map[string]*client{},
 		telemetry:      telemetry{},
@@ -122,6 +144,7 @@ func NewState(clientExpiry time.Duration, maxClosedConns, maxClientStats int, ma
 		maxClientStats: maxClientStats,
 		maxDNSStats:    maxDNSStats,
 		maxHTTPStats:   maxHTTPStats,
+		maxDBStats:     maxDBStats,
 		buf:            make([]byte, ConnectionByteKeyMaxLen),
 	}
 }
@@ -148,11 +171,13 @@ func (ns *networkState) GetDelta(
 	closed []ConnectionStats,
 	dnsStats dns.StatsByKeyByNameByType,
 	httpStats map[http.Key]http.RequestStats,
+	dbStats map[database.Key]database.RequestStats,
 ) Delta {
 	ns.Lock()
 	defer ns.Unlock()
 
 	ns.storeClosedConnections(closed)
+	ns.storeDNSSnapshot(dnsStats, active, closed)
 
 	// Update the latest known time
 	ns.latestTimeEpoch = latestTime
@@ -181,6 +206,9 @@ func (ns *networkState) GetDelta(
 		if len(httpStats) > 0 {
 			ns.storeHTTPStats(httpStats)
 		}
+		if len(dbStats) > 0 {
+			ns.storeDatabaseStats(dbStats)
+		}
 
 		dnsDelta := ns.clients[id].dnsStats
 		ns.clients[id].dnsStats = make(dns.StatsByKeyByNameByType)
@@ -194,6 +222,7 @@ func (ns *networkState) GetDelta(
 				buffer: clientBuffer,
 			},
 			HTTP:     ns.getHTTPDelta(id),
+			Database: ns.getDatabaseDelta(id),
 			DNSStats: dnsDelta,
 		}
 	}
@@ -222,6 +251,9 @@ func (ns *networkState) GetDelta(
 	if len(httpStats) > 0 {
 		ns.storeHTTPStats(httpStats)
 	}
+	if len(dbStats) > 0 {
+		ns.storeDatabaseStats(dbStats)
+	}
 
 	dnsDelta := ns.clients[id].dnsStats
 	ns.clients[id].dnsStats = make(dns.StatsByKeyByNameByType)
@@ -232,10 +264,62 @@ func (ns *networkState) GetDelta(
 			buffer: clientBuffer,
 		},
 		HTTP:     ns.getHTTPDelta(id),
+		Database: ns.getDatabaseDelta(id),
 		DNSStats: dnsDelta,
 	}
 }
 
+func (ns *networkState) GetDNSSnapshot(active, closed []ConnectionStats, dnsStats dns.StatsByKeyByNameByType) DNSSnapshot {
+	ns.Lock()
+	defer ns.Unlock()
+
+	// The closed connections are copied, since the caller reuses their buffer
+	ns.appendClosedConnections(closed)
+	if len(dnsStats) > 0 {
+		ns.storeDNSStats(dnsStats)
+	}
+
+	if ns.dnsSnapshot == nil {
+		ns.dnsSnapshot = newDNSSnapshot()
+	}
+	ns.storeDNSSnapshot(dnsStats, active, closed)
+
+	snapshot := *ns.dnsSnapshot
+	ns.dnsSnapshot = newDNSSnapshot()
+	return snapshot
+}
+
+func newDNSSnapshot() *DNSSnapshot {
+	return &DNSSnapshot{
+		DNSStats: make(dns.StatsByKeyByNameByType),
+		Pids:     make(map[dns.Key]uint32),
+	}
+}
+
+// storeDNSSnapshot stores the DNS stats in the DNS snapshot, along with the pids of the given connections sending the DNS queries
+func (ns *networkState) storeDNSSnapshot(stats dns.StatsByKeyByNameByType, conns ...[]ConnectionStats) {
+	if ns.dnsSnapshot == nil {
+		return
+	}
+
+	ns.mergeDNSStats(ns.dnsSnapshot.DNSStats, stats)
+	for _, cs := range conns {
+		for i := range cs {
+			key, ok := DNSKey(&cs[i])
+			if !ok {
+				continue
+			}
+			if _, ok := ns.dnsSnapshot.DNSStats[key]; !ok {
+				continue
+			}
+			// Like when the connections are encoded, the stats of a key are attributed to its first connection
+			if _, ok := ns.dnsSnapshot.Pids[key]; !ok {
+				ns.dnsSnapshot.Pids[key] = cs[i].Pid
+			}
+		}
+	}
+}
+
 // getConnsByKey returns a mapping of byte-key -> connection for easier access + manipulation
 func getConnsByKey(conns []ConnectionStats, buf []byte) map[string]*ConnectionStats {
 	connsByKey := make(map[string]*ConnectionStats, len(conns))
@@ -256,11 +340,18 @@ func (ns *networkState) storeClosedConnections(conns []ConnectionStats) {
 	// if there is only one client registered we don't bother to copy the data
 	if len(ns.clients) == 1 {
 		for _, client := range ns.clients {
-			client.closedConnections = conns
-			return
+			if len(client.closedConnections) == 0 {
+				client.closedConnections = conns
+				return
+			}
 		}
 	}
 
+	ns.appendClosedConnections(conns)
+}
+
+// appendClosedConnections copies the given connections to the closed connections of every client
+func (ns *networkState) appendClosedConnections(conns []ConnectionStats) {
 	for _, client := range ns.clients {
 		var (
 			allow    = ns.maxClosedConns - len(client.closedConnections)
@@ -290,50 +381,66 @@ func (ns *networkState) storeDNSStats(stats dns.StatsByKeyByNameByType) {
 		for _, c := range ns.clients {
 			if len(c.dnsStats) == 0 {
 				c.dnsStats = stats
+				return
 			}
-			return
 		}
 	}
 
 	for _, client := range ns.clients {
-		dnsStatsThisClient := getDeepDNSStatsCount(client.dnsStats)
-		for key, statsByDomain := range stats {
-			for domain, statsByQtype := range statsByDomain {
-				for qtype, dnsStats := range statsByQtype {
-
-					if _, ok := client.dnsStats[key]; !ok {
-						if dnsStatsThisClient >= ns.maxDNSStats {
-							ns.telemetry.dnsStatsDropped++
-							continue
-						}
-						client.dnsStats[key] = make(map[*intern.Value]map[dns.QueryType]dns.Stats)
+		ns.mergeDNSStats(client.dnsStats, stats)
+	}
+}
+
+// mergeDNSStats adds the DNS stats of src to dst, without sharing any map between them
+func (ns *networkState) mergeDNSStats(dst, src dns.StatsByKeyByNameByType) {
+	dnsStatsCount := getDeepDNSStatsCount(dst)
+	for key, statsByDomain := range src {
+		for domain, statsByQtype := range statsByDomain {
+			for qtype, dnsStats := range statsByQtype {
+
+				if _, ok := dst[key]; !ok {
+					if dnsStatsCount >= ns.maxDNSStats {
+						ns.telemetry.dnsStatsDropped++
+						continue
 					}
-					if _, ok := client.dnsStats[key][domain]; !ok {
-						if dnsStatsThisClient >= ns.maxDNSStats {
-							ns.telemetry.dnsStatsDropped++
-							continue
-						}
-						client.dnsStats[key][domain] = make(map[dns.QueryType]dns.Stats)
+					dst[key] = make(map[*intern.Value]map[dns.QueryType]dns.Stats)
+				}
+				if _, ok := dst[key][domain]; !ok {
+					if dnsStatsCount >= ns.maxDNSStats {
+						ns.telemetry.dnsStatsDropped++
+						continue
 					}
+					dst[key][domain] = make(map[dns.QueryType]dns.Stats)
+				}
 
-					// If we've seen DNS stats for this key already, let's combine the two
-					if prev, ok := client.dnsStats[key][domain][qtype]; ok {
-						prev.Timeouts += dnsStats.Timeouts
-						prev.SuccessLatencySum += dnsStats.SuccessLatencySum
-						prev.FailureLatencySum += dnsStats.FailureLatencySum
-						for rcode, count := range dnsStats.CountByRcode {
-							prev.CountByRcode[rcode] += count
-						}
-						client.dnsStats[key][domain][qtype] = prev
-					} else {
-						if dnsStatsThisClient >= ns.maxDNSStats {
-							ns.telemetry.dnsStatsDropped++
-							continue
-						}
-						client.dnsStats[key][domain][qtype] = dnsStats
-						dnsStatsThisClient++
+				// If we've seen DNS stats for this key already, let's combine the two
+				prev, ok := dst[key][domain][qtype]
+				if !ok {
+					if dnsStatsCount >= ns.maxDNSStats {
+						ns.telemetry.dnsStatsDropped++
+						continue
+					}
+					dnsStatsCount++
+				}
+				prev.Timeouts += dnsStats.Timeouts
+				prev.SuccessLatencySum += dnsStats.SuccessLatencySum
+				prev.FailureLatencySum += dnsStats.FailureLatencySum
+				if prev.CountByRcode == nil {
+					prev.CountByRcode = make(map[uint32]uint32)
+				}
+				for rcode, count := range dnsStats.CountByRcode {
+					prev.CountByRcode[rcode] += count
+				}
+				for rcode, latencies := range dnsStats.LatenciesByRcode {
+					if prev.LatenciesByRcode == nil {
+						prev.LatenciesByRcode = make(map[uint32]*latency.Stats)
+					}
+					if _, ok := prev.LatenciesByRcode[rcode]; !ok {
+						prev.LatenciesByRcode[rcode] = &latency.Stats{}
 					}
+					prev.LatenciesByRcode[rcode].CombineWith(latencies)
 				}
+				dst[key][domain][qtype] = prev
 			}
 		}
 	}
@@ -361,6 +468,28 @@ func (ns *networkState) getHTTPDelta(clientID string) map[http.Key]http.RequestS
 	return delta
 }
 
+// storeDatabaseStats stores latest database stats for all clients
+func (ns *networkState) storeDatabaseStats(allStats map[database.Key]database.RequestStats) {
+	for key, stats := range allStats {
+		for _, client := range ns.clients {
+			prevStats, ok := client.dbStatsDelta[key]
+			if !ok && len(client.dbStatsDelta) >= ns.maxDBStats {
+				ns.telemetry.dbStatsDropped++
+				continue
+			}
+
+			prevStats.CombineWith(stats)
+			client.dbStatsDelta[key] = prevStats
+		}
+	}
+}
+
+func (ns *networkState) getDatabaseDelta(clientID string) map[database.Key]database.RequestStats {
+	delta := ns.clients[clientID].dbStatsDelta
+	ns.clients[clientID].dbStatsDelta = make(map[database.Key]database.RequestStats)
+	return delta
+}
+
 // newClient creates a new client and returns true if the given client already exists
 func (ns *networkState) newClient(clientID string) (*client, bool) {
 	if c, ok := ns.clients[clientID]; ok {
@@ -373,6 +502,7 @@ func (ns *networkState) newClient(clientID string) (*client, bool) {
 		closedConnections: make([]ConnectionStats, 0, 1000),
 		dnsStats:          dns.StatsByKeyByNameByType{},
 		httpStatsDelta:    map[http.Key]http.RequestStats{},
+		dbStatsDelta:      map[database.Key]database.RequestStats{},
 	}
 	ns.clients[clientID] = c
 	return c, false
@@ -593,6 +723,7 @@ func (ns *networkState) RemoveConnections(keys []string) {
 		s += " [%d closed connections dropped]"
 		s += " [%d dns stats dropped]"
 		s += " [%d HTTP stats dropped]"
+		s += " [%d database stats dropped]"
 		s += " [%d DNS pid collisions]"
 		s += " [%d time sync collisions]"
 		log.Warnf(s,
@@ -601,6 +732,7 @@ func (ns *networkState) RemoveConnections(keys []string) {
 			ns.telemetry.closedConnDropped,
 			ns.telemetry.dnsStatsDropped,
 			ns.telemetry.httpStatsDropped,
+			ns.telemetry.dbStatsDropped,
 			ns.telemetry.dnsPidCollisions,
 			ns.telemetry.timeSyncCollisions)
 	}
@@ -631,6 +763,7 @@ func (ns *networkState) GetStats() map[string]interface{} {
 			"time_sync_collisions": ns.telemetry.timeSyncCollisions,
 			"dns_stats_dropped":    ns.telemetry.dnsStatsDropped,
 			"http_stats_dropped":   ns.telemetry.httpStatsDropped,
+			"db_stats_dropped":     ns.telemetry.dbStatsDropped,
 			"dns_pid_collisions":   ns.telemetry.dnsPidCollisions,
 		},
 		"current_time":       time.Now().Unix(),
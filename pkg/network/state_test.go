@@ -10,8 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	"github.com/DataDog/datadog-agent/pkg/network/http"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"go4.org/intern"
 
@@ -80,12 +82,12 @@ func BenchmarkConnectionsGet(b *testing.B) {
 			ns := newDefaultState()
 
 			// Initial fetch to set up client
-			ns.GetDelta(DEBUGCLIENT, latestTime, nil, nil, nil, nil)
+			ns.GetDelta(DEBUGCLIENT, latestTime, nil, nil, nil, nil, nil)
 			b.ResetTimer()
 			b.ReportAllocs()
 
 			for n := 0; n < b.N; n++ {
-				ns.GetDelta(DEBUGCLIENT, latestTime, conns[:bench.connCount], closed[:bench.closedCount], nil, nil)
+				ns.GetDelta(DEBUGCLIENT, latestTime, conns[:bench.connCount], closed[:bench.closedCount], nil, nil, nil)
 			}
 		})
 	}
@@ -115,10 +117,10 @@ func TestRemoveConnections(t *testing.T) {
 
 	clientID := "1"
 	state := newDefaultState().(*networkState)
-	conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil).Conns
+	conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 	assert.Equal(t, 0, len(conns))
 
-	conns = state.GetDelta(clientID, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+	conns = state.GetDelta(clientID, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, conn, conns[0])
 
@@ -151,7 +153,7 @@ func TestRetrieveClosedConnection(t *testing.T) {
 
 	t.Run("without prior registration", func(t *testing.T) {
 		state := newDefaultState()
-		conns := state.GetDelta(clientID, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil).Conns
+		conns := state.GetDelta(clientID, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil, nil).Conns
 
 		assert.Equal(t, 0, len(conns))
 	})
@@ -159,19 +161,19 @@ func TestRetrieveClosedConnection(t *testing.T) {
 	t.Run("with registration", func(t *testing.T) {
 		state := newDefaultState()
 
-		conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
-		conns = state.GetDelta(clientID, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil).Conns
+		conns = state.GetDelta(clientID, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, conn, conns[0])
 
 		// An other client that is not registered should not have the closed connection
-		conns = state.GetDelta("2", latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta("2", latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// It should no more have connections stored
-		conns = state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 	})
 }
@@ -179,11 +181,11 @@ func TestRetrieveClosedConnection(t *testing.T) {
 func TestCleanupClient(t *testing.T) {
 	clientID := "1"
 
-	state := NewState(100*time.Millisecond, 50000, 75000, 75000, 75000)
+	state := NewState(100*time.Millisecond, 50000, 75000, 75000, 75000, 75000)
 	clients := state.(*networkState).getClients()
 	assert.Equal(t, 0, len(clients))
 
-	conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil).Conns
+	conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 	assert.Equal(t, 0, len(conns))
 
 	// Should be a no op
@@ -233,15 +235,15 @@ func TestLastStats(t *testing.T) {
 	conn3.MonotonicRetransmits += dRetransmits
 
 	// First get, we should not have any connections stored
-	conns := state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil).Conns
+	conns := state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 	assert.Equal(t, 0, len(conns))
 
 	// Same for an other client
-	conns = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil).Conns
+	conns = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 	assert.Equal(t, 0, len(conns))
 
 	// We should have only one connection but with last stats equal to monotonic
-	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, conn.MonotonicSentBytes, conns[0].LastSentBytes)
 	assert.Equal(t, conn.MonotonicRecvBytes, conns[0].LastRecvBytes)
@@ -251,7 +253,7 @@ func TestLastStats(t *testing.T) {
 	assert.Equal(t, conn.MonotonicRetransmits, conns[0].MonotonicRetransmits)
 
 	// This client didn't collect the first connection so last stats = monotonic
-	conns = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{conn2}, nil, nil, nil).Conns
+	conns = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{conn2}, nil, nil, nil, nil).Conns
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, conn2.MonotonicSentBytes, conns[0].LastSentBytes)
 	assert.Equal(t, conn2.MonotonicRecvBytes, conns[0].LastRecvBytes)
@@ -261,7 +263,7 @@ func TestLastStats(t *testing.T) {
 	assert.Equal(t, conn2.MonotonicRetransmits, conns[0].MonotonicRetransmits)
 
 	// client 1 should have conn3 - conn1 since it did not collected conn2
-	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn3}, nil, nil, nil).Conns
+	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn3}, nil, nil, nil, nil).Conns
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, 2*dSent, conns[0].LastSentBytes)
 	assert.Equal(t, 2*dRecv, conns[0].LastRecvBytes)
@@ -271,7 +273,7 @@ func TestLastStats(t *testing.T) {
 	assert.Equal(t, conn3.MonotonicRetransmits, conns[0].MonotonicRetransmits)
 
 	// client 2 should have conn3 - conn2
-	conns = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{conn3}, nil, nil, nil).Conns
+	conns = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{conn3}, nil, nil, nil, nil).Conns
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, dSent, conns[0].LastSentBytes)
 	assert.Equal(t, dRecv, conns[0].LastRecvBytes)
@@ -308,11 +310,11 @@ func TestLastStatsForClosedConnection(t *testing.T) {
 	conn2.MonotonicRetransmits += dRetransmits
 
 	// First get, we should not have any connections stored
-	conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil).Conns
+	conns := state.GetDelta(clientID, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 	assert.Equal(t, 0, len(conns))
 
 	// We should have one connection with last stats equal to monotonic stats
-	conns = state.GetDelta(clientID, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+	conns = state.GetDelta(clientID, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, conn.MonotonicSentBytes, conns[0].LastSentBytes)
 	assert.Equal(t, conn.MonotonicRecvBytes, conns[0].LastRecvBytes)
@@ -322,7 +324,7 @@ func TestLastStatsForClosedConnection(t *testing.T) {
 	assert.Equal(t, conn.MonotonicRetransmits, conns[0].MonotonicRetransmits)
 
 	// We should have one connection with last stats
-	conns = state.GetDelta(clientID, latestEpochTime(), nil, []ConnectionStats{conn2}, nil, nil).Conns
+	conns = state.GetDelta(clientID, latestEpochTime(), nil, []ConnectionStats{conn2}, nil, nil, nil).Conns
 
 	assert.Equal(t, 1, len(conns))
 	assert.Equal(t, dSent, conns[0].LastSentBytes)
@@ -373,7 +375,7 @@ func TestRaceConditions(t *testing.T) {
 				case <-timer.C:
 					return
 				default:
-					state.GetDelta(c, latestEpochTime(), genConns(nConns), nil, nil, nil)
+					state.GetDelta(c, latestEpochTime(), genConns(nConns), nil, nil, nil, nil)
 				}
 			}
 		}(fmt.Sprintf("%d", i))
@@ -412,11 +414,11 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// Second get, we should have monotonic and last stats = 3
-		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
@@ -438,7 +440,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		conn2 := conn
@@ -446,7 +448,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn2.LastUpdateEpoch++
 
 		// Second get, we should have monotonic and last stats = 8
-		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn, conn2}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn, conn2}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 8, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 8, int(conns[0].LastSentBytes))
@@ -470,7 +472,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get for client c, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Len(t, conns, 0)
 
 		conn := ConnectionStats{
@@ -485,7 +487,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		}
 
 		// Simulate this connection starting
-		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 		require.Len(t, conns, 1)
 		assert.EqualValues(t, 1, conns[0].LastSentBytes)
 		assert.EqualValues(t, 1, conns[0].MonotonicSentBytes)
@@ -498,7 +500,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn.MonotonicSentBytes = 1
 		conn.LastUpdateEpoch = latestEpochTime()
 		// Retrieve the connections
-		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, closed, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, closed, nil, nil, nil).Conns
 		require.Len(t, conns, 1)
 		assert.EqualValues(t, 2, conns[0].LastSentBytes)
 		assert.EqualValues(t, 3, conns[0].MonotonicSentBytes)
@@ -506,7 +508,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn.MonotonicSentBytes++
 		conn.LastUpdateEpoch = latestEpochTime()
 		// Store the connection as closed
-		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil, nil).Conns
 		require.Len(t, conns, 1)
 		assert.EqualValues(t, 1, conns[0].LastSentBytes)
 		assert.EqualValues(t, 2, conns[0].MonotonicSentBytes)
@@ -530,7 +532,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		conn2 := conn
@@ -539,7 +541,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs := []ConnectionStats{conn2}
 
 		// Second get, we should have monotonic and last stats = 5
-		conns = state.GetDelta(client, latestEpochTime(), cs, []ConnectionStats{conn}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), cs, []ConnectionStats{conn}, nil, nil, nil).Conns
 		require.Equal(t, 1, len(conns))
 		assert.Equal(t, 5, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 5, int(conns[0].LastSentBytes))
@@ -554,7 +556,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn3.LastUpdateEpoch++
 
 		// Third get, we should have monotonic = 6 and last stats = 4
-		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn3}, []ConnectionStats{conn2}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn3}, []ConnectionStats{conn2}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 6, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 4, int(conns[0].LastSentBytes))
@@ -563,7 +565,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn3.MonotonicSentBytes += 2
 
 		// 4th get, we should have monotonic = 3 and last stats = 2
-		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn3}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn3}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 2, int(conns[0].LastSentBytes))
@@ -585,14 +587,14 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// this is to register we should not have anything
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// Store the connection as opened
 		cs := []ConnectionStats{conn}
 
 		// First get, we should have monotonic = 3 and last seen = 3
-		conns = state.GetDelta(client, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
@@ -602,7 +604,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn2.MonotonicSentBytes = 8
 
 		// Second get, we should have monotonic = 8 and last stats = 5
-		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn2}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn2}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 8, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 5, int(conns[0].LastSentBytes))
@@ -642,15 +644,15 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get for client c, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// First get for client d, we should have nothing
-		conns = state.GetDelta(clientD, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// Second get for client d we should have monotonic and last stats = 3
-		conns = state.GetDelta(clientD, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
@@ -662,7 +664,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs := []ConnectionStats{conn2}
 
 		// Second get, for client c we should have monotonic and last stats = 5
-		conns = state.GetDelta(client, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 5, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 5, int(conns[0].LastSentBytes))
@@ -673,7 +675,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs = []ConnectionStats{conn2}
 
 		// Third get, for client d we should have monotonic = 3 and last stats = 3
-		conns = state.GetDelta(clientD, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
@@ -689,7 +691,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs = []ConnectionStats{conn3}
 
 		// Third get, for client c, we should have monotonic = 6 and last stats = 4
-		conns = state.GetDelta(client, latestEpochTime(), cs, []ConnectionStats{conn2}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), cs, []ConnectionStats{conn2}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 6, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 4, int(conns[0].LastSentBytes))
@@ -700,7 +702,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs = []ConnectionStats{conn3}
 
 		// 4th get, for client d, we should have monotonic = 7 and last stats = 4
-		conns = state.GetDelta(clientD, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 7, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 4, int(conns[0].LastSentBytes))
@@ -710,13 +712,13 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn3.LastUpdateEpoch++
 
 		// 4th get, for client c we should have monotonic = 3 and last stats = 2
-		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn3}, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn3}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 2, int(conns[0].LastSentBytes))
 
 		// 5th get, for client d we should have monotonic = 3 and last stats = 1
-		conns = state.GetDelta(clientD, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 1, int(conns[0].LastSentBytes))
@@ -764,15 +766,15 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get for client c, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// First get for client d, we should have nothing
-		conns = state.GetDelta(clientD, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// First get for client e, we should have nothing
-		conns = state.GetDelta(clientE, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta(clientE, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// Store the connection
@@ -781,7 +783,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs := []ConnectionStats{conn}
 
 		// Second get for client e we should have monotonic and last stats = 2
-		conns = state.GetDelta(clientE, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(clientE, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 2, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 2, int(conns[0].LastSentBytes))
@@ -791,13 +793,13 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn.LastUpdateEpoch++
 
 		// Second get for client d we should have monotonic and last stats = 3
-		conns = state.GetDelta(clientD, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), nil, []ConnectionStats{conn}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
 
 		// Third get for client e we should have monotonic = 3and last stats = 1
-		conns = state.GetDelta(clientE, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns = state.GetDelta(clientE, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 1, int(conns[0].LastSentBytes))
@@ -809,7 +811,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs = []ConnectionStats{conn2}
 
 		// Second get, for client c we should have monotonic and last stats = 5
-		conns = state.GetDelta(client, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 5, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 5, int(conns[0].LastSentBytes))
@@ -820,7 +822,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		cs = []ConnectionStats{conn2}
 
 		// Third get, for client d we should have monotonic = 3 and last stats = 3
-		conns = state.GetDelta(clientD, latestEpochTime(), cs, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), cs, nil, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
@@ -830,7 +832,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn2.LastUpdateEpoch++
 
 		// 4th get, for client e we should have monotonic = 5 and last stats = 5
-		conns = state.GetDelta(clientE, latestEpochTime(), nil, []ConnectionStats{conn2}, nil, nil).Conns
+		conns = state.GetDelta(clientE, latestEpochTime(), nil, []ConnectionStats{conn2}, nil, nil, nil).Conns
 		assert.Equal(t, 1, len(conns))
 		assert.Equal(t, 5, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 5, int(conns[0].LastSentBytes))
@@ -866,11 +868,11 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		state := newDefaultState()
 
 		// First get for client c, we should have nothing
-		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns
+		conns := state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 		assert.Equal(t, 0, len(conns))
 
 		// Second get for client c we should have monotonic and last stats = 3
-		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 		assert.Len(t, conns, 1)
 		assert.Equal(t, 3, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 3, int(conns[0].LastSentBytes))
@@ -880,7 +882,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn2.LastUpdateEpoch++
 
 		// First get for client d we should have monotonic = 4 and last bytes = 4
-		conns = state.GetDelta(clientD, latestEpochTime(), []ConnectionStats{conn2}, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), []ConnectionStats{conn2}, nil, nil, nil, nil).Conns
 		assert.Len(t, conns, 1)
 		assert.Equal(t, 4, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 0, int(conns[0].LastSentBytes))
@@ -890,7 +892,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn3.LastUpdateEpoch++
 
 		// Third get for client c we should have monotonic = 7 and last bytes = 4
-		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn3}, nil, nil, nil).Conns
+		conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn3}, nil, nil, nil, nil).Conns
 		assert.Len(t, conns, 1)
 		assert.Equal(t, 7, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 4, int(conns[0].LastSentBytes))
@@ -900,7 +902,7 @@ func TestSameKeyEdgeCases(t *testing.T) {
 		conn4.LastUpdateEpoch++
 
 		// Second get for client d we should have monotonic = 9 and last bytes = 5
-		conns = state.GetDelta(clientD, latestEpochTime(), []ConnectionStats{conn4}, nil, nil, nil).Conns
+		conns = state.GetDelta(clientD, latestEpochTime(), []ConnectionStats{conn4}, nil, nil, nil, nil).Conns
 		assert.Len(t, conns, 1)
 		assert.Equal(t, 9, int(conns[0].MonotonicSentBytes))
 		assert.Equal(t, 5, int(conns[0].LastSentBytes))
@@ -923,10 +925,10 @@ func TestStatsResetOnUnderflow(t *testing.T) {
 	state := newDefaultState()
 
 	// Register the client
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 
 	// Get the connections once to register stats
-	conns := state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+	conns := state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 	require.Len(t, conns, 1)
 
 	// Expect LastStats to be 3
@@ -936,7 +938,7 @@ func TestStatsResetOnUnderflow(t *testing.T) {
 	// Get the connections again but by simulating an underflow
 	conn.MonotonicSentBytes--
 
-	conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil).Conns
+	conns = state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, nil, nil, nil, nil).Conns
 	require.Len(t, conns, 1)
 	expected := conn
 	expected.LastSentBytes = 2
@@ -966,8 +968,8 @@ func TestDoubleCloseOnTwoClients(t *testing.T) {
 	state := newDefaultState()
 
 	// Register the clients
-	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
-	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 
 	// Store the closed connection twice
 	conn2 := conn
@@ -976,12 +978,12 @@ func TestDoubleCloseOnTwoClients(t *testing.T) {
 
 	expectedConn.LastUpdateEpoch = conn2.LastUpdateEpoch
 	// Get the connections for client1 we should have only one with stats = 2*conn
-	conns := state.GetDelta(client1, latestEpochTime(), nil, closed, nil, nil).Conns
+	conns := state.GetDelta(client1, latestEpochTime(), nil, closed, nil, nil, nil).Conns
 	require.Len(t, conns, 1)
 	assert.Equal(t, expectedConn, conns[0])
 
 	// Same for client2
-	conns = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil).Conns
+	conns = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil).Conns
 	require.Len(t, conns, 1)
 	assert.Equal(t, expectedConn, conns[0])
 }
@@ -1000,7 +1002,7 @@ func TestUnorderedCloseEvent(t *testing.T) {
 	state := newDefaultState()
 
 	// Register the client
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 
 	// Simulate storing a closed connection while we were reading from the eBPF map
 	// in this case the closed conn will have an earlier epoch
@@ -1012,22 +1014,22 @@ func TestUnorderedCloseEvent(t *testing.T) {
 	conn.LastUpdateEpoch--
 	conn.MonotonicSentBytes--
 	conn.MonotonicRecvBytes = 0
-	conns := state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, closed, nil, nil).Conns
+	conns := state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn}, closed, nil, nil, nil).Conns
 	require.Len(t, conns, 1)
 	assert.EqualValues(t, 4, conns[0].LastSentBytes)
 	assert.EqualValues(t, 1, conns[0].LastRecvBytes)
 
 	// Simulate some other gets
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 
 	// Simulate having the connection getting active again
 	conn.LastUpdateEpoch = latestEpochTime()
 	conn.MonotonicSentBytes--
 	closed = []ConnectionStats{conn}
 
-	conns = state.GetDelta(client, latestEpochTime(), nil, closed, nil, nil).Conns
+	conns = state.GetDelta(client, latestEpochTime(), nil, closed, nil, nil, nil).Conns
 	require.Len(t, conns, 1)
 	assert.EqualValues(t, 2, conns[0].LastSentBytes)
 	assert.EqualValues(t, 0, conns[0].LastRecvBytes)
@@ -1035,7 +1037,7 @@ func TestUnorderedCloseEvent(t *testing.T) {
 	// Ensure we don't have underflows / unordered conns
 	assert.Zero(t, state.(*networkState).telemetry.statsResets)
 
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 }
 
 func TestAggregateClosedConnectionsTimestamp(t *testing.T) {
@@ -1052,7 +1054,7 @@ func TestAggregateClosedConnectionsTimestamp(t *testing.T) {
 	state := newDefaultState()
 
 	// Register the client
-	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 
 	conn1 := conn
 	conn1.LastUpdateEpoch = latestEpochTime()
@@ -1064,7 +1066,7 @@ func TestAggregateClosedConnectionsTimestamp(t *testing.T) {
 	conn3.LastUpdateEpoch = latestEpochTime()
 
 	// Make sure the connections we get has the latest timestamp
-	delta := state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn1, conn2, conn3}, nil, nil)
+	delta := state.GetDelta(client, latestEpochTime(), nil, []ConnectionStats{conn1, conn2, conn3}, nil, nil, nil)
 	assert.Equal(t, conn3.LastUpdateEpoch, delta.Conns[0].LastUpdateEpoch)
 }
 
@@ -1087,7 +1089,9 @@ func TestDNSStatsWithMultipleClients(t *testing.T) {
 		stats := make(map[dns.QueryType]dns.Stats)
 		countByRcode := make(map[uint32]uint32)
 		countByRcode[uint32(DNSResponseCodeNoError)] = 1
-		stats[dns.TypeA] = dns.Stats{CountByRcode: countByRcode}
+		latenciesByRcode := make(map[uint32]*latency.Stats)
+		latenciesByRcode[uint32(DNSResponseCodeNoError)] = &latency.Stats{Count: 1, FirstLatencySample: 1e6}
+		stats[dns.TypeA] = dns.Stats{CountByRcode: countByRcode, LatenciesByRcode: latenciesByRcode}
 		statsByDomain[dKey] = make(map[*intern.Value]map[dns.QueryType]dns.Stats)
 		statsByDomain[dKey][d] = stats
 		return statsByDomain
@@ -1109,30 +1113,31 @@ func TestDNSStatsWithMultipleClients(t *testing.T) {
 		queryTypeStats, ok := domainStats[qtype]
 		require.Truef(t, ok, "couldn't find DNSStats for query type: %s", qtype)
 
+		require.EqualValues(t, queryTypeStats.CountByRcode[uint32(code)], queryTypeStats.LatenciesByRcode[uint32(code)].Count)
 		return queryTypeStats.CountByRcode[uint32(code)]
 	}
 
 	// Register the first two clients
-	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
-	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
 
 	c.LastUpdateEpoch = latestEpochTime()
 
-	delta := state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, getStats(), nil)
+	delta := state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, getStats(), nil, nil)
 	require.Len(t, delta.Conns, 1)
 
 	rcode := getRCodeFrom(delta, delta.Conns[0], "foo.com", dns.TypeA, DNSResponseCodeNoError)
 	assert.EqualValues(t, 1, rcode)
 
 	// Register the third client but also pass in dns stats
-	delta = state.GetDelta(client3, latestEpochTime(), []ConnectionStats{c}, nil, getStats(), nil)
+	delta = state.GetDelta(client3, latestEpochTime(), []ConnectionStats{c}, nil, getStats(), nil, nil)
 	require.Len(t, delta.Conns, 1)
 
 	// DNS stats should be available for the new client
 	rcode = getRCodeFrom(delta, delta.Conns[0], "foo.com", dns.TypeA, DNSResponseCodeNoError)
 	assert.EqualValues(t, 1, rcode)
 
-	delta = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{c}, nil, getStats(), nil)
+	delta = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{c}, nil, getStats(), nil, nil)
 	require.Len(t, delta.Conns, 1)
 
 	// 2nd client should get accumulated stats
@@ -1140,6 +1145,71 @@ func TestDNSStatsWithMultipleClients(t *testing.T) {
 	assert.EqualValues(t, 3, rcode)
 }
 
+func TestDNSSnapshot(t *testing.T) {
+	newConn := func(pid uint32, port uint16) ConnectionStats {
+		return ConnectionStats{
+			Pid:    pid,
+			Type:   UDP,
+			Family: AFINET,
+			Source: util.AddressFromString("10.1.1.1"),
+			Dest:   util.AddressFromString("8.8.8.8"),
+			SPort:  port,
+			DPort:  53,
+		}
+	}
+	getStats := func(c ConnectionStats) dns.StatsByKeyByNameByType {
+		key, _ := DNSKey(&c)
+		return dns.StatsByKeyByNameByType{
+			key: {
+				intern.GetByString("foo.com"): {
+					dns.TypeA: {
+						CountByRcode:     map[uint32]uint32{3: 1},
+						LatenciesByRcode: map[uint32]*latency.Stats{3: {Count: 1, FirstLatencySample: 1e6}},
+					},
+				},
+			},
+		}
+	}
+	getCount := func(stats dns.StatsByKeyByNameByType, c ConnectionStats) uint32 {
+		key, _ := DNSKey(&c)
+		return stats[key][intern.GetByString("foo.com")][dns.TypeA].CountByRcode[3]
+	}
+
+	conn1 := newConn(1, 1001)
+	conn2 := newConn(2, 1002)
+	client := "client"
+	state := newDefaultState()
+
+	// The DNS stats are only kept for the snapshots once they have been requested
+	snapshot := state.GetDNSSnapshot(nil, nil, nil)
+	assert.Empty(t, snapshot.DNSStats)
+	assert.Empty(t, state.GetStats()["clients"])
+
+	assert.Len(t, state.GetDelta(client, latestEpochTime(), nil, nil, nil, nil, nil).Conns, 0)
+	state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn1}, nil, getStats(conn1), nil, nil)
+	state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn1}, nil, getStats(conn1), nil, nil)
+
+	// The closed connections and the DNS stats passed to the snapshot are kept for the client
+	snapshot = state.GetDNSSnapshot([]ConnectionStats{conn1}, []ConnectionStats{conn2}, getStats(conn2))
+	assert.Len(t, state.GetStats()["clients"], 1)
+	assert.EqualValues(t, 2, getCount(snapshot.DNSStats, conn1))
+	assert.EqualValues(t, 1, getCount(snapshot.DNSStats, conn2))
+	key1, _ := DNSKey(&conn1)
+	key2, _ := DNSKey(&conn2)
+	assert.Equal(t, map[dns.Key]uint32{key1: 1, key2: 2}, snapshot.Pids)
+
+	delta := state.GetDelta(client, latestEpochTime(), []ConnectionStats{conn1}, nil, getStats(conn1), nil, nil)
+	assert.Len(t, delta.Conns, 2)
+	assert.EqualValues(t, 1, getCount(delta.DNSStats, conn1))
+	assert.EqualValues(t, 1, getCount(delta.DNSStats, conn2))
+
+	snapshot = state.GetDNSSnapshot(nil, nil, nil)
+	assert.EqualValues(t, 1, getCount(snapshot.DNSStats, conn1))
+	assert.Len(t, snapshot.DNSStats, 1)
+
+	assert.Empty(t, state.GetDNSSnapshot(nil, nil, nil).DNSStats)
+}
+
 func TestHTTPStats(t *testing.T) {
 	c := ConnectionStats{
 		Source: util.AddressFromString("1.1.1.1"),
@@ -1156,13 +1226,13 @@ func TestHTTPStats(t *testing.T) {
 
 	// Register client & pass in HTTP stats
 	state := newDefaultState()
-	delta := state.GetDelta("client", latestEpochTime(), nil, []ConnectionStats{c}, nil, httpStats)
+	delta := state.GetDelta("client", latestEpochTime(), nil, []ConnectionStats{c}, nil, httpStats, nil)
 
 	// Verify connection has HTTP data embedded in it
 	assert.Len(t, delta.HTTP, 1)
 
 	// Verify HTTP data has been flushed
-	delta = state.GetDelta("client", latestEpochTime(), nil, []ConnectionStats{c}, nil, nil)
+	delta = state.GetDelta("client", latestEpochTime(), nil, []ConnectionStats{c}, nil, nil, nil)
 	assert.Len(t, delta.HTTP, 0)
 }
 
@@ -1188,38 +1258,78 @@ func TestHTTPStatsWithMultipleClients(t *testing.T) {
 	state := newDefaultState()
 
 	// Register the first two clients
-	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil).HTTP, 0)
-	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil).HTTP, 0)
+	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil, nil).HTTP, 0)
+	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil).HTTP, 0)
 
 	// Store the connection to both clients & pass HTTP stats to the first client
 	c.LastUpdateEpoch = latestEpochTime()
 
-	delta := state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, nil, getStats("/testpath"))
+	delta := state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, nil, getStats("/testpath"), nil)
 	assert.Len(t, delta.HTTP, 1)
 
 	// Verify that the HTTP stats were also stored in the second client
-	delta = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil)
+	delta = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil)
 	assert.Len(t, delta.HTTP, 1)
 
 	// Register a third client & verify that it does not have the HTTP stats
-	delta = state.GetDelta(client3, latestEpochTime(), []ConnectionStats{c}, nil, nil, nil)
+	delta = state.GetDelta(client3, latestEpochTime(), []ConnectionStats{c}, nil, nil, nil, nil)
 	assert.Len(t, delta.HTTP, 0)
 
 	c.LastUpdateEpoch = latestEpochTime()
 
 	// Pass in new HTTP stats to the first client
-	delta = state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, nil, getStats("/testpath2"))
+	delta = state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, nil, getStats("/testpath2"), nil)
 	assert.Len(t, delta.HTTP, 1)
 
 	// And the second client
-	delta = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, getStats("/testpath3"))
+	delta = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, getStats("/testpath3"), nil)
 	assert.Len(t, delta.HTTP, 2)
 
 	// Verify that the third client also accumulated both new HTTP stats
-	delta = state.GetDelta(client3, latestEpochTime(), nil, nil, nil, nil)
+	delta = state.GetDelta(client3, latestEpochTime(), nil, nil, nil, nil, nil)
 	assert.Len(t, delta.HTTP, 2)
 }
 
+func TestDatabaseStats(t *testing.T) {
+	c := ConnectionStats{
+		Source: util.AddressFromString("1.1.1.1"),
+		Dest:   util.AddressFromString("0.0.0.0"),
+		SPort:  1000,
+		DPort:  5432,
+	}
+
+	getStats := func(command string, latency float64) map[database.Key]database.RequestStats {
+		key := database.NewKey(c.Source, c.Dest, c.SPort, c.DPort, database.ProtocolPostgres, command)
+		var rs database.RequestStats
+		rs.AddRequest(false, latency)
+		return map[database.Key]database.RequestStats{key: rs}
+	}
+
+	client1 := "client1"
+	client2 := "client2"
+	state := newDefaultState()
+
+	// Register both clients
+	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil, nil).Database, 0)
+	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, nil).Database, 0)
+
+	delta := state.GetDelta(client1, latestEpochTime(), nil, []ConnectionStats{c}, nil, nil, getStats("SELECT", 10))
+	assert.Len(t, delta.Database, 1)
+
+	// Verify database data has been flushed for the first client
+	delta = state.GetDelta(client1, latestEpochTime(), nil, nil, nil, nil, getStats("SELECT", 20))
+	require.Len(t, delta.Database, 1)
+	for _, stats := range delta.Database {
+		assert.Equal(t, 1, stats.Success.Count)
+	}
+
+	// Verify the second client accumulated the stats of both calls
+	delta = state.GetDelta(client2, latestEpochTime(), nil, nil, nil, nil, getStats("INSERT", 30))
+	require.Len(t, delta.Database, 2)
+	key := database.NewKey(c.Source, c.Dest, c.SPort, c.DPort, database.ProtocolPostgres, "SELECT")
+	assert.Equal(t, 2, delta.Database[key].Success.Count)
+}
+
 func TestDetermineConnectionIntraHost(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1428,7 +1538,7 @@ func latestEpochTime() uint64 {
 
 func newDefaultState() State {
 	// Using values from ebpf.NewConfig()
-	return NewState(2*time.Minute, 50000, 75000, 75000, 7500)
+	return NewState(2*time.Minute, 50000, 75000, 75000, 7500, 7500)
 }
 
 func getIPProtocol(nt ConnectionType) uint8 {
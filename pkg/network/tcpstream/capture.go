@@ -0,0 +1,112 @@
+// +build linux_bpf
+
+package tcpstream
+
+import (
+	"sync"
+	"time"
+
+	filterpkg "github.com/DataDog/datadog-agent/pkg/network/filter"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// expirationInterval is the interval at which the idle flows are forgotten
+const expirationInterval = 30 * time.Second
+
+// Capture is responsible for:
+// * Creating a raw socket with a classic BPF filter matching the ports of the servers;
+// * Feeding the TCP segments of the captured packets to a Tracker;
+// * Periodically forgetting the idle flows of the Tracker;
+type Capture struct {
+	source  *filterpkg.AFPacketSource
+	parser  *Parser
+	tracker *Tracker
+
+	// the segment being processed, to avoid allocations
+	segment        Segment
+	lastExpiration time.Time
+
+	exit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewCapture returns a Capture of the traffic of the servers listening on the given ports, whose raw socket
+// is created inside the root network namespace found in procRoot
+func NewCapture(procRoot string, ports []uint16, tracker *Tracker) (*Capture, error) {
+	filter, err := filterpkg.TCPPortsFilter(ports)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the RAW_SOCKET inside the root network namespace
+	var (
+		source    *filterpkg.AFPacketSource
+		sourceErr error
+	)
+	err = util.WithRootNS(procRoot, func() error {
+		source, sourceErr = filterpkg.NewPacketSourceWithFilter(filter)
+		return sourceErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Capture{
+		source:  source,
+		parser:  NewParser(source.PacketType()),
+		tracker: tracker,
+		exit:    make(chan struct{}),
+	}, nil
+}
+
+// Start consuming the captured traffic
+func (c *Capture) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			if err := c.source.VisitPackets(c.exit, c.processPacket); err != nil {
+				log.Warnf("error reading packet: %s", err)
+			}
+
+			select {
+			case <-c.exit:
+				return
+			default:
+				c.removeExpired(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop the capture, and close its raw socket. It must only be called once.
+func (c *Capture) Stop() {
+	close(c.exit)
+	c.wg.Wait()
+	c.source.Close()
+}
+
+// processPacket feeds the TCP segment of a packet to the Tracker. The packet data can't be referenced
+// after this method call since the underlying memory content gets invalidated by `afpacket`.
+func (c *Capture) processPacket(data []byte, ts time.Time) error {
+	ok, err := c.parser.Parse(data, ts, &c.segment)
+	if err != nil {
+		c.tracker.telemetry.AddDecodingError()
+		return nil
+	}
+	if ok {
+		c.tracker.Process(&c.segment)
+	}
+
+	c.removeExpired(ts)
+	return nil
+}
+
+func (c *Capture) removeExpired(now time.Time) {
+	if now.Sub(c.lastExpiration) < expirationInterval {
+		return
+	}
+	c.tracker.RemoveExpired(now)
+	c.lastExpiration = now
+}
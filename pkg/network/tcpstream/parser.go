@@ -0,0 +1,82 @@
+package tcpstream
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Segment is a TCP segment of a connection
+type Segment struct {
+	Src, Dst         util.Address
+	SrcPort, DstPort uint16
+	Seq              uint32
+	SYN, FIN, RST    bool
+	Payload          []byte
+	Timestamp        time.Time
+}
+
+// Parser extracts the TCP segments from the captured packets
+type Parser struct {
+	decoder *gopacket.DecodingLayerParser
+	layers  []gopacket.LayerType
+	ipv4    *layers.IPv4
+	ipv6    *layers.IPv6
+	tcp     *layers.TCP
+}
+
+// NewParser returns a Parser for the packets starting with a layer of the given type
+func NewParser(layerType gopacket.LayerType) *Parser {
+	ipv4 := &layers.IPv4{}
+	ipv6 := &layers.IPv6{}
+	tcp := &layers.TCP{}
+
+	decoder := gopacket.NewDecodingLayerParser(layerType, &layers.Ethernet{}, ipv4, ipv6, tcp)
+	// the TCP payload is decoded by the Tracker
+	decoder.IgnoreUnsupported = true
+
+	return &Parser{
+		decoder: decoder,
+		ipv4:    ipv4,
+		ipv6:    ipv6,
+		tcp:     tcp,
+	}
+}
+
+// Parse fills s with the TCP segment of the packet, and returns false if the packet holds no TCP segment.
+// The payload of the segment references data.
+func (p *Parser) Parse(data []byte, ts time.Time, s *Segment) (bool, error) {
+	*s = Segment{}
+	if err := p.decoder.DecodeLayers(data, &p.layers); err != nil {
+		return false, err
+	}
+
+	var isTCP bool
+	for _, layer := range p.layers {
+		switch layer {
+		case layers.LayerTypeIPv4:
+			s.Src = util.AddressFromNetIP(p.ipv4.SrcIP)
+			s.Dst = util.AddressFromNetIP(p.ipv4.DstIP)
+		case layers.LayerTypeIPv6:
+			s.Src = util.AddressFromNetIP(p.ipv6.SrcIP)
+			s.Dst = util.AddressFromNetIP(p.ipv6.DstIP)
+		case layers.LayerTypeTCP:
+			isTCP = true
+		}
+	}
+	if !isTCP || s.Src == nil {
+		return false, nil
+	}
+
+	s.SrcPort = uint16(p.tcp.SrcPort)
+	s.DstPort = uint16(p.tcp.DstPort)
+	s.Seq = p.tcp.Seq
+	s.SYN = p.tcp.SYN
+	s.FIN = p.tcp.FIN
+	s.RST = p.tcp.RST
+	s.Payload = p.tcp.Payload
+	s.Timestamp = ts
+	return true, nil
+}
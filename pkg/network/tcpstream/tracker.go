@@ -0,0 +1,219 @@
+package tcpstream
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+const (
+	// flowExpiration is the duration after which a flow having no traffic is forgotten
+	flowExpiration = 2 * time.Minute
+
+	// defaultMaxFlows is the default maximum number of flows tracked at the same time
+	defaultMaxFlows = 10000
+)
+
+// Decoder decodes the payloads of a connection
+type Decoder interface {
+	Write(fromClient bool, data []byte, ts time.Time) error
+}
+
+// Flusher is implemented by the decoders which keep an operation pending until the next one starts.
+// Flush is called when the flow of the decoder is forgotten.
+type Flusher interface {
+	Flush()
+}
+
+// Connection is a connection from a client to a server
+type Connection struct {
+	Client, Server         util.Address
+	ClientPort, ServerPort uint16
+}
+
+// Telemetry counts the segments the Tracker could not decode. Its fields are updated atomically.
+type Telemetry struct {
+	DecodingErrors int64 // this happens when a stream can not be decoded
+	StreamGaps     int64 // this happens when segments of a stream are lost
+	FlowsDropped   int64 // this happens when the Tracker reaches capacity
+}
+
+// AddDecodingError counts a stream which could not be decoded
+func (t *Telemetry) AddDecodingError() {
+	atomic.AddInt64(&t.DecodingErrors, 1)
+}
+
+func (t *Telemetry) addStreamGap() {
+	atomic.AddInt64(&t.StreamGaps, 1)
+}
+
+func (t *Telemetry) addFlowDropped() {
+	atomic.AddInt64(&t.FlowsDropped, 1)
+}
+
+// Reset returns the current counters, and resets them
+func (t *Telemetry) Reset() Telemetry {
+	return Telemetry{
+		DecodingErrors: atomic.SwapInt64(&t.DecodingErrors, 0),
+		StreamGaps:     atomic.SwapInt64(&t.StreamGaps, 0),
+		FlowsDropped:   atomic.SwapInt64(&t.FlowsDropped, 0),
+	}
+}
+
+// flowKey identifies the flow of a connection
+type flowKey struct {
+	clientIPHigh, clientIPLow uint64
+	serverIPHigh, serverIPLow uint64
+	clientPort, serverPort    uint16
+}
+
+// flow is the state of a connection to a server
+type flow struct {
+	decoder  Decoder
+	nextSeq  [2]uint32 // next expected sequence number of each direction, indexed by fromClient
+	synced   [2]bool   // whether the sequence numbers of each direction are known
+	lastSeen time.Time
+}
+
+// Tracker reassembles the TCP streams of the connections to the servers, and feeds them to the
+// decoders of their connection. A flow which can not be decoded anymore, because some segments
+// were lost or because the decoder failed, is forgotten, and decoding starts again with the next
+// segment sent by the client, which usually starts with a new request.
+type Tracker struct {
+	isServerPort func(port uint16) bool
+	newDecoder   func(c Connection) Decoder
+	telemetry    *Telemetry
+
+	flows    map[flowKey]*flow
+	maxFlows int
+}
+
+// NewTracker returns a new Tracker. isServerPort tells whether a port is the one of a server, and newDecoder
+// returns the decoder of a new connection.
+func NewTracker(isServerPort func(port uint16) bool, newDecoder func(c Connection) Decoder, telemetry *Telemetry) *Tracker {
+	return &Tracker{
+		isServerPort: isServerPort,
+		newDecoder:   newDecoder,
+		telemetry:    telemetry,
+		flows:        make(map[flowKey]*flow),
+		maxFlows:     defaultMaxFlows,
+	}
+}
+
+// Process feeds a segment to the decoder of its flow
+func (t *Tracker) Process(s *Segment) {
+	var (
+		c          Connection
+		fromClient bool
+	)
+	if t.isServerPort(s.DstPort) {
+		fromClient = true
+		c = Connection{Client: s.Src, Server: s.Dst, ClientPort: s.SrcPort, ServerPort: s.DstPort}
+	} else if t.isServerPort(s.SrcPort) {
+		c = Connection{Client: s.Dst, Server: s.Src, ClientPort: s.DstPort, ServerPort: s.SrcPort}
+	} else {
+		return
+	}
+	key := newFlowKey(c)
+
+	f := t.flows[key]
+	if s.SYN && fromClient && f != nil {
+		// a new connection reusing the tuple of a previous one
+		t.remove(key, f)
+		f = nil
+	}
+	if f == nil {
+		if len(s.Payload) == 0 && !s.SYN {
+			return
+		}
+		// decoding starts with the client, unless the connection is captured from its beginning
+		if !fromClient && !s.SYN {
+			return
+		}
+		if len(t.flows) >= t.maxFlows {
+			t.telemetry.addFlowDropped()
+			return
+		}
+		f = &flow{decoder: t.newDecoder(c)}
+		t.flows[key] = f
+	}
+	f.lastSeen = s.Timestamp
+
+	dir := 0
+	if fromClient {
+		dir = 1
+	}
+	seq := s.Seq
+	if s.SYN {
+		// the SYN flag takes a sequence number
+		seq++
+		f.nextSeq[dir] = seq
+		f.synced[dir] = true
+	}
+
+	if payload := s.Payload; len(payload) > 0 {
+		if !f.synced[dir] {
+			if !fromClient && !f.synced[1] {
+				// the server answers requests sent before the client was seen
+				return
+			}
+			f.nextSeq[dir] = seq
+			f.synced[dir] = true
+		}
+
+		switch diff := int32(seq - f.nextSeq[dir]); {
+		case diff > 0:
+			// a segment was lost
+			t.telemetry.addStreamGap()
+			t.remove(key, f)
+			return
+		case diff < 0:
+			// retransmission
+			if -int(diff) >= len(payload) {
+				return
+			}
+			payload = payload[-diff:]
+		}
+		f.nextSeq[dir] += uint32(len(payload))
+
+		if err := f.decoder.Write(fromClient, payload, s.Timestamp); err != nil {
+			t.telemetry.AddDecodingError()
+			t.remove(key, f)
+			return
+		}
+	}
+
+	if s.FIN || s.RST {
+		t.remove(key, f)
+	}
+}
+
+// RemoveExpired forgets the flows which had no traffic since flowExpiration
+func (t *Tracker) RemoveExpired(now time.Time) {
+	for key, f := range t.flows {
+		if now.Sub(f.lastSeen) > flowExpiration {
+			t.remove(key, f)
+		}
+	}
+}
+
+func (t *Tracker) remove(key flowKey, f *flow) {
+	delete(t.flows, key)
+	if flusher, ok := f.decoder.(Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func newFlowKey(c Connection) flowKey {
+	clientLow, clientHigh := util.ToLowHigh(c.Client)
+	serverLow, serverHigh := util.ToLowHigh(c.Server)
+	return flowKey{
+		clientIPHigh: clientHigh,
+		clientIPLow:  clientLow,
+		serverIPHigh: serverHigh,
+		serverIPLow:  serverLow,
+		clientPort:   c.ClientPort,
+		serverPort:   c.ServerPort,
+	}
+}
@@ -0,0 +1,239 @@
+package tcpstream
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	clientIP = net.ParseIP("10.0.0.1")
+	serverIP = net.ParseIP("10.0.0.2")
+	t0       = time.Unix(1600000000, 0)
+)
+
+// recordingDecoder records the payloads of a stream, and fails when receiving "error"
+type recordingDecoder struct {
+	client, server string
+	flushed        bool
+}
+
+func (d *recordingDecoder) Write(fromClient bool, data []byte, _ time.Time) error {
+	if string(data) == "error" {
+		return assert.AnError
+	}
+	if fromClient {
+		d.client += string(data)
+	} else {
+		d.server += string(data)
+	}
+	return nil
+}
+
+func (d *recordingDecoder) Flush() {
+	d.flushed = true
+}
+
+// testConnection feeds the packets of a connection to a Tracker
+type testConnection struct {
+	t         *testing.T
+	parser    *Parser
+	tracker   *Tracker
+	telemetry *Telemetry
+	decoders  []*recordingDecoder
+
+	client, server         net.IP
+	clientPort, serverPort uint16
+	clientSeq, serverSeq   uint32
+}
+
+func newTestConnection(t *testing.T, serverPort uint16) *testConnection {
+	c := &testConnection{
+		t:          t,
+		parser:     NewParser(layers.LayerTypeEthernet),
+		telemetry:  &Telemetry{},
+		client:     clientIP,
+		server:     serverIP,
+		clientPort: 40000,
+		serverPort: serverPort,
+		clientSeq:  1000,
+		serverSeq:  5000,
+	}
+	isServerPort := func(port uint16) bool { return port == 80 }
+	newDecoder := func(conn Connection) Decoder {
+		assert.Equal(t, uint16(80), conn.ServerPort)
+		assert.Equal(t, c.clientPort, conn.ClientPort)
+		d := &recordingDecoder{}
+		c.decoders = append(c.decoders, d)
+		return d
+	}
+	c.tracker = NewTracker(isServerPort, newDecoder, c.telemetry)
+	return c
+}
+
+func (c *testConnection) send(fromClient bool, tcp layers.TCP, payload string) {
+	src, dst := c.server, c.client
+	tcp.SrcPort, tcp.DstPort = layers.TCPPort(c.serverPort), layers.TCPPort(c.clientPort)
+	seq := &c.serverSeq
+	if fromClient {
+		src, dst = c.client, c.server
+		tcp.SrcPort, tcp.DstPort = tcp.DstPort, tcp.SrcPort
+		seq = &c.clientSeq
+	}
+	tcp.Seq = *seq
+	tcp.ACK = true
+	tcp.Window = 1024
+	*seq += uint32(len(payload))
+	if tcp.SYN || tcp.FIN {
+		*seq++
+	}
+
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: src, DstIP: dst}
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 1},
+		DstMAC:       net.HardwareAddr{0, 0, 0, 0, 0, 2},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	require.NoError(c.t, tcp.SetNetworkLayerForChecksum(ip))
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(c.t, gopacket.SerializeLayers(buf, opts, eth, ip, &tcp, gopacket.Payload(payload)))
+
+	var s Segment
+	ok, err := c.parser.Parse(buf.Bytes(), t0, &s)
+	require.NoError(c.t, err)
+	require.True(c.t, ok)
+	c.tracker.Process(&s)
+}
+
+func (c *testConnection) handshake() {
+	c.send(true, layers.TCP{SYN: true}, "")
+	c.send(false, layers.TCP{SYN: true}, "")
+}
+
+func TestFlow(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.handshake()
+	c.send(true, layers.TCP{PSH: true}, "request")
+	c.send(false, layers.TCP{PSH: true}, "response")
+
+	require.Len(t, c.decoders, 1)
+	d := c.decoders[0]
+	assert.Equal(t, "request", d.client)
+	assert.Equal(t, "response", d.server)
+	assert.False(t, d.flushed)
+
+	c.send(true, layers.TCP{FIN: true}, "")
+	assert.Empty(t, c.tracker.flows)
+	assert.True(t, d.flushed)
+}
+
+func TestFlowCapturedMidStream(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.clientSeq, c.serverSeq = 123456, 654321
+
+	// the reply to a request sent before the capture started is ignored
+	c.send(false, layers.TCP{PSH: true}, "response")
+	assert.Empty(t, c.tracker.flows)
+
+	c.send(true, layers.TCP{PSH: true}, "request")
+	c.send(false, layers.TCP{PSH: true}, "response")
+	require.Len(t, c.decoders, 1)
+	assert.Equal(t, "request", c.decoders[0].client)
+	assert.Equal(t, "response", c.decoders[0].server)
+}
+
+func TestRetransmission(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.handshake()
+
+	seq := c.clientSeq
+	c.send(true, layers.TCP{PSH: true}, "first")
+	// the segment is sent again, and followed by new data in the same segment
+	c.clientSeq = seq
+	c.send(true, layers.TCP{PSH: true}, "first,second")
+	// the segment is entirely retransmitted
+	c.clientSeq = seq
+	c.send(true, layers.TCP{PSH: true}, "first")
+
+	require.Len(t, c.decoders, 1)
+	assert.Equal(t, "first,second", c.decoders[0].client)
+}
+
+func TestLostSegment(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.handshake()
+
+	c.send(true, layers.TCP{PSH: true}, "request")
+	// the response is lost
+	c.serverSeq += 5
+	c.send(false, layers.TCP{PSH: true}, "response")
+	assert.Empty(t, c.tracker.flows)
+	assert.Equal(t, int64(1), c.telemetry.StreamGaps)
+	require.Len(t, c.decoders, 1)
+	assert.True(t, c.decoders[0].flushed)
+
+	// decoding starts again with the next request
+	c.send(true, layers.TCP{PSH: true}, "request")
+	c.send(false, layers.TCP{PSH: true}, "response")
+	require.Len(t, c.decoders, 2)
+	assert.Equal(t, "request", c.decoders[1].client)
+	assert.Equal(t, "response", c.decoders[1].server)
+}
+
+func TestDecodingError(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.handshake()
+
+	c.send(true, layers.TCP{PSH: true}, "error")
+	assert.Empty(t, c.tracker.flows)
+	assert.Equal(t, int64(1), c.telemetry.DecodingErrors)
+}
+
+func TestTupleReuse(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.handshake()
+	c.send(true, layers.TCP{PSH: true}, "request")
+
+	c.clientSeq += 100000
+	c.handshake()
+	require.Len(t, c.decoders, 2)
+	assert.True(t, c.decoders[0].flushed)
+	assert.Len(t, c.tracker.flows, 1)
+}
+
+func TestFlowExpiration(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.handshake()
+	require.Len(t, c.tracker.flows, 1)
+
+	c.tracker.RemoveExpired(t0.Add(time.Minute))
+	assert.Len(t, c.tracker.flows, 1)
+	c.tracker.RemoveExpired(t0.Add(flowExpiration + time.Second))
+	assert.Empty(t, c.tracker.flows)
+	assert.True(t, c.decoders[0].flushed)
+}
+
+func TestMaxFlows(t *testing.T) {
+	c := newTestConnection(t, 80)
+	c.tracker.maxFlows = 1
+	c.handshake()
+
+	// both the SYN and the SYN-ACK of the second connection are dropped
+	c.clientPort++
+	c.handshake()
+	assert.Len(t, c.tracker.flows, 1)
+	assert.Equal(t, int64(2), c.telemetry.FlowsDropped)
+}
+
+func TestIgnoredPorts(t *testing.T) {
+	c := newTestConnection(t, 8080)
+	c.handshake()
+	c.send(true, layers.TCP{PSH: true}, "request")
+	assert.Empty(t, c.tracker.flows)
+}
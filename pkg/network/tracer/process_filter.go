@@ -0,0 +1,63 @@
+// +build linux_bpf
+
+package tracer
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/tagger/remote"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// processExcluder excludes the connections of the processes matching the process filters
+type processExcluder struct {
+	filters  []*network.ProcessFilter
+	resolver *network.ProcessInfoResolver
+	tagger   *remote.Tagger // only set when a filter matches on the container tags
+}
+
+func newProcessExcluder(cfg *config.Config) *processExcluder {
+	filters := network.ParseProcessFilters(cfg.ExcludedProcesses)
+	if len(filters) == 0 {
+		return nil
+	}
+
+	e := &processExcluder{filters: filters}
+	var containerTags network.ContainerTagsFunc
+	if network.ProcessFiltersNeedContainerTags(filters) {
+		// the tags of the containers are retrieved from the core agent
+		e.tagger = remote.NewTagger()
+		go func() {
+			if err := e.tagger.Init(); err != nil {
+				log.Errorf("failed to init the tagger used by the process filters: %s", err)
+			}
+		}()
+		containerTags = func(containerID string) []string {
+			tags, _ := e.tagger.Tag("container_id://"+containerID, collectors.HighCardinality)
+			return tags
+		}
+	}
+	e.resolver = network.NewProcessInfoResolver(cfg.ProcRoot, containerTags)
+	log.Infof("excluding the connections of the processes matching %d process filters", len(filters))
+	return e
+}
+
+// isExcluded returns whether the connection belongs to a process matching the process filters
+func (e *processExcluder) isExcluded(conn *network.ConnectionStats) bool {
+	if e == nil {
+		return false
+	}
+	return network.IsExcludedProcess(e.filters, e.resolver.Resolve(conn.Pid, time.Now()))
+}
+
+func (e *processExcluder) stop() {
+	if e == nil || e.tagger == nil {
+		return
+	}
+	if err := e.tagger.Stop(); err != nil {
+		log.Debugf("error stopping the tagger used by the process filters: %s", err)
+	}
+}
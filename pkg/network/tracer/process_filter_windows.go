@@ -0,0 +1,25 @@
+// +build windows,npm
+
+package tracer
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// processExcluder is a no-op on Windows: the connections can not be filtered by process yet
+type processExcluder struct{}
+
+func newProcessExcluder(cfg *config.Config) *processExcluder {
+	if len(cfg.ExcludedProcesses) > 0 {
+		log.Warn("system_probe_config.process_excludes is not supported on Windows and will be ignored")
+	}
+	return nil
+}
+
+func (e *processExcluder) isExcluded(conn *network.ConnectionStats) bool {
+	return false
+}
+
+func (e *processExcluder) stop() {}
@@ -17,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/network"
 	"github.com/DataDog/datadog-agent/pkg/network/config"
 	"github.com/DataDog/datadog-agent/pkg/network/config/sysctl"
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	netebpf "github.com/DataDog/datadog-agent/pkg/network/ebpf"
 	"github.com/DataDog/datadog-agent/pkg/network/ebpf/probes"
@@ -35,12 +36,19 @@ import (
 
 const defaultUDPConnTimeoutNanoSeconds = uint64(time.Duration(120) * time.Second)
 
+// httpMonitor is implemented by http.Monitor, which relies on eBPF, and by http.PacketMonitor, its packet capture fallback
+type httpMonitor interface {
+	GetHTTPStats() map[http.Key]http.RequestStats
+	Stop()
+}
+
 type Tracer struct {
 	config      *config.Config
 	state       network.State
 	conntracker netlink.Conntracker
 	reverseDNS  dns.ReverseDNS
-	httpMonitor *http.Monitor
+	httpMonitor httpMonitor
+	dbMonitor   *database.Monitor
 	ebpfTracer  connection.Tracer
 
 	// Telemetry
@@ -71,6 +79,8 @@ type Tracer struct {
 	// Connections for the tracer to exclude
 	sourceExcludes []*network.ConnectionFilter
 	destExcludes   []*network.ConnectionFilter
+	// Processes of which the connections are excluded
+	processExcludes *processExcluder
 
 	gwLookup *gatewayLookup
 
@@ -141,6 +151,7 @@ func NewTracer(config *config.Config) (*Tracer, error) {
 		config.MaxConnectionsStateBuffered,
 		config.MaxDNSStatsBuffered,
 		config.MaxHTTPStatsBuffered,
+		config.MaxDatabaseStatsBuffered,
 	)
 
 	tr := &Tracer{
@@ -148,11 +159,13 @@ func NewTracer(config *config.Config) (*Tracer, error) {
 		state:                      state,
 		reverseDNS:                 newReverseDNS(!pre410Kernel, config),
 		httpMonitor:                newHTTPMonitor(!pre410Kernel, config, ebpfTracer, constantEditors),
+		dbMonitor:                  newDatabaseMonitor(config),
 		activeBuffer:               network.NewConnectionBuffer(512),
 		closedBuffer:               network.NewConnectionBuffer(512),
 		conntracker:                conntracker,
 		sourceExcludes:             network.ParseConnectionFilters(config.ExcludedSourceConnections),
 		destExcludes:               network.ParseConnectionFilters(config.ExcludedDestinationConnections),
+		processExcludes:            newProcessExcluder(config),
 		buf:                        make([]byte, network.ConnectionByteKeyMaxLen),
 		sysctlUDPConnTimeout:       sysctl.NewInt(config.ProcRoot, "net/netfilter/nf_conntrack_udp_timeout", time.Minute),
 		sysctlUDPConnStreamTimeout: sysctl.NewInt(config.ProcRoot, "net/netfilter/nf_conntrack_udp_timeout_stream", time.Minute),
@@ -276,8 +289,12 @@ func (t *Tracer) closedCB(cs *network.ConnectionStats) (keep bool) {
 func (t *Tracer) Stop() {
 	t.reverseDNS.Close()
 	t.ebpfTracer.Stop()
-	t.httpMonitor.Stop()
+	if t.httpMonitor != nil {
+		t.httpMonitor.Stop()
+	}
+	t.dbMonitor.Stop()
 	t.conntracker.Close()
+	t.processExcludes.stop()
 }
 
 func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, error) {
@@ -292,7 +309,7 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 		return nil, fmt.Errorf("error retrieving connections: %s", err)
 	}
 
-	delta := t.state.GetDelta(clientID, latestTime, active, closed, t.reverseDNS.GetDNSStats(), t.httpMonitor.GetHTTPStats())
+	delta := t.state.GetDelta(clientID, latestTime, active, closed, t.reverseDNS.GetDNSStats(), t.getHTTPStats(), t.dbMonitor.GetDatabaseStats())
 	t.activeBuffer.Reset()
 	t.closedBuffer.Reset()
 
@@ -309,11 +326,28 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 		DNS:                         names,
 		DNSStats:                    delta.DNSStats,
 		HTTP:                        delta.HTTP,
+		Database:                    delta.Database,
 		ConnTelemetry:               ctm,
 		CompilationTelemetryByAsset: rctm,
 	}, nil
 }
 
+// GetDNSSnapshot returns the DNS stats collected since its previous call, without registering a client
+func (t *Tracer) GetDNSSnapshot() (network.DNSSnapshot, error) {
+	t.bufferLock.Lock()
+	defer t.bufferLock.Unlock()
+
+	_, err := t.getConnections(t.activeBuffer, t.closedBuffer)
+	if err != nil {
+		return network.DNSSnapshot{}, fmt.Errorf("error retrieving connections: %s", err)
+	}
+
+	snapshot := t.state.GetDNSSnapshot(t.activeBuffer.Connections(), t.closedBuffer.Connections(), t.reverseDNS.GetDNSStats())
+	t.activeBuffer.Reset()
+	t.closedBuffer.Reset()
+	return snapshot, nil
+}
+
 func (t *Tracer) getConnTelemetry(mapSize int) *network.ConnectionsTelemetry {
 	kprobeStats := ddebpf.GetProbeTotals()
 	tm := &network.ConnectionsTelemetry{
@@ -566,11 +600,13 @@ func (t *Tracer) DebugEBPFMaps(maps ...string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if t.httpMonitor == nil {
+	// the packet capture fallback of the HTTP monitor has no eBPF map
+	monitor, ok := t.httpMonitor.(*http.Monitor)
+	if !ok {
 		return "tracer:\n" + tracerMaps, nil
 	}
 
-	httpMaps, err := t.httpMonitor.DumpMaps(maps...)
+	httpMaps, err := monitor.DumpMaps(maps...)
 	if err != nil {
 		return "", err
 	}
@@ -617,34 +653,75 @@ func (t *Tracer) connVia(cs *network.ConnectionStats) {
 	cs.Via = t.gwLookup.Lookup(cs)
 }
 
-func newHTTPMonitor(supported bool, c *config.Config, tracer connection.Tracer, offsets []manager.ConstantEditor) *http.Monitor {
+func (t *Tracer) getHTTPStats() map[http.Key]http.RequestStats {
+	if t.httpMonitor == nil {
+		return nil
+	}
+	return t.httpMonitor.GetHTTPStats()
+}
+
+func newHTTPMonitor(supported bool, c *config.Config, tracer connection.Tracer, offsets []manager.ConstantEditor) httpMonitor {
 	if !c.EnableHTTPMonitoring {
 		return nil
 	}
 
 	if !supported {
 		log.Warnf("http monitoring is not supported by this kernel version. please refer to system-probe's documentation")
-		return nil
+		return newHTTPPacketMonitor(c)
 	}
 	// Shared with the HTTP program
 	sockFDMap := tracer.GetMap(string(probes.SockByPidFDMap))
 	monitor, err := http.NewMonitor(c, offsets, sockFDMap)
 	if err != nil {
 		log.Errorf("could not instantiate http monitor: %s", err)
-		return nil
+		return newHTTPPacketMonitor(c)
 	}
 
 	err = monitor.Start()
 	if errors.Is(err, syscall.ENOMEM) {
 		log.Error("could not enable http monitoring: not enough memory to attach http ebpf socket filter. please consider raising the limit via sysctl -w net.core.optmem_max=<LIMIT>")
+	} else if err != nil {
+		log.Errorf("could not enable http monitoring: %s", err)
+	}
+
+	if err != nil {
+		monitor.Stop()
+		return newHTTPPacketMonitor(c)
+	}
+
+	log.Info("http monitoring enabled")
+	return monitor
+}
+
+// newHTTPPacketMonitor returns the packet capture fallback of the HTTP monitor, if it is enabled
+func newHTTPPacketMonitor(c *config.Config) httpMonitor {
+	if !c.EnableHTTPMonitoringFallback {
 		return nil
 	}
 
+	monitor, err := http.NewPacketMonitor(c)
 	if err != nil {
-		log.Errorf("could not enable http monitoring: %s", err)
+		log.Errorf("could not enable http monitoring fallback: %s", err)
 		return nil
 	}
 
-	log.Info("http monitoring enabled")
+	monitor.Start()
+	log.Infof("http monitoring enabled with packet capture (ports: %v)", c.HTTPPorts)
+	return monitor
+}
+
+func newDatabaseMonitor(c *config.Config) *database.Monitor {
+	if !c.EnablePostgresMonitoring && !c.EnableRedisMonitoring {
+		return nil
+	}
+
+	monitor, err := database.NewMonitor(c)
+	if err != nil {
+		log.Errorf("could not enable database monitoring: %s", err)
+		return nil
+	}
+
+	monitor.Start()
+	log.Infof("database monitoring enabled (postgres: %t, redis: %t)", c.EnablePostgresMonitoring, c.EnableRedisMonitoring)
 	return monitor
 }
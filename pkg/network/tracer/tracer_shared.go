@@ -8,12 +8,16 @@ import (
 
 // shouldSkipConnection returns whether or not the tracer should ignore a given connection:
 //  • Local DNS (*:53) requests if configured (default: true)
+//  • Connections matching the user-defined connection filters
+//  • Connections of the processes matching the user-defined process filters
 func (t *Tracer) shouldSkipConnection(conn *network.ConnectionStats) bool {
 	isDNSConnection := conn.DPort == 53 || conn.SPort == 53
 	if !t.config.CollectLocalDNS && isDNSConnection && conn.Dest.IsLoopback() {
 		return true
 	} else if network.IsExcludedConnection(t.sourceExcludes, t.destExcludes, conn) {
 		return true
+	} else if t.processExcludes.isExcluded(conn) {
+		return true
 	}
 	return false
 }
@@ -683,6 +683,32 @@ func TestSkipConnectionDNS(t *testing.T) {
 	})
 }
 
+func TestSkipConnectionProcess(t *testing.T) {
+	cfg := testConfig()
+	cfg.ExcludedProcesses = []config.ProcessExcludeRule{{ProcessName: "^envoy$"}}
+	tr := &Tracer{config: cfg, processExcludes: newProcessExcluder(cfg)}
+	defer tr.processExcludes.stop()
+
+	// the test process is not excluded
+	assert.False(t, tr.shouldSkipConnection(&network.ConnectionStats{
+		Source: util.AddressFromString("10.0.0.1"),
+		Dest:   util.AddressFromString("10.0.0.2"),
+		SPort:  1000, DPort: 8080,
+		Pid: uint32(os.Getpid()),
+	}))
+
+	cfg.ExcludedProcesses = []config.ProcessExcludeRule{{Cmdline: regexp.QuoteMeta(os.Args[0])}}
+	tr = &Tracer{config: cfg, processExcludes: newProcessExcluder(cfg)}
+	defer tr.processExcludes.stop()
+
+	assert.True(t, tr.shouldSkipConnection(&network.ConnectionStats{
+		Source: util.AddressFromString("10.0.0.1"),
+		Dest:   util.AddressFromString("10.0.0.2"),
+		SPort:  1000, DPort: 8080,
+		Pid: uint32(os.Getpid()),
+	}))
+}
+
 func byAddress(l, r net.Addr) func(c network.ConnectionStats) bool {
 	return func(c network.ConnectionStats) bool {
 		return addrMatches(l, c.Source.String(), c.SPort) && addrMatches(r, c.Dest.String(), c.DPort)
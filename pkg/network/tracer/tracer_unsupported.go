@@ -24,6 +24,11 @@ func (t *Tracer) GetActiveConnections(_ string) (*network.Connections, error) {
 	return nil, ebpf.ErrNotImplemented
 }
 
+// GetDNSSnapshot is not implemented on this OS for Tracer
+func (t *Tracer) GetDNSSnapshot() (network.DNSSnapshot, error) {
+	return network.DNSSnapshot{}, ebpf.ErrNotImplemented
+}
+
 // GetStats is not implemented on this OS for Tracer
 func (t *Tracer) GetStats() (map[string]interface{}, error) {
 	return nil, ebpf.ErrNotImplemented
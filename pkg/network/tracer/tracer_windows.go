@@ -43,6 +43,8 @@ type Tracer struct {
 	// Connections for the tracer to exclude
 	sourceExcludes []*network.ConnectionFilter
 	destExcludes   []*network.ConnectionFilter
+	// Processes of which the connections are excluded
+	processExcludes *processExcluder
 }
 
 // NewTracer returns an initialized tracer struct
@@ -62,6 +64,7 @@ func NewTracer(config *config.Config) (*Tracer, error) {
 		config.MaxConnectionsStateBuffered,
 		config.MaxDNSStatsBuffered,
 		config.MaxHTTPStatsBuffered,
+		config.MaxDatabaseStatsBuffered,
 	)
 
 	reverseDNS := dns.NewNullReverseDNS()
@@ -83,6 +86,7 @@ func NewTracer(config *config.Config) (*Tracer, error) {
 		reverseDNS:      reverseDNS,
 		sourceExcludes:  network.ParseConnectionFilters(config.ExcludedSourceConnections),
 		destExcludes:    network.ParseConnectionFilters(config.ExcludedDestinationConnections),
+		processExcludes: newProcessExcluder(config),
 	}
 
 	return tr, nil
@@ -119,7 +123,7 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 	// check for expired clients in the state
 	t.state.RemoveExpiredClients(time.Now())
 
-	delta := t.state.GetDelta(clientID, uint64(time.Now().Nanosecond()), activeConnStats, closedConnStats, t.reverseDNS.GetDNSStats(), nil)
+	delta := t.state.GetDelta(clientID, uint64(time.Now().Nanosecond()), activeConnStats, closedConnStats, t.reverseDNS.GetDNSStats(), nil, nil)
 	var ips []util.Address
 	for _, conn := range delta.Conns {
 		ips = append(ips, conn.Source, conn.Dest)
@@ -132,6 +136,24 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 	}, nil
 }
 
+// GetDNSSnapshot returns the DNS stats collected since its previous call, without registering a client
+func (t *Tracer) GetDNSSnapshot() (network.DNSSnapshot, error) {
+	t.connLock.Lock()
+	defer t.connLock.Unlock()
+
+	t.activeBuffer.Reset()
+	t.closedBuffer.Reset()
+
+	_, _, err := t.driverInterface.GetConnectionStats(t.activeBuffer, t.closedBuffer, func(c *network.ConnectionStats) bool {
+		return !t.shouldSkipConnection(c)
+	})
+	if err != nil {
+		return network.DNSSnapshot{}, fmt.Errorf("error retrieving connections from driver: %w", err)
+	}
+
+	return t.state.GetDNSSnapshot(t.activeBuffer.Connections(), t.closedBuffer.Connections(), t.reverseDNS.GetDNSStats()), nil
+}
+
 // GetStats returns a map of statistics about the current tracer's internal state
 func (t *Tracer) GetStats() (map[string]interface{}, error) {
 	driverStats, err := t.driverInterface.GetStats()
@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/ebpf/probe"
+	"github.com/DataDog/datadog-agent/pkg/network"
 )
 
 const (
@@ -48,6 +49,13 @@ func (r *RemoteSysProbeUtil) GetCheck(check string) (interface{}, error) {
 			return nil, err
 		}
 		return stats, nil
+	} else if check == "network_dns" {
+		var stats []network.DNSFailures
+		err = json.Unmarshal(body, &stats)
+		if err != nil {
+			return nil, err
+		}
+		return stats, nil
 	}
 
 	return nil, fmt.Errorf("Invalid check name: %s", check)
@@ -9,6 +9,8 @@ import (
 	"bytes"
 
 	jsoniter "github.com/json-iterator/go"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // Marshaler is an interface for metrics that are able to serialize themselves to JSON and protobuf
@@ -34,6 +36,8 @@ type BufferContext struct {
 	CompressorInput   *bytes.Buffer
 	CompressorOutput  *bytes.Buffer
 	PrecompressionBuf *bytes.Buffer
+	// Codec compresses the payloads, it must support streams
+	Codec compression.Codec
 }
 
 // DefaultBufferContext initialize the default compression buffers
@@ -42,5 +46,6 @@ func DefaultBufferContext() *BufferContext {
 		bytes.NewBuffer(make([]byte, 0, 1024)),
 		bytes.NewBuffer(make([]byte, 0, 1024)),
 		bytes.NewBuffer(make([]byte, 0, 1024)),
+		compression.DefaultCodec(),
 	}
 }
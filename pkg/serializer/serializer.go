@@ -123,6 +123,12 @@ type Serializer struct {
 	enableServiceChecksJSONStream bool
 	enableEventsJSONStream        bool
 	enableSketchProtobufStream    bool
+
+	// codec is the compression method used instead of the one selected at
+	// build time, nil if the default compression method is used.
+	codec                               compression.Codec
+	jsonExtraHeadersWithCompression     http.Header
+	protobufExtraHeadersWithCompression http.Header
 }
 
 // NewSerializer returns a new Serializer initialized
@@ -158,9 +164,78 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder forwarde
 		log.Warn("JSON to V1 intake is disabled: all payloads to that endpoint will be dropped")
 	}
 
+	if kind := config.Datadog.GetString("serializer_compressor_kind"); kind != "" {
+		if err := s.setCodec(kind); err != nil {
+			log.Errorf("Cannot use the compression codec %q, falling back to the default one: %s", kind, err)
+		}
+	}
+
 	return s
 }
 
+// setCodec makes the serializer compress its payloads with the codec registered
+// under the given name instead of the one selected at build time
+func (s *Serializer) setCodec(kind string) error {
+	codec, err := compression.GetCodec(kind)
+	if err != nil {
+		return err
+	}
+	if codec.ContentEncoding() == compression.ContentEncoding {
+		return nil
+	}
+
+	s.codec = codec
+	s.jsonExtraHeadersWithCompression = withContentEncoding(jsonExtraHeaders, codec.ContentEncoding())
+	s.protobufExtraHeadersWithCompression = withContentEncoding(protobufExtraHeaders, codec.ContentEncoding())
+
+	if compression.SupportsStream(codec) {
+		s.seriesJSONPayloadBuilder = stream.NewJSONPayloadBuilderWithCodec(config.Datadog.GetBool("enable_json_stream_shared_compressor_buffers"), codec)
+		return nil
+	}
+
+	if s.enableJSONStream || s.enableServiceChecksJSONStream || s.enableEventsJSONStream || s.enableSketchProtobufStream {
+		log.Infof("Stream payload serialization is disabled as the %q compression codec does not support streams", kind)
+	}
+	s.enableJSONStream = false
+	s.enableServiceChecksJSONStream = false
+	s.enableEventsJSONStream = false
+	s.enableSketchProtobufStream = false
+	return nil
+}
+
+// withContentEncoding returns a copy of headers with the given Content-Encoding
+func withContentEncoding(headers http.Header, contentEncoding string) http.Header {
+	h := headers.Clone()
+	if contentEncoding != "" {
+		h.Set("Content-Encoding", contentEncoding)
+	}
+	return h
+}
+
+// compressionCodec returns the codec used to compress the payloads
+func (s Serializer) compressionCodec() compression.Codec {
+	if s.codec != nil {
+		return s.codec
+	}
+	return compression.DefaultCodec()
+}
+
+// jsonHeadersWithCompression returns the headers of the compressed JSON payloads
+func (s Serializer) jsonHeadersWithCompression() http.Header {
+	if s.jsonExtraHeadersWithCompression != nil {
+		return s.jsonExtraHeadersWithCompression
+	}
+	return jsonExtraHeadersWithCompression
+}
+
+// protobufHeadersWithCompression returns the headers of the compressed protobuf payloads
+func (s Serializer) protobufHeadersWithCompression() http.Header {
+	if s.protobufExtraHeadersWithCompression != nil {
+		return s.protobufExtraHeadersWithCompression
+	}
+	return protobufExtraHeadersWithCompression
+}
+
 func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool, useV1API bool) (forwarder.Payloads, http.Header, error) {
 	var marshalType split.MarshalType
 	var extraHeaders http.Header
@@ -168,20 +243,24 @@ func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool,
 	if useV1API {
 		marshalType = split.MarshalJSON
 		if compress {
-			extraHeaders = jsonExtraHeadersWithCompression
+			extraHeaders = s.jsonHeadersWithCompression()
 		} else {
 			extraHeaders = jsonExtraHeaders
 		}
 	} else {
 		marshalType = split.Marshal
 		if compress {
-			extraHeaders = protobufExtraHeadersWithCompression
+			extraHeaders = s.protobufHeadersWithCompression()
 		} else {
 			extraHeaders = protobufExtraHeaders
 		}
 	}
 
-	payloads, err := split.Payloads(payload, compress, marshalType)
+	var codec compression.Codec
+	if compress {
+		codec = s.compressionCodec()
+	}
+	payloads, err := split.PayloadsWithCodec(payload, codec, marshalType)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
@@ -192,7 +271,7 @@ func (s Serializer) serializePayload(payload marshaler.Marshaler, compress bool,
 
 func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
 	payloads, err := s.seriesJSONPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy)
-	return payloads, jsonExtraHeadersWithCompression, err
+	return payloads, s.jsonHeadersWithCompression(), err
 }
 
 // As events are gathered by SourceType, the serialization logic is more complex than for the other serializations.
@@ -320,9 +399,11 @@ func (s *Serializer) SendSketch(sketches marshaler.Marshaler) error {
 	}
 
 	if s.enableSketchProtobufStream {
-		payloads, err := sketches.MarshalSplitCompress(marshaler.DefaultBufferContext())
+		bufferContext := marshaler.DefaultBufferContext()
+		bufferContext.Codec = s.compressionCodec()
+		payloads, err := sketches.MarshalSplitCompress(bufferContext)
 		if err == nil {
-			return s.Forwarder.SubmitSketchSeries(payloads, protobufExtraHeadersWithCompression)
+			return s.Forwarder.SubmitSketchSeries(payloads, s.protobufHeadersWithCompression())
 		}
 		log.Warnf("Error: %v trying to stream compress SketchSeriesList - falling back to split/compress method", err)
 	}
@@ -353,7 +434,7 @@ func (s *Serializer) SendAgentchecksMetadata(m marshaler.Marshaler) error {
 }
 
 func (s *Serializer) sendMetadata(m marshaler.Marshaler, submit func(payload forwarder.Payloads, extra http.Header) error) error {
-	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerialize(m, true, split.MarshalJSON)
+	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerializeWithCodec(m, s.compressionCodec(), split.MarshalJSON)
 	if err != nil {
 		return fmt.Errorf("could not determine size of metadata payload: %s", err)
 	}
@@ -364,7 +445,7 @@ func (s *Serializer) sendMetadata(m marshaler.Marshaler, submit func(payload for
 		return fmt.Errorf("metadata payload was too big to send (%d bytes compressed, %d bytes uncompressed), metadata payloads cannot be split", len(compressedPayload), len(payload))
 	}
 
-	if err := submit(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
+	if err := submit(forwarder.Payloads{&compressedPayload}, s.jsonHeadersWithCompression()); err != nil {
 		return err
 	}
 
@@ -384,11 +465,11 @@ func (s *Serializer) SendProcessesMetadata(data interface{}) error {
 	if err != nil {
 		return fmt.Errorf("could not serialize processes metadata payload: %s", err)
 	}
-	compressedPayload, err := compression.Compress(nil, payload)
+	compressedPayload, err := s.compressionCodec().Compress(nil, payload)
 	if err != nil {
 		return fmt.Errorf("could not compress processes metadata payload: %s", err)
 	}
-	if err := s.Forwarder.SubmitV1Intake(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
+	if err := s.Forwarder.SubmitV1Intake(forwarder.Payloads{&compressedPayload}, s.jsonHeadersWithCompression()); err != nil {
 		return err
 	}
 
@@ -84,6 +84,30 @@ func TestInitExtraHeadersWithCompression(t *testing.T) {
 	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
 }
 
+func TestSetCodec(t *testing.T) {
+	kind := compression.ZlibKind
+	if compression.DefaultCodec().Name() == compression.ZlibKind {
+		kind = compression.NoneKind
+	}
+	codec, err := compression.GetCodec(kind)
+	require.NoError(t, err)
+
+	s := NewSerializer(nil, nil)
+	enableJSONStream := s.enableJSONStream
+	enableSketchProtobufStream := s.enableSketchProtobufStream
+	require.NoError(t, s.setCodec(kind))
+
+	assert.Equal(t, codec, s.compressionCodec())
+	assert.Equal(t, codec.ContentEncoding(), s.jsonHeadersWithCompression().Get("Content-Encoding"))
+	assert.Equal(t, codec.ContentEncoding(), s.protobufHeadersWithCompression().Get("Content-Encoding"))
+	assert.Equal(t, protobufContentType, s.protobufHeadersWithCompression().Get("Content-Type"))
+	// the codec supports streams, the stream serialization is kept
+	assert.Equal(t, enableJSONStream, s.enableJSONStream)
+	assert.Equal(t, enableSketchProtobufStream, s.enableSketchProtobufStream)
+
+	assert.Error(t, s.setCodec("unknown"))
+}
+
 func TestAgentPayloadVersion(t *testing.T) {
 	assert.NotEmpty(t, AgentPayloadVersion, "AgentPayloadVersion is empty, indicates that the package was not built correctly")
 }
@@ -55,10 +55,25 @@ func init() {
 
 }
 
+// defaultCodec returns the codec selected at build time if compress is true,
+// nil otherwise
+func defaultCodec(compress bool) compression.Codec {
+	if !compress {
+		return nil
+	}
+	return compression.DefaultCodec()
+}
+
 // CheckSizeAndSerialize Check the size of a payload and marshall it (optionally compress it)
 // The dual role makes sense as you will never serialize without checking the size of the payload
 func CheckSizeAndSerialize(m marshaler.Marshaler, compress bool, mType MarshalType) (bool, []byte, []byte, error) {
-	compressedPayload, payload, err := serializeMarshaller(m, compress, mType)
+	return CheckSizeAndSerializeWithCodec(m, defaultCodec(compress), mType)
+}
+
+// CheckSizeAndSerializeWithCodec is like CheckSizeAndSerialize but compresses the payload
+// with the given codec. A nil codec means no compression.
+func CheckSizeAndSerializeWithCodec(m marshaler.Marshaler, codec compression.Codec, mType MarshalType) (bool, []byte, []byte, error) {
+	compressedPayload, payload, err := serializeMarshaller(m, codec, mType)
 	if err != nil {
 		return false, nil, nil, err
 	}
@@ -70,9 +85,15 @@ func CheckSizeAndSerialize(m marshaler.Marshaler, compress bool, mType MarshalTy
 
 // Payloads serializes a metadata payload and sends it to the forwarder
 func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarder.Payloads, error) {
+	return PayloadsWithCodec(m, defaultCodec(compress), mType)
+}
+
+// PayloadsWithCodec is like Payloads but compresses the payloads with the given codec.
+// A nil codec means no compression.
+func PayloadsWithCodec(m marshaler.Marshaler, codec compression.Codec, mType MarshalType) (forwarder.Payloads, error) {
 	marshallers := []marshaler.Marshaler{m}
 	smallEnoughPayloads := forwarder.Payloads{}
-	tooBig, compressedPayload, _, err := CheckSizeAndSerialize(m, compress, mType)
+	tooBig, compressedPayload, _, err := CheckSizeAndSerializeWithCodec(m, codec, mType)
 	if err != nil {
 		return smallEnoughPayloads, err
 	}
@@ -99,7 +120,7 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 		for _, toSplit := range tempSlice {
 			var e error
 			// we have to do this every time to get the proper payload
-			compressedPayload, payload, e := serializeMarshaller(toSplit, compress, mType)
+			compressedPayload, payload, e := serializeMarshaller(toSplit, codec, mType)
 			if e != nil {
 				return smallEnoughPayloads, e
 			}
@@ -121,7 +142,7 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 			// after the payload has been split, loop through the chunks
 			for _, chunk := range chunks {
 				// serialize the payload
-				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerialize(chunk, compress, mType)
+				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerializeWithCodec(chunk, codec, mType)
 				if err != nil {
 					log.Debugf("Error serializing a chunk: %s", err)
 					continue
@@ -155,7 +176,7 @@ func Payloads(m marshaler.Marshaler, compress bool, mType MarshalType) (forwarde
 }
 
 // serializeMarshaller serializes the marshaller and returns both the compressed and uncompressed payloads
-func serializeMarshaller(m marshaler.Marshaler, compress bool, mType MarshalType) ([]byte, []byte, error) {
+func serializeMarshaller(m marshaler.Marshaler, codec compression.Codec, mType MarshalType) ([]byte, []byte, error) {
 	var payload []byte
 	var compressedPayload []byte
 	var err error
@@ -164,8 +185,8 @@ func serializeMarshaller(m marshaler.Marshaler, compress bool, mType MarshalType
 	if err != nil {
 		return nil, nil, err
 	}
-	if compress {
-		compressedPayload, err = compression.Compress(nil, payload)
+	if codec != nil {
+		compressedPayload, err = codec.Compress(nil, payload)
 		if err != nil {
 			return nil, nil, err
 		}
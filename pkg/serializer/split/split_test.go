@@ -97,6 +97,32 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 	require.Equal(t, originalLength, newLength)
 }
 
+func TestSplitPayloadsWithCodec(t *testing.T) {
+	codec, err := compression.GetCodec(compression.ZlibKind)
+	require.NoError(t, err)
+
+	testSeries := metrics.Series{&metrics.Serie{
+		Points: []metrics.Point{{Ts: 12345.0, Value: float64(21.21)}},
+		MType:  metrics.APIGaugeType,
+		Name:   "test.metrics",
+		Host:   "localHost",
+		Tags:   []string{"tag1", "tag2:yes"},
+	}}
+
+	payloads, err := PayloadsWithCodec(testSeries, codec, MarshalJSON)
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+
+	decompressed, err := codec.Decompress(nil, *payloads[0])
+	require.NoError(t, err)
+
+	var s = map[string]metrics.Series{}
+	err = json.Unmarshal(decompressed, &s)
+	require.NoError(t, err)
+	require.Len(t, s["series"], 1)
+	require.Equal(t, "test.metrics", s["series"][0].Name)
+}
+
 var result forwarder.Payloads
 
 func BenchmarkSplitPayloadsSeries(b *testing.B) {
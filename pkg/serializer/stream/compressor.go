@@ -9,7 +9,6 @@ package stream
 
 import (
 	"bytes"
-	"compress/zlib"
 	"errors"
 	"expvar"
 
@@ -63,7 +62,8 @@ func init() {
 type Compressor struct {
 	input               *bytes.Buffer // temporary buffer for data that has not been compressed yet
 	compressed          *bytes.Buffer // output buffer containing the compressed payload
-	zipper              *zlib.Writer
+	zipper              compression.StreamWriter
+	codec               compression.Codec
 	header              []byte // json header to print at the beginning of the payload
 	footer              []byte // json footer to append at the end of the payload
 	uncompressedWritten int    // uncompressed bytes written
@@ -76,7 +76,13 @@ type Compressor struct {
 	separator           []byte
 }
 
+// NewCompressor returns a Compressor using the compression method selected at build time
 func NewCompressor(input, output *bytes.Buffer, header, footer []byte, separator []byte) (*Compressor, error) {
+	return NewCompressorWithCodec(input, output, header, footer, separator, compression.DefaultCodec())
+}
+
+// NewCompressorWithCodec returns a Compressor using the given codec, which must support streams
+func NewCompressorWithCodec(input, output *bytes.Buffer, header, footer []byte, separator []byte, codec compression.Codec) (*Compressor, error) {
 	// the backend accepts payloads up to 3MB compressed / 50MB uncompressed but
 	// prefers small uncompressed payloads of ~4MB
 	maxPayloadSize := config.Datadog.GetInt("serializer_max_payload_size")
@@ -90,11 +96,16 @@ func NewCompressor(input, output *bytes.Buffer, header, footer []byte, separator
 		maxPayloadSize:      maxPayloadSize,
 		maxUncompressedSize: maxUncompressedSize,
 		maxUnzippedItemSize: maxPayloadSize - len(footer) - len(header),
-		maxZippedItemSize:   maxUncompressedSize - compression.CompressBound(len(footer)+len(header)),
+		maxZippedItemSize:   maxUncompressedSize - codec.CompressBound(len(footer)+len(header)),
 		separator:           separator,
+		codec:               codec,
 	}
 
-	c.zipper = zlib.NewWriter(c.compressed)
+	zipper, err := compression.NewStreamWriter(codec, c.compressed)
+	if err != nil {
+		return nil, err
+	}
+	c.zipper = zipper
 	n, err := c.zipper.Write(header)
 	c.uncompressedWritten += n
 
@@ -106,7 +117,7 @@ func NewCompressor(input, output *bytes.Buffer, header, footer []byte, separator
 // that could actually fit after compression. That said it is probably impossible
 // to have a 2MB+ item that is valid for the backend.
 func (c *Compressor) checkItemSize(data []byte) bool {
-	return len(data) < c.maxUnzippedItemSize && compression.CompressBound(len(data)) < c.maxZippedItemSize
+	return len(data) < c.maxUnzippedItemSize && c.codec.CompressBound(len(data)) < c.maxZippedItemSize
 }
 
 // hasRoomForItem checks if the current payload has enough room to store the given item
@@ -115,7 +126,7 @@ func (c *Compressor) hasRoomForItem(item []byte) bool {
 	if !c.firstItem {
 		uncompressedDataSize += len(c.separator)
 	}
-	return compression.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
+	return c.codec.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
 }
 
 // pack flushes the temporary uncompressed buffer input to the compression writer
@@ -188,7 +199,7 @@ func (c *Compressor) Close() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Add zlib footer and close
+	// Add the compression footer and close
 	err = c.zipper.Close()
 	if err != nil {
 		return nil, err
@@ -20,6 +20,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 var (
@@ -183,3 +184,21 @@ func TestLockedCompressorProducesSamePayloads(t *testing.T) {
 
 	require.Equal(t, payloadToString(*payloads1[0]), payloadToString(*payloads2[0]))
 }
+
+func TestPayloadBuilderWithCodec(t *testing.T) {
+	m := &dummyMarshaller{
+		items:  []string{"A", "B", "C"},
+		header: "{[",
+		footer: "]}",
+	}
+
+	codec, err := compression.GetCodec(compression.NoneKind)
+	require.NoError(t, err)
+
+	builder := NewJSONPayloadBuilderWithCodec(true, codec)
+	payloads, err := builder.Build(m)
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+
+	require.Equal(t, "{[A,B,C]}", string(*payloads[0]))
+}
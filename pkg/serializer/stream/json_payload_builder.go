@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -66,9 +67,17 @@ type JSONPayloadBuilder struct {
 	shareAndLockBuffers           bool
 	input, output                 *bytes.Buffer
 	mu                            sync.Mutex
+	codec                         compression.Codec
 }
 
+// NewJSONPayloadBuilder returns a JSONPayloadBuilder using the compression method selected at build time
 func NewJSONPayloadBuilder(shareAndLockBuffers bool) *JSONPayloadBuilder {
+	return NewJSONPayloadBuilderWithCodec(shareAndLockBuffers, compression.DefaultCodec())
+}
+
+// NewJSONPayloadBuilderWithCodec returns a JSONPayloadBuilder compressing the
+// payloads with the given codec, which must support streams
+func NewJSONPayloadBuilderWithCodec(shareAndLockBuffers bool, codec compression.Codec) *JSONPayloadBuilder {
 	if shareAndLockBuffers {
 		return &JSONPayloadBuilder{
 			inputSizeHint:       4096,
@@ -76,12 +85,14 @@ func NewJSONPayloadBuilder(shareAndLockBuffers bool) *JSONPayloadBuilder {
 			shareAndLockBuffers: true,
 			input:               bytes.NewBuffer(make([]byte, 0, 4096)),
 			output:              bytes.NewBuffer(make([]byte, 0, 4096)),
+			codec:               codec,
 		}
 	}
 	return &JSONPayloadBuilder{
 		inputSizeHint:       4096,
 		outputSizeHint:      4096,
 		shareAndLockBuffers: false,
+		codec:               codec,
 	}
 }
 
@@ -151,7 +162,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 		return nil, err
 	}
 
-	compressor, err := NewCompressor(input, output, header.Bytes(), footer.Bytes(), []byte(","))
+	compressor, err := NewCompressorWithCodec(input, output, header.Bytes(), footer.Bytes(), []byte(","), b.codec)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +192,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 			payloads = append(payloads, &payload)
 			input.Reset()
 			output.Reset()
-			compressor, err = NewCompressor(input, output, header.Bytes(), footer.Bytes(), []byte(","))
+			compressor, err = NewCompressorWithCodec(input, output, header.Bytes(), footer.Bytes(), []byte(","), b.codec)
 			if err != nil {
 				return nil, err
 			}
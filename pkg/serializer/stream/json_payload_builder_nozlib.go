@@ -12,6 +12,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // OnErrItemTooBigPolicy defines the behavior when OnErrItemTooBig occurs.
@@ -34,6 +35,11 @@ func NewJSONPayloadBuilder(shareAndLockBuffers bool) *JSONPayloadBuilder {
 	return nil
 }
 
+// NewJSONPayloadBuilderWithCodec is not implemented when zlib is not available.
+func NewJSONPayloadBuilderWithCodec(shareAndLockBuffers bool, codec compression.Codec) *JSONPayloadBuilder {
+	return nil
+}
+
 // BuildWithOnErrItemTooBigPolicy is not implemented when zlib is not available.
 func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(marshaler.StreamJSONMarshaler, OnErrItemTooBigPolicy) (forwarder.Payloads, error) {
 	return nil, fmt.Errorf("not implemented")
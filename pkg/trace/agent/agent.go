@@ -42,10 +42,12 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	TagFilter             *filters.TagFilter
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	ExceptionSampler      *sampler.ExceptionSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
+	TailSampler           *sampler.TailSampler // nil unless tail-based sampling is enabled
 	EventProcessor        *event.Processor
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
@@ -76,6 +78,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		TagFilter:             filters.NewTagFilter(conf.TagFilterRules, conf.DefaultEnv),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		ExceptionSampler:      sampler.NewExceptionSampler(),
@@ -89,7 +92,13 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		ctx:                   ctx,
 	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt)
+	if agnt.Concentrator.Exporter != nil {
+		agnt.Receiver.MetricsHandler = agnt.Concentrator.Exporter.Handler()
+	}
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf.OTLPReceiver)
+	if conf.TailSampling.Enabled {
+		agnt.TailSampler = sampler.NewTailSampler(conf.TailSampling, agnt.sendTailSampled)
+	}
 	return agnt
 }
 
@@ -107,6 +116,10 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
+	go a.reportInfo()
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
@@ -157,6 +170,10 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			if a.TailSampler != nil {
+				// decide the buffered traces while the trace writer is still running
+				a.TailSampler.Stop()
+			}
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
@@ -221,6 +238,17 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		var keep bool
+		if t, keep = a.TagFilter.Filter(t, root); !keep {
+			log.Debugf("Trace rejected by tag filter rules. root: %v", root)
+			atomic.AddInt64(&ts.TracesFiltered, 1)
+			atomic.AddInt64(&ts.SpansFiltered, tracen)
+			continue
+		}
+		if n := tracen - int64(len(t)); n > 0 {
+			atomic.AddInt64(&ts.SpansFiltered, n)
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range t {
 			for k, v := range a.conf.GlobalTags {
@@ -382,9 +410,39 @@ func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) (events []*pb.Span,
 	atomic.AddInt64(&ts.EventsExtracted, int64(numExtracted))
 	atomic.AddInt64(&ts.EventsSampled, int64(len(events)))
 
+	if !sampled && a.TailSampler != nil {
+		// give the dropped traces a second chance, once complete
+		a.TailSampler.Add(pt.Trace)
+	}
 	return events, sampled
 }
 
+// sendTailSampled sends a trace kept by the tail sampler to the trace writer.
+func (a *Agent) sendTailSampled(t pb.Trace) {
+	a.TraceWriter.In <- &writer.SampledSpans{
+		Traces:    []*pb.APITrace{traceutil.APITrace(t)},
+		Size:      t.Msgsize(),
+		SpanCount: int64(len(t)),
+	}
+}
+
+// reportInfo periodically exposes the tail sampler and tag filter stats with expvar.
+func (a *Agent) reportInfo() {
+	t := time.NewTicker(10 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if a.TailSampler != nil {
+				info.UpdateTailSamplerInfo(a.TailSampler.Stats())
+			}
+			info.UpdateTagFilterInfo(a.TagFilter.Stats())
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
+
 // runSamplers runs all the agent's samplers on pt and returns the sampling decision
 // along with the sampling rate.
 func (a *Agent) runSamplers(pt ProcessedTrace, hasPriority bool) bool {
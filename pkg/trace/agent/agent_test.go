@@ -143,6 +143,55 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(2, want.SpansFiltered)
 	})
 
+	t.Run("TagFilter", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.TagFilterRules = []*config.TagFilterRule{
+			{Name: "health", Action: config.TagFilterReject, Tag: "http.url", Re: regexp.MustCompile("/health$")},
+			{Name: "cache", Action: config.TagFilterDropSpans, Tag: "component", Re: regexp.MustCompile("^redis$")},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(spanID uint64, meta map[string]string) *pb.Span {
+			return &pb.Span{
+				TraceID:  1,
+				SpanID:   spanID,
+				ParentID: 1,
+				Service:  "web",
+				Name:     "web.request",
+				Resource: "GET /",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+				Meta:     meta,
+			}
+		}
+
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		assert := assert.New(t)
+
+		root := newSpan(1, map[string]string{"http.url": "http://host/health"})
+		root.ParentID = 0
+		agnt.Process(&api.Payload{
+			Traces: pb.Traces{{root, newSpan(2, nil)}},
+			Source: want,
+		})
+		assert.EqualValues(1, want.TracesFiltered)
+		assert.EqualValues(2, want.SpansFiltered)
+
+		root = newSpan(1, nil)
+		root.ParentID = 0
+		agnt.Process(&api.Payload{
+			Traces: pb.Traces{{root, newSpan(2, map[string]string{"component": "redis"}), newSpan(3, nil)}},
+			Source: want,
+		})
+		assert.EqualValues(1, want.TracesFiltered)
+		assert.EqualValues(3, want.SpansFiltered)
+		assert.Equal(map[string]int64{"health": 1, "cache": 1}, agnt.TagFilter.Stats())
+	})
+
 	t.Run("BlacklistPayload", func(t *testing.T) {
 		// Regression test for DataDog/datadog-agent#6500
 		cfg := config.New()
@@ -366,6 +415,58 @@ func TestProcess(t *testing.T) {
 		// without missing a trace
 		assert.Equal(t, gotCount, len(traces))
 	})
+
+	t.Run("tail-sampling", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.TailSampling.Enabled = true
+		cfg.TailSampling.DecisionWait = 100 * time.Millisecond
+		cfg.TailSampling.Rules = []*config.TailSamplingRule{{Name: "gold", Tag: "customer.tier:gold"}}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+		agnt.TailSampler.Start()
+		defer agnt.TailSampler.Stop()
+
+		span := func(traceID uint64) pb.Trace {
+			return pb.Trace{{
+				Service:  "checkout",
+				Name:     "http.request",
+				Resource: "GET /cart",
+				TraceID:  traceID,
+				SpanID:   1,
+				Start:    time.Now().Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+				Meta:     map[string]string{"customer.tier": "gold"},
+				Metrics:  map[string]float64{sampler.KeySamplingPriority: 0},
+			}}
+		}
+		// the first trace is kept by the exception sampler, which then drops the second one
+		go agnt.Process(&api.Payload{
+			Traces: pb.Traces{span(1), span(2)},
+			Source: agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+
+		timeout := time.After(3 * time.Second)
+		for {
+			select {
+			case ss := <-agnt.TraceWriter.In:
+				if len(ss.Traces) == 0 {
+					continue
+				}
+				root := ss.Traces[0].Spans[0]
+				if root.TraceID == 1 {
+					assert.NotContains(t, root.Meta, "_dd.tail_sampling.rule")
+					continue
+				}
+				assert.EqualValues(t, 2, root.TraceID)
+				assert.Equal(t, "gold", root.Meta["_dd.tail_sampling.rule"])
+				return
+			case <-timeout:
+				t.Fatal("timed out")
+			}
+		}
+	})
 }
 
 func TestClientComputedTopLevel(t *testing.T) {
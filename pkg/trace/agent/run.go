@@ -27,6 +27,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -78,6 +79,20 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flags.Capture > 0 {
+		if err := replay.RequestCapture(os.Stdout, cfg, flags.Capture); err != nil {
+			osutil.Exitf("Failed to start capture: %s", err)
+		}
+		return
+	}
+
+	if flags.Replay != "" {
+		if err := replay.Replay(ctx, os.Stdout, cfg, flags.Replay); err != nil {
+			osutil.Exitf("Failed to replay capture: %s", err)
+		}
+		return
+	}
+
 	if err := coreconfig.SetupLogger(
 		coreconfig.LoggerName("TRACE"),
 		cfg.LogLevel,
@@ -44,6 +44,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -71,6 +72,9 @@ type HTTPReceiver struct {
 	Stats       *info.ReceiverStats
 	RateLimiter *rateLimiter
 
+	// MetricsHandler serves the /metrics endpoint, enabled by apm_config.prometheus_stats.
+	MetricsHandler http.Handler
+
 	out              chan *Payload
 	conf             *config.AgentConfig
 	dynConf          *sampler.DynamicConfig
@@ -78,6 +82,7 @@ type HTTPReceiver struct {
 	statsProcessor   StatsProcessor
 	appsecHandler    http.Handler
 	configSubscriber *config.Subscriber
+	capture          *replay.Capture
 
 	debug               bool
 	rateLimiterResponse int // HTTP status code when refusing
@@ -106,6 +111,7 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 		conf:             conf,
 		dynConf:          dynConf,
 		appsecHandler:    appsecHandler,
+		capture:          replay.NewCapture(conf.CapturePath),
 
 		debug:               strings.ToLower(conf.LogLevel) == "debug",
 		rateLimiterResponse: rateLimiterResponse,
@@ -120,7 +126,7 @@ func (r *HTTPReceiver) buildMux() *http.ServeMux {
 	hash, infoHandler := r.makeInfoHandler()
 	r.attachDebugHandlers(mux)
 	for _, e := range endpoints {
-		if e.IsEnabled != nil && !e.IsEnabled() {
+		if e.IsEnabled != nil && !e.IsEnabled(r.conf) {
 			continue
 		}
 		mux.Handle(e.Pattern, replyWithVersion(hash, e.Handler(r)))
@@ -232,6 +238,8 @@ func (r *HTTPReceiver) attachDebugHandlers(mux *http.ServeMux) {
 		runtime.SetBlockProfileRate(0)
 	})
 
+	mux.HandleFunc("/debug/capture", r.handleCapture)
+
 	mux.Handle("/debug/vars", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		// allow the GUI to call this endpoint so that the status can be reported
 		w.Header().Set("Access-Control-Allow-Origin", "http://127.0.0.1:"+mainconfig.Datadog.GetString("GUI_port"))
@@ -284,6 +292,7 @@ func (r *HTTPReceiver) Stop() error {
 	<-r.exit
 
 	r.RateLimiter.Stop()
+	r.capture.Stop()
 
 	expiry := time.Now().Add(5 * time.Second) // give it 5 seconds
 	ctx, cancel := context.WithDeadline(context.Background(), expiry)
@@ -304,6 +313,8 @@ func (r *HTTPReceiver) handleWithVersion(v Version, f func(Version, http.Respons
 			return
 		}
 
+		r.capture.Record(req, r.conf.MaxRequestBytes)
+
 		// TODO(x): replace with http.MaxBytesReader?
 		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 
@@ -445,6 +456,7 @@ func (r *HTTPReceiver) handleStats(w http.ResponseWriter, req *http.Request) {
 	defer timing.Since("datadog.trace_agent.receiver.stats_process_ms", time.Now())
 
 	ts := r.tagStats(v06, req.Header)
+	r.capture.Record(req, r.conf.MaxRequestBytes)
 	rd := apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 	req.Header.Set("Accept", "application/msgpack")
 	var in pb.ClientStatsPayload
@@ -559,7 +571,48 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 		ClientComputedStats:    req.Header.Get(headerComputedStats) != "",
 		ClientDroppedP0s:       droppedTracesFromHeader(req.Header, ts),
 	}
+	r.sendPayload(payload)
+}
+
+// handleConvertedTraces returns a handler for traces sent in a third-party format, such as
+// Zipkin or Jaeger. The traces are converted by decode and processed like the Datadog ones.
+func (r *HTTPReceiver) handleConvertedTraces(decode func(*http.Request) (pb.Traces, error)) func(Version, http.ResponseWriter, *http.Request) {
+	return func(v Version, w http.ResponseWriter, req *http.Request) {
+		ts := r.tagStats(v, req.Header)
+		start := time.Now()
+		traces, err := decode(req)
+		defer func(err error) {
+			tags := append(ts.AsTags(), fmt.Sprintf("success:%v", err == nil))
+			metrics.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), tags, 1)
+		}(err)
+		if err != nil {
+			httpDecodingError(err, []string{"handler:traces", fmt.Sprintf("v:%s", v)}, w)
+			log.Errorf("Cannot decode %s traces payload: %v", v, err)
+			return
+		}
+		if r.rateLimited(int64(len(traces))) {
+			w.WriteHeader(r.rateLimiterResponse)
+			atomic.AddInt64(&ts.PayloadRefused, 1)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		atomic.AddInt64(&ts.TracesReceived, int64(len(traces)))
+		atomic.AddInt64(&ts.TracesBytes, req.Body.(*apiutil.LimitedReader).Count)
+		atomic.AddInt64(&ts.PayloadAccepted, 1)
+
+		cid := req.Header.Get(headerContainerID)
+		r.sendPayload(&Payload{
+			Source:        ts,
+			Traces:        traces,
+			ContainerID:   cid,
+			ContainerTags: getContainerTags(cid),
+		})
+	}
+}
 
+// sendPayload sends payload to the output channel without ever dropping it.
+func (r *HTTPReceiver) sendPayload(payload *Payload) {
 	select {
 	case r.out <- payload:
 		// ok
@@ -1174,3 +1174,39 @@ func msgpTraces(t *testing.T, traces pb.Traces) []byte {
 	}
 	return bts
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("datadog_trace_hits_total 1\n"))
+	})
+	for name, tt := range map[string]struct {
+		enabled bool
+		handler http.Handler
+		code    int
+	}{
+		"disabled":   {enabled: false, handler: metrics, code: http.StatusNotFound},
+		"enabled":    {enabled: true, handler: metrics, code: http.StatusOK},
+		"no-handler": {enabled: true, handler: nil, code: http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conf := newTestReceiverConfig()
+			conf.PrometheusStats.Enabled = tt.enabled
+			r := newTestReceiverFromConfig(conf)
+			r.MetricsHandler = tt.handler
+			server := httptest.NewServer(r.buildMux())
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + "/metrics")
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, "datadog_trace_hits_total 1\n", string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+)
+
+// handleCapture starts capturing the trace and stats payloads received, for the duration
+// given by the "duration" query parameter (e.g. "30s"). It replies with the path of the
+// capture file. See "trace-agent -capture". Only local clients are allowed to start a capture.
+func (r *HTTPReceiver) handleCapture(w http.ResponseWriter, req *http.Request) {
+	if !isLocalRequest(req) {
+		http.Error(w, "captures can only be started from the local host", http.StatusForbidden)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d, err := time.ParseDuration(req.URL.Query().Get("duration"))
+	if err != nil || d <= 0 || d > replay.MaxCaptureDuration {
+		http.Error(w, fmt.Sprintf("duration must be greater than 0 and at most %s", replay.MaxCaptureDuration), http.StatusBadRequest)
+		return
+	}
+	path, err := r.capture.Start(d)
+	switch {
+	case err == replay.ErrCaptureInProgress:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(replay.CaptureResponse{Path: path})
+}
+
+// isLocalRequest reports whether req comes from the local host. Requests received over
+// a Unix Domain Socket or a Windows named pipe have no remote IP and are local.
+func isLocalRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || ip.IsLoopback()
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+	"github.com/DataDog/datadog-agent/pkg/trace/test/testutil"
+)
+
+func TestHandleCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	conf := newTestReceiverConfig()
+	conf.CapturePath = dir
+	r := newTestReceiverFromConfig(conf)
+	defer r.capture.Stop()
+
+	capture := func(method, duration string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/debug/capture?duration="+duration, nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+		r.handleCapture(rec, req)
+		return rec
+	}
+	remote := httptest.NewRecorder()
+	r.handleCapture(remote, httptest.NewRequest("POST", "/debug/capture?duration=1m", nil))
+	assert.Equal(t, http.StatusForbidden, remote.Code)
+	assert.False(t, r.capture.Active())
+	assert.Equal(t, http.StatusMethodNotAllowed, capture("GET", "1m").Code)
+	assert.Equal(t, http.StatusBadRequest, capture("POST", "abc").Code)
+	assert.Equal(t, http.StatusBadRequest, capture("POST", "1h").Code)
+
+	resp := capture("POST", "1m")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var cr replay.CaptureResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&cr))
+	assert.Equal(t, http.StatusConflict, capture("POST", "1m").Code)
+
+	// the captured payload is still processed
+	traces := testutil.GetTestTraces(1, 1, false)
+	bts, err := traces.MarshalMsg(nil)
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/v0.4/traces", bytes.NewReader(bts))
+	req.Header.Set("Content-Type", "application/msgpack")
+	rec := httptest.NewRecorder()
+	r.handleWithVersion(v04, r.handleTraces)(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	select {
+	case p := <-r.out:
+		assert.Len(t, p.Traces, 1)
+	case <-time.After(time.Second):
+		t.Fatal("no data received")
+	}
+
+	r.capture.Stop()
+	f, err := os.Open(cr.Path)
+	require.NoError(t, err)
+	defer f.Close()
+	captured, err := replay.NewReader(f).Next()
+	require.NoError(t, err)
+	assert.Equal(t, "/v0.4/traces", captured.Path)
+	assert.Equal(t, "application/msgpack", captured.Header.Get("Content-Type"))
+	var got pb.Traces
+	_, err = got.UnmarshalMsg(captured.Body)
+	require.NoError(t, err)
+	assert.Equal(t, traces, got)
+}
+
+func TestIsLocalRequest(t *testing.T) {
+	for addr, local := range map[string]bool{
+		"127.0.0.1:1234":  true,
+		"[::1]:1234":      true,
+		"@":               true, // unix socket
+		"192.0.2.1:1234":  false,
+		"[2001:db8::1]:1": false,
+	} {
+		req := httptest.NewRequest("POST", "/debug/capture", nil)
+		req.RemoteAddr = addr
+		assert.Equal(t, local, isLocalRequest(req), addr)
+	}
+}
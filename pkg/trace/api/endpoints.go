@@ -8,6 +8,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/config/features"
 )
 
@@ -25,7 +26,7 @@ type endpoint struct {
 
 	// IsEnabled specifies a function which reports whether this endpoint should be enabled
 	// based on the given config conf.
-	IsEnabled func() bool
+	IsEnabled func(conf *config.AgentConfig) bool
 }
 
 // endpoints specifies the list of endpoints registered for the trace-agent API.
@@ -99,6 +100,33 @@ var endpoints = []endpoint{
 	{
 		Pattern:   "/v0.6/config",
 		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleConfig) },
-		IsEnabled: func() bool { return features.Has("config_endpoint") },
+		IsEnabled: func(_ *config.AgentConfig) bool { return features.Has("config_endpoint") },
+	},
+	{
+		Pattern: "/api/v2/spans",
+		Handler: func(r *HTTPReceiver) http.Handler {
+			return r.handleWithVersion(zipkinV2, r.handleConvertedTraces(decodeZipkinSpans))
+		},
+		Hidden:    true,
+		IsEnabled: func(conf *config.AgentConfig) bool { return conf.ZipkinReceiverEnabled },
+	},
+	{
+		Pattern: "/api/traces",
+		Handler: func(r *HTTPReceiver) http.Handler {
+			return r.handleWithVersion(jaegerThrift, r.handleConvertedTraces(decodeJaegerBatch))
+		},
+		Hidden:    true,
+		IsEnabled: func(conf *config.AgentConfig) bool { return conf.JaegerReceiverEnabled },
+	},
+	{
+		Pattern: "/metrics",
+		Handler: func(r *HTTPReceiver) http.Handler {
+			if r.MetricsHandler == nil {
+				return http.NotFoundHandler()
+			}
+			return r.MetricsHandler
+		},
+		Hidden:    true,
+		IsEnabled: func(conf *config.AgentConfig) bool { return conf.PrometheusStats.Enabled },
 	},
 }
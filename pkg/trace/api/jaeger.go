@@ -0,0 +1,565 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// jaegerThrift is the endpoint version reported for the traces received in the Jaeger Thrift format.
+const jaegerThrift Version = "jaeger_thrift"
+
+// jaegerBatch is a batch of spans of a single process, as described in
+// https://github.com/jaegertracing/jaeger-idl/blob/master/thrift/jaeger.thrift.
+type jaegerBatch struct {
+	ServiceName string
+	ProcessTags []jaegerTag
+	Spans       []jaegerSpan
+}
+
+type jaegerSpan struct {
+	TraceIDLow    uint64
+	TraceIDHigh   uint64
+	SpanID        uint64
+	ParentSpanID  uint64
+	OperationName string
+	References    []jaegerSpanRef
+	Flags         int32
+	StartTime     int64 // microseconds since epoch
+	Duration      int64 // microseconds
+	Tags          []jaegerTag
+	Logs          []jaegerLog
+}
+
+type jaegerSpanRef struct {
+	RefType int32
+	SpanID  uint64
+}
+
+type jaegerLog struct {
+	Timestamp int64 // microseconds since epoch
+	Fields    []jaegerTag
+}
+
+// jaegerTag is a typed key/value pair, only the value matching its type is set.
+type jaegerTag struct {
+	Key    string
+	Type   int32
+	Str    string
+	Double float64
+	Bool   bool
+	Long   int64
+	Binary []byte
+}
+
+// Jaeger tag types.
+const (
+	jaegerTagString int32 = iota
+	jaegerTagDouble
+	jaegerTagBool
+	jaegerTagLong
+	jaegerTagBinary
+)
+
+// jaegerRefChildOf is the reference type of a span to its parent.
+const jaegerRefChildOf int32 = 0
+
+// jaegerFlagDebug is set in the flags of the spans of traces forced by the user.
+const jaegerFlagDebug int32 = 2
+
+// String returns the value of the tag as a string.
+func (t *jaegerTag) String() string {
+	switch t.Type {
+	case jaegerTagDouble:
+		return strconv.FormatFloat(t.Double, 'f', -1, 64)
+	case jaegerTagBool:
+		return strconv.FormatBool(t.Bool)
+	case jaegerTagLong:
+		return strconv.FormatInt(t.Long, 10)
+	case jaegerTagBinary:
+		return base64.StdEncoding.EncodeToString(t.Binary)
+	}
+	return t.Str
+}
+
+// decodeJaegerBatch decodes the Jaeger batch encoded with the Thrift binary protocol in the body
+// of req, as sent by the Jaeger clients to the collector, and returns its spans grouped by trace.
+// The compact protocol, used by the clients to send spans to the Jaeger agent over UDP, is not supported.
+func decodeJaegerBatch(req *http.Request) (pb.Traces, error) {
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := unmarshalJaegerBatch(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	processMeta := make(map[string]string, len(batch.ProcessTags))
+	for i := range batch.ProcessTags {
+		processMeta[batch.ProcessTags[i].Key] = batch.ProcessTags[i].String()
+	}
+	tracesByID := make(map[uint64]pb.Trace)
+	for i := range batch.Spans {
+		span := convertJaegerSpan(batch.ServiceName, processMeta, &batch.Spans[i])
+		tracesByID[span.TraceID] = append(tracesByID[span.TraceID], span)
+	}
+	traces := make(pb.Traces, 0, len(tracesByID))
+	for _, trace := range tracesByID {
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// convertJaegerSpan converts the Jaeger span in to a Datadog span, and uses the service and
+// the process tags of its batch to further augment it.
+func convertJaegerSpan(service string, processMeta map[string]string, in *jaegerSpan) *pb.Span {
+	span := &pb.Span{
+		TraceID:  in.TraceIDLow,
+		SpanID:   in.SpanID,
+		ParentID: in.ParentSpanID,
+		Start:    in.StartTime * 1000,
+		Duration: in.Duration * 1000,
+		Service:  service,
+		Resource: in.OperationName,
+		Meta:     make(map[string]string, len(processMeta)+len(in.Tags)),
+		Metrics: map[string]float64{
+			// auto-keep all incoming traces; they have already been sampled
+			// by the Jaeger tracer.
+			sampler.KeySamplingPriority: float64(sampler.PriorityAutoKeep),
+		},
+	}
+	if in.Flags&jaegerFlagDebug != 0 {
+		span.Metrics[sampler.KeySamplingPriority] = float64(sampler.PriorityUserKeep)
+	}
+	if span.ParentID == 0 {
+		for _, ref := range in.References {
+			if ref.RefType == jaegerRefChildOf {
+				span.ParentID = ref.SpanID
+				break
+			}
+		}
+	}
+	for k, v := range processMeta {
+		span.Meta[k] = v
+	}
+
+	var kind string
+	for i := range in.Tags {
+		tag := &in.Tags[i]
+		switch tag.Key {
+		case "span.kind":
+			kind = strings.ToLower(tag.Str)
+		case "error":
+			if tag.Bool || tag.Str == "true" {
+				span.Error = 1
+			}
+		default:
+			switch tag.Type {
+			case jaegerTagDouble:
+				span.Metrics[tag.Key] = tag.Double
+			case jaegerTagLong:
+				span.Metrics[tag.Key] = float64(tag.Long)
+			default:
+				span.Meta[tag.Key] = tag.String()
+			}
+		}
+	}
+	if span.Error == 1 {
+		jaegerLogs2Error(in.Logs, span)
+	}
+	span.Name = "jaeger." + foreignSpanKindName(kind)
+	completeForeignSpan(kind, span)
+	return span
+}
+
+// jaegerLogs2Error applies the error details recorded in the logs of a span, as specified by
+// the OpenTracing semantic conventions, to the given span attributes.
+func jaegerLogs2Error(logs []jaegerLog, span *pb.Span) {
+	for _, l := range logs {
+		fields := make(map[string]string, len(l.Fields))
+		for i := range l.Fields {
+			fields[l.Fields[i].Key] = l.Fields[i].String()
+		}
+		if fields["event"] != "error" {
+			continue
+		}
+		if msg := fields["message"]; msg != "" {
+			span.Meta["error.msg"] = msg
+		} else if obj := fields["error.object"]; obj != "" {
+			span.Meta["error.msg"] = obj
+		}
+		if typ := fields["error.kind"]; typ != "" {
+			span.Meta["error.type"] = typ
+		}
+		if stack := fields["stack"]; stack != "" {
+			span.Meta["error.stack"] = stack
+		}
+	}
+}
+
+// errJaegerThrift is returned when a Thrift encoded Jaeger payload is malformed.
+var errJaegerThrift = errors.New("malformed jaeger thrift payload")
+
+// Thrift types, as encoded by the binary protocol.
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+// thriftMaxDepth limits the nesting of the structures skipped by the decoder.
+const thriftMaxDepth = 64
+
+// thriftReader decodes values encoded with the Thrift binary protocol.
+type thriftReader struct {
+	buf []byte
+}
+
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.buf) < n {
+		return nil, errJaegerThrift
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	v, err := r.readI64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+func (r *thriftReader) readBinary() ([]byte, error) {
+	size, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	return r.next(int(size))
+}
+
+func (r *thriftReader) readString() (string, error) {
+	b, err := r.readBinary()
+	return string(b), err
+}
+
+// readList reads the header of a list and calls fn for each of its elements, which must be of
+// the given type. Elements of another type are skipped.
+func (r *thriftReader) readList(typ byte, fn func() error) error {
+	elemType, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	size, err := r.readI32()
+	if err != nil {
+		return err
+	}
+	if size < 0 || int(size) > len(r.buf) {
+		return errJaegerThrift
+	}
+	for i := 0; i < int(size); i++ {
+		if elemType != typ {
+			err = r.skip(elemType, 0)
+		} else {
+			err = fn()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readStruct calls fn with the ID and type of each field of a struct, until its end.
+// fn must read the value of the field, or skip it.
+func (r *thriftReader) readStruct(fn func(id int16, typ byte) error) error {
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		if err := fn(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte, depth int) error {
+	if depth > thriftMaxDepth {
+		return errJaegerThrift
+	}
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readBinary()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) error {
+			return r.skip(typ, depth+1)
+		})
+	case thriftMap:
+		var keyType, valueType byte
+		var size int32
+		if keyType, err = r.readByte(); err != nil {
+			return err
+		}
+		if valueType, err = r.readByte(); err != nil {
+			return err
+		}
+		if size, err = r.readI32(); err != nil {
+			return err
+		}
+		if size < 0 || int(size) > len(r.buf) {
+			return errJaegerThrift
+		}
+		for i := 0; i < int(size) && err == nil; i++ {
+			if err = r.skip(keyType, depth+1); err == nil {
+				err = r.skip(valueType, depth+1)
+			}
+		}
+	case thriftSet, thriftList:
+		var elemType byte
+		var size int32
+		if elemType, err = r.readByte(); err != nil {
+			return err
+		}
+		if size, err = r.readI32(); err != nil {
+			return err
+		}
+		if size < 0 || int(size) > len(r.buf) {
+			return errJaegerThrift
+		}
+		for i := 0; i < int(size) && err == nil; i++ {
+			err = r.skip(elemType, depth+1)
+		}
+	default:
+		err = errJaegerThrift
+	}
+	return err
+}
+
+// unmarshalJaegerBatch decodes a jaeger.Batch struct encoded with the Thrift binary protocol.
+func unmarshalJaegerBatch(buf []byte) (*jaegerBatch, error) {
+	r := &thriftReader{buf: buf}
+	var batch jaegerBatch
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			return r.readStruct(func(id int16, typ byte) error {
+				switch {
+				case id == 1 && typ == thriftString:
+					var err error
+					batch.ServiceName, err = r.readString()
+					return err
+				case id == 2 && typ == thriftList:
+					return r.readList(thriftStruct, func() error {
+						tag, err := r.readJaegerTag()
+						batch.ProcessTags = append(batch.ProcessTags, tag)
+						return err
+					})
+				}
+				return r.skip(typ, 0)
+			})
+		case id == 2 && typ == thriftList:
+			return r.readList(thriftStruct, func() error {
+				span, err := r.readJaegerSpan()
+				batch.Spans = append(batch.Spans, span)
+				return err
+			})
+		}
+		return r.skip(typ, 0)
+	})
+	return &batch, err
+}
+
+func (r *thriftReader) readJaegerSpan() (jaegerSpan, error) {
+	var span jaegerSpan
+	err := r.readStruct(func(id int16, typ byte) error {
+		var (
+			v   int64
+			err error
+		)
+		switch {
+		case id >= 1 && id <= 4 && typ == thriftI64:
+			v, err = r.readI64()
+			switch id {
+			case 1:
+				span.TraceIDLow = uint64(v)
+			case 2:
+				span.TraceIDHigh = uint64(v)
+			case 3:
+				span.SpanID = uint64(v)
+			case 4:
+				span.ParentSpanID = uint64(v)
+			}
+		case id == 5 && typ == thriftString:
+			span.OperationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				ref, err := r.readJaegerSpanRef()
+				span.References = append(span.References, ref)
+				return err
+			})
+		case id == 7 && typ == thriftI32:
+			span.Flags, err = r.readI32()
+		case id == 8 && typ == thriftI64:
+			span.StartTime, err = r.readI64()
+		case id == 9 && typ == thriftI64:
+			span.Duration, err = r.readI64()
+		case id == 10 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				tag, err := r.readJaegerTag()
+				span.Tags = append(span.Tags, tag)
+				return err
+			})
+		case id == 11 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				l, err := r.readJaegerLog()
+				span.Logs = append(span.Logs, l)
+				return err
+			})
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return span, err
+}
+
+func (r *thriftReader) readJaegerSpanRef() (jaegerSpanRef, error) {
+	var ref jaegerSpanRef
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			ref.RefType, err = r.readI32()
+		case id == 4 && typ == thriftI64:
+			var v int64
+			v, err = r.readI64()
+			ref.SpanID = uint64(v)
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return ref, err
+}
+
+func (r *thriftReader) readJaegerLog() (jaegerLog, error) {
+	var l jaegerLog
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			l.Timestamp, err = r.readI64()
+		case id == 2 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				tag, err := r.readJaegerTag()
+				l.Fields = append(l.Fields, tag)
+				return err
+			})
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return l, err
+}
+
+func (r *thriftReader) readJaegerTag() (jaegerTag, error) {
+	var tag jaegerTag
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			tag.Key, err = r.readString()
+		case id == 2 && typ == thriftI32:
+			tag.Type, err = r.readI32()
+		case id == 3 && typ == thriftString:
+			tag.Str, err = r.readString()
+		case id == 4 && typ == thriftDouble:
+			tag.Double, err = r.readDouble()
+		case id == 5 && typ == thriftBool:
+			var b byte
+			b, err = r.readByte()
+			tag.Bool = b != 0
+		case id == 6 && typ == thriftI64:
+			tag.Long, err = r.readI64()
+		case id == 7 && typ == thriftString:
+			tag.Binary, err = r.readBinary()
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return tag, err
+}
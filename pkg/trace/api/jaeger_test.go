@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// thriftWriter encodes values with the Thrift binary protocol.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id)
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	binary.Write(w, binary.BigEndian, v)
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	binary.Write(w, binary.BigEndian, v)
+}
+
+func (w *thriftWriter) str(id int16, v string) {
+	w.field(id, thriftString)
+	binary.Write(w, binary.BigEndian, int32(len(v)))
+	w.WriteString(v)
+}
+
+func (w *thriftWriter) list(id int16, typ byte, size int) {
+	w.field(id, thriftList)
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, int32(size))
+}
+
+func (w *thriftWriter) tag(key string, typ int32, value interface{}) {
+	w.str(1, key)
+	w.i32(2, typ)
+	switch v := value.(type) {
+	case string:
+		w.str(3, v)
+	case float64:
+		w.field(4, thriftDouble)
+		binary.Write(w, binary.BigEndian, math.Float64bits(v))
+	case bool:
+		w.field(5, thriftBool)
+		if v {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case int64:
+		w.i64(6, v)
+	}
+	w.stop()
+}
+
+// jaegerTestBatch returns a batch of two spans of the same trace, the client one
+// referencing its parent and reporting an error.
+func jaegerTestBatch() []byte {
+	var w thriftWriter
+	// process
+	w.field(1, thriftStruct)
+	w.str(1, "checkout")
+	w.list(2, thriftStruct, 1)
+	w.tag("deployment.environment", jaegerTagString, "prod")
+	w.stop()
+
+	w.list(2, thriftStruct, 2)
+	// server span
+	w.i64(1, 42)
+	w.i64(2, 1)
+	w.i64(3, 100)
+	w.str(5, "HTTP GET")
+	w.i32(7, 1)
+	w.i64(8, 1600000000000000)
+	w.i64(9, 2500)
+	w.list(10, thriftStruct, 4)
+	w.tag("span.kind", jaegerTagString, "server")
+	w.tag("http.method", jaegerTagString, "GET")
+	w.tag("http.route", jaegerTagString, "/cart")
+	w.tag("http.status_code", jaegerTagLong, int64(200))
+	w.stop()
+	// client span
+	w.i64(1, 42)
+	w.i64(3, 101)
+	w.list(6, thriftStruct, 1)
+	w.i32(1, jaegerRefChildOf)
+	w.i64(2, 42)
+	w.i64(3, 0)
+	w.i64(4, 100)
+	w.stop()
+	w.str(5, "query")
+	w.i64(8, 1600000000001000)
+	w.i64(9, 1000)
+	w.list(10, thriftStruct, 4)
+	w.tag("span.kind", jaegerTagString, "client")
+	w.tag("db.type", jaegerTagString, "sql")
+	w.tag("error", jaegerTagBool, true)
+	w.tag("sampler.param", jaegerTagDouble, 0.5)
+	w.list(11, thriftStruct, 1)
+	w.i64(1, 1600000000001500)
+	w.list(2, thriftStruct, 3)
+	w.tag("event", jaegerTagString, "error")
+	w.tag("error.kind", jaegerTagString, "Timeout")
+	w.tag("message", jaegerTagString, "query timed out")
+	w.stop()
+	// an unknown field is skipped
+	w.field(42, thriftMap)
+	w.WriteByte(thriftString)
+	w.WriteByte(thriftI32)
+	binary.Write(&w, binary.BigEndian, int32(0))
+	w.stop()
+	w.stop()
+	return w.Bytes()
+}
+
+func TestDecodeJaegerBatch(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(jaegerTestBatch()))
+	require.NoError(t, err)
+
+	traces, err := decodeJaegerBatch(req)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 2)
+
+	assert.Equal(t, &pb.Span{
+		Service:  "checkout",
+		Name:     "jaeger.server",
+		Resource: "GET /cart",
+		TraceID:  42,
+		SpanID:   100,
+		Start:    1600000000000000000,
+		Duration: 2500000,
+		Type:     "web",
+		Meta: map[string]string{
+			"deployment.environment": "prod",
+			"env":                    "prod",
+			"http.method":            "GET",
+			"http.route":             "/cart",
+		},
+		Metrics: map[string]float64{
+			sampler.KeySamplingPriority: float64(sampler.PriorityAutoKeep),
+			"http.status_code":          200,
+		},
+	}, traces[0][0])
+
+	client := traces[0][1]
+	assert.EqualValues(t, 100, client.ParentID)
+	assert.Equal(t, "jaeger.client", client.Name)
+	assert.Equal(t, "query", client.Resource)
+	assert.Equal(t, "db", client.Type)
+	assert.EqualValues(t, 1, client.Error)
+	assert.Equal(t, "query timed out", client.Meta["error.msg"])
+	assert.Equal(t, "Timeout", client.Meta["error.type"])
+	assert.Equal(t, 0.5, client.Metrics["sampler.param"])
+}
+
+func TestDecodeJaegerBatchMalformed(t *testing.T) {
+	batch := jaegerTestBatch()
+	for _, payload := range [][]byte{
+		batch[:len(batch)/2],
+		// a list announcing more elements than the payload can hold
+		{thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff},
+		// an unknown type
+		{42, 0, 1},
+	} {
+		req, err := http.NewRequest("POST", "/api/traces", bytes.NewReader(payload))
+		require.NoError(t, err)
+		_, err = decodeJaegerBatch(req)
+		assert.Equal(t, errJaegerThrift, err)
+	}
+}
+
+func TestJaegerEndpoint(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.JaegerReceiverEnabled = true
+	r := newTestReceiverFromConfig(conf)
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/traces", "application/x-thrift", bytes.NewReader(jaegerTestBatch()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	payload := <-r.out
+	assert.Len(t, payload.Traces, 1)
+	assert.Equal(t, string(jaegerThrift), payload.Source.EndpointVersion)
+}
@@ -0,0 +1,375 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// zipkinV2 is the endpoint version reported for the traces received in the Zipkin v2 format.
+const zipkinV2 Version = "zipkin_v2"
+
+// zipkinSpan is a span in the Zipkin v2 format, as described in https://zipkin.io/zipkin-api/#/default/post_spans.
+// The protobuf encoded spans are decoded into it as well, with their IDs hex encoded.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind"`
+	Name           string             `json:"name"`
+	Timestamp      uint64             `json:"timestamp"` // microseconds since epoch
+	Duration       uint64             `json:"duration"`  // microseconds
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+	Debug          bool               `json:"debug"`
+	Shared         bool               `json:"shared"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"` // microseconds since epoch
+	Value     string `json:"value"`
+}
+
+// decodeZipkinSpans decodes the list of Zipkin v2 spans in the body of req, JSON or protobuf encoded
+// depending on its content type, and returns them grouped by trace.
+func decodeZipkinSpans(req *http.Request) (pb.Traces, error) {
+	var spans []zipkinSpan
+	switch getMediaType(req) {
+	case "application/x-protobuf":
+		buf, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		spans, err = unmarshalZipkinProtoSpans(buf)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		if err := json.NewDecoder(req.Body).Decode(&spans); err != nil {
+			return nil, err
+		}
+	}
+
+	tracesByID := make(map[uint64]pb.Trace)
+	for i := range spans {
+		span, err := convertZipkinSpan(&spans[i])
+		if err != nil {
+			return nil, err
+		}
+		tracesByID[span.TraceID] = append(tracesByID[span.TraceID], span)
+	}
+	traces := make(pb.Traces, 0, len(tracesByID))
+	for _, trace := range tracesByID {
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// convertZipkinSpan converts the Zipkin span in to a Datadog span.
+func convertZipkinSpan(in *zipkinSpan) (*pb.Span, error) {
+	traceID, err := hexIDToUint64(in.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace ID %q: %v", in.TraceID, err)
+	}
+	spanID, err := hexIDToUint64(in.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid span ID %q: %v", in.ID, err)
+	}
+	parentID, err := hexIDToUint64(in.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent ID %q: %v", in.ParentID, err)
+	}
+
+	kind := strings.ToLower(in.Kind)
+	span := &pb.Span{
+		Name:     "zipkin." + foreignSpanKindName(kind),
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    int64(in.Timestamp) * 1000,
+		Duration: int64(in.Duration) * 1000,
+		Resource: in.Name,
+		Meta:     make(map[string]string, len(in.Tags)),
+		Metrics: map[string]float64{
+			// auto-keep all incoming traces; they have already been sampled
+			// by the Zipkin tracer.
+			sampler.KeySamplingPriority: float64(sampler.PriorityAutoKeep),
+		},
+	}
+	if in.Debug {
+		span.Metrics[sampler.KeySamplingPriority] = float64(sampler.PriorityUserKeep)
+	}
+	if in.LocalEndpoint != nil {
+		span.Service = in.LocalEndpoint.ServiceName
+	}
+	if in.RemoteEndpoint != nil {
+		if in.RemoteEndpoint.ServiceName != "" {
+			span.Meta["peer.service"] = in.RemoteEndpoint.ServiceName
+		}
+		if ip := in.RemoteEndpoint.IPv4 + in.RemoteEndpoint.IPv6; ip != "" {
+			span.Meta["out.host"] = ip
+		}
+		if in.RemoteEndpoint.Port != 0 {
+			span.Meta["out.port"] = strconv.Itoa(in.RemoteEndpoint.Port)
+		}
+	}
+	for k, v := range in.Tags {
+		span.Meta[k] = v
+	}
+	if len(in.Annotations) > 0 {
+		events := make([]map[string]interface{}, 0, len(in.Annotations))
+		for _, a := range in.Annotations {
+			events = append(events, map[string]interface{}{"time_unix_nano": a.Timestamp * 1000, "name": a.Value})
+		}
+		if b, err := json.Marshal(events); err == nil {
+			span.Meta["events"] = string(b)
+		}
+	}
+	if msg, ok := span.Meta["error"]; ok {
+		// Zipkin tracers set the "error" tag to the error message, or to an empty string
+		span.Error = 1
+		delete(span.Meta, "error")
+		if msg != "" && msg != "true" {
+			span.Meta["error.msg"] = msg
+		}
+	}
+	completeForeignSpan(kind, span)
+	return span, nil
+}
+
+// completeForeignSpan sets the environment, resource and type of a span converted from a third-party
+// format, based on its kind (server, client, producer or consumer) and tags.
+func completeForeignSpan(kind string, span *pb.Span) {
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta["deployment.environment"]; env != "" {
+			span.Meta["env"] = env
+		}
+	}
+	if r := resourceFromTags(span.Meta); r != "" {
+		span.Resource = r
+	}
+	if span.Resource == "" {
+		span.Resource = span.Name
+	}
+	switch kind {
+	case "server":
+		span.Type = "web"
+	case "client":
+		span.Type = "http"
+		switch db := span.Meta["db.system"]; db {
+		case "":
+			if span.Meta["db.type"] != "" {
+				span.Type = "db"
+			}
+		case "redis", "memcached":
+			span.Type = "cache"
+		default:
+			span.Type = "db"
+		}
+	default:
+		span.Type = "custom"
+	}
+}
+
+// foreignSpanKindName returns the Datadog span name suffix of the given third-party span kind.
+func foreignSpanKindName(kind string) string {
+	switch kind {
+	case "server", "client", "producer", "consumer":
+		return kind
+	case "":
+		return "internal"
+	}
+	return "unknown"
+}
+
+// hexIDToUint64 returns the lower 64 bits of the given hex encoded ID, which can be 64 or 128 bits long.
+func hexIDToUint64(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	if len(id) > 16 {
+		id = id[len(id)-16:]
+	}
+	return strconv.ParseUint(id, 16, 64)
+}
+
+// errZipkinProto is returned when a protobuf encoded Zipkin payload is malformed.
+var errZipkinProto = errors.New("malformed zipkin protobuf payload")
+
+// zipkinProtoKinds maps the values of the Span.Kind protobuf enum to the kinds of the JSON format.
+var zipkinProtoKinds = map[uint64]string{
+	1: "CLIENT",
+	2: "SERVER",
+	3: "PRODUCER",
+	4: "CONSUMER",
+}
+
+// unmarshalZipkinProtoSpans decodes a zipkin.proto3.ListOfSpans message, as described in
+// https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto.
+func unmarshalZipkinProtoSpans(buf []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	err := walkProtoFields(buf, func(field int, value protoValue) error {
+		if field != 1 {
+			return nil
+		}
+		span, err := unmarshalZipkinProtoSpan(value.bytes)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	return spans, err
+}
+
+func unmarshalZipkinProtoSpan(buf []byte) (zipkinSpan, error) {
+	span := zipkinSpan{Tags: make(map[string]string)}
+	err := walkProtoFields(buf, func(field int, value protoValue) error {
+		var err error
+		switch field {
+		case 1:
+			span.TraceID = hex.EncodeToString(value.bytes)
+		case 2:
+			span.ParentID = hex.EncodeToString(value.bytes)
+		case 3:
+			span.ID = hex.EncodeToString(value.bytes)
+		case 4:
+			span.Kind = zipkinProtoKinds[value.number]
+		case 5:
+			span.Name = string(value.bytes)
+		case 6:
+			span.Timestamp = value.number
+		case 7:
+			span.Duration = value.number
+		case 8:
+			span.LocalEndpoint, err = unmarshalZipkinProtoEndpoint(value.bytes)
+		case 9:
+			span.RemoteEndpoint, err = unmarshalZipkinProtoEndpoint(value.bytes)
+		case 10:
+			var a zipkinAnnotation
+			err = walkProtoFields(value.bytes, func(field int, value protoValue) error {
+				switch field {
+				case 1:
+					a.Timestamp = value.number
+				case 2:
+					a.Value = string(value.bytes)
+				}
+				return nil
+			})
+			span.Annotations = append(span.Annotations, a)
+		case 11:
+			var k, v string
+			err = walkProtoFields(value.bytes, func(field int, value protoValue) error {
+				switch field {
+				case 1:
+					k = string(value.bytes)
+				case 2:
+					v = string(value.bytes)
+				}
+				return nil
+			})
+			span.Tags[k] = v
+		case 12:
+			span.Debug = value.number != 0
+		case 13:
+			span.Shared = value.number != 0
+		}
+		return err
+	})
+	return span, err
+}
+
+func unmarshalZipkinProtoEndpoint(buf []byte) (*zipkinEndpoint, error) {
+	var e zipkinEndpoint
+	err := walkProtoFields(buf, func(field int, value protoValue) error {
+		switch field {
+		case 1:
+			e.ServiceName = string(value.bytes)
+		case 2:
+			if len(value.bytes) == 4 {
+				e.IPv4 = fmt.Sprintf("%d.%d.%d.%d", value.bytes[0], value.bytes[1], value.bytes[2], value.bytes[3])
+			}
+		case 4:
+			e.Port = int(value.number)
+		}
+		return nil
+	})
+	return &e, err
+}
+
+// protoValue holds the value of a protobuf field: number for the varint and fixed size
+// wire types, bytes for the length-delimited one.
+type protoValue struct {
+	number uint64
+	bytes  []byte
+}
+
+// walkProtoFields calls fn with the number and value of each field of the protobuf message in buf.
+func walkProtoFields(buf []byte, fn func(field int, value protoValue) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return errZipkinProto
+		}
+		buf = buf[n:]
+
+		var value protoValue
+		switch key & 7 {
+		case 0: // varint
+			value.number, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return errZipkinProto
+			}
+			buf = buf[n:]
+		case 1: // 64-bit
+			if len(buf) < 8 {
+				return errZipkinProto
+			}
+			value.number = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || size > uint64(len(buf)-n) || size > math.MaxInt32 {
+				return errZipkinProto
+			}
+			value.bytes = buf[n : n+int(size)]
+			buf = buf[n+int(size):]
+		case 5: // 32-bit
+			if len(buf) < 4 {
+				return errZipkinProto
+			}
+			value.number = uint64(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		default:
+			return errZipkinProto
+		}
+		if err := fn(int(key>>3), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
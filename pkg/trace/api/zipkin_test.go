@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+const zipkinJSONPayload = `[
+  {
+    "traceId": "463ac35c9f6413ad48485a3953bb6124",
+    "id": "a2fb4a1d1a96d312",
+    "name": "get /api",
+    "kind": "SERVER",
+    "timestamp": 1472470996199000,
+    "duration": 207000,
+    "localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1"},
+    "tags": {"http.method": "GET", "http.route": "/api", "error": "connection reset"},
+    "annotations": [{"timestamp": 1472470996238000, "value": "ws"}]
+  },
+  {
+    "traceId": "48485a3953bb6124",
+    "parentId": "a2fb4a1d1a96d312",
+    "id": "b2fb4a1d1a96d313",
+    "name": "select",
+    "kind": "CLIENT",
+    "timestamp": 1472470996200000,
+    "duration": 1000,
+    "localEndpoint": {"serviceName": "frontend"},
+    "remoteEndpoint": {"serviceName": "postgres", "ipv4": "10.0.0.1", "port": 5432},
+    "tags": {"db.system": "postgresql"}
+  }
+]`
+
+func TestDecodeZipkinJSON(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(zipkinJSONPayload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	traces, err := decodeZipkinSpans(req)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 2)
+
+	server, client := traces[0][0], traces[0][1]
+	assert.Equal(t, &pb.Span{
+		Service:  "frontend",
+		Name:     "zipkin.server",
+		Resource: "GET /api",
+		TraceID:  0x48485a3953bb6124,
+		SpanID:   0xa2fb4a1d1a96d312,
+		Start:    1472470996199000000,
+		Duration: 207000000,
+		Error:    1,
+		Type:     "web",
+		Meta: map[string]string{
+			"http.method": "GET",
+			"http.route":  "/api",
+			"error.msg":   "connection reset",
+			"events":      `[{"name":"ws","time_unix_nano":1472470996238000000}]`,
+		},
+		Metrics: map[string]float64{sampler.KeySamplingPriority: float64(sampler.PriorityAutoKeep)},
+	}, server)
+
+	assert.Equal(t, server.SpanID, client.ParentID)
+	assert.Equal(t, "zipkin.client", client.Name)
+	assert.Equal(t, "select", client.Resource)
+	assert.Equal(t, "db", client.Type)
+	assert.Equal(t, "postgres", client.Meta["peer.service"])
+	assert.Equal(t, "10.0.0.1", client.Meta["out.host"])
+	assert.Equal(t, "5432", client.Meta["out.port"])
+}
+
+func TestDecodeZipkinInvalidID(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v2/spans", strings.NewReader(`[{"traceId": "xyz", "id": "1"}]`))
+	require.NoError(t, err)
+	_, err = decodeZipkinSpans(req)
+	assert.Error(t, err)
+}
+
+// protoField appends to buf the given protobuf field, encoded as a varint when value is an
+// uint64, as a 64-bit number when it is a [8]byte, and length-delimited otherwise.
+func protoField(buf []byte, field int, value interface{}) []byte {
+	varint := func(buf []byte, v uint64) []byte {
+		var tmp [binary.MaxVarintLen64]byte
+		return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+	}
+	switch v := value.(type) {
+	case uint64:
+		buf = varint(buf, uint64(field)<<3)
+		return varint(buf, v)
+	case [8]byte:
+		buf = varint(buf, uint64(field)<<3|1)
+		return append(buf, v[:]...)
+	case string:
+		value = []byte(v)
+	}
+	b := value.([]byte)
+	buf = varint(buf, uint64(field)<<3|2)
+	buf = varint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+func TestDecodeZipkinProto(t *testing.T) {
+	var timestamp [8]byte
+	binary.LittleEndian.PutUint64(timestamp[:], 1472470996199000)
+
+	var endpoint []byte
+	endpoint = protoField(endpoint, 1, "backend")
+	var remoteEndpoint []byte
+	remoteEndpoint = protoField(remoteEndpoint, 1, "redis")
+	remoteEndpoint = protoField(remoteEndpoint, 2, []byte{10, 0, 0, 2})
+	remoteEndpoint = protoField(remoteEndpoint, 4, uint64(6379))
+	var tag []byte
+	tag = protoField(tag, 1, "db.system")
+	tag = protoField(tag, 2, "redis")
+
+	var span []byte
+	span = protoField(span, 1, []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2})
+	span = protoField(span, 2, []byte{0, 0, 0, 0, 0, 0, 0, 3})
+	span = protoField(span, 3, []byte{0, 0, 0, 0, 0, 0, 0, 4})
+	span = protoField(span, 4, uint64(1))
+	span = protoField(span, 5, "GET")
+	span = protoField(span, 6, timestamp)
+	span = protoField(span, 7, uint64(42))
+	span = protoField(span, 8, endpoint)
+	span = protoField(span, 9, remoteEndpoint)
+	span = protoField(span, 11, tag)
+	span = protoField(span, 12, uint64(1))
+	// an unknown field is ignored
+	span = protoField(span, 42, "unknown")
+	payload := protoField(nil, 1, span)
+
+	req, err := http.NewRequest("POST", "/api/v2/spans", bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	traces, err := decodeZipkinSpans(req)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 1)
+	assert.Equal(t, &pb.Span{
+		Service:  "backend",
+		Name:     "zipkin.client",
+		Resource: "GET",
+		TraceID:  2,
+		SpanID:   4,
+		ParentID: 3,
+		Start:    1472470996199000000,
+		Duration: 42000,
+		Type:     "cache",
+		Meta: map[string]string{
+			"db.system":    "redis",
+			"peer.service": "redis",
+			"out.host":     "10.0.0.2",
+			"out.port":     "6379",
+		},
+		Metrics: map[string]float64{sampler.KeySamplingPriority: float64(sampler.PriorityUserKeep)},
+	}, traces[0][0])
+
+	req, err = http.NewRequest("POST", "/api/v2/spans", bytes.NewReader(payload[:len(payload)-3]))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	_, err = decodeZipkinSpans(req)
+	assert.Equal(t, errZipkinProto, err)
+}
+
+func TestZipkinEndpoint(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		server := httptest.NewServer(r.buildMux())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/api/v2/spans", "application/json", strings.NewReader(zipkinJSONPayload))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		conf := newTestReceiverConfig()
+		conf.ZipkinReceiverEnabled = true
+		r := newTestReceiverFromConfig(conf)
+		server := httptest.NewServer(r.buildMux())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/api/v2/spans", "application/json", strings.NewReader(zipkinJSONPayload))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		payload := <-r.out
+		assert.Len(t, payload.Traces, 1)
+		assert.Equal(t, string(zipkinV2), payload.Source.EndpointVersion)
+		assert.EqualValues(t, 1, payload.Source.TracesReceived)
+		assert.EqualValues(t, 1, payload.Source.PayloadAccepted)
+
+		resp, err = http.Post(server.URL+"/api/v2/spans", "application/json", strings.NewReader(`[{`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -62,6 +63,9 @@ type ObfuscationConfig struct {
 	// HTTP holds the obfuscation settings for HTTP URLs.
 	HTTP HTTPObfuscationConfig `mapstructure:"http"`
 
+	// SQL holds the obfuscation settings for SQL queries.
+	SQL SQLObfuscationConfig `mapstructure:"sql"`
+
 	// RemoveStackTraces specifies whether stack traces should be removed.
 	// More specifically "error.stack" tag values will be cleared.
 	RemoveStackTraces bool `mapstructure:"remove_stack_traces"`
@@ -73,6 +77,18 @@ type ObfuscationConfig struct {
 	// Memcached holds the configuration for obfuscating the "memcached.command" tag
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
+
+	// GraphQL holds the configuration for obfuscating the "graphql.query" tag and the
+	// resource of spans of type "graphql".
+	GraphQL Enablable `mapstructure:"graphql"`
+
+	// Kafka holds the configuration for scrubbing the "kafka.header.<key>" tags of spans
+	// of type "queue" or "kafka".
+	Kafka KeyScrubConfig `mapstructure:"kafka"`
+
+	// GRPC holds the configuration for scrubbing the "grpc.metadata.<key>" tags of spans
+	// of type "rpc" or "grpc".
+	GRPC KeyScrubConfig `mapstructure:"grpc"`
 }
 
 // HTTPObfuscationConfig holds the configuration settings for HTTP obfuscation.
@@ -84,6 +100,24 @@ type HTTPObfuscationConfig struct {
 	RemovePathDigits bool `mapstructure:"remove_paths_with_digits" json:"remove_path_digits"`
 }
 
+// SQLObfuscationConfig holds the configuration settings for SQL obfuscation.
+type SQLObfuscationConfig struct {
+	// TableNames specifies whether the names of the tables addressed by SQL queries should be
+	// extracted into the "sql.tables" tag.
+	TableNames bool `mapstructure:"table_names"`
+}
+
+// KeyScrubConfig holds the configuration for scrubbing the values of message headers or
+// request metadata, based on their key.
+type KeyScrubConfig struct {
+	// Enabled specifies whether scrubbing is enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// ScrubKeys specifies the case insensitive keys whose values are replaced by "?".
+	// The key "*" scrubs all values.
+	ScrubKeys []string `mapstructure:"scrub_keys"`
+}
+
 // Enablable can represent any option that has an "enabled" boolean sub-field.
 type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
@@ -122,6 +156,45 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// List of the actions of the tag filter rules.
+const (
+	// TagFilterReject drops the traces matching the rule.
+	TagFilterReject = "reject"
+	// TagFilterRequire drops the traces not matching the rule.
+	TagFilterRequire = "require"
+	// TagFilterDropSpans removes the spans matching the rule from the traces, which are kept.
+	TagFilterDropSpans = "drop_spans"
+)
+
+// TagFilterRule specifies a rule filtering traces, or spans, based on their tags.
+type TagFilterRule struct {
+	// Name identifies the rule in the counts of traces and spans it dropped. It defaults to "rule_<index>".
+	Name string `mapstructure:"name"`
+
+	// Action specifies what happens to the traces matching the rule: one of TagFilterReject (default),
+	// TagFilterRequire or TagFilterDropSpans.
+	Action string `mapstructure:"action"`
+
+	// Tag specifies the name of the tag that the rule addresses. "resource.name" targets the resource.
+	Tag string `mapstructure:"tag"`
+
+	// Pattern specifies a regexp pattern that the value of the tag must match. When empty,
+	// the tag only needs to be present.
+	Pattern string `mapstructure:"pattern"`
+
+	// Re holds the compiled Pattern and is only used internally.
+	Re *regexp.Regexp `mapstructure:"-"`
+
+	// AnySpan causes the reject and require rules to match traces having any matching span,
+	// instead of only looking at their root span.
+	AnySpan bool `mapstructure:"any_span"`
+
+	// Services and Envs limit the rule to the traces of the given services and envs. For the
+	// drop_spans action, the service of each span is considered rather than the service of the root.
+	Services []string `mapstructure:"services"`
+	Envs     []string `mapstructure:"envs"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -137,6 +210,73 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// PrometheusStatsConfig holds the configuration of the /metrics endpoint of the receiver, which
+// exposes the stats computed by the agent in the Prometheus text format.
+type PrometheusStatsConfig struct {
+	// Enabled reports whether the endpoint is enabled.
+	Enabled bool
+
+	// MaxSeries specifies the maximum number of series exposed. Once it is reached, the stats
+	// of the new series are aggregated into a single overflow series.
+	MaxSeries int
+}
+
+// SpoolConfig holds the configuration of the on-disk spool of the writers, which stores the payloads
+// that could not be sent nor kept in memory while the intake is unreachable. The traces and stats
+// writers have separate quotas; a quota of 0 disables the spool of the writer.
+type SpoolConfig struct {
+	// Path specifies the directory in which the payloads are stored.
+	Path string
+
+	// TracesMaxSize specifies the maximum disk space, in bytes, used by the trace payloads
+	// of each endpoint.
+	TracesMaxSize int64
+
+	// StatsMaxSize specifies the maximum disk space, in bytes, used by the stats payloads
+	// of each endpoint.
+	StatsMaxSize int64
+
+	// MaxDiskRatio specifies the maximum ratio of the disk capacity in use above which
+	// payloads are no longer stored.
+	MaxDiskRatio float64
+}
+
+// TailSamplingConfig holds the configuration of the tail-based sampling stage, which buffers
+// the traces dropped by the other samplers and keeps the ones matching a rule once complete.
+type TailSamplingConfig struct {
+	// Enabled reports whether the tail-based sampling stage is enabled.
+	Enabled bool
+
+	// DecisionWait is how long traces are buffered, starting from their first chunk,
+	// before the rules are evaluated on them.
+	DecisionWait time.Duration
+
+	// MaxSpans limits the number of spans buffered. Once it is reached, the oldest
+	// traces are evicted and decided on the spans received so far.
+	MaxSpans int
+
+	// Rules specifies the rules a trace is kept for. A trace is kept when it matches any rule.
+	Rules []*TailSamplingRule
+}
+
+// TailSamplingRule specifies a tail-based sampling rule. A trace matches a rule when it
+// matches all of its conditions, and a rule needs at least one condition.
+type TailSamplingRule struct {
+	// Name identifies the rule in the logs and on the traces it keeps.
+	Name string `mapstructure:"name"`
+
+	// MinDurationSeconds matches the traces lasting at least this long, from the start
+	// of their first span to the end of their last one. Fractions are permitted.
+	MinDurationSeconds float64 `mapstructure:"min_duration_seconds"`
+
+	// Error matches the traces having at least one span with an error.
+	Error bool `mapstructure:"error"`
+
+	// Tag matches the traces having at least one span with the given tag, of the
+	// form "key" to match any value, or "key:value".
+	Tag string `mapstructure:"tag"`
+}
+
 func (c *AgentConfig) applyDatadogConfig() error {
 	if len(c.Endpoints) == 0 {
 		c.Endpoints = []*Endpoint{{}}
@@ -235,6 +375,28 @@ func (c *AgentConfig) applyDatadogConfig() error {
 	if config.Datadog.IsSet("apm_config.connection_limit") {
 		c.ConnectionLimit = config.Datadog.GetInt("apm_config.connection_limit")
 	}
+	if config.Datadog.IsSet("apm_config.zipkin_receiver.enabled") {
+		c.ZipkinReceiverEnabled = config.Datadog.GetBool("apm_config.zipkin_receiver.enabled")
+	}
+	if config.Datadog.IsSet("apm_config.jaeger_receiver.enabled") {
+		c.JaegerReceiverEnabled = config.Datadog.GetBool("apm_config.jaeger_receiver.enabled")
+	}
+	c.CapturePath = filepath.Join(config.Datadog.GetString("run_path"), "trace_capture")
+	if k := "apm_config.capture_path"; config.Datadog.IsSet(k) {
+		c.CapturePath = config.Datadog.GetString(k)
+	}
+	if config.Datadog.IsSet("apm_config.extra_aggregators") {
+		c.ExtraAggregators = config.Datadog.GetStringSlice("apm_config.extra_aggregators")
+	}
+	if config.Datadog.IsSet("apm_config.extra_aggregators_max_cardinality") {
+		c.ExtraAggregatorsMaxCardinality = config.Datadog.GetInt("apm_config.extra_aggregators_max_cardinality")
+	}
+	if k := "apm_config.prometheus_stats.enabled"; config.Datadog.IsSet(k) {
+		c.PrometheusStats.Enabled = config.Datadog.GetBool(k)
+	}
+	if k := "apm_config.prometheus_stats.max_series"; config.Datadog.IsSet(k) {
+		c.PrometheusStats.MaxSeries = config.Datadog.GetInt(k)
+	}
 	if config.Datadog.IsSet("apm_config.extra_sample_rate") {
 		c.ExtraSampleRate = config.Datadog.GetFloat64("apm_config.extra_sample_rate")
 	}
@@ -300,6 +462,24 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if k := "apm_config.tail_sampling.enabled"; config.Datadog.IsSet(k) {
+		c.TailSampling.Enabled = config.Datadog.GetBool(k)
+	}
+	if k := "apm_config.tail_sampling.decision_wait_seconds"; config.Datadog.IsSet(k) {
+		c.TailSampling.DecisionWait = time.Duration(config.Datadog.GetFloat64(k) * float64(time.Second))
+	}
+	if k := "apm_config.tail_sampling.max_spans"; config.Datadog.IsSet(k) {
+		c.TailSampling.MaxSpans = config.Datadog.GetInt(k)
+	}
+	if k := "apm_config.tail_sampling.rules"; config.Datadog.IsSet(k) {
+		var rules []*TailSamplingRule
+		if err := config.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"rule_name\",\"min_duration_seconds\":1.5,\"error\":true,\"tag\":\"key:value\"}]', error: %v", k, err)
+		} else {
+			c.TailSampling.Rules = rules
+		}
+	}
+
 	if config.Datadog.IsSet("apm_config.filter_tags.require") {
 		tags := config.Datadog.GetStringSlice("apm_config.filter_tags.require")
 		for _, tag := range tags {
@@ -313,6 +493,18 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if k := "apm_config.filter_tags.rules"; config.Datadog.IsSet(k) {
+		var rules []*TagFilterRule
+		if err := config.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"rule_name\",\"action\":\"reject\",\"tag\":\"tag_name\",\"pattern\":\"pattern\",\"any_span\":true,\"services\":[\"service\"],\"envs\":[\"env\"]}]', error: %v", k, err)
+		} else {
+			if err := compileTagFilterRules(rules); err != nil {
+				osutil.Exitf("filter_tags.rules: %s", err)
+			}
+			c.TagFilterRules = rules
+		}
+	}
+
 	// undocumented
 	if config.Datadog.IsSet("apm_config.max_cpu_percent") {
 		c.MaxCPU = config.Datadog.GetFloat64("apm_config.max_cpu_percent") / 100
@@ -330,6 +522,19 @@ func (c *AgentConfig) applyDatadogConfig() error {
 			log.Errorf("Error reading writer config %q: %v", key, err)
 		}
 	}
+	c.Spool.Path = filepath.Join(config.Datadog.GetString("run_path"), "trace_spool")
+	if k := "apm_config.spool.path"; config.Datadog.IsSet(k) {
+		c.Spool.Path = config.Datadog.GetString(k)
+	}
+	if k := "apm_config.spool.traces_max_size_in_bytes"; config.Datadog.IsSet(k) {
+		c.Spool.TracesMaxSize = config.Datadog.GetInt64(k)
+	}
+	if k := "apm_config.spool.stats_max_size_in_bytes"; config.Datadog.IsSet(k) {
+		c.Spool.StatsMaxSize = config.Datadog.GetInt64(k)
+	}
+	if k := "apm_config.spool.max_disk_ratio"; config.Datadog.IsSet(k) {
+		c.Spool.MaxDiskRatio = config.Datadog.GetFloat64(k)
+	}
 	if config.Datadog.IsSet("apm_config.connection_reset_interval") {
 		c.ConnectionResetInterval = getDuration(config.Datadog.GetInt("apm_config.connection_reset_interval"))
 	}
@@ -446,6 +651,35 @@ func compileReplaceRules(rules []*ReplaceRule) error {
 	return nil
 }
 
+// compileTagFilterRules validates the tag filter rules, sets their default name and action, and compiles
+// their regular expressions. If it fails it returns the first error.
+func compileTagFilterRules(rules []*TagFilterRule) error {
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = "rule_" + strconv.Itoa(i)
+		}
+		switch r.Action {
+		case "":
+			r.Action = TagFilterReject
+		case TagFilterReject, TagFilterRequire, TagFilterDropSpans:
+		default:
+			return fmt.Errorf("rule %q: unknown action %q, it must be one of %q, %q or %q", r.Name, r.Action, TagFilterReject, TagFilterRequire, TagFilterDropSpans)
+		}
+		if r.Tag == "" {
+			return fmt.Errorf("rule %q: all rules must have a \"tag\"", r.Name)
+		}
+		if r.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %q: %s", r.Name, err)
+		}
+		r.Re = re
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -28,6 +28,35 @@ func TestParseRepaceRules(t *testing.T) {
 	}
 }
 
+// TestCompileTagFilterRules tests the compileTagFilterRules helper function.
+func TestCompileTagFilterRules(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		assert := assert.New(t)
+		rules := []*TagFilterRule{
+			{Tag: "http.url", Pattern: "/health$"},
+			{Name: "tenant", Action: TagFilterRequire, Tag: "tenant"},
+		}
+		if err := compileTagFilterRules(rules); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal("rule_0", rules[0].Name)
+		assert.Equal(TagFilterReject, rules[0].Action)
+		assert.Equal("/health$", rules[0].Re.String())
+		assert.Equal("tenant", rules[1].Name)
+		assert.Nil(rules[1].Re)
+	})
+
+	for name, rule := range map[string]*TagFilterRule{
+		"action":  {Action: "drop", Tag: "http.url"},
+		"tag":     {Pattern: "/health$"},
+		"pattern": {Tag: "http.url", Pattern: "[123"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, compileTagFilterRules([]*TagFilterRule{rule}))
+		})
+	}
+}
+
 func TestSplitTag(t *testing.T) {
 	for _, tt := range []struct {
 		tag string
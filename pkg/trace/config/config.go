@@ -63,13 +63,16 @@ type AgentConfig struct {
 	Endpoints []*Endpoint
 
 	// Concentrator
-	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
-	ExtraAggregators []string
+	BucketInterval                 time.Duration         // the size of our pre-aggregation per bucket
+	ExtraAggregators               []string              // span tags added to the dimensions stats are aggregated on
+	ExtraAggregatorsMaxCardinality int                   // maximum number of distinct values per extra aggregator and per bucket, 0 for no limit
+	PrometheusStats                PrometheusStatsConfig // local exposition of the stats computed by the agent
 
 	// Sampler configuration
 	ExtraSampleRate float64
 	TargetTPS       float64
 	MaxEPS          float64
+	TailSampling    *TailSamplingConfig
 
 	// Receiver
 	ReceiverHost    string
@@ -79,11 +82,17 @@ type AgentConfig struct {
 	ReceiverTimeout int
 	MaxRequestBytes int64 // specifies the maximum allowed request size for incoming trace payloads
 
+	ZipkinReceiverEnabled bool // accept Zipkin v2 spans on /api/v2/spans
+	JaegerReceiverEnabled bool // accept Jaeger Thrift batches on /api/traces
+
+	CapturePath string // directory in which the payload captures requested with "trace-agent -capture" are written
+
 	// Writers
 	SynchronousFlushing     bool // Mode where traces are only submitted when FlushAsync is called, used for Serverless Extension
 	StatsWriter             *WriterConfig
 	TraceWriter             *WriterConfig
 	ConnectionResetInterval time.Duration // frequency at which outgoing connections are reset. 0 means no reset is performed
+	Spool                   SpoolConfig   // on-disk buffering of the payloads which could not be sent
 
 	// internal telemetry
 	StatsdHost string
@@ -129,6 +138,9 @@ type AgentConfig struct {
 	// RejectTags specifies a list of tags which must be absent on the root span in order for a trace to be accepted.
 	RejectTags []*Tag
 
+	// TagFilterRules specifies a list of rules filtering traces, or spans, based on their tags.
+	TagFilterRules []*TagFilterRule
+
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 }
@@ -152,11 +164,17 @@ func New() *AgentConfig {
 		DefaultEnv:          "none",
 		Endpoints:           []*Endpoint{{Host: "https://trace.agent.datadoghq.com"}},
 
-		BucketInterval: time.Duration(10) * time.Second,
+		BucketInterval:                 time.Duration(10) * time.Second,
+		ExtraAggregatorsMaxCardinality: 100,
+		PrometheusStats:                PrometheusStatsConfig{MaxSeries: 1000},
 
 		ExtraSampleRate: 1.0,
 		TargetTPS:       10,
 		MaxEPS:          200,
+		TailSampling: &TailSamplingConfig{
+			DecisionWait: 10 * time.Second,
+			MaxSpans:     100000,
+		},
 
 		ReceiverHost:    "localhost",
 		ReceiverPort:    8126,
@@ -165,6 +183,7 @@ func New() *AgentConfig {
 		StatsWriter:             new(WriterConfig),
 		TraceWriter:             new(WriterConfig),
 		ConnectionResetInterval: 0, // disabled
+		Spool:                   SpoolConfig{MaxDiskRatio: 0.80},
 
 		StatsdHost: "localhost",
 		StatsdPort: 8125,
@@ -240,8 +240,26 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.Equal("test", c.DefaultEnv)
 	assert.Equal(123, c.ConnectionLimit)
 	assert.Equal(18126, c.ReceiverPort)
+	assert.True(c.ZipkinReceiverEnabled)
+	assert.False(c.JaegerReceiverEnabled)
+	assert.Equal(PrometheusStatsConfig{Enabled: true, MaxSeries: 200}, c.PrometheusStats)
+	assert.Equal(SpoolConfig{
+		Path:          "/var/spool/trace-agent",
+		TracesMaxSize: 100 * 1024 * 1024,
+		StatsMaxSize:  10 * 1024 * 1024,
+		MaxDiskRatio:  0.5,
+	}, c.Spool)
 	assert.Equal(0.5, c.ExtraSampleRate)
 	assert.Equal(5.0, c.TargetTPS)
+	assert.Equal(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: 2500 * time.Millisecond,
+		MaxSpans:     100000,
+		Rules: []*TailSamplingRule{
+			{Name: "slow", MinDurationSeconds: 1.5},
+			{Name: "gold-errors", Error: true, Tag: "customer.tier:gold"},
+		},
+	}, c.TailSampling)
 	assert.Equal(50.0, c.MaxEPS)
 	assert.Equal(0.5, c.MaxCPU)
 	assert.EqualValues(123.4, c.MaxMemory)
@@ -303,9 +321,13 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.EqualValues([]string{"uid", "cat_id"}, o.Mongo.KeepValues)
 	assert.True(o.HTTP.RemoveQueryString)
 	assert.True(o.HTTP.RemovePathDigits)
+	assert.True(o.SQL.TableNames)
 	assert.True(o.RemoveStackTraces)
 	assert.True(c.Obfuscation.Redis.Enabled)
 	assert.True(c.Obfuscation.Memcached.Enabled)
+	assert.True(o.GraphQL.Enabled)
+	assert.Equal(KeyScrubConfig{Enabled: true, ScrubKeys: []string{"authorization"}}, o.Kafka)
+	assert.Equal(KeyScrubConfig{Enabled: true, ScrubKeys: []string{"authorization", "x-api-key"}}, o.GRPC)
 }
 
 func TestUndocumentedYamlConfig(t *testing.T) {
@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// TagFilter is a filter which drops traces, or some of their spans, based on
+// the tag rules of its settings.
+type TagFilter struct {
+	defaultEnv string
+	rules      []*tagFilterRule
+}
+
+// tagFilterRule is a config.TagFilterRule ready to be evaluated.
+type tagFilterRule struct {
+	*config.TagFilterRule
+
+	services map[string]struct{} // nil means all
+	envs     map[string]struct{} // nil means all
+	dropped  int64               // atomic; number of traces, or spans for TagFilterDropSpans, dropped
+}
+
+// NewTagFilter returns a new TagFilter which will use the given set of rules. The
+// defaultEnv is the env of the traces which do not specify one.
+func NewTagFilter(rules []*config.TagFilterRule, defaultEnv string) *TagFilter {
+	f := &TagFilter{defaultEnv: defaultEnv}
+	for _, r := range rules {
+		f.rules = append(f.rules, &tagFilterRule{
+			TagFilterRule: r,
+			services:      toSet(r.Services),
+			envs:          toSet(r.Envs),
+		})
+	}
+	return f
+}
+
+func toSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// Filter applies the rules to the trace chunk t having the given root. It reports whether the
+// trace should be kept and, if so, returns it without the spans removed by the drop_spans rules.
+// The root span is never removed.
+func (f *TagFilter) Filter(t pb.Trace, root *pb.Span) (pb.Trace, bool) {
+	if len(f.rules) == 0 {
+		return t, true
+	}
+	env := ""
+	for _, r := range f.rules {
+		if r.envs != nil && env == "" {
+			env = f.defaultEnv
+			if v := traceutil.GetEnv(t); v != "" {
+				env = v
+			}
+		}
+		if !r.inEnv(env) {
+			continue
+		}
+		if r.Action == config.TagFilterDropSpans {
+			t = r.dropSpans(t, root)
+			continue
+		}
+		if !r.inService(root.Service) {
+			continue
+		}
+		matched := r.matches(root)
+		for i := 0; r.AnySpan && !matched && i < len(t); i++ {
+			matched = r.matches(t[i])
+		}
+		if matched == (r.Action == config.TagFilterReject) {
+			atomic.AddInt64(&r.dropped, 1)
+			return t, false
+		}
+	}
+	return t, true
+}
+
+// dropSpans removes the spans of t matching the rule, except root. The children of the removed
+// spans are attached to their closest kept ancestor, so that they are not seen as top-level spans.
+func (r *tagFilterRule) dropSpans(t pb.Trace, root *pb.Span) pb.Trace {
+	var parents map[uint64]uint64 // span ID -> parent ID of the dropped spans
+	kept := t[:0]
+	for _, span := range t {
+		if span != root && r.inService(span.Service) && r.matches(span) {
+			if parents == nil {
+				parents = make(map[uint64]uint64)
+			}
+			parents[span.SpanID] = span.ParentID
+			continue
+		}
+		kept = append(kept, span)
+	}
+	if len(parents) == 0 {
+		return kept
+	}
+
+	for _, span := range kept {
+		// bounded in case the parent IDs of the dropped spans form a cycle
+		for i := 0; i < len(parents); i++ {
+			parentID, ok := parents[span.ParentID]
+			if !ok {
+				break
+			}
+			span.ParentID = parentID
+		}
+	}
+	atomic.AddInt64(&r.dropped, int64(len(t)-len(kept)))
+	for i := len(kept); i < len(t); i++ {
+		// release the references to the dropped spans
+		t[i] = nil
+	}
+	return kept
+}
+
+// matches reports whether span has the tag of the rule, with a value matching its pattern if any.
+func (r *tagFilterRule) matches(span *pb.Span) bool {
+	var v string
+	if r.Tag == "resource.name" {
+		v = span.Resource
+	} else {
+		var ok bool
+		if v, ok = span.Meta[r.Tag]; !ok {
+			return false
+		}
+	}
+	return r.Re == nil || r.Re.MatchString(v)
+}
+
+func (r *tagFilterRule) inService(service string) bool {
+	if r.services == nil {
+		return true
+	}
+	_, ok := r.services[service]
+	return ok
+}
+
+func (r *tagFilterRule) inEnv(env string) bool {
+	if r.envs == nil {
+		return true
+	}
+	_, ok := r.envs[env]
+	return ok
+}
+
+// Stats returns the number of traces dropped by each rule since the filter was created, keyed
+// by rule name. For the drop_spans rules, it is the number of spans dropped.
+func (f *TagFilter) Stats() map[string]int64 {
+	stats := make(map[string]int64, len(f.rules))
+	for _, r := range f.rules {
+		stats[r.Name] += atomic.LoadInt64(&r.dropped)
+	}
+	return stats
+}
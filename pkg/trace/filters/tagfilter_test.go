@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTrace(service string, meta ...map[string]string) (pb.Trace, *pb.Span) {
+	var t pb.Trace
+	for i, m := range meta {
+		span := &pb.Span{TraceID: 1, SpanID: uint64(i + 1), Service: service, Resource: "GET /", Meta: m}
+		if i > 0 {
+			span.ParentID = 1
+		}
+		t = append(t, span)
+	}
+	return t, t[0]
+}
+
+func TestTagFilter(t *testing.T) {
+	healthChecks := &config.TagFilterRule{
+		Name:    "health-checks",
+		Action:  config.TagFilterReject,
+		Tag:     "http.url",
+		Pattern: "/(health|ping)$",
+		Re:      regexp.MustCompile("/(health|ping)$"),
+	}
+	canary := &config.TagFilterRule{
+		Name:     "canary",
+		Action:   config.TagFilterReject,
+		Tag:      "canary",
+		AnySpan:  true,
+		Services: []string{"web"},
+		Envs:     []string{"prod"},
+	}
+	tenant := &config.TagFilterRule{
+		Name:     "tenant",
+		Action:   config.TagFilterRequire,
+		Tag:      "tenant",
+		Services: []string{"billing"},
+	}
+	cache := &config.TagFilterRule{
+		Name:    "cache",
+		Action:  config.TagFilterDropSpans,
+		Tag:     "resource.name",
+		Pattern: "^GET cache",
+		Re:      regexp.MustCompile("^GET cache"),
+	}
+	f := NewTagFilter([]*config.TagFilterRule{healthChecks, canary, tenant, cache}, "prod")
+
+	for name, tt := range map[string]struct {
+		trace pb.Trace
+		keep  bool
+	}{
+		"health-check": {
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("web", map[string]string{"http.url": "http://host/health"})
+				return t
+			}(),
+		},
+		"health-check-child": {
+			// only the root is looked at
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("web", map[string]string{"http.url": "http://host/api"}, map[string]string{"http.url": "http://host/ping"})
+				return t
+			}(),
+			keep: true,
+		},
+		"canary-child": {
+			// any span is looked at, in the default env
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("web", nil, map[string]string{"canary": "true"})
+				return t
+			}(),
+		},
+		"canary-other-env": {
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("web", map[string]string{"env": "staging"}, map[string]string{"canary": "true"})
+				return t
+			}(),
+			keep: true,
+		},
+		"canary-other-service": {
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("api", nil, map[string]string{"canary": "true"})
+				return t
+			}(),
+			keep: true,
+		},
+		"billing-without-tenant": {
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("billing", nil)
+				return t
+			}(),
+		},
+		"billing-with-tenant": {
+			trace: func() pb.Trace {
+				t, _ := newTestTrace("billing", map[string]string{"tenant": "acme"})
+				return t
+			}(),
+			keep: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, keep := f.Filter(tt.trace, tt.trace[0])
+			assert.Equal(t, tt.keep, keep)
+		})
+	}
+
+	t.Run("drop-spans", func(t *testing.T) {
+		trace, root := newTestTrace("web", nil, nil, nil, nil)
+		root.Resource = "GET cache root"
+		trace[1].Resource = "GET cache 1"
+		trace[3].Resource = "GET cache 3"
+		got, keep := f.Filter(trace, root)
+		assert.True(t, keep)
+		if assert.Len(t, got, 2) {
+			// the root is kept even though it matches
+			assert.Equal(t, uint64(1), got[0].SpanID)
+			assert.Equal(t, uint64(3), got[1].SpanID)
+		}
+	})
+
+	assert.Equal(t, map[string]int64{"health-checks": 1, "canary": 1, "tenant": 1, "cache": 2}, f.Stats())
+
+	t.Run("empty", func(t *testing.T) {
+		f := NewTagFilter(nil, "")
+		trace, root := newTestTrace("web", nil, nil)
+		got, keep := f.Filter(trace, root)
+		assert.True(t, keep)
+		assert.Equal(t, trace, got)
+		assert.Empty(t, f.Stats())
+	})
+}
+
+func TestTagFilterDropMiddleSpans(t *testing.T) {
+	f := NewTagFilter([]*config.TagFilterRule{{
+		Name:   "cache",
+		Action: config.TagFilterDropSpans,
+		Tag:    "cache",
+	}}, "")
+
+	// root -> cache -> handler -> db
+	//              \-> cache -> handler
+	trace := pb.Trace{
+		{TraceID: 1, SpanID: 1, Service: "web"},
+		{TraceID: 1, SpanID: 2, ParentID: 1, Service: "web", Meta: map[string]string{"cache": "hit"}},
+		{TraceID: 1, SpanID: 3, ParentID: 2, Service: "web"},
+		{TraceID: 1, SpanID: 4, ParentID: 3, Service: "db"},
+		{TraceID: 1, SpanID: 5, ParentID: 2, Service: "web", Meta: map[string]string{"cache": "miss"}},
+		{TraceID: 1, SpanID: 6, ParentID: 5, Service: "web"},
+	}
+	got, keep := f.Filter(trace, trace[0])
+	assert.True(t, keep)
+
+	parents := make(map[uint64]uint64)
+	for _, span := range got {
+		parents[span.SpanID] = span.ParentID
+	}
+	// the children of the dropped spans are attached to their closest kept ancestor
+	assert.Equal(t, map[uint64]uint64{1: 0, 3: 1, 4: 3, 6: 1}, parents)
+
+	traceutil.ComputeTopLevel(got)
+	topLevel := make(map[uint64]bool)
+	for _, span := range got {
+		topLevel[span.SpanID] = traceutil.HasTopLevel(span)
+	}
+	assert.Equal(t, map[uint64]bool{1: true, 3: false, 4: true, 6: false}, topLevel)
+}
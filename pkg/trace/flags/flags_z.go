@@ -5,7 +5,10 @@
 
 package flags
 
-import "flag"
+import (
+	"flag"
+	"time"
+)
 
 var (
 	// ConfigPath specifies the path to the configuration file.
@@ -23,6 +26,13 @@ var (
 	// Info will display information about a running agent.
 	Info bool
 
+	// Capture specifies the duration for which a running agent should capture the payloads it receives.
+	// When zero, no capture is requested.
+	Capture time.Duration
+
+	// Replay specifies the path of a capture file to replay against a running agent.
+	Replay string
+
 	// CPUProfile specifies the path to output CPU profiling information to.
 	// When empty, CPU profiling is disabled.
 	CPUProfile string
@@ -46,6 +56,8 @@ func init() {
 	flag.StringVar(&PIDFilePath, "pid", "", "Path to set pidfile for process")
 	flag.BoolVar(&Version, "version", false, "Show version information and exit")
 	flag.BoolVar(&Info, "info", false, "Show info about running trace agent process and exit")
+	flag.DurationVar(&Capture, "capture", 0, "Capture the payloads received by the running trace agent for the given `duration` (e.g. 30s) and exit")
+	flag.StringVar(&Replay, "replay", "", "Replay the payloads of the given capture `file` against the running trace agent and exit")
 
 	// profiling
 	flag.StringVar(&CPUProfile, "cpuprofile", "", "Write cpu profile to file")
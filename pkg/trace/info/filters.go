@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package info
+
+// UpdateTagFilterInfo updates the number of traces, or spans, dropped by each tag filter rule
+func UpdateTagFilterInfo(dropped map[string]int64) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	tagFilterInfo = dropped
+}
+
+func publishTagFilterInfo() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return tagFilterInfo
+}
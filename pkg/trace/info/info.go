@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 )
 
@@ -33,6 +34,8 @@ var (
 
 	traceWriterInfo TraceWriterInfo
 	statsWriterInfo StatsWriterInfo
+	tailSamplerInfo *sampler.TailSamplerStats // nil unless tail-based sampling is enabled
+	tagFilterInfo   map[string]int64          // traces, or spans, dropped by tag filter rule
 
 	watchdogInfo     watchdog.Info
 	rateByService    map[string]float64
@@ -77,13 +80,22 @@ const (
   {{if lt .Status.RateLimiter.TargetRate 1.0}}
   WARNING: Rate-limiter keep percentage: {{percent .Status.RateLimiter.TargetRate}} %
   {{end}}
+  {{ with .Status.TailSampler }}
+  Tail sampling: {{.Traces}} traces ({{.Spans}} spans) buffered, {{.Kept}} traces kept, {{.Dropped}} traces dropped
+  {{if gt .Evicted 0}}WARNING: Tail sampling evicted {{.Evicted}} traces ({{.EvictedSpans}} spans) before the end of their window{{end}}
+  {{end}}
+  {{ range $rule, $dropped := .Status.TagFilters }}
+  Tag filter rule '{{ $rule }}': {{ $dropped }} dropped
+  {{ end }}
 
   --- Writer stats (1 min) ---
 
   Traces: {{.Status.TraceWriter.Payloads}} payloads, {{.Status.TraceWriter.Traces}} traces, {{if gt .Status.TraceWriter.Events 0}}{{.Status.TraceWriter.Events}} events, {{end}}{{.Status.TraceWriter.Bytes}} bytes
   {{if gt .Status.TraceWriter.Errors 0}}WARNING: Traces API errors (1 min): {{.Status.TraceWriter.Errors}}{{end}}
+  {{if gt .Status.TraceWriter.SpoolPayloads 0}}WARNING: Traces stored on disk: {{.Status.TraceWriter.SpoolPayloads}} payloads, {{.Status.TraceWriter.SpoolBytes}} bytes{{end}}
   Stats: {{.Status.StatsWriter.Payloads}} payloads, {{.Status.StatsWriter.StatsBuckets}} stats buckets, {{.Status.StatsWriter.Bytes}} bytes
   {{if gt .Status.StatsWriter.Errors 0}}WARNING: Stats API errors (1 min): {{.Status.StatsWriter.Errors}}{{end}}
+  {{if gt .Status.StatsWriter.SpoolPayloads 0}}WARNING: Stats stored on disk: {{.Status.StatsWriter.SpoolPayloads}} payloads, {{.Status.StatsWriter.SpoolBytes}} bytes{{end}}
 `
 
 	notRunningTmplSrc = `{{.Banner}}
@@ -219,6 +231,8 @@ func InitInfo(conf *config.AgentConfig) error {
 		expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
 		expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
 		expvar.Publish("ratelimiter", expvar.Func(publishRateLimiterStats))
+		expvar.Publish("tail_sampler", expvar.Func(publishTailSamplerInfo))
+		expvar.Publish("tag_filters", expvar.Func(publishTagFilterInfo))
 
 		// copy the config to ensure we don't expose sensitive data such as API keys
 		c := *conf
@@ -269,14 +283,16 @@ type StatusInfo struct {
 	MemStats struct {
 		Alloc uint64
 	} `json:"memstats"`
-	Version       infoVersion        `json:"version"`
-	Receiver      []TagStats         `json:"receiver"`
-	RateByService map[string]float64 `json:"ratebyservice"`
-	TraceWriter   TraceWriterInfo    `json:"trace_writer"`
-	StatsWriter   StatsWriterInfo    `json:"stats_writer"`
-	Watchdog      watchdog.Info      `json:"watchdog"`
-	RateLimiter   RateLimiterStats   `json:"ratelimiter"`
-	Config        config.AgentConfig `json:"config"`
+	Version       infoVersion               `json:"version"`
+	Receiver      []TagStats                `json:"receiver"`
+	RateByService map[string]float64        `json:"ratebyservice"`
+	TraceWriter   TraceWriterInfo           `json:"trace_writer"`
+	StatsWriter   StatsWriterInfo           `json:"stats_writer"`
+	Watchdog      watchdog.Info             `json:"watchdog"`
+	RateLimiter   RateLimiterStats          `json:"ratelimiter"`
+	TailSampler   *sampler.TailSamplerStats `json:"tail_sampler"`
+	TagFilters    map[string]int64          `json:"tag_filters"`
+	Config        config.AgentConfig        `json:"config"`
 }
 
 func getProgramBanner(version string) (string, string) {
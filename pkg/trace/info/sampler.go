@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package info
+
+import "github.com/DataDog/datadog-agent/pkg/trace/sampler"
+
+// UpdateTailSamplerInfo updates internal tail sampler stats
+func UpdateTailSamplerInfo(tss sampler.TailSamplerStats) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	tailSamplerInfo = &tss
+}
+
+func publishTailSamplerInfo() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return tailSamplerInfo
+}
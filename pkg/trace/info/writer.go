@@ -17,6 +17,8 @@ type TraceWriterInfo struct {
 	BytesUncompressed int64
 	BytesEstimated    int64
 	SingleMaxSize     int64
+	SpoolPayloads     int64 // payloads stored on disk, waiting to be sent
+	SpoolBytes        int64 // disk space used by the stored payloads
 }
 
 // StatsWriterInfo represents statistics from the stats writer.
@@ -29,6 +31,8 @@ type StatsWriterInfo struct {
 	Retries        int64
 	Splits         int64
 	Bytes          int64
+	SpoolPayloads  int64 // payloads stored on disk, waiting to be sent
+	SpoolBytes     int64 // disk space used by the stored payloads
 }
 
 // UpdateTraceWriterInfo updates internal trace writer stats
@@ -38,6 +42,15 @@ func UpdateTraceWriterInfo(tws TraceWriterInfo) {
 	traceWriterInfo = tws
 }
 
+// UpdateTraceWriterSpoolInfo updates the number of payloads stored on disk by the trace writer,
+// and their size in bytes.
+func UpdateTraceWriterSpoolInfo(payloads, size int64) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	traceWriterInfo.SpoolPayloads = payloads
+	traceWriterInfo.SpoolBytes = size
+}
+
 func publishTraceWriterInfo() interface{} {
 	infoMu.RLock()
 	defer infoMu.RUnlock()
@@ -51,6 +64,15 @@ func UpdateStatsWriterInfo(sws StatsWriterInfo) {
 	statsWriterInfo = sws
 }
 
+// UpdateStatsWriterSpoolInfo updates the number of payloads stored on disk by the stats writer,
+// and their size in bytes.
+func UpdateStatsWriterSpoolInfo(payloads, size int64) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	statsWriterInfo.SpoolPayloads = payloads
+	statsWriterInfo.SpoolBytes = size
+}
+
 func publishStatsWriterInfo() interface{} {
 	infoMu.RLock()
 	defer infoMu.RUnlock()
@@ -0,0 +1,320 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// graphQLQueryTag is the tag holding the query document of GraphQL spans.
+	graphQLQueryTag = "graphql.query"
+
+	// nonParsableGraphQL replaces the GraphQL documents which could not be obfuscated.
+	nonParsableGraphQL = "Non-parsable GraphQL query"
+
+	// graphQLCacheKeyPrefix prefixes the keys of the obfuscated GraphQL documents in the
+	// query cache, which is shared with SQL.
+	graphQLCacheKeyPrefix = "graphql:"
+)
+
+// obfuscateGraphQL obfuscates the query document found in the "graphql.query" tag and,
+// when it holds one, in the resource of the span.
+func (o *Obfuscator) obfuscateGraphQL(span *pb.Span) {
+	if strings.IndexByte(span.Resource, '{') != -1 {
+		span.Resource = o.obfuscateGraphQLOrDiscard(span.Resource)
+	}
+	if q, ok := span.Meta[graphQLQueryTag]; ok && q != "" {
+		span.Meta[graphQLQueryTag] = o.obfuscateGraphQLOrDiscard(q)
+	}
+}
+
+// obfuscateGraphQLOrDiscard returns the obfuscated GraphQL document in, or nonParsableGraphQL
+// if in could not be parsed.
+func (o *Obfuscator) obfuscateGraphQLOrDiscard(in string) string {
+	out, err := o.ObfuscateGraphQLString(in)
+	if err != nil {
+		// discard the document to avoid leaking its literals
+		log.Debugf("Error parsing GraphQL query: %v. Query: %q", err, in)
+		return nonParsableGraphQL
+	}
+	return out
+}
+
+// ObfuscateGraphQLString normalizes the given GraphQL document: the literal values of arguments,
+// variable defaults and input objects are replaced by "?", lists of literals are collapsed into
+// a single "?", comments are removed and whitespace is compacted. The shape of the operations,
+// including their names, fields, variables and directives, is kept.
+func (o *Obfuscator) ObfuscateGraphQLString(in string) (string, error) {
+	key := graphQLCacheKeyPrefix + in
+	if v, ok := o.queryCache.Get(key); ok {
+		return v.(string), nil
+	}
+	out, err := obfuscateGraphQLString(in)
+	if err != nil {
+		return "", err
+	}
+	o.queryCache.Set(key, out, int64(len(out)))
+	return out, nil
+}
+
+func obfuscateGraphQLString(in string) (string, error) {
+	var (
+		tok       = graphQLTokenizer{in: in}
+		out       strings.Builder
+		prev      graphQLToken
+		listDepth int // depth of the lists of values being written
+		depth     int // depth of the selection sets and input objects being written
+	)
+	out.Grow(len(in))
+	for {
+		t, err := tok.next()
+		if err != nil {
+			return "", err
+		}
+		if t.kind == gqlEOF {
+			break
+		}
+		inValue := prev.is(":") || prev.is("=") || listDepth > 0
+		switch {
+		case t.kind == gqlString || t.kind == gqlNumber:
+			t = graphQLToken{kind: gqlLiteral, text: "?"}
+		case t.kind == gqlName && inValue && (t.text == "true" || t.text == "false"):
+			t = graphQLToken{kind: gqlLiteral, text: "?"}
+		case t.is("[") && inValue:
+			if tok.skipLiteralList() {
+				t = graphQLToken{kind: gqlLiteral, text: "[?]"}
+				break
+			}
+			listDepth++
+		case t.is("]") && listDepth > 0:
+			listDepth--
+		}
+		if graphQLNeedsSpace(prev, t, depth == 0) {
+			out.WriteByte(' ')
+		}
+		out.WriteString(t.text)
+		prev = t
+		if t.is("{") {
+			depth++
+		} else if t.is("}") {
+			depth--
+		}
+	}
+	if out.Len() == 0 {
+		return "", errors.New("empty document")
+	}
+	return out.String(), nil
+}
+
+// graphQLNeedsSpace reports whether a space should be written between the tokens prev and t,
+// topLevel reporting whether they are outside of any selection set.
+func graphQLNeedsSpace(prev, t graphQLToken, topLevel bool) bool {
+	switch {
+	case prev.kind == gqlEOF:
+		// t is the first token
+		return false
+	case prev.is("(") || prev.is("[") || prev.is("$") || prev.is("@"):
+		return false
+	case t.is(")") || t.is("]") || t.is(":") || t.is(",") || t.is("!"):
+		return false
+	case t.is("(") && prev.kind == gqlName:
+		// field(args), @directive(args), query Name($var: Type), but query ($var: Type)
+		return topLevel && (prev.text == "query" || prev.text == "mutation" || prev.text == "subscription")
+	case prev.is("..."):
+		// ...FragmentName, but ... on Type and ... @directive
+		return t.kind != gqlName || t.text == "on"
+	}
+	return true
+}
+
+// graphQLTokenKind specifies the kind of a GraphQL token.
+type graphQLTokenKind int
+
+const (
+	gqlEOF graphQLTokenKind = iota
+	gqlPunctuator
+	gqlName
+	gqlNumber
+	gqlString
+	gqlLiteral // obfuscated value
+)
+
+// graphQLToken is a lexical token of a GraphQL document.
+type graphQLToken struct {
+	kind graphQLTokenKind
+	text string
+}
+
+// is reports whether t is the punctuator p.
+func (t graphQLToken) is(p string) bool { return t.kind == gqlPunctuator && t.text == p }
+
+// graphQLTokenizer splits GraphQL documents into tokens, as specified by
+// https://spec.graphql.org/June2018/#sec-Language.Source-Text
+// Insignificant characters, except commas, are skipped.
+type graphQLTokenizer struct {
+	in  string
+	pos int
+}
+
+// next returns the next token of the document, having the gqlEOF kind at its end.
+func (tok *graphQLTokenizer) next() (graphQLToken, error) {
+	tok.skipIgnored()
+	if tok.pos >= len(tok.in) {
+		return graphQLToken{kind: gqlEOF}, nil
+	}
+	start := tok.pos
+	c := tok.in[tok.pos]
+	switch {
+	case strings.IndexByte("!$&():=@[]{|},", c) != -1:
+		tok.pos++
+		return graphQLToken{kind: gqlPunctuator, text: tok.in[start:tok.pos]}, nil
+	case c == '.':
+		if !strings.HasPrefix(tok.in[tok.pos:], "...") {
+			return graphQLToken{}, fmt.Errorf("unexpected character %q at position %d", c, start)
+		}
+		tok.pos += 3
+		return graphQLToken{kind: gqlPunctuator, text: "..."}, nil
+	case isGraphQLNameStart(c):
+		for tok.pos < len(tok.in) && isGraphQLNameChar(tok.in[tok.pos]) {
+			tok.pos++
+		}
+		return graphQLToken{kind: gqlName, text: tok.in[start:tok.pos]}, nil
+	case c == '-' || isDigit(rune(c)):
+		return tok.scanNumber()
+	case c == '"':
+		return tok.scanString()
+	}
+	return graphQLToken{}, fmt.Errorf("unexpected character %q at position %d", c, start)
+}
+
+// skipIgnored skips the whitespace, line terminators, comments and byte order marks.
+func (tok *graphQLTokenizer) skipIgnored() {
+	for tok.pos < len(tok.in) {
+		switch c := tok.in[tok.pos]; c {
+		case ' ', '\t', '\n', '\r':
+			tok.pos++
+		case '#':
+			for tok.pos < len(tok.in) && tok.in[tok.pos] != '\n' && tok.in[tok.pos] != '\r' {
+				tok.pos++
+			}
+		default:
+			if strings.HasPrefix(tok.in[tok.pos:], "\ufeff") {
+				tok.pos += len("\ufeff")
+				continue
+			}
+			return
+		}
+	}
+}
+
+// scanNumber scans an IntValue or a FloatValue.
+func (tok *graphQLTokenizer) scanNumber() (graphQLToken, error) {
+	start := tok.pos
+	if tok.in[tok.pos] == '-' {
+		tok.pos++
+	}
+	if !tok.skipDigits() {
+		return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+	}
+	if tok.pos < len(tok.in) && tok.in[tok.pos] == '.' {
+		tok.pos++
+		if !tok.skipDigits() {
+			return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+		}
+	}
+	if tok.pos < len(tok.in) && (tok.in[tok.pos] == 'e' || tok.in[tok.pos] == 'E') {
+		tok.pos++
+		if tok.pos < len(tok.in) && (tok.in[tok.pos] == '+' || tok.in[tok.pos] == '-') {
+			tok.pos++
+		}
+		if !tok.skipDigits() {
+			return graphQLToken{}, fmt.Errorf("invalid number at position %d", start)
+		}
+	}
+	return graphQLToken{kind: gqlNumber, text: tok.in[start:tok.pos]}, nil
+}
+
+// skipDigits skips a sequence of digits, reporting whether it was not empty.
+func (tok *graphQLTokenizer) skipDigits() bool {
+	start := tok.pos
+	for tok.pos < len(tok.in) && isDigit(rune(tok.in[tok.pos])) {
+		tok.pos++
+	}
+	return tok.pos > start
+}
+
+// scanString scans a StringValue, which may be a block string.
+func (tok *graphQLTokenizer) scanString() (graphQLToken, error) {
+	start := tok.pos
+	if strings.HasPrefix(tok.in[tok.pos:], `"""`) {
+		tok.pos += 3
+		for tok.pos < len(tok.in) {
+			switch {
+			case strings.HasPrefix(tok.in[tok.pos:], `\"""`):
+				tok.pos += 4
+			case strings.HasPrefix(tok.in[tok.pos:], `"""`):
+				tok.pos += 3
+				return graphQLToken{kind: gqlString, text: tok.in[start:tok.pos]}, nil
+			default:
+				tok.pos++
+			}
+		}
+		return graphQLToken{}, fmt.Errorf("unterminated block string at position %d", start)
+	}
+	tok.pos++
+	for tok.pos < len(tok.in) {
+		switch tok.in[tok.pos] {
+		case '\\':
+			tok.pos += 2
+		case '"':
+			tok.pos++
+			return graphQLToken{kind: gqlString, text: tok.in[start:tok.pos]}, nil
+		case '\n', '\r':
+			return graphQLToken{}, fmt.Errorf("unterminated string at position %d", start)
+		default:
+			tok.pos++
+		}
+	}
+	return graphQLToken{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+// skipLiteralList is called after reading the opening bracket of a list value. If the list
+// only holds literals, it skips it up to its closing bracket included and returns true.
+// Otherwise, it leaves the tokenizer unchanged and returns false.
+func (tok *graphQLTokenizer) skipLiteralList() bool {
+	start := tok.pos
+	for {
+		t, err := tok.next()
+		switch {
+		case err != nil:
+			tok.pos = start
+			return false
+		case t.is("]"):
+			return true
+		case t.kind == gqlString || t.kind == gqlNumber || t.is(","):
+		case t.kind == gqlName && (t.text == "true" || t.text == "false" || t.text == "null"):
+		default:
+			tok.pos = start
+			return false
+		}
+	}
+}
+
+// isGraphQLNameStart reports whether c may start a GraphQL name, which is made of ASCII
+// letters, digits and underscores.
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return isGraphQLNameStart(c) || isDigit(rune(c))
+}
@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/config/features"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQLString(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{ user(id: 4) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			`query GetUser($id: ID!, $withFriends: Boolean = true) {
+				user(id: $id) {
+					name
+					friends(first: 10, after: "Y3Vyc29y") @include(if: $withFriends) {
+						name # the friends' names
+					}
+				}
+			}`,
+			`query GetUser($id: ID!, $withFriends: Boolean = ?) { user(id: $id) { name friends(first: ?, after: ?) @include(if: $withFriends) { name } } }`,
+		},
+		{
+			`mutation {
+				createUser(input: {email: "jane@example.com", age: -42.5e3, admin: false, role: ADMIN, manager: null}) { id }
+			}`,
+			`mutation { createUser(input: { email: ?, age: ?, admin: ?, role: ADMIN, manager: null }) { id } }`,
+		},
+		{
+			`query { users(ids: [1, 2, 3], tags: ["a", "b"]) { id } }`,
+			`query { users(ids: [?], tags: [?]) { id } }`,
+		},
+		{
+			`query { users(filter: [{name: "x"}, {name: "y"}]) { id } }`,
+			`query { users(filter: [{ name: ? }, { name: ? }]) { id } }`,
+		},
+		{
+			`query ($ids: [ID!]! = [1]) { nodes(ids: $ids) { id } }`,
+			`query ($ids: [ID!]! = [?]) { nodes(ids: $ids) { id } }`,
+		},
+		{
+			`query { search(text: """multi
+			line "quoted" \""" text""") { ...Result ... on User { name } } }
+			fragment Result on Node { id }`,
+			`query { search(text: ?) { ...Result ... on User { name } } } fragment Result on Node { id }`,
+		},
+		{
+			`{ query(text: "x") { id } }`,
+			`{ query(text: ?) { id } }`,
+		},
+		{
+			`subscription { true false }`,
+			`subscription { true false }`,
+		},
+	} {
+		out, err := obfuscateGraphQLString(tt.in)
+		if assert.NoError(t, err, tt.in) {
+			assert.Equal(t, tt.out, out)
+		}
+	}
+
+	for _, in := range []string{
+		``,
+		`# only a comment`,
+		`{ user(name: "unterminated) { id } }`,
+		`{ user(name: """unterminated) { id } }`,
+		`{ user(id: 1.) { id } }`,
+		`{ user(id: 1) { .name } }`,
+		`{ user(id: %) { id } }`,
+	} {
+		_, err := obfuscateGraphQLString(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestObfuscateGraphQL(t *testing.T) {
+	o := NewObfuscator(&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}})
+	defer o.Stop()
+
+	t.Run("resource", func(t *testing.T) {
+		span := &pb.Span{
+			Type:     "graphql",
+			Resource: `query { user(id: 1) { name } }`,
+			Meta:     map[string]string{"graphql.query": `query { user(id: 1) { name } }`},
+		}
+		o.Obfuscate(span)
+		assert.Equal(t, `query { user(id: ?) { name } }`, span.Resource)
+		assert.Equal(t, `query { user(id: ?) { name } }`, span.Meta["graphql.query"])
+	})
+
+	t.Run("operation", func(t *testing.T) {
+		span := &pb.Span{
+			Type:     "graphql",
+			Resource: "query GetUser",
+			Meta:     map[string]string{"graphql.query": `query GetUser { user(id: 1) { name } }`},
+		}
+		o.Obfuscate(span)
+		assert.Equal(t, "query GetUser", span.Resource)
+		assert.Equal(t, `query GetUser { user(id: ?) { name } }`, span.Meta["graphql.query"])
+	})
+
+	t.Run("non-parsable", func(t *testing.T) {
+		span := &pb.Span{
+			Type: "graphql",
+			Meta: map[string]string{"graphql.query": `query { user(name: "jane`},
+		}
+		o.Obfuscate(span)
+		assert.Equal(t, nonParsableGraphQL, span.Meta["graphql.query"])
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		b := &pb.ClientGroupedStats{Type: "graphql", Resource: `{ user(id: 1) { name } }`}
+		o.ObfuscateStatsGroup(b)
+		assert.Equal(t, `{ user(id: ?) { name } }`, b.Resource)
+	})
+}
+
+func TestKeyScrubber(t *testing.T) {
+	s := newKeyScrubber(config.KeyScrubConfig{
+		Enabled:   true,
+		ScrubKeys: []string{"Authorization", "x-api-key"},
+	}, grpcMetadataTagPrefixes)
+	span := &pb.Span{
+		Type: "rpc",
+		Meta: map[string]string{
+			"grpc.metadata.authorization":        "Bearer secret",
+			"grpc.request.metadata.X-Api-Key":    "secret",
+			"grpc.response.metadata.x-api-key":   "secret",
+			"grpc.metadata.user-agent":           "grpc-go/1.40.0",
+			"grpc.method.name":                   "GetUser",
+			"authorization":                      "not a metadata tag",
+			"grpc.metadata.authorization-method": "token",
+		},
+	}
+	s.scrub(span)
+	assert.Equal(t, map[string]string{
+		"grpc.metadata.authorization":        "?",
+		"grpc.request.metadata.X-Api-Key":    "?",
+		"grpc.response.metadata.x-api-key":   "?",
+		"grpc.metadata.user-agent":           "grpc-go/1.40.0",
+		"grpc.method.name":                   "GetUser",
+		"authorization":                      "not a metadata tag",
+		"grpc.metadata.authorization-method": "token",
+	}, span.Meta)
+
+	assert.Nil(t, newKeyScrubber(config.KeyScrubConfig{ScrubKeys: []string{"*"}}, grpcMetadataTagPrefixes))
+	assert.Nil(t, newKeyScrubber(config.KeyScrubConfig{Enabled: true}, grpcMetadataTagPrefixes))
+}
+
+func TestObfuscateGraphQLCache(t *testing.T) {
+	features.Set("sql_cache")
+	defer features.Set(os.Getenv("DD_APM_FEATURES"))
+	o := NewObfuscator(nil)
+	defer o.Stop()
+
+	// the same string obfuscated as GraphQL and as SQL is cached separately
+	in := "query { user }"
+	for i := 0; i < 2; i++ {
+		out, err := o.ObfuscateGraphQLString(in)
+		assert.NoError(t, err)
+		assert.Equal(t, "query { user }", out)
+		for _, q := range []string{in, "graphql:" + in} {
+			_, err := o.ObfuscateSQLString(q)
+			assert.NoError(t, err)
+		}
+		time.Sleep(10 * time.Millisecond) // ristretto sets items asynchronously
+	}
+}
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+var (
+	// kafkaHeaderTagPrefixes lists the prefixes of the tags holding Kafka message headers.
+	kafkaHeaderTagPrefixes = []string{"kafka.header."}
+
+	// grpcMetadataTagPrefixes lists the prefixes of the tags holding gRPC metadata.
+	grpcMetadataTagPrefixes = []string{"grpc.metadata.", "grpc.request.metadata.", "grpc.response.metadata."}
+)
+
+// keyScrubber replaces with "?" the values of the tags holding the headers, or metadata, whose
+// key is one of the configured ones. The tags are named after a prefix followed by the key.
+type keyScrubber struct {
+	prefixes []string
+	all      bool                // scrub all keys
+	keys     map[string]struct{} // lowercase keys to scrub
+}
+
+// newKeyScrubber returns a keyScrubber for the tags having one of the given prefixes, or nil if
+// it is disabled or has no keys to scrub.
+func newKeyScrubber(cfg config.KeyScrubConfig, prefixes []string) *keyScrubber {
+	if !cfg.Enabled || len(cfg.ScrubKeys) == 0 {
+		return nil
+	}
+	s := keyScrubber{
+		prefixes: prefixes,
+		keys:     make(map[string]struct{}, len(cfg.ScrubKeys)),
+	}
+	for _, k := range cfg.ScrubKeys {
+		if k == "*" {
+			s.all = true
+		}
+		// header and metadata keys are case insensitive
+		s.keys[strings.ToLower(k)] = struct{}{}
+	}
+	return &s
+}
+
+// scrub scrubs the values of the span's tags which hold a header having one of the scrubbed keys.
+func (s *keyScrubber) scrub(span *pb.Span) {
+	for k := range span.Meta {
+		if s.scrubs(k) {
+			span.Meta[k] = "?"
+		}
+	}
+}
+
+// scrubs reports whether the value of the tag named tag should be scrubbed.
+func (s *keyScrubber) scrubs(tag string) bool {
+	for _, p := range s.prefixes {
+		if !strings.HasPrefix(tag, p) {
+			continue
+		}
+		if s.all {
+			return true
+		}
+		_, ok := s.keys[strings.ToLower(tag[len(p):])]
+		return ok
+	}
+	return false
+}
@@ -9,6 +9,7 @@ package obfuscate
 
 import (
 	"bytes"
+	"strings"
 	"sync/atomic"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
@@ -26,12 +27,14 @@ type Obfuscator struct {
 	mongo                *jsonObfuscator // nil if disabled
 	sqlExecPlan          *jsonObfuscator // nil if disabled
 	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
+	kafkaHeaders         *keyScrubber    // nil if disabled
+	grpcMetadata         *keyScrubber    // nil if disabled
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// A non-zero value means 'yes'. Different SQL engines behave in different ways and the tokenizer needs
 	// to be generic.
 	// Not safe for concurrent use.
 	sqlLiteralEscapes int32
-	// queryCache keeps a cache of already obfuscated queries.
+	// queryCache keeps a cache of already obfuscated SQL queries and GraphQL documents.
 	queryCache *measuredCache
 }
 
@@ -40,8 +43,23 @@ type Obfuscator struct {
 type SQLOptions struct {
 	// ReplaceDigits causes the obfuscator to replace digits in identifiers and table names with question marks.
 	ReplaceDigits bool `json:"replace_digits"`
+
+	// DBMS specifies the database management system the queries are meant for, so that they are
+	// tokenized following its dialect. It is one of the DBMS* constants; the tokenizer uses a generic
+	// grammar when it is empty or unknown.
+	DBMS string `json:"dbms"`
+
+	// TableNames causes the obfuscator to extract the names of the tables that queries address.
+	TableNames bool `json:"table_names"`
 }
 
+// List of the database management systems whose dialect is understood by the SQL tokenizer.
+const (
+	DBMSPostgres  = "postgresql"
+	DBMSMySQL     = "mysql"
+	DBMSSQLServer = "mssql"
+)
+
 // SetSQLLiteralEscapes sets whether or not escape characters should be treated literally by the SQL obfuscator.
 func (o *Obfuscator) SetSQLLiteralEscapes(ok bool) {
 	if ok {
@@ -77,6 +95,8 @@ func NewObfuscator(cfg *config.ObfuscationConfig) *Obfuscator {
 	if cfg.SQLExecPlanNormalize.Enabled {
 		o.sqlExecPlanNormalize = newJSONObfuscator(&cfg.SQLExecPlanNormalize, &o)
 	}
+	o.kafkaHeaders = newKeyScrubber(cfg.Kafka, kafkaHeaderTagPrefixes)
+	o.grpcMetadata = newKeyScrubber(cfg.GRPC, grpcMetadataTagPrefixes)
 	return &o
 }
 
@@ -104,6 +124,18 @@ func (o *Obfuscator) Obfuscate(span *pb.Span) {
 		o.obfuscateJSON(span, "mongodb.query", o.mongo)
 	case "elasticsearch":
 		o.obfuscateJSON(span, "elasticsearch.body", o.es)
+	case "graphql":
+		if o.opts.GraphQL.Enabled {
+			o.obfuscateGraphQL(span)
+		}
+	case "queue", "kafka":
+		if o.kafkaHeaders != nil {
+			o.kafkaHeaders.scrub(span)
+		}
+	case "rpc", "grpc":
+		if o.grpcMetadata != nil {
+			o.grpcMetadata.scrub(span)
+		}
 	}
 }
 
@@ -120,6 +152,10 @@ func (o *Obfuscator) ObfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		if o.opts.GraphQL.Enabled && strings.IndexByte(b.Resource, '{') != -1 {
+			b.Resource = o.obfuscateGraphQLOrDiscard(b.Resource)
+		}
 	}
 }
 
@@ -140,6 +140,7 @@ func TestObfuscateStatsGroup(t *testing.T) {
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), nonParsableResource},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
+		{statsGroup("graphql", `{ user(id: 1) { name } }`), `{ user(id: 1) { name } }`},
 	} {
 		o.ObfuscateStatsGroup(tt.in)
 		assert.Equal(t, tt.in.Resource, tt.out)
@@ -276,6 +277,54 @@ func TestObfuscateConfig(t *testing.T) {
 		"set key 0 0 0 noreply\r\nvalue",
 		&config.ObfuscationConfig{},
 	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(email: "jane@example.com") { id } }`,
+		`query { user(email: ?) { id } }`,
+		&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(email: "jane@example.com") { id } }`,
+		`query { user(email: "jane@example.com") { id } }`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("kafka/enabled", testConfig(
+		"queue",
+		"kafka.header.Authorization",
+		"Bearer secret",
+		"?",
+		&config.ObfuscationConfig{Kafka: config.KeyScrubConfig{Enabled: true, ScrubKeys: []string{"authorization"}}},
+	))
+
+	t.Run("kafka/disabled", testConfig(
+		"queue",
+		"kafka.header.Authorization",
+		"Bearer secret",
+		"Bearer secret",
+		&config.ObfuscationConfig{Kafka: config.KeyScrubConfig{ScrubKeys: []string{"authorization"}}},
+	))
+
+	t.Run("grpc/enabled", testConfig(
+		"rpc",
+		"grpc.metadata.x-user-email",
+		"jane@example.com",
+		"?",
+		&config.ObfuscationConfig{GRPC: config.KeyScrubConfig{Enabled: true, ScrubKeys: []string{"*"}}},
+	))
+
+	t.Run("grpc/disabled", testConfig(
+		"rpc",
+		"grpc.metadata.x-user-email",
+		"jane@example.com",
+		"jane@example.com",
+		&config.ObfuscationConfig{},
+	))
 }
 
 // TestSQLObfuscationOptionsDeserializationMethod checks if the use of easyjson results in the same deserialization
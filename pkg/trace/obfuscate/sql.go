@@ -166,6 +166,12 @@ func (f *groupingFilter) Filter(token, lastToken TokenKind, buffer []byte) (toke
 	case f.groupFilter > 0 && (token == ',' || token == '?'):
 		// if we are in a group drop all commas
 		return markFilteredGroupable(token), nil, nil
+	case f.groupFilter > 0 && token == ColonCast && isFilteredGroupable(lastToken):
+		// a cast of a grouped value, e.g. '( ?::text, ?::text )', is only kept on the first one
+		if f.groupFilter > 1 {
+			return token, nil, nil
+		}
+		return token, buffer, nil
 	case f.groupMulti > 1:
 		// drop all tokens since we're in a counting group
 		// and they're duplicated
@@ -209,32 +215,62 @@ func (f *groupingFilter) Reset() {
 // some elements such as comments and aliases and obfuscation attempts to hide sensitive information
 // in strings and numbers by redacting them.
 func (o *Obfuscator) ObfuscateSQLString(in string) (*ObfuscatedQuery, error) {
-	return o.ObfuscateSQLStringWithOptions(in, SQLOptions{ReplaceDigits: features.Has("quantize_sql_tables") || features.Has("replace_sql_digits")})
+	return o.ObfuscateSQLStringWithOptions(in, o.sqlOptions(""))
+}
+
+// sqlOptions returns the SQL options resulting from the obfuscator's configuration and enabled features,
+// for queries meant for the given DBMS.
+func (o *Obfuscator) sqlOptions(dbms string) SQLOptions {
+	return SQLOptions{
+		ReplaceDigits: features.Has("quantize_sql_tables") || features.Has("replace_sql_digits"),
+		DBMS:          dbms,
+		TableNames:    o.opts.SQL.TableNames,
+	}
+}
+
+// sqlCacheKeyPrefix prefixes the keys of the obfuscated SQL queries in the query cache, which
+// is shared with GraphQL.
+const sqlCacheKeyPrefix = "sql:"
+
+// cacheKey returns the key under which the result of obfuscating the query in using opts is cached.
+func (opts SQLOptions) cacheKey(in string) string {
+	if opts == (SQLOptions{}) {
+		return sqlCacheKeyPrefix + in
+	}
+	flags := [2]byte{'0', '0'}
+	if opts.ReplaceDigits {
+		flags[0] = '1'
+	}
+	if opts.TableNames {
+		flags[1] = '1'
+	}
+	return sqlCacheKeyPrefix + opts.DBMS + ":" + string(flags[:]) + ":" + in
 }
 
 // ObfuscateSQLStringWithOptions accepts an optional SQLOptions to change the behavior of the obfuscator
 // to quantize and obfuscate the given input SQL query string. Quantization removes some elements such as comments
 // and aliases and obfuscation attempts to hide sensitive information in strings and numbers by redacting them.
 func (o *Obfuscator) ObfuscateSQLStringWithOptions(in string, opts SQLOptions) (*ObfuscatedQuery, error) {
-	if v, ok := o.queryCache.Get(in); ok {
+	key := opts.cacheKey(in)
+	if v, ok := o.queryCache.Get(key); ok {
 		return v.(*ObfuscatedQuery), nil
 	}
 	oq, err := o.obfuscateSQLString(in, opts)
 	if err != nil {
 		return oq, err
 	}
-	o.queryCache.Set(in, oq, oq.Cost())
+	o.queryCache.Set(key, oq, oq.Cost())
 	return oq, nil
 }
 
 func (o *Obfuscator) obfuscateSQLString(in string, opts SQLOptions) (*ObfuscatedQuery, error) {
 	lesc := o.SQLLiteralEscapes()
-	tok := NewSQLTokenizer(in, lesc)
+	tok := NewSQLTokenizerWithDBMS(in, lesc, opts.DBMS)
 	out, err := attemptObfuscationWithOptions(tok, opts)
 	if err != nil && tok.SeenEscape() {
 		// If the tokenizer failed, but saw an escape character in the process,
 		// try again treating escapes differently
-		tok = NewSQLTokenizer(in, !lesc)
+		tok = NewSQLTokenizerWithDBMS(in, !lesc, opts.DBMS)
 		if out, err2 := attemptObfuscationWithOptions(tok, opts); err2 == nil {
 			// If the second attempt succeeded, change the default behavior so that
 			// on the next run we get it right in the first run.
@@ -326,7 +362,7 @@ func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
 // set of filters. An optional SQLOptions may be given to change the behavior.
 func attemptObfuscationWithOptions(tokenizer *SQLTokenizer, opts SQLOptions) (*ObfuscatedQuery, error) {
 	var (
-		storeTableNames = features.Has("table_names")
+		storeTableNames = opts.TableNames || features.Has("table_names")
 		out             = bytes.NewBuffer(make([]byte, 0, len(tokenizer.buf)))
 		err             error
 		lastToken       TokenKind
@@ -365,6 +401,12 @@ func attemptObfuscationWithOptions(tokenizer *SQLTokenizer, opts SQLOptions) (*O
 			if out.Len() != 0 {
 				switch token {
 				case ',':
+				case ColonCast:
+					if tokenizer.dbms == DBMSPostgres {
+						// casts are kept next to their value, e.g. '?::text'
+						break
+					}
+					out.WriteRune(' ')
 				case '=':
 					if lastToken == ':' {
 						// do not add a space before an equals if a colon was
@@ -393,7 +435,7 @@ func (o *Obfuscator) obfuscateSQL(span *pb.Span) {
 	if span.Resource == "" {
 		return
 	}
-	oq, err := o.ObfuscateSQLString(span.Resource)
+	oq, err := o.ObfuscateSQLStringWithOptions(span.Resource, o.sqlOptions(dbmsFromDBType(span.Meta["db.type"])))
 	if err != nil {
 		// we have an error, discard the SQL to avoid polluting user resources.
 		log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
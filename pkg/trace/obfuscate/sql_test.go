@@ -13,6 +13,7 @@ import (
 	"sync/atomic"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/test/testutil"
 	"github.com/stretchr/testify/assert"
@@ -252,6 +253,147 @@ func TestSQLTableNames(t *testing.T) {
 		NewObfuscator(nil).Obfuscate(span)
 		assert.Empty(t, span.Meta["sql.tables"])
 	})
+
+	t.Run("config", func(t *testing.T) {
+		span := &pb.Span{
+			Resource: "SELECT * FROM users u JOIN orders o ON u.id = o.user_id WHERE u.id = 42",
+			Type:     "sql",
+		}
+		NewObfuscator(&config.ObfuscationConfig{SQL: config.SQLObfuscationConfig{TableNames: true}}).Obfuscate(span)
+		assert.Equal(t, "users,orders", span.Meta["sql.tables"])
+	})
+
+	t.Run("dialect", func(t *testing.T) {
+		span := &pb.Span{
+			Resource: "SELECT * FROM [dbo].[users] WHERE name = N'john'",
+			Type:     "sql",
+			Meta:     map[string]string{"db.type": "mssql"},
+		}
+		NewObfuscator(&config.ObfuscationConfig{SQL: config.SQLObfuscationConfig{TableNames: true}}).Obfuscate(span)
+		assert.Equal(t, "SELECT * FROM dbo.users WHERE name = ?", span.Resource)
+		assert.Equal(t, "dbo.users", span.Meta["sql.tables"])
+	})
+}
+
+func TestSQLDialects(t *testing.T) {
+	for _, tt := range []struct {
+		dbms       string
+		query      string
+		obfuscated string
+		tables     string
+	}{
+		{
+			dbms:       DBMSPostgres,
+			query:      `SELECT $$$$, $tag$it's $$ here$tag$ FROM t`,
+			obfuscated: "SELECT ? FROM t",
+			tables:     "t",
+		},
+		{
+			dbms:       DBMSPostgres,
+			query:      `SELECT $a$x$$a$ FROM t WHERE id = $1`,
+			obfuscated: "SELECT ? FROM t WHERE id = ?",
+			tables:     "t",
+		},
+		{
+			dbms:       DBMSPostgres,
+			query:      `SELECT * FROM "public"."users" WHERE "name" = 'C:\' AND id = '1'::int`,
+			obfuscated: "SELECT * FROM public.users WHERE name = ? AND id = ?::int",
+			tables:     "public.users",
+		},
+		{
+			dbms:       DBMSPostgres,
+			query:      `SELECT * FROM users WHERE id IN ('a'::text, 'b'::text, 'c' :: text) AND tags = '{1,2}'::int[]`,
+			obfuscated: "SELECT * FROM users WHERE id IN ( ?::text ) AND tags = ?::int[]",
+			tables:     "users",
+		},
+		{
+			dbms:       DBMSPostgres,
+			query:      `UPDATE users SET bio = E'it\'s me', flags = B'101' WHERE id = $1::uuid`,
+			obfuscated: "UPDATE users SET bio = ? flags = ? WHERE id = ?::uuid",
+			tables:     "users",
+		},
+		{
+			dbms:       DBMSSQLServer,
+			query:      `SELECT [id], [order details].[qty] FROM [dbo].[users] u JOIN dbo.[weird]]name] w ON u.id = w.id WHERE name = N'x' AND p = 'C:'`,
+			obfuscated: "SELECT id, order details.qty FROM dbo.users u JOIN dbo.weird]name w ON u.id = w.id WHERE name = ? AND p = ?",
+			tables:     "dbo.users,dbo.weird]name",
+		},
+		{
+			dbms:       DBMSSQLServer,
+			query:      `SELECT * INTO #tmp FROM [users] AS [u] WHERE "id" = 1`,
+			obfuscated: "SELECT * INTO #tmp FROM users WHERE id = ?",
+			tables:     "#tmp,users",
+		},
+		{
+			dbms:       DBMSMySQL,
+			query:      "SELECT `a b`, `weird$name`, `db`.`table`.`col` FROM `db`.`table` WHERE x IN (\"a\",\"b\") AND y = _utf8mb4'z' AND `q``x` = 1",
+			obfuscated: "SELECT a b, weird$name, db.table.col FROM db.table WHERE x IN ( ? ) AND y = ? AND q`x = ?",
+			tables:     "db.table",
+		},
+		{
+			dbms:       DBMSMySQL,
+			query:      `SELECT * FROM db.` + "`t`" + ` WHERE a = 'it\'s' AND b = "x"`,
+			obfuscated: "SELECT * FROM db.t WHERE a = ? AND b = ?",
+			tables:     "db.t",
+		},
+		{
+			query:      `SELECT * FROM [dbo].[users] WHERE a = '1'::int`,
+			obfuscated: "SELECT * FROM [ dbo ] . [ users ] WHERE a = ? :: int",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			oq, err := NewObfuscator(nil).ObfuscateSQLStringWithOptions(tt.query, SQLOptions{DBMS: tt.dbms, TableNames: true})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.obfuscated, oq.Query)
+			assert.Equal(t, tt.tables, oq.TablesCSV)
+		})
+	}
+
+	t.Run("db.type", func(t *testing.T) {
+		for dbType, dbms := range map[string]string{
+			"postgres":   DBMSPostgres,
+			"postgresql": DBMSPostgres,
+			"MySQL":      DBMSMySQL,
+			"mariadb":    DBMSMySQL,
+			"sqlserver":  DBMSSQLServer,
+			"mssql":      DBMSSQLServer,
+			"oracle":     "",
+			"":           "",
+		} {
+			assert.Equal(t, dbms, dbmsFromDBType(dbType), dbType)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			dbms  string
+			query string
+		}{
+			{DBMSPostgres, "SELECT $a-b$x$a-b$"},
+			{DBMSPostgres, `SELECT * FROM "users`},
+			{DBMSSQLServer, "SELECT * FROM [users"},
+			{DBMSMySQL, "SELECT * FROM `users"},
+		} {
+			_, err := NewObfuscator(nil).ObfuscateSQLStringWithOptions(tt.query, SQLOptions{DBMS: tt.dbms})
+			assert.Error(t, err, tt.query)
+		}
+	})
+}
+
+func TestSQLOptionsCacheKey(t *testing.T) {
+	query := "SELECT * FROM users"
+	assert.Equal(t, "sql:"+query, SQLOptions{}.cacheKey(query))
+	keys := map[string]struct{}{}
+	for _, opts := range []SQLOptions{
+		{},
+		{ReplaceDigits: true},
+		{TableNames: true},
+		{DBMS: DBMSPostgres},
+		{DBMS: DBMSPostgres, ReplaceDigits: true},
+	} {
+		keys[opts.cacheKey(query)] = struct{}{}
+	}
+	assert.Len(t, keys, 5)
 }
 
 func TestSQLReplaceDigits(t *testing.T) {
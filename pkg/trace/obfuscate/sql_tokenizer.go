@@ -8,6 +8,7 @@ package obfuscate
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
@@ -145,17 +146,64 @@ type SQLTokenizer struct {
 
 	literalEscapes bool // indicates we should not treat backslashes as escape characters
 	seenEscape     bool // indicates whether this tokenizer has seen an escape character within a string
+
+	dbms string // the DBMS whose dialect is followed (one of the DBMS* constants), or empty for the generic one
 }
 
 // NewSQLTokenizer creates a new SQLTokenizer for the given SQL string. The literalEscapes argument specifies
 // whether escape characters should be treated literally or as such.
 func NewSQLTokenizer(sql string, literalEscapes bool) *SQLTokenizer {
+	return NewSQLTokenizerWithDBMS(sql, literalEscapes, "")
+}
+
+// NewSQLTokenizerWithDBMS creates a new SQLTokenizer for the given SQL string, following the lexical rules
+// of the given DBMS. The DBMS may be one of the DBMS* constants or a "db.type" span tag value; a generic
+// grammar is used when it is empty or unknown.
+func NewSQLTokenizerWithDBMS(sql string, literalEscapes bool, dbms string) *SQLTokenizer {
 	return &SQLTokenizer{
 		buf:            []byte(sql),
 		literalEscapes: literalEscapes,
+		dbms:           dbmsFromDBType(dbms),
 	}
 }
 
+// dbmsFromDBType returns the DBMS constant matching the given "db.type" span tag value, or an
+// empty string if the tokenizer has no specific dialect for it.
+func dbmsFromDBType(dbType string) string {
+	switch strings.ToLower(dbType) {
+	case DBMSPostgres, "postgres", "pg":
+		return DBMSPostgres
+	case DBMSMySQL, "mariadb":
+		return DBMSMySQL
+	case DBMSSQLServer, "sqlserver", "sql server":
+		return DBMSSQLServer
+	}
+	return ""
+}
+
+// escapeMode specifies how backslashes are treated within strings.
+type escapeMode int
+
+const (
+	// escapeDetect treats backslashes as escape characters, unless literalEscapes is set. Since
+	// the right setting is not known upfront, the obfuscator retries with the opposite one when
+	// a query fails to be tokenized after seeing an escape character.
+	escapeDetect escapeMode = iota
+	// escapeAlways treats backslashes as escape characters, e.g. in Postgres E'...' strings.
+	escapeAlways
+	// escapeNever treats backslashes as regular characters, e.g. in standard Postgres strings.
+	escapeNever
+)
+
+// stringEscapes returns the way backslashes are treated within the regular strings of the dialect.
+func (tkn *SQLTokenizer) stringEscapes() escapeMode {
+	switch tkn.dbms {
+	case DBMSPostgres, DBMSSQLServer:
+		return escapeNever
+	}
+	return escapeDetect
+}
+
 // Reset the underlying buffer and positions
 func (tkn *SQLTokenizer) Reset(in string) {
 	tkn.pos = 0
@@ -221,6 +269,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case ':':
 			if tkn.lastChar == ':' {
 				tkn.advance()
+				if tkn.dbms == DBMSPostgres {
+					return tkn.scanColonCast()
+				}
 				return ColonCast, []byte("::")
 			}
 			if tkn.lastChar != '=' {
@@ -235,7 +286,12 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			default:
 				return TokenKind(ch), tkn.bytes()
 			}
-		case '=', ',', ';', '(', ')', '+', '*', '&', '|', '^', '[', ']', '?':
+		case '[':
+			if tkn.dbms == DBMSSQLServer {
+				return tkn.scanQuotedIdentifier(nil, '[', ']')
+			}
+			return TokenKind(ch), tkn.bytes()
+		case '=', ',', ';', '(', ')', '+', '*', '&', '|', '^', ']', '?':
 			return TokenKind(ch), tkn.bytes()
 		case '.':
 			if isDigit(tkn.lastChar) {
@@ -266,6 +322,10 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 				return TokenKind(ch), tkn.bytes()
 			}
 		case '#':
+			if tkn.dbms == DBMSSQLServer && isLetter(tkn.lastChar) {
+				// temporary table, e.g. #users or ##users
+				return tkn.scanIdentifier()
+			}
 			tkn.advance()
 			return tkn.scanCommentType1("#")
 		case '<':
@@ -312,8 +372,18 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '\'':
 			return tkn.scanString(ch, String)
 		case '"':
+			switch tkn.dbms {
+			case DBMSPostgres, DBMSSQLServer:
+				return tkn.scanQuotedIdentifier(nil, '"', '"')
+			case DBMSMySQL:
+				// unless ANSI_QUOTES is enabled, double quotes delimit strings in MySQL
+				return tkn.scanString(ch, String)
+			}
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
+			if tkn.dbms == DBMSMySQL {
+				return tkn.scanQuotedIdentifier(nil, '`', '`')
+			}
 			return tkn.scanLiteralIdentifier('`')
 		case '%':
 			if tkn.lastChar == '(' {
@@ -336,7 +406,7 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			if kind == DollarQuotedFunc {
 				// this is considered an embedded query, we should try and
 				// obfuscate it
-				out, err := attemptObfuscation(NewSQLTokenizer(string(tok), tkn.literalEscapes))
+				out, err := attemptObfuscation(NewSQLTokenizerWithDBMS(string(tok), tkn.literalEscapes, tkn.dbms))
 				if err != nil {
 					// if we can't obfuscate it, treat it as a regular string
 					return DollarQuotedString, tok
@@ -423,6 +493,20 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	}
 
 	t := tkn.bytes()
+	if tkn.dbms != "" {
+		if tkn.lastChar == '\'' {
+			if escapes, ok := tkn.stringPrefix(t); ok {
+				// prefixed string constant, e.g. E'\n' or N'text'
+				tkn.advance()
+				return tkn.scanStringWithEscapes('\'', String, escapes)
+			}
+		}
+		if open, close := tkn.identifierQuotes(); tkn.lastChar == open && len(t) > 0 && t[len(t)-1] == '.' {
+			// quoted part of a qualified name, e.g. dbo.[users]
+			tkn.advance()
+			return tkn.scanQuotedIdentifier(t, open, close)
+		}
+	}
 	// Space allows us to upper-case identifiers 256 bytes long or less without allocating heap
 	// storage for them, since space is allocated on the stack. A size of 256 bytes was chosen
 	// based on the allowed length of sql identifiers in various sql implementations.
@@ -434,6 +518,125 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	return ID, t
 }
 
+// stringPrefix reports whether the identifier t, directly followed by a single quote, is a prefix of
+// a string constant in the dialect, e.g. E'...' in Postgres, and how backslashes are treated in it.
+func (tkn *SQLTokenizer) stringPrefix(t []byte) (escapeMode, bool) {
+	switch tkn.dbms {
+	case DBMSPostgres:
+		switch string(t) {
+		case "E", "e":
+			return escapeAlways, true
+		case "B", "b", "X", "x":
+			return escapeNever, true
+		}
+	case DBMSMySQL:
+		switch {
+		case string(t) == "N", string(t) == "n", string(t) == "B", string(t) == "b", string(t) == "X", string(t) == "x":
+			return escapeDetect, true
+		case len(t) > 1 && t[0] == '_':
+			// character set introducer, e.g. _utf8mb4'text'
+			return escapeDetect, true
+		}
+	case DBMSSQLServer:
+		if string(t) == "N" || string(t) == "n" {
+			return escapeNever, true
+		}
+	}
+	return escapeDetect, false
+}
+
+// identifierQuotes returns the opening and closing quotes of the identifiers in the dialect, or zero
+// values if it has none.
+func (tkn *SQLTokenizer) identifierQuotes() (open, close rune) {
+	switch tkn.dbms {
+	case DBMSPostgres:
+		return '"', '"'
+	case DBMSMySQL:
+		return '`', '`'
+	case DBMSSQLServer:
+		return '[', ']'
+	}
+	return 0, 0
+}
+
+// scanQuotedIdentifier scans an identifier enclosed between the given quotes, in which the closing
+// quote is escaped by doubling it, e.g. [weird]]name] in SQL Server. The opening quote must have
+// been read already. Qualified names made of quoted and unquoted parts, such as [dbo].[users], are
+// returned as a single identifier without the quotes, prefixed by the given already scanned prefix.
+func (tkn *SQLTokenizer) scanQuotedIdentifier(prefix []byte, open, close rune) (TokenKind, []byte) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(prefix)+32))
+	buf.Write(prefix)
+	quoted := true
+	for {
+		if quoted {
+			for {
+				ch := tkn.lastChar
+				if ch == EndChar {
+					tkn.setErr(`unexpected EOF in quoted identifier, expected "%c"`, close)
+					return LexError, buf.Bytes()
+				}
+				tkn.advance()
+				if ch == close {
+					if tkn.lastChar != close {
+						break
+					}
+					// doubling the closing quote embeds it within the identifier
+					tkn.advance()
+				}
+				buf.WriteRune(ch)
+			}
+		} else {
+			for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '*' {
+				buf.WriteRune(tkn.lastChar)
+				tkn.advance()
+			}
+		}
+		if tkn.lastChar != '.' {
+			break
+		}
+		buf.WriteByte('.')
+		tkn.advance()
+		if quoted = tkn.lastChar == open; quoted {
+			tkn.advance()
+		}
+	}
+	tkn.bytes()
+	return ID, buf.Bytes()
+}
+
+// scanColonCast scans a Postgres type cast following "::", e.g. "::text[]", returning it along
+// with the name of the type so that the cast is kept next to the value it applies to.
+func (tkn *SQLTokenizer) scanColonCast() (TokenKind, []byte) {
+	for unicode.IsSpace(tkn.lastChar) {
+		tkn.advance()
+	}
+	tkn.bytes() // throw away the "::" and the blanks following it
+	if tkn.lastChar == '"' {
+		tkn.advance()
+		kind, name := tkn.scanQuotedIdentifier(nil, '"', '"')
+		if kind == LexError {
+			return kind, name
+		}
+		return ColonCast, append([]byte("::"), name...)
+	}
+	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '.' {
+		tkn.advance()
+	}
+	for tkn.lastChar == '[' {
+		// array type, e.g. "::int[]" or "::int[3]"
+		tkn.advance()
+		for isDigit(tkn.lastChar) {
+			tkn.advance()
+		}
+		if tkn.lastChar != ']' {
+			tkn.setErr(`expected "]" in array type cast, got "%c" (%d)`, tkn.lastChar, tkn.lastChar)
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+	}
+	return ColonCast, append([]byte("::"), tkn.bytes()...)
+}
+
 func (tkn *SQLTokenizer) scanLiteralIdentifier(quote rune) (TokenKind, []byte) {
 	tkn.bytes() // throw away initial quote
 	if !isLetter(tkn.lastChar) && !isDigit(tkn.lastChar) {
@@ -473,21 +676,37 @@ func (tkn *SQLTokenizer) scanFormatParameter(prefix rune) (TokenKind, []byte) {
 // scanDollarQuotedString scans a Postgres dollar-quoted string constant.
 // See: https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-DOLLAR-QUOTING
 func (tkn *SQLTokenizer) scanDollarQuotedString() (TokenKind, []byte) {
-	kind, tag := tkn.scanString('$', String)
-	if kind == LexError {
-		return kind, tkn.bytes()
+	var delim []byte
+	if tkn.dbms == DBMSPostgres {
+		// the tag is empty, or follows the rules of unquoted identifiers without dollar signs
+		delim = []byte{'$'}
+		for isLeadingLetter(tkn.lastChar) || (len(delim) > 1 && isDigit(tkn.lastChar)) {
+			delim = append(delim, runeBytes(tkn.lastChar)...)
+			tkn.advance()
+		}
+		if tkn.lastChar != '$' {
+			tkn.setErr(`invalid dollar-quoted string tag, unexpected "%c" (%d)`, tkn.lastChar, tkn.lastChar)
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+		delim = append(delim, '$')
+	} else {
+		kind, tag := tkn.scanString('$', String)
+		if kind == LexError {
+			return kind, tkn.bytes()
+		}
+		delim = tag
+		// on empty strings, tkn.scanString returns the delimiters
+		if string(delim) != "$$" {
+			// on non-empty strings, the delimiter is $tag$
+			delim = append([]byte{'$'}, delim...)
+			delim = append(delim, '$')
+		}
 	}
 	var (
 		got int
 		buf bytes.Buffer
 	)
-	delim := tag
-	// on empty strings, tkn.scanString returns the delimiters
-	if string(delim) != "$$" {
-		// on non-empty strings, the delimiter is $tag$
-		delim = append([]byte{'$'}, delim...)
-		delim = append(delim, '$')
-	}
 	for {
 		ch := tkn.lastChar
 		tkn.advance()
@@ -509,6 +728,11 @@ func (tkn *SQLTokenizer) scanDollarQuotedString() (TokenKind, []byte) {
 				return LexError, buf.Bytes()
 			}
 			got = 0
+			if byte(ch) == delim[0] {
+				// this might be the start of the delimiter, e.g. the second "$" in "$$tag$"
+				got++
+				continue
+			}
 		}
 		buf.WriteRune(ch)
 	}
@@ -636,6 +860,11 @@ exit:
 }
 
 func (tkn *SQLTokenizer) scanString(delim rune, kind TokenKind) (TokenKind, []byte) {
+	return tkn.scanStringWithEscapes(delim, kind, tkn.stringEscapes())
+}
+
+// scanStringWithEscapes scans a string ending with delim, treating backslashes according to escapes.
+func (tkn *SQLTokenizer) scanStringWithEscapes(delim rune, kind TokenKind, escapes escapeMode) (TokenKind, []byte) {
 	buf := bytes.NewBuffer(tkn.buf[:0])
 	for {
 		ch := tkn.lastChar
@@ -648,10 +877,11 @@ func (tkn *SQLTokenizer) scanString(delim rune, kind TokenKind) (TokenKind, []by
 				// a single delimiter denotes the end of the string
 				break
 			}
-		} else if ch == escapeCharacter {
-			tkn.seenEscape = true
-
-			if !tkn.literalEscapes {
+		} else if ch == escapeCharacter && escapes != escapeNever {
+			if escapes == escapeDetect {
+				tkn.seenEscape = true
+			}
+			if escapes == escapeAlways || !tkn.literalEscapes {
 				// treat as an escape character
 				ch = tkn.lastChar
 				tkn.advance()
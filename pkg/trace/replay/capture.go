@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MaxCaptureDuration specifies the maximum duration of a capture.
+const MaxCaptureDuration = 10 * time.Minute
+
+// MaxCaptureSize specifies the maximum size of a capture file, in bytes. The capture
+// is stopped once it is reached.
+const MaxCaptureSize = 256 * 1024 * 1024
+
+// ErrCaptureInProgress is returned when starting a capture while another one is in progress.
+var ErrCaptureInProgress = errors.New("a capture is already in progress")
+
+// Capture records the requests received by the trace-agent into capture files, while started.
+type Capture struct {
+	dir     string
+	maxSize int64
+	active  int32 // atomic; 1 while capturing
+
+	mu    sync.Mutex
+	f     *os.File
+	w     *Writer
+	timer *time.Timer
+}
+
+// NewCapture returns a new Capture writing its files to the directory dir.
+func NewCapture(dir string) *Capture {
+	return &Capture{dir: dir, maxSize: MaxCaptureSize}
+}
+
+// Active reports whether requests are being captured.
+func (c *Capture) Active() bool {
+	return atomic.LoadInt32(&c.active) == 1
+}
+
+// Start starts capturing the requests for the duration d into a new file, returning its path.
+func (c *Capture) Start(d time.Duration) (string, error) {
+	if d <= 0 || d > MaxCaptureDuration {
+		return "", fmt.Errorf("capture duration must be greater than 0 and at most %s", MaxCaptureDuration)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f != nil {
+		return "", ErrCaptureInProgress
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating capture directory: %v", err)
+	}
+	path := filepath.Join(c.dir, fmt.Sprintf("capture-%s.jsonl", time.Now().Format("20060102-150405.000")))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("error creating capture file: %v", err)
+	}
+	c.f = f
+	c.w = NewWriter(f)
+	c.timer = time.AfterFunc(d, c.Stop)
+	atomic.StoreInt32(&c.active, 1)
+	log.Infof("Capturing the received payloads for %s into %s", d, path)
+	return path, nil
+}
+
+// Stop stops the capture in progress, if any.
+func (c *Capture) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopLocked()
+}
+
+// stopLocked stops the capture in progress, if any. The caller must hold c.mu.
+func (c *Capture) stopLocked() {
+	if c.f == nil {
+		return
+	}
+	atomic.StoreInt32(&c.active, 0)
+	c.timer.Stop()
+	if err := c.w.Flush(); err != nil {
+		log.Errorf("Error writing capture file: %v", err)
+	}
+	if err := c.f.Close(); err != nil {
+		log.Errorf("Error closing capture file: %v", err)
+	}
+	log.Infof("Capture written to %s", c.f.Name())
+	c.f, c.w, c.timer = nil, nil, nil
+}
+
+// Record records the request req if a capture is in progress, reading at most maxBytes of its
+// body. The body is replaced so that it can still be read in full by the request handler.
+func (c *Capture) Record(req *http.Request, maxBytes int64) {
+	if !c.Active() {
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBytes))
+	rec := &Record{
+		Time:   time.Now(),
+		Path:   req.URL.Path,
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+	rec.Header.Del("DD-API-KEY")
+	var rest io.Reader = req.Body
+	if err != nil {
+		// keep the reading error for the handler to see
+		rest = errReader{err}
+	}
+	req.Body = &capturedBody{Reader: io.MultiReader(bytes.NewReader(body), rest), Closer: req.Body}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.w == nil {
+		// the capture ended meanwhile
+		return
+	}
+	if err := c.w.Write(rec); err != nil {
+		log.Errorf("Error writing capture file: %v", err)
+	}
+	if c.w.Size() >= c.maxSize {
+		log.Warnf("Capture file reached its maximum size of %d bytes, stopping the capture", c.maxSize)
+		c.stopLocked()
+	}
+}
+
+// capturedBody is a request body of which the beginning was already read.
+type capturedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader is an io.Reader always returning err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
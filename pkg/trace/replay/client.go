@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+// CaptureResponse is the response of the capture endpoint of the trace-agent.
+type CaptureResponse struct {
+	// Path specifies the path of the capture file.
+	Path string `json:"path"`
+}
+
+// receiverURL returns the base URL of the receiver of the trace-agent running with conf.
+func receiverURL(conf *config.AgentConfig) string {
+	return fmt.Sprintf("http://%s:%d", conf.ReceiverHost, conf.ReceiverPort)
+}
+
+// RequestCapture asks the trace-agent running with conf to capture the payloads it receives
+// for the duration d, and writes the path of the capture file to w.
+func RequestCapture(w io.Writer, conf *config.AgentConfig, d time.Duration) error {
+	u := receiverURL(conf) + "/debug/capture?" + url.Values{"duration": {d.String()}}.Encode()
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(u, "", nil)
+	if err != nil {
+		return fmt.Errorf("could not reach the trace-agent on port %d: %v", conf.ReceiverPort, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("capture request failed (%s): %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var cr CaptureResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
+		return fmt.Errorf("error decoding capture response: %v", err)
+	}
+	fmt.Fprintf(w, "Capturing the payloads received by the trace-agent for %s into %s\n", d, cr.Path)
+	return nil
+}
+
+// Replay sends the requests of the capture file at path to the trace-agent running with conf,
+// preserving the time elapsed between them. It writes a summary of the replay to w.
+func Replay(ctx context.Context, w io.Writer, conf *config.AgentConfig, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return replay(ctx, w, receiverURL(conf), NewReader(f))
+}
+
+func replay(ctx context.Context, w io.Writer, baseURL string, r *Reader) error {
+	client := http.Client{Timeout: 10 * time.Second}
+	var (
+		start, first time.Time
+		sent, failed int
+	)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first.IsZero() {
+			start, first = time.Now(), rec.Time
+		}
+		if wait := rec.Time.Sub(first) - time.Since(start); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		req, err := http.NewRequest(http.MethodPost, baseURL+rec.Path, bytes.NewReader(rec.Body))
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		for k, v := range rec.Header {
+			req.Header[k] = v
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("error sending %s payload: %v", rec.Path, err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		sent++
+		if resp.StatusCode >= http.StatusBadRequest {
+			failed++
+			fmt.Fprintf(w, "%s payload captured at %s was refused: %s\n", rec.Path, rec.Time.Format(time.RFC3339Nano), resp.Status)
+		}
+	}
+	fmt.Fprintf(w, "Replayed %d payloads, %d refused\n", sent, failed)
+	return nil
+}
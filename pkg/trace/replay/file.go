@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay implements the capture of the payloads received by the trace-agent
+// and their replay against a running trace-agent.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// fileFormat and fileVersion identify capture files. They are written as the first
+// line of the file, followed by one JSON encoded Record per line.
+const (
+	fileFormat  = "datadog-trace-capture"
+	fileVersion = 1
+)
+
+// fileHeader is the first line of a capture file.
+type fileHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// Record holds a request received by the trace-agent.
+type Record struct {
+	// Time specifies when the request was received.
+	Time time.Time `json:"time"`
+	// Path specifies the path of the endpoint, e.g. "/v0.4/traces".
+	Path string `json:"path"`
+	// Header holds the request headers.
+	Header http.Header `json:"header"`
+	// Body holds the raw request body.
+	Body []byte `json:"body"`
+}
+
+// Writer writes records to a capture file. It is safe for concurrent use.
+type Writer struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	cw  *countingWriter
+	enc *json.Encoder
+}
+
+// NewWriter returns a new Writer writing to w, starting with the file header.
+func NewWriter(w io.Writer) *Writer {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	enc := json.NewEncoder(cw)
+	// the buffer holds on to write errors, which are returned by the next Write or Flush
+	_ = enc.Encode(fileHeader{Format: fileFormat, Version: fileVersion})
+	return &Writer{w: bw, cw: cw, enc: enc}
+}
+
+// Write writes the record r.
+func (w *Writer) Write(r *Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(r)
+}
+
+// Size returns the number of bytes written so far, including the buffered ones.
+func (w *Writer) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cw.n
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Flush()
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Reader reads records from a capture file.
+type Reader struct {
+	dec *json.Decoder
+	err error // error reading the header
+}
+
+// NewReader returns a new Reader reading the capture file from r.
+func NewReader(r io.Reader) *Reader {
+	rd := &Reader{dec: json.NewDecoder(bufio.NewReader(r))}
+	var h fileHeader
+	switch err := rd.dec.Decode(&h); {
+	case err == io.EOF:
+		rd.err = errors.New("empty capture file")
+	case err != nil:
+		rd.err = fmt.Errorf("error reading capture file header: %v", err)
+	case h.Format != fileFormat:
+		rd.err = errors.New("not a trace-agent capture file")
+	case h.Version > fileVersion:
+		rd.err = fmt.Errorf("unsupported capture file version %d, expected at most %d", h.Version, fileVersion)
+	}
+	return rd
+}
+
+// Next returns the next record, or io.EOF once all records were read.
+func (r *Reader) Next() (*Record, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var rec Record
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("error reading capture file: %v", err)
+	}
+	return &rec, nil
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	now := time.Now()
+	records := []*Record{
+		{Time: now, Path: "/v0.4/traces", Header: http.Header{"Content-Type": {"application/msgpack"}}, Body: []byte{0x90, 0x00, 0xff}},
+		{Time: now.Add(time.Second), Path: "/v0.6/stats", Header: http.Header{}, Body: []byte("stats")},
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, r := range records {
+		require.NoError(t, w.Write(r))
+	}
+	require.NoError(t, w.Flush())
+
+	r := NewReader(&buf)
+	for _, want := range records {
+		got, err := r.Next()
+		require.NoError(t, err)
+		assert.True(t, want.Time.Equal(got.Time))
+		assert.Equal(t, want.Path, got.Path)
+		assert.Equal(t, want.Header, got.Header)
+		assert.Equal(t, want.Body, got.Body)
+	}
+	_, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	t.Run("invalid", func(t *testing.T) {
+		for in, msg := range map[string]string{
+			"":                         "empty capture file",
+			"{\"format\":\"other\"}\n": "not a trace-agent capture file",
+			"{\"format\":\"datadog-trace-capture\",\"version\":2}\n": "unsupported capture file version 2",
+		} {
+			_, err := NewReader(strings.NewReader(in)).Next()
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+	})
+}
+
+func TestCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace-capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := NewCapture(dir)
+
+	send := func(body string) string {
+		req := httptest.NewRequest("POST", "/v0.4/traces", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set("DD-API-KEY", "secret")
+		c.Record(req, 4)
+		// the handler still reads the whole body
+		b, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "ignored", send("ignored"))
+
+	_, err = c.Start(0)
+	assert.Error(t, err)
+	path, err := c.Start(time.Minute)
+	require.NoError(t, err)
+	assert.True(t, c.Active())
+	assert.Equal(t, filepath.Dir(path), dir)
+	_, err = c.Start(time.Minute)
+	assert.Equal(t, ErrCaptureInProgress, err)
+
+	assert.Equal(t, "first", send("first"))
+	assert.Equal(t, "sec", send("sec"))
+	c.Stop()
+	assert.False(t, c.Active())
+	assert.Equal(t, "after", send("after"))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r := NewReader(f)
+	for _, body := range []string{"firs", "sec"} {
+		rec, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "/v0.4/traces", rec.Path)
+		assert.Equal(t, body, string(rec.Body))
+		assert.Equal(t, "application/msgpack", rec.Header.Get("Content-Type"))
+		assert.Empty(t, rec.Header.Get("DD-API-KEY"))
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := c.Start(10 * time.Millisecond)
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return !c.Active() }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		c := NewCapture(t.TempDir())
+		c.maxSize = 1024
+		_, err := c.Start(time.Minute)
+		require.NoError(t, err)
+		c.Record(httptest.NewRequest("POST", "/v0.4/traces", strings.NewReader("small")), 2048)
+		assert.True(t, c.Active())
+		c.Record(httptest.NewRequest("POST", "/v0.4/traces", strings.NewReader(strings.Repeat("a", 1024))), 2048)
+		assert.False(t, c.Active())
+	})
+}
+
+func TestReplay(t *testing.T) {
+	type request struct {
+		path, contentType, body string
+		at                      time.Time
+	}
+	var (
+		mu       sync.Mutex
+		received []request
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		mu.Lock()
+		received = append(received, request{req.URL.Path, req.Header.Get("Content-Type"), string(body), time.Now()})
+		mu.Unlock()
+		if req.URL.Path == "/v0.6/stats" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(&Record{Time: now, Path: "/v0.4/traces", Header: http.Header{"Content-Type": {"application/msgpack"}}, Body: []byte("a")})
+	w.Write(&Record{Time: now.Add(50 * time.Millisecond), Path: "/v0.6/stats", Body: []byte("b")})
+	w.Flush()
+
+	var out bytes.Buffer
+	require.NoError(t, replay(context.Background(), &out, srv.URL, NewReader(&buf)))
+	assert.Contains(t, out.String(), "/v0.6/stats payload captured at")
+	assert.Contains(t, out.String(), "Replayed 2 payloads, 1 refused")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, request{"/v0.4/traces", "application/msgpack", "a", received[0].at}, received[0])
+	assert.Equal(t, "/v0.6/stats", received[1].path)
+	assert.Equal(t, "b", received[1].body)
+	// the time elapsed between the requests is preserved
+	assert.True(t, received[1].at.Sub(received[0].at) >= 40*time.Millisecond)
+}
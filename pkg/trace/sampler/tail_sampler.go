@@ -0,0 +1,303 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// tailSamplingRuleKey is the meta key set on the root span of the traces kept by the
+	// tail sampler, holding the name of the rule they matched.
+	tailSamplingRuleKey = "_dd.tail_sampling.rule"
+	// tailDecisionPeriod specifies the frequency at which buffered traces are decided.
+	tailDecisionPeriod = time.Second
+)
+
+// TailSampler buffers the chunks of the traces dropped by the other samplers for a fixed
+// window, starting with their first chunk, and keeps the complete traces matching one of
+// its rules. It bounds its memory usage by limiting the number of spans buffered: once the
+// limit is reached, the oldest traces are evicted and decided on the spans received so far.
+type TailSampler struct {
+	// Variables access through the 'atomic' package must be 64bits aligned.
+	kept         int64
+	dropped      int64
+	evicted      int64
+	evictedSpans int64
+
+	wait     time.Duration
+	maxSpans int
+	rules    []*tailSamplingRule
+	keep     func(pb.Trace)
+
+	mu     sync.Mutex
+	traces map[uint64]*tailTrace // buffered traces by ID
+	queue  []*tailTrace          // buffered traces in arrival order, which is also the decision order
+	spans  int                   // number of spans buffered
+	totals TailSamplerStats      // totals reported so far
+
+	exit    chan struct{}
+	stopped chan struct{}
+}
+
+// TailSamplerStats represents statistics from the tail sampler. Traces and Spans are the
+// number currently buffered, the other fields are totals since the sampler started.
+type TailSamplerStats struct {
+	Traces       int64
+	Spans        int64
+	Kept         int64
+	Dropped      int64
+	Evicted      int64 // traces decided before the end of their window to bound memory usage
+	EvictedSpans int64
+}
+
+// tailTrace holds the spans received for a trace being buffered.
+type tailTrace struct {
+	id     uint64
+	spans  pb.Trace
+	decide time.Time
+}
+
+// tailSamplingRule is a config.TailSamplingRule ready to be evaluated.
+type tailSamplingRule struct {
+	name        string
+	minDuration int64 // nanoseconds
+	error       bool
+	tagKey      string
+	tagValue    string
+}
+
+// NewTailSampler returns a tail sampler calling keep with each trace it keeps. keep is
+// called from the sampler goroutine, or from the callers of Add on evictions.
+func NewTailSampler(conf *config.TailSamplingConfig, keep func(pb.Trace)) *TailSampler {
+	var rules []*tailSamplingRule
+	for i, r := range conf.Rules {
+		rule := &tailSamplingRule{
+			name:        r.Name,
+			minDuration: int64(r.MinDurationSeconds * float64(time.Second)),
+			error:       r.Error,
+		}
+		if rule.name == "" {
+			rule.name = "rule_" + strconv.Itoa(i)
+		}
+		if r.Tag != "" {
+			rule.tagKey = r.Tag
+			if i := strings.IndexByte(r.Tag, ':'); i > 0 {
+				rule.tagKey, rule.tagValue = r.Tag[:i], r.Tag[i+1:]
+			}
+		}
+		if rule.minDuration <= 0 && !rule.error && rule.tagKey == "" {
+			log.Warnf("Ignoring tail sampling rule %q: it has no condition", rule.name)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return &TailSampler{
+		wait:     conf.DecisionWait,
+		maxSpans: conf.MaxSpans,
+		rules:    rules,
+		keep:     keep,
+		traces:   make(map[uint64]*tailTrace),
+		exit:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start starts deciding the buffered traces once their window ends.
+func (s *TailSampler) Start() {
+	go func() {
+		defer watchdog.LogOnPanic()
+		decideTicker := time.NewTicker(tailDecisionPeriod)
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer decideTicker.Stop()
+		defer statsTicker.Stop()
+		for {
+			select {
+			case now := <-decideTicker.C:
+				s.flush(now)
+			case <-statsTicker.C:
+				s.report()
+			case <-s.exit:
+				// decide all the traces left, without waiting for the end of their window
+				s.flush(time.Now().Add(s.wait))
+				s.report()
+				close(s.stopped)
+				return
+			}
+		}
+	}()
+}
+
+// Stop decides the traces left and stops the sampler.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	<-s.stopped
+}
+
+// Add buffers the given trace chunk. The spans must not be modified afterwards.
+func (s *TailSampler) Add(t pb.Trace) {
+	s.add(time.Now(), t)
+}
+
+func (s *TailSampler) add(now time.Time, t pb.Trace) {
+	if len(t) == 0 {
+		return
+	}
+	s.mu.Lock()
+	tt, ok := s.traces[t[0].TraceID]
+	if !ok {
+		tt = &tailTrace{id: t[0].TraceID, decide: now.Add(s.wait)}
+		s.traces[tt.id] = tt
+		s.queue = append(s.queue, tt)
+	}
+	tt.spans = append(tt.spans, t...)
+	s.spans += len(t)
+	var evicted []*tailTrace
+	for s.maxSpans > 0 && s.spans > s.maxSpans && len(s.queue) > 0 {
+		e := s.pop()
+		atomic.AddInt64(&s.evictedSpans, int64(len(e.spans)))
+		evicted = append(evicted, e)
+	}
+	s.mu.Unlock()
+
+	if len(evicted) > 0 {
+		atomic.AddInt64(&s.evicted, int64(len(evicted)))
+		s.decide(evicted)
+	}
+}
+
+// pop removes the oldest trace from the buffer and returns it. It must be called with mu held.
+func (s *TailSampler) pop() *tailTrace {
+	tt := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.traces, tt.id)
+	s.spans -= len(tt.spans)
+	return tt
+}
+
+// flush decides the traces whose window ended at the given time.
+func (s *TailSampler) flush(now time.Time) {
+	var expired []*tailTrace
+	s.mu.Lock()
+	for len(s.queue) > 0 && !now.Before(s.queue[0].decide) {
+		expired = append(expired, s.pop())
+	}
+	s.mu.Unlock()
+	s.decide(expired)
+}
+
+// decide keeps the given traces if they match a rule.
+func (s *TailSampler) decide(traces []*tailTrace) {
+	for _, tt := range traces {
+		rule := s.match(tt.spans)
+		if rule == nil {
+			atomic.AddInt64(&s.dropped, 1)
+			continue
+		}
+		atomic.AddInt64(&s.kept, 1)
+		root := traceutil.GetRoot(tt.spans)
+		for i, span := range tt.spans {
+			if span != root {
+				continue
+			}
+			// the spans are still read by the concentrator, tag a copy of the root
+			rootCopy := *root
+			rootCopy.Meta = make(map[string]string, len(root.Meta)+1)
+			for k, v := range root.Meta {
+				rootCopy.Meta[k] = v
+			}
+			rootCopy.Meta[tailSamplingRuleKey] = rule.name
+			tt.spans[i] = &rootCopy
+			break
+		}
+		s.keep(tt.spans)
+	}
+}
+
+// match returns the first rule matched by t, or nil.
+func (s *TailSampler) match(t pb.Trace) *tailSamplingRule {
+	for _, r := range s.rules {
+		if r.matches(t) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *tailSamplingRule) matches(t pb.Trace) bool {
+	if r.minDuration > 0 && traceDuration(t) < r.minDuration {
+		return false
+	}
+	var hasError, hasTag bool
+	for _, span := range t {
+		if span.Error != 0 {
+			hasError = true
+		}
+		if r.tagKey != "" {
+			if v, ok := span.Meta[r.tagKey]; ok && (r.tagValue == "" || v == r.tagValue) {
+				hasTag = true
+			}
+		}
+	}
+	return (!r.error || hasError) && (r.tagKey == "" || hasTag)
+}
+
+// traceDuration returns the time elapsed between the start of the first span of t and the end of its last one.
+func traceDuration(t pb.Trace) int64 {
+	var start, end int64
+	for i, span := range t {
+		if i == 0 || span.Start < start {
+			start = span.Start
+		}
+		if e := span.Start + span.Duration; i == 0 || e > end {
+			end = e
+		}
+	}
+	return end - start
+}
+
+// Stats returns the stats of the sampler, up to its last report.
+func (s *TailSampler) Stats() TailSamplerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.totals
+	stats.Traces = int64(len(s.traces))
+	stats.Spans = int64(s.spans)
+	return stats
+}
+
+func (s *TailSampler) report() {
+	kept := atomic.SwapInt64(&s.kept, 0)
+	dropped := atomic.SwapInt64(&s.dropped, 0)
+	evicted := atomic.SwapInt64(&s.evicted, 0)
+	evictedSpans := atomic.SwapInt64(&s.evictedSpans, 0)
+	s.mu.Lock()
+	traces, spans := len(s.traces), s.spans
+	s.totals.Kept += kept
+	s.totals.Dropped += dropped
+	s.totals.Evicted += evicted
+	s.totals.EvictedSpans += evictedSpans
+	s.mu.Unlock()
+
+	metrics.Count("datadog.trace_agent.sampler.tail.kept", kept, nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.dropped", dropped, nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.evicted", evicted, nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.evicted_spans", evictedSpans, nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.traces", float64(traces), nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.spans", float64(spans), nil, 1)
+}
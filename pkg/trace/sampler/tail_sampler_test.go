@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+func newTestTailSampler(maxSpans int, rules ...*config.TailSamplingRule) (*TailSampler, *[]pb.Trace) {
+	var kept []pb.Trace
+	s := NewTailSampler(&config.TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: 10 * time.Second,
+		MaxSpans:     maxSpans,
+		Rules:        rules,
+	}, func(t pb.Trace) { kept = append(kept, t) })
+	return s, &kept
+}
+
+func TestTailSamplerRules(t *testing.T) {
+	s, kept := newTestTailSampler(0,
+		&config.TailSamplingRule{Name: "slow", MinDurationSeconds: 1},
+		&config.TailSamplingRule{Name: "checkout-errors", Error: true, Tag: "http.route:/checkout"},
+		&config.TailSamplingRule{Tag: "customer.tier"},
+		&config.TailSamplingRule{Name: "empty"},
+	)
+	assert.Len(t, s.rules, 3)
+	assert.Equal(t, "rule_2", s.rules[2].name)
+
+	for _, tt := range []struct {
+		trace pb.Trace
+		rule  string
+	}{
+		{
+			// the root is short, but the trace lasts 1.5s
+			trace: pb.Trace{
+				{TraceID: 1, SpanID: 1, Start: 0, Duration: 100},
+				{TraceID: 1, SpanID: 2, ParentID: 1, Start: 1e9, Duration: 5e8},
+			},
+			rule: "slow",
+		},
+		{
+			trace: pb.Trace{
+				{TraceID: 2, SpanID: 1, Duration: 100, Meta: map[string]string{"http.route": "/checkout"}},
+				{TraceID: 2, SpanID: 2, ParentID: 1, Duration: 10, Error: 1},
+			},
+			rule: "checkout-errors",
+		},
+		{
+			trace: pb.Trace{
+				{TraceID: 3, SpanID: 1, Duration: 100, Meta: map[string]string{"http.route": "/cart"}},
+				{TraceID: 3, SpanID: 2, ParentID: 1, Duration: 10, Error: 1},
+			},
+		},
+		{
+			trace: pb.Trace{
+				{TraceID: 4, SpanID: 1, Duration: 100},
+				{TraceID: 4, SpanID: 2, ParentID: 1, Duration: 10, Meta: map[string]string{"customer.tier": "gold"}},
+			},
+			rule: "rule_2",
+		},
+		{
+			trace: pb.Trace{{TraceID: 5, SpanID: 1, Duration: 100}},
+		},
+	} {
+		rule := s.match(tt.trace)
+		if tt.rule == "" {
+			assert.Nil(t, rule)
+			continue
+		}
+		if assert.NotNil(t, rule) {
+			assert.Equal(t, tt.rule, rule.name)
+		}
+	}
+	assert.Empty(t, *kept)
+}
+
+func TestTailSamplerWindow(t *testing.T) {
+	s, kept := newTestTailSampler(0, &config.TailSamplingRule{Name: "errors", Error: true})
+	now := time.Now()
+
+	// the error is in the second chunk of the trace, received later
+	root := &pb.Span{TraceID: 1, SpanID: 1, Duration: 100, Meta: map[string]string{"env": "prod"}}
+	s.add(now, pb.Trace{root})
+	s.add(now, pb.Trace{{TraceID: 2, SpanID: 3, Duration: 100}})
+	s.add(now.Add(5*time.Second), pb.Trace{{TraceID: 1, SpanID: 2, ParentID: 1, Duration: 10, Error: 1}})
+	s.add(now.Add(5*time.Second), pb.Trace{{TraceID: 3, SpanID: 4, Duration: 10, Error: 1}})
+	assert.EqualValues(t, TailSamplerStats{Traces: 3, Spans: 4}, s.Stats())
+
+	s.flush(now.Add(9 * time.Second))
+	assert.Empty(t, *kept)
+
+	s.flush(now.Add(10 * time.Second))
+	assert.Len(t, *kept, 1)
+	trace := (*kept)[0]
+	assert.Len(t, trace, 2)
+	assert.Equal(t, "errors", trace[0].Meta[tailSamplingRuleKey])
+	assert.Equal(t, "prod", trace[0].Meta["env"])
+	// the original root span is left untouched
+	assert.NotContains(t, root.Meta, tailSamplingRuleKey)
+
+	s.report()
+	assert.EqualValues(t, TailSamplerStats{Traces: 1, Spans: 1, Kept: 1, Dropped: 1}, s.Stats())
+
+	s.flush(now.Add(15 * time.Second))
+	assert.Len(t, *kept, 2)
+	s.report()
+	assert.EqualValues(t, TailSamplerStats{Kept: 2, Dropped: 1}, s.Stats())
+}
+
+func TestTailSamplerEviction(t *testing.T) {
+	s, kept := newTestTailSampler(3, &config.TailSamplingRule{Name: "errors", Error: true})
+	now := time.Now()
+
+	s.add(now, pb.Trace{{TraceID: 1, SpanID: 1, Error: 1}, {TraceID: 1, SpanID: 2, ParentID: 1}})
+	s.add(now, pb.Trace{{TraceID: 2, SpanID: 3}})
+	assert.Empty(t, *kept)
+
+	// going over the limit evicts the oldest trace, decided on what was received so far
+	s.add(now, pb.Trace{{TraceID: 3, SpanID: 4}, {TraceID: 3, SpanID: 5, ParentID: 4}})
+	assert.Len(t, *kept, 1)
+	assert.EqualValues(t, 1, (*kept)[0][0].TraceID)
+
+	// a trace larger than the limit is decided right away
+	s.add(now, pb.Trace{{TraceID: 4, SpanID: 6}, {TraceID: 4, SpanID: 7}, {TraceID: 4, SpanID: 8}, {TraceID: 4, SpanID: 9}})
+	s.report()
+	assert.EqualValues(t, TailSamplerStats{Kept: 1, Dropped: 3, Evicted: 4, EvictedSpans: 9}, s.Stats())
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	s, kept := newTestTailSampler(0, &config.TailSamplingRule{Name: "errors", Error: true})
+	s.Start()
+	s.Add(pb.Trace{{TraceID: 1, SpanID: 1, Error: 1}})
+	s.Add(pb.Trace{{TraceID: 2, SpanID: 2}})
+	s.Stop()
+	assert.Len(t, *kept, 1)
+	assert.EqualValues(t, TailSamplerStats{Kept: 1, Dropped: 1}, s.Stats())
+}
@@ -31,6 +31,8 @@ type BucketsAggregationKey struct {
 	Type       string
 	StatusCode uint32
 	Synthetics bool
+	// ExtraTags holds the "key:value" tags of the extra aggregation dimensions, joined by extraTagsSeparator.
+	ExtraTags string
 }
 
 // PayloadAggregationKey specifies the key by which a payload is aggregated.
@@ -54,8 +56,9 @@ func getStatusCode(s *pb.Span) uint32 {
 	return uint32(c)
 }
 
-// NewAggregationFromSpan creates a new aggregation from the provided span and env
-func NewAggregationFromSpan(s *pb.Span, env string, agentHostname, containerID string) Aggregation {
+// NewAggregationFromSpan creates a new aggregation from the provided span and env.
+// The tags of the extra aggregation dimensions are added when extra is not nil.
+func NewAggregationFromSpan(s *pb.Span, env string, agentHostname, containerID string, extra *ExtraAggregators) Aggregation {
 	synthetics := strings.HasPrefix(traceutil.GetMetaDefault(s, tagOrigin, ""), tagSynthetics)
 	hostname := traceutil.GetMetaDefault(s, tagHostname, "")
 	if hostname == "" {
@@ -75,6 +78,7 @@ func NewAggregationFromSpan(s *pb.Span, env string, agentHostname, containerID s
 			Type:       s.Type,
 			StatusCode: getStatusCode(s),
 			Synthetics: synthetics,
+			ExtraTags:  extra.tagsFromSpan(s),
 		},
 	}
 }
@@ -88,6 +92,15 @@ func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 			Name:       g.Name,
 			StatusCode: g.HTTPStatusCode,
 			Synthetics: g.Synthetics,
+			ExtraTags:  strings.Join(g.ExtraTags, extraTagsSeparator),
 		},
 	}
 }
+
+// splitExtraTags returns the "key:value" tags of the extra aggregation dimensions of an aggregation key.
+func splitExtraTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, extraTagsSeparator)
+}
@@ -1,6 +1,7 @@
 package stats
 
 import (
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
@@ -40,6 +41,9 @@ type ClientStatsAggregator struct {
 	agentEnv      string
 	agentHostname string
 
+	extraAggregators *ExtraAggregators
+	lastExtraReset   time.Time
+
 	exit chan struct{}
 	done chan struct{}
 }
@@ -56,6 +60,9 @@ func NewClientStatsAggregator(conf *config.AgentConfig, out chan pb.StatsPayload
 		oldestTs:      alignAggTs(time.Now().Add(bucketDuration - oldestBucketStart)),
 		exit:          make(chan struct{}),
 		done:          make(chan struct{}),
+
+		extraAggregators: NewExtraAggregators(conf.ExtraAggregators, conf.ExtraAggregatorsMaxCardinality),
+		lastExtraReset:   time.Now(),
 	}
 }
 
@@ -94,6 +101,12 @@ func (a *ClientStatsAggregator) flushOnTime(now time.Time) {
 		}
 	}
 	a.oldestTs = flushTs
+	// the cardinality of the extra aggregation dimensions is limited per client bucket duration,
+	// like the one of the stats computed by the concentrator
+	if now.Sub(a.lastExtraReset) >= clientBucketDuration {
+		a.extraAggregators.reset()
+		a.lastExtraReset = now
+	}
 }
 
 func (a *ClientStatsAggregator) flushAll() {
@@ -123,6 +136,9 @@ func (a *ClientStatsAggregator) add(now time.Time, p pb.ClientStatsPayload) {
 			clientBucket.AgentTimeShift = ts.Sub(clientBucketStart).Nanoseconds()
 			clientBucket.Start = uint64(ts.UnixNano())
 		}
+		for i := range clientBucket.Stats {
+			clientBucket.Stats[i].ExtraTags = a.extraAggregators.tagsFromGroup(clientBucket.Stats[i].ExtraTags)
+		}
 		b, ok := a.buckets[ts.Unix()]
 		if !ok {
 			b = &bucket{ts: ts}
@@ -231,6 +247,7 @@ func (b *bucket) aggregationToPayloads() []pb.ClientStatsPayload {
 				HTTPStatusCode: aggrKey.StatusCode,
 				Type:           aggrKey.Type,
 				Synthetics:     aggrKey.Synthetics,
+				ExtraTags:      splitExtraTags(aggrKey.ExtraTags),
 				Hits:           counts.hits,
 				Errors:         counts.errors,
 				Duration:       counts.duration,
@@ -265,6 +282,7 @@ func newBucketAggregationKey(b pb.ClientGroupedStats) BucketsAggregationKey {
 		Type:       b.Type,
 		Synthetics: b.Synthetics,
 		StatusCode: b.HTTPStatusCode,
+		ExtraTags:  strings.Join(b.ExtraTags, extraTagsSeparator),
 	}
 }
 
@@ -65,6 +65,9 @@ func getTestStatsWithStart(start time.Time) pb.ClientStatsPayload {
 	b := pb.ClientStatsBucket{}
 	fuzzer.Fuzz(&b)
 	b.Start = uint64(start.UnixNano())
+	for i := range b.Stats {
+		b.Stats[i].ExtraTags = nil
+	}
 	p := pb.ClientStatsPayload{}
 	fuzzer.Fuzz(&p)
 	p.Tags = nil
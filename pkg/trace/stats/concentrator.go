@@ -44,6 +44,10 @@ type Concentrator struct {
 	mu            sync.Mutex
 	agentEnv      string
 	agentHostname string
+	// extraAggregators adds the configured span tags to the aggregation key, guarded by mu.
+	extraAggregators *ExtraAggregators
+	// Exporter exposes the flushed stats in the Prometheus format. It is nil unless enabled.
+	Exporter *PrometheusExporter
 }
 
 // NewConcentrator initializes a new concentrator ready to be started
@@ -62,6 +66,11 @@ func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now tim
 		exit:          make(chan struct{}),
 		agentEnv:      conf.DefaultEnv,
 		agentHostname: conf.Hostname,
+
+		extraAggregators: NewExtraAggregators(conf.ExtraAggregators, conf.ExtraAggregatorsMaxCardinality),
+	}
+	if conf.PrometheusStats.Enabled {
+		c.Exporter = NewPrometheusExporter(conf.PrometheusStats.MaxSeries, c.extraAggregators)
 	}
 	return &c
 }
@@ -157,7 +166,7 @@ func (c *Concentrator) addNow(i *EnvTrace, containerID string) {
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
 			c.buckets[btime] = b
 		}
-		b.HandleSpan(s, env, c.agentHostname, containerID)
+		b.HandleSpan(s, env, c.agentHostname, containerID, c.extraAggregators)
 	}
 }
 
@@ -178,6 +187,9 @@ func (c *Concentrator) flushNow(now int64) pb.StatsPayload {
 			continue
 		}
 		log.Debugf("flushing bucket %d", ts)
+		if c.Exporter != nil {
+			c.Exporter.add(srb, time.Unix(0, now))
+		}
 		for k, b := range srb.Export() {
 			m[k] = append(m[k], b)
 		}
@@ -190,6 +202,11 @@ func (c *Concentrator) flushNow(now int64) pb.StatsPayload {
 		log.Debugf("update oldestTs to %d", newOldestTs)
 		c.oldestTs = newOldestTs
 	}
+	// the cardinality of the extra aggregation dimensions is limited per flush interval
+	c.extraAggregators.reset()
+	if c.Exporter != nil {
+		c.Exporter.flushed(time.Unix(0, now))
+	}
 	c.mu.Unlock()
 	sb := make([]pb.ClientStatsPayload, 0, len(m))
 	for k, s := range m {
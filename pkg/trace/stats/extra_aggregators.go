@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// ExtraTagOverflowValue replaces the values of an extra aggregation dimension once
+	// its cardinality limit is reached, so that they are aggregated in a single group.
+	ExtraTagOverflowValue = "_overflow"
+
+	// extraTagsSeparator separates the tags of the extra aggregation dimensions in an aggregation key.
+	// Tag values may contain commas, but not null characters.
+	extraTagsSeparator = "\x00"
+)
+
+// ExtraAggregators adds span tags, such as `peer.service` or `db.instance`, to the dimensions
+// stats are aggregated on. To protect the backend from high-cardinality tags, each tag can take
+// at most maxCardinality distinct values between two resets, the next values are replaced by
+// ExtraTagOverflowValue. It is not thread-safe and a nil *ExtraAggregators adds no dimension.
+type ExtraAggregators struct {
+	keys           []string
+	maxCardinality int
+
+	values    map[string]map[string]struct{} // distinct values seen per tag key since the last reset
+	overflows map[string]int64               // values replaced per tag key since the last reset
+}
+
+// NewExtraAggregators returns extra aggregators for the given tag keys, or nil if there is none.
+// A maxCardinality of 0 disables the cardinality limit.
+func NewExtraAggregators(keys []string, maxCardinality int) *ExtraAggregators {
+	var filtered []string
+	seen := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if _, ok := seen[k]; ok || k == "" {
+			continue
+		}
+		seen[k] = struct{}{}
+		filtered = append(filtered, k)
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return &ExtraAggregators{
+		keys:           filtered,
+		maxCardinality: maxCardinality,
+		values:         make(map[string]map[string]struct{}, len(filtered)),
+		overflows:      make(map[string]int64),
+	}
+}
+
+// tagsFromSpan returns the aggregation key tags of the extra dimensions set on s.
+func (e *ExtraAggregators) tagsFromSpan(s *pb.Span) string {
+	if e == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, k := range e.keys {
+		v := s.Meta[k]
+		if v == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(extraTagsSeparator)
+		}
+		b.WriteString(k)
+		b.WriteByte(':')
+		b.WriteString(e.limit(k, v))
+	}
+	return b.String()
+}
+
+// tagsFromGroup returns the tags of the extra dimensions from the given client computed tags, in the
+// configured order and with the cardinality limit applied. Tags of other keys are dropped.
+func (e *ExtraAggregators) tagsFromGroup(tags []string) []string {
+	if e == nil || len(tags) == 0 {
+		return nil
+	}
+	var out []string
+	for _, k := range e.keys {
+		for _, t := range tags {
+			if len(t) <= len(k) || t[len(k)] != ':' || !strings.HasPrefix(t, k) {
+				continue
+			}
+			if v := t[len(k)+1:]; v != "" {
+				out = append(out, k+":"+e.limit(k, v))
+			}
+			break
+		}
+	}
+	return out
+}
+
+// limit returns v, or ExtraTagOverflowValue if the tag k already has too many distinct values.
+func (e *ExtraAggregators) limit(k, v string) string {
+	if e.maxCardinality <= 0 {
+		return v
+	}
+	values, ok := e.values[k]
+	if !ok {
+		values = make(map[string]struct{})
+		e.values[k] = values
+	}
+	if _, ok := values[v]; ok {
+		return v
+	}
+	if len(values) >= e.maxCardinality {
+		e.overflows[k]++
+		return ExtraTagOverflowValue
+	}
+	values[v] = struct{}{}
+	return v
+}
+
+// reset forgets the values seen so far, and reports how many were replaced because of the cardinality limit.
+func (e *ExtraAggregators) reset() {
+	if e == nil {
+		return
+	}
+	for k, n := range e.overflows {
+		metrics.Count("datadog.trace_agent.stats.extra_aggregators.overflow", n, []string{"tag_key:" + k}, 1)
+		delete(e.overflows, k)
+	}
+	for k := range e.values {
+		delete(e.values, k)
+	}
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+func TestNewExtraAggregators(t *testing.T) {
+	assert.Nil(t, NewExtraAggregators(nil, 10))
+	assert.Nil(t, NewExtraAggregators([]string{"", " "}, 10))
+	e := NewExtraAggregators([]string{"peer.service", " db.instance", "peer.service"}, 10)
+	assert.Equal(t, []string{"peer.service", "db.instance"}, e.keys)
+}
+
+func TestExtraAggregatorsTagsFromSpan(t *testing.T) {
+	e := NewExtraAggregators([]string{"peer.service", "db.instance", "tier"}, 2)
+	span := func(meta map[string]string) *pb.Span { return &pb.Span{Meta: meta} }
+
+	assert.Equal(t, "", e.tagsFromSpan(span(nil)))
+	assert.Equal(t, "peer.service:billing\x00tier:gold,premium",
+		e.tagsFromSpan(span(map[string]string{"tier": "gold,premium", "peer.service": "billing", "other": "x"})))
+	assert.Equal(t, []string{"peer.service:billing", "tier:gold,premium"}, splitExtraTags("peer.service:billing\x00tier:gold,premium"))
+
+	// the third distinct value of a tag overflows, the ones already seen are kept
+	assert.Equal(t, "peer.service:payments", e.tagsFromSpan(span(map[string]string{"peer.service": "payments"})))
+	assert.Equal(t, "peer.service:_overflow", e.tagsFromSpan(span(map[string]string{"peer.service": "users"})))
+	assert.Equal(t, "peer.service:billing", e.tagsFromSpan(span(map[string]string{"peer.service": "billing"})))
+	assert.EqualValues(t, 1, e.overflows["peer.service"])
+
+	e.reset()
+	assert.Empty(t, e.overflows)
+	assert.Equal(t, "peer.service:users", e.tagsFromSpan(span(map[string]string{"peer.service": "users"})))
+
+	var nilAggregators *ExtraAggregators
+	assert.Equal(t, "", nilAggregators.tagsFromSpan(span(map[string]string{"peer.service": "users"})))
+	nilAggregators.reset()
+}
+
+func TestExtraAggregatorsTagsFromGroup(t *testing.T) {
+	e := NewExtraAggregators([]string{"peer.service", "tier"}, 1)
+	assert.Nil(t, e.tagsFromGroup(nil))
+	assert.Equal(t, []string{"peer.service:billing", "tier:gold"},
+		e.tagsFromGroup([]string{"tier:gold", "unknown:value", "peer.service:billing", "peer.service.name:x"}))
+	assert.Equal(t, []string{"peer.service:_overflow"}, e.tagsFromGroup([]string{"peer.service:users", "tier:"}))
+
+	var nilAggregators *ExtraAggregators
+	assert.Nil(t, nilAggregators.tagsFromGroup([]string{"peer.service:billing"}))
+}
+
+func TestConcentratorExtraAggregators(t *testing.T) {
+	now := time.Now()
+	c := NewConcentrator(&config.AgentConfig{
+		BucketInterval:                 time.Duration(testBucketInterval),
+		DefaultEnv:                     "env",
+		Hostname:                       "hostname",
+		ExtraAggregators:               []string{"peer.service"},
+		ExtraAggregatorsMaxCardinality: 2,
+	}, make(chan pb.StatsPayload), now)
+
+	var trace pb.Trace
+	for i, peer := range []string{"billing", "payments", "billing", "users", "accounts", ""} {
+		span := testSpan(uint64(i+1), 0, 10, 0, "A1", "resource1", 0)
+		if peer != "" {
+			span.Meta = map[string]string{"peer.service": peer}
+		}
+		trace = append(trace, span)
+	}
+	traceutil.ComputeTopLevel(trace)
+	c.addNow(&EnvTrace{Env: "none", Trace: NewWeightedTrace(trace, traceutil.GetRoot(trace))}, "")
+
+	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+	assert.Len(t, stats.Stats, 1)
+	hits := make(map[string]uint64)
+	for _, g := range stats.Stats[0].Stats[0].Stats {
+		hits[fmt.Sprint(g.ExtraTags)] = g.Hits
+	}
+	assert.Equal(t, map[string]uint64{
+		"[peer.service:billing]":   2,
+		"[peer.service:payments]":  1,
+		"[peer.service:_overflow]": 2,
+		"[]":                       1,
+	}, hits)
+}
+
+func TestAggregatorExtraAggregators(t *testing.T) {
+	a := NewClientStatsAggregator(&config.AgentConfig{
+		DefaultEnv:       "agentEnv",
+		Hostname:         "agentHostname",
+		ExtraAggregators: []string{"peer.service"},
+	}, make(chan pb.StatsPayload, 100))
+	payloadTime := time.Now().Truncate(bucketDuration)
+	insertionTime := payloadTime.Add(time.Second)
+
+	k := BucketsAggregationKey{Service: "s", Name: "n", Resource: "r"}
+	for _, peer := range []string{"billing", "payments", "billing"} {
+		p := payloadWithCounts(payloadTime, k, 1, 0, 10)
+		p.Stats[0].Stats[0].ExtraTags = []string{"peer.service:" + peer, "unknown:value"}
+		a.add(insertionTime, p)
+	}
+	a.flushOnTime(payloadTime.Add(oldestBucketStart))
+
+	var counts pb.StatsPayload
+	for len(a.out) > 0 {
+		counts = <-a.out
+	}
+	assert.Equal(t, keyCounts, counts.Stats[0].AgentAggregation)
+	var tags []string
+	hits := make(map[string]uint64)
+	for _, g := range counts.Stats[0].Stats[0].Stats {
+		tags = append(tags, g.ExtraTags...)
+		hits[g.ExtraTags[0]] = g.Hits
+	}
+	sort.Strings(tags)
+	assert.Equal(t, []string{"peer.service:billing", "peer.service:payments"}, tags)
+	assert.Equal(t, map[string]uint64{"peer.service:billing": 2, "peer.service:payments": 1}, hits)
+}
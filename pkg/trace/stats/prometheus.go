@@ -0,0 +1,282 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// prometheusSeriesTTL specifies the duration after which a series which got no stats is
+	// no longer exposed.
+	prometheusSeriesTTL = 15 * time.Minute
+
+	// prometheusNamespace prefixes the names of the exposed metrics.
+	prometheusNamespace = "datadog_trace"
+)
+
+// prometheusQuantiles are the quantiles of the durations exposed for each series.
+var prometheusQuantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+// prometheusLabels are the names of the labels of the exposed series, before the ones of the
+// extra aggregators.
+var prometheusLabels = []string{"env", "service", "name", "resource", "type", "http_status_code", "version", "synthetics"}
+
+// prometheusSeries holds the stats of an exposed series.
+type prometheusSeries struct {
+	labels   []string
+	hits     float64 // since the series was created
+	errors   float64 // since the series was created
+	duration float64 // total duration, in seconds, since the series was created
+	// sketch holds the distribution of the durations of the last flush in which the series got stats.
+	sketch   *ddsketch.DDSketch
+	flush    uint64 // flush in which sketch was last reset
+	lastSeen time.Time
+}
+
+// PrometheusExporter exposes the stats flushed by the concentrator in the Prometheus text format:
+// the hits and errors as counters and the durations as summaries, with the quantiles of the last
+// flush in which the series got stats. At most maxSeries series are exposed; the stats of the
+// next ones are aggregated in a single series, of which all labels have the ExtraTagOverflowValue.
+// It is safe for concurrent use.
+type PrometheusExporter struct {
+	maxSeries  int
+	extraKeys  []string // keys of the extra aggregators which have a label
+	extraNames []string // label names of extraKeys
+
+	hitsDesc     *prometheus.Desc
+	errorsDesc   *prometheus.Desc
+	durationDesc *prometheus.Desc
+	handler      http.Handler
+
+	mu         sync.Mutex
+	series     map[string]*prometheusSeries // by prometheusSeriesKey of their labels
+	overflow   *prometheusSeries            // nil until maxSeries is reached
+	overflowed int64                        // groups of stats aggregated in overflow since the last flush
+	flush      uint64                       // number of flushes
+}
+
+// NewPrometheusExporter returns a new PrometheusExporter exposing at most maxSeries series, with a
+// label for each of the given extra aggregators keys.
+func NewPrometheusExporter(maxSeries int, extra *ExtraAggregators) *PrometheusExporter {
+	e := &PrometheusExporter{
+		maxSeries: maxSeries,
+		series:    make(map[string]*prometheusSeries),
+	}
+	names := append([]string(nil), prometheusLabels...)
+	if extra != nil {
+		for _, k := range extra.keys {
+			name := prometheusLabelName(k)
+			if name == "" || contains(names, name) {
+				log.Warnf("Extra aggregator %q has no valid or unique Prometheus label name, it is not exposed on /metrics", k)
+				continue
+			}
+			names = append(names, name)
+			e.extraKeys = append(e.extraKeys, k)
+			e.extraNames = append(e.extraNames, name)
+		}
+	}
+	e.hitsDesc = prometheus.NewDesc(prometheusNamespace+"_hits_total", "Number of spans.", names, nil)
+	e.errorsDesc = prometheus.NewDesc(prometheusNamespace+"_errors_total", "Number of spans having an error.", names, nil)
+	e.durationDesc = prometheus.NewDesc(prometheusNamespace+"_duration_seconds", "Duration of the spans.", names, nil)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	e.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog: prometheusErrorLogger{},
+		// expose the valid series even if some could not be gathered
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	return e
+}
+
+// Handler returns the http.Handler serving the stats.
+func (e *PrometheusExporter) Handler() http.Handler {
+	return e.handler
+}
+
+// add adds the stats of the bucket b, which is being flushed.
+func (e *PrometheusExporter) add(b *RawBucket, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for aggr, gs := range b.data {
+		s := e.getSeries(aggr, now)
+		s.hits += gs.hits
+		s.errors += gs.errors
+		s.duration += gs.duration / 1e9
+		s.lastSeen = now
+		if s.flush != e.flush || s.sketch == nil {
+			// only keep the distribution of the last flush
+			s.sketch = gs.okDistribution.Copy()
+			s.flush = e.flush
+		} else if err := s.sketch.MergeWith(gs.okDistribution); err != nil {
+			log.Debugf("Error merging duration sketches: %v", err)
+		}
+		if err := s.sketch.MergeWith(gs.errDistribution); err != nil {
+			log.Debugf("Error merging duration sketches: %v", err)
+		}
+	}
+}
+
+// flushed is called once the buckets of a flush are added. It removes the expired series and
+// reports the number of groups of stats aggregated in the overflow series.
+func (e *PrometheusExporter) flushed(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.flush++
+	for k, s := range e.series {
+		if now.Sub(s.lastSeen) > prometheusSeriesTTL {
+			delete(e.series, k)
+		}
+	}
+	if e.overflow != nil && now.Sub(e.overflow.lastSeen) > prometheusSeriesTTL {
+		e.overflow = nil
+	}
+	if e.overflowed > 0 {
+		metrics.Count("datadog.trace_agent.stats.prometheus.overflow", e.overflowed, nil, 1)
+		e.overflowed = 0
+	}
+}
+
+// getSeries returns the series of the stats aggregated by aggr, creating it if needed. The stats
+// of the aggregations having the same label values, such as the ones differing by their host name,
+// container ID or tags which are not exposed, are merged in the same series.
+func (e *PrometheusExporter) getSeries(aggr Aggregation, now time.Time) *prometheusSeries {
+	labels := e.labels(aggr)
+	k := prometheusSeriesKey(labels)
+	if s, ok := e.series[k]; ok {
+		return s
+	}
+	if len(e.series) < e.maxSeries || e.maxSeries <= 0 {
+		s := &prometheusSeries{labels: labels, lastSeen: now}
+		e.series[k] = s
+		return s
+	}
+	e.overflowed++
+	if e.overflow == nil {
+		labels := make([]string, len(prometheusLabels)+len(e.extraNames))
+		for i := range labels {
+			labels[i] = ExtraTagOverflowValue
+		}
+		e.overflow = &prometheusSeries{labels: labels, lastSeen: now}
+	}
+	return e.overflow
+}
+
+// labels returns the label values of the series of the stats aggregated by aggr.
+func (e *PrometheusExporter) labels(aggr Aggregation) []string {
+	labels := []string{
+		aggr.Env,
+		aggr.Service,
+		aggr.Name,
+		aggr.Resource,
+		aggr.Type,
+		strconv.FormatUint(uint64(aggr.StatusCode), 10),
+		aggr.Version,
+		strconv.FormatBool(aggr.Synthetics),
+	}
+	tags := splitExtraTags(aggr.ExtraTags)
+	for _, key := range e.extraKeys {
+		v := ""
+		for _, t := range tags {
+			if strings.HasPrefix(t, key) && len(t) > len(key) && t[len(key)] == ':' {
+				v = t[len(key)+1:]
+				break
+			}
+		}
+		labels = append(labels, v)
+	}
+	for i, v := range labels {
+		// label values must be valid UTF-8, unlike span tags
+		labels[i] = strings.ToValidUTF8(v, "\uFFFD")
+	}
+	return labels
+}
+
+// prometheusSeriesKey returns the key identifying the series having the given label values, which
+// are valid UTF-8 and can therefore not contain the 0xff separator.
+func prometheusSeriesKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+// Describe implements prometheus.Collector.
+func (e *PrometheusExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.hitsDesc
+	ch <- e.errorsDesc
+	ch <- e.durationDesc
+}
+
+// Collect implements prometheus.Collector.
+func (e *PrometheusExporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.series {
+		e.collectSeries(ch, s)
+	}
+	if e.overflow != nil {
+		e.collectSeries(ch, e.overflow)
+	}
+}
+
+func (e *PrometheusExporter) collectSeries(ch chan<- prometheus.Metric, s *prometheusSeries) {
+	ch <- prometheus.MustNewConstMetric(e.hitsDesc, prometheus.CounterValue, s.hits, s.labels...)
+	ch <- prometheus.MustNewConstMetric(e.errorsDesc, prometheus.CounterValue, s.errors, s.labels...)
+	quantiles := make(map[float64]float64, len(prometheusQuantiles))
+	if s.sketch != nil && !s.sketch.IsEmpty() {
+		values, err := s.sketch.GetValuesAtQuantiles(prometheusQuantiles)
+		if err == nil {
+			for i, q := range prometheusQuantiles {
+				quantiles[q] = values[i] / 1e9
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstSummary(e.durationDesc, uint64(math.Round(s.hits)), s.duration, quantiles, s.labels...)
+}
+
+// prometheusLabelName returns the Prometheus label name of the span tag key k, replacing the invalid
+// characters by underscores. It returns an empty string if k can not be turned into a valid name.
+func prometheusLabelName(k string) string {
+	name := []byte(k)
+	for i, c := range name {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || strings.HasPrefix(string(name), "__") {
+		// names starting with two underscores are reserved
+		return ""
+	}
+	return string(name)
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// prometheusErrorLogger logs the errors of the /metrics handler.
+type prometheusErrorLogger struct{}
+
+// Println implements promhttp.Logger.
+func (prometheusErrorLogger) Println(v ...interface{}) {
+	log.Error(v...)
+}
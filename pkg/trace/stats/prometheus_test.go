@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPrometheusTestConcentrator(now time.Time, maxSeries int) *Concentrator {
+	return NewConcentrator(&config.AgentConfig{
+		BucketInterval:   time.Duration(testBucketInterval),
+		DefaultEnv:       "env",
+		Hostname:         "hostname",
+		ExtraAggregators: []string{"peer.service", "__internal"},
+		PrometheusStats:  config.PrometheusStatsConfig{Enabled: true, MaxSeries: maxSeries},
+	}, make(chan pb.StatsPayload), now)
+}
+
+func addPrometheusTestSpans(c *Concentrator, spans ...*pb.Span) {
+	trace := pb.Trace(spans)
+	traceutil.ComputeTopLevel(trace)
+	c.addNow(&EnvTrace{Env: "prod", Trace: NewWeightedTrace(trace, traceutil.GetRoot(trace))}, "container")
+}
+
+func scrape(t *testing.T, e *PrometheusExporter) string {
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+	return rec.Body.String()
+}
+
+// metricValue returns the value of the series in the scraped output out.
+func metricValue(t *testing.T, out, series string) float64 {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			assert.NoError(t, err)
+			return v
+		}
+	}
+	t.Errorf("series %s not found in:\n%s", series, out)
+	return 0
+}
+
+func TestPrometheusExporter(t *testing.T) {
+	now := time.Now()
+	c := newPrometheusTestConcentrator(now, 10)
+	flushTime := now.UnixNano() + int64(c.bufferLen)*testBucketInterval
+
+	span := func(id uint64, duration int64, err int32) *pb.Span {
+		s := testSpan(id, 0, duration, 0, "A1", "resource1", err)
+		s.Meta = map[string]string{"peer.service": "billing"}
+		return s
+	}
+	addPrometheusTestSpans(c, span(1, 1e6, 0), span(2, 2e6, 0), span(3, 3e6, 1))
+	c.flushNow(flushTime)
+
+	labels := `{env="prod",http_status_code="0",name="query",peer_service="billing",resource="resource1",service="A1",synthetics="false",type="db",version=""}`
+	out := scrape(t, c.Exporter)
+	assert.Contains(t, out, "datadog_trace_hits_total"+labels+" 3\n")
+	assert.Contains(t, out, "datadog_trace_errors_total"+labels+" 1\n")
+	assert.Contains(t, out, "datadog_trace_duration_seconds_count"+labels+" 3\n")
+	assert.Contains(t, out, "datadog_trace_duration_seconds_sum"+labels+" 0.006\n")
+	assert.InDelta(t, 0.002, metricValue(t, out, "datadog_trace_duration_seconds"+strings.TrimSuffix(labels, "}")+`,quantile="0.5"}`), 0.0001)
+	assert.NotContains(t, out, "container")
+	assert.NotContains(t, out, "internal")
+
+	t.Run("cumulative", func(t *testing.T) {
+		addPrometheusTestSpans(c, span(4, 10e6, 0))
+		c.flushNow(flushTime + 2*int64(c.bufferLen)*testBucketInterval)
+		out := scrape(t, c.Exporter)
+		assert.Contains(t, out, "datadog_trace_hits_total"+labels+" 4\n")
+		assert.Contains(t, out, "datadog_trace_errors_total"+labels+" 1\n")
+		// the quantiles are the ones of the last flush
+		assert.InDelta(t, 0.01, metricValue(t, out, "datadog_trace_duration_seconds"+strings.TrimSuffix(labels, "}")+`,quantile="0.5"}`), 0.0002)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		c.Exporter.flushed(time.Unix(0, flushTime).Add(prometheusSeriesTTL + time.Minute))
+		assert.NotContains(t, scrape(t, c.Exporter), "datadog_trace_hits_total{")
+	})
+}
+
+func TestPrometheusExporterMergedSeries(t *testing.T) {
+	now := time.Now()
+	c := newPrometheusTestConcentrator(now, 10)
+
+	// the stats only differing by tags which are not exposed, or whose values are the same once
+	// made valid UTF-8, are merged in the same series
+	for i, meta := range []map[string]string{
+		{"peer.service": "billing", "__internal": "a"},
+		{"peer.service": "billing", "__internal": "b"},
+		{"peer.service": "billing\xff"},
+		{"peer.service": "billing\xfe"},
+	} {
+		s := testSpan(uint64(i+1), 0, 10, 0, "A1", "resource1", 0)
+		s.Meta = meta
+		addPrometheusTestSpans(c, s)
+	}
+	c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+
+	out := scrape(t, c.Exporter)
+	assert.Len(t, c.Exporter.series, 2)
+	labels := `{env="prod",http_status_code="0",name="query",peer_service="%s",resource="resource1",service="A1",synthetics="false",type="db",version=""}`
+	assert.Contains(t, out, "datadog_trace_hits_total"+fmt.Sprintf(labels, "billing")+" 2\n")
+	assert.Contains(t, out, "datadog_trace_hits_total"+fmt.Sprintf(labels, "billing\uFFFD")+" 2\n")
+}
+
+func TestPrometheusExporterMaxSeries(t *testing.T) {
+	now := time.Now()
+	c := newPrometheusTestConcentrator(now, 2)
+	addPrometheusTestSpans(c,
+		testSpan(1, 0, 10, 0, "A1", "resource1", 0),
+		testSpan(2, 1, 10, 0, "A2", "resource1", 0),
+		testSpan(3, 1, 10, 0, "A3", "resource1", 1),
+		testSpan(4, 1, 10, 0, "A4", "resource1", 0),
+	)
+	c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+
+	out := scrape(t, c.Exporter)
+	assert.Len(t, c.Exporter.series, 2)
+	overflow := `{env="_overflow",http_status_code="_overflow",name="_overflow",peer_service="_overflow",resource="_overflow",service="_overflow",synthetics="_overflow",type="_overflow",version="_overflow"}`
+	assert.Contains(t, out, "datadog_trace_hits_total"+overflow+" 2\n")
+	assert.Contains(t, out, "datadog_trace_errors_total"+overflow+" 1\n")
+}
+
+func TestConcentratorPrometheusDisabled(t *testing.T) {
+	assert.Nil(t, NewTestConcentrator(time.Now()).Exporter)
+}
+
+func TestPrometheusLabelName(t *testing.T) {
+	for in, out := range map[string]string{
+		"peer.service": "peer_service",
+		"db-instance":  "db_instance",
+		"tier2":        "tier2",
+		"2tier":        "_tier",
+		"_dd.origin":   "_dd_origin",
+		"__name":       "",
+		"_.name":       "",
+		"":             "",
+	} {
+		assert.Equal(t, out, prometheusLabelName(in), in)
+	}
+}
@@ -73,6 +73,7 @@ func (s *groupedStats) export(a Aggregation) (pb.ClientGroupedStats, error) {
 		OkSummary:      okSummary,
 		ErrorSummary:   errSummary,
 		Synthetics:     a.Synthetics,
+		ExtraTags:      splitExtraTags(a.ExtraTags),
 	}, nil
 }
 
@@ -148,11 +149,11 @@ func (sb *RawBucket) Export() map[PayloadAggregationKey]pb.ClientStatsBucket {
 }
 
 // HandleSpan adds the span to this bucket stats, aggregated with the finest grain matching given aggregators
-func (sb *RawBucket) HandleSpan(s *WeightedSpan, env string, agentHostname, containerID string) {
+func (sb *RawBucket) HandleSpan(s *WeightedSpan, env string, agentHostname, containerID string, extra *ExtraAggregators) {
 	if env == "" {
 		panic("env should never be empty")
 	}
-	aggr := NewAggregationFromSpan(s.Span, env, agentHostname, containerID)
+	aggr := NewAggregationFromSpan(s.Span, env, agentHostname, containerID, extra)
 	sb.add(s, aggr)
 }
 
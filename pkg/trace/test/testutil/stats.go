@@ -22,7 +22,7 @@ func BucketWithSpans(spans []*stats.WeightedSpan) pb.ClientStatsBucket {
 	for _, s := range spans {
 		// override version to ensure all buckets will have the same payload key.
 		s.Meta["version"] = ""
-		srb.HandleSpan(s, defaultEnv, defaultHostname, defaultContainerID)
+		srb.HandleSpan(s, defaultEnv, defaultHostname, defaultContainerID, nil)
 	}
 	buckets := srb.Export()
 	if len(buckets) != 1 {
@@ -15,6 +15,7 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -23,13 +24,15 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // newSenders returns a list of senders based on the given agent configuration, using climit
-// as the maximum number of concurrent outgoing connections, writing to path.
-func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int) []*sender {
+// as the maximum number of concurrent outgoing connections, writing to path. When spoolSize is
+// not 0, each sender stores up to spoolSize bytes of the payloads it could not send on disk.
+func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int, spoolSize int64) []*sender {
 	if e := cfg.Endpoints; len(e) == 0 || e[0].Host == "" || e[0].APIKey == "" {
 		panic(errors.New("config was not properly validated"))
 	}
@@ -42,6 +45,15 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 		if err != nil {
 			osutil.Exitf("Invalid host endpoint: %q", endpoint.Host)
 		}
+		var sp *spool
+		if spoolSize > 0 {
+			dir := filepath.Join(cfg.Spool.Path, spoolDirName(url, endpoint.APIKey))
+			if sp, err = newSpool(dir, spoolSize, cfg.Spool.MaxDiskRatio, filesystem.NewDisk()); err != nil {
+				// continue without storing the payloads on disk
+				log.Errorf("Error creating the payload spool in %s: %v", dir, err)
+				sp = nil
+			}
+		}
 		senders[i] = newSender(&senderConfig{
 			client:    client,
 			maxConns:  int(maxConns),
@@ -49,6 +61,7 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			url:       url,
 			apiKey:    endpoint.APIKey,
 			recorder:  r,
+			spool:     sp,
 		})
 	}
 	return senders
@@ -73,8 +86,11 @@ const (
 	// eventTypeRejected specifies that the edge rejected this payload.
 	eventTypeRejected
 	// eventTypeDropped specifies that a payload had to be dropped to make room
-	// in the queue.
+	// in the queue, or in the spool.
 	eventTypeDropped
+	// eventTypeSpooled specifies that a payload was stored on disk to make room
+	// in the queue.
+	eventTypeSpooled
 )
 
 var eventTypeStrings = map[eventType]string{
@@ -82,6 +98,7 @@ var eventTypeStrings = map[eventType]string{
 	eventTypeSent:     "eventTypeSent",
 	eventTypeRejected: "eventTypeRejected",
 	eventTypeDropped:  "eventTypeDropped",
+	eventTypeSpooled:  "eventTypeSpooled",
 }
 
 // String implements fmt.Stringer.
@@ -126,6 +143,9 @@ type senderConfig struct {
 	// recorder specifies the eventRecorder to use when reporting events occurring
 	// in the sender.
 	recorder eventRecorder
+	// spool specifies the on-disk queue in which payloads are stored instead of being
+	// dropped, when the queue is full. It is nil when disabled.
+	spool *spool
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -137,6 +157,7 @@ type sender struct {
 	climit   chan struct{} // semaphore for limiting concurrent connections
 	inflight int32         // inflight payloads
 	attempt  int32         // active retry attempt
+	unspool  chan struct{} // notifies that spooled payloads may be queued again; nil without spool
 
 	mu     sync.RWMutex // guards closed
 	closed bool         // closed reports if the loop is stopped
@@ -149,6 +170,10 @@ func newSender(cfg *senderConfig) *sender {
 		queue:  make(chan *payload, cfg.maxQueued),
 		climit: make(chan struct{}, cfg.maxConns),
 	}
+	if cfg.spool != nil {
+		s.unspool = make(chan struct{}, 1)
+		go s.unspoolLoop()
+	}
 	go s.loop()
 	return &s
 }
@@ -183,6 +208,9 @@ func (s *sender) Stop() {
 	s.closed = true
 	s.mu.Unlock()
 	close(s.queue)
+	if s.unspool != nil {
+		close(s.unspool)
+	}
 }
 
 // WaitForInflight blocks until all in progress payloads are sent,
@@ -212,10 +240,10 @@ func (s *sender) Push(p *payload) {
 			atomic.AddInt32(&s.inflight, 1)
 			return
 		default:
-			// drop the oldest item in the queue to make room
+			// drop, or spool, the oldest item in the queue to make room
 			select {
 			case p := <-s.queue:
-				s.releasePayload(p, eventTypeDropped, &eventData{
+				s.spoolOrDrop(p, &eventData{
 					bytes: p.body.Len(),
 					count: 1,
 				})
@@ -249,7 +277,10 @@ func (s *sender) sendPayload(p *payload) {
 		s.mu.RLock()
 		defer s.mu.RUnlock()
 		if s.closed {
-			// sender is stopped
+			// sender is stopped; keep the payload on disk to send it on the next run
+			if s.cfg.spool != nil {
+				s.spoolOrDrop(p, stats)
+			}
 			return
 		}
 		atomic.AddInt32(&s.attempt, 1)
@@ -265,8 +296,8 @@ func (s *sender) sendPayload(p *payload) {
 			s.recordEvent(eventTypeRetry, stats)
 			return
 		default:
-			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
+			// queue is full; since this is the oldest payload, we drop or spool it
+			s.spoolOrDrop(p, stats)
 		}
 	case nil:
 		// request was successful; the retry queue may have grown large - we should
@@ -279,12 +310,93 @@ func (s *sender) sendPayload(p *payload) {
 			}
 		}
 		s.releasePayload(p, eventTypeSent, stats)
+		// the destination is reachable, send the spooled payloads
+		s.notifyUnspool()
 	default:
 		// this is a fatal error, we have to drop this payload
 		s.releasePayload(p, eventTypeRejected, stats)
 	}
 }
 
+// spoolOrDrop stores the payload p in the spool if there is one, or drops it. data holds
+// the details of the event which led to it.
+func (s *sender) spoolOrDrop(p *payload, data *eventData) {
+	if s.cfg.spool == nil {
+		s.releasePayload(p, eventTypeDropped, data)
+		return
+	}
+	removed, err := s.cfg.spool.push(p)
+	for _, size := range removed {
+		s.recordEvent(eventTypeDropped, &eventData{bytes: int(size), count: 1})
+	}
+	if err != nil {
+		log.Debugf("Error spooling payload: %v", err)
+		s.releasePayload(p, eventTypeDropped, data)
+		return
+	}
+	s.releasePayload(p, eventTypeSpooled, data)
+}
+
+// notifyUnspool notifies unspoolLoop that the spooled payloads may be queued again.
+func (s *sender) notifyUnspool() {
+	if s.unspool == nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.unspool <- struct{}{}:
+	default:
+		// already notified
+	}
+}
+
+// unspoolLoop queues the spooled payloads again each time it is notified, until the sender is stopped.
+func (s *sender) unspoolLoop() {
+	for range s.unspool {
+		s.unspoolPayloads()
+	}
+}
+
+// unspoolPayloads moves the spooled payloads back to the queue, from the oldest to the newest,
+// as long as the queue is at most half full.
+func (s *sender) unspoolPayloads() {
+	for {
+		s.mu.RLock()
+		if s.closed || len(s.queue) > cap(s.queue)/2 {
+			s.mu.RUnlock()
+			return
+		}
+		p, f, err := s.cfg.spool.peek()
+		if err != nil {
+			s.mu.RUnlock()
+			log.Errorf("Error reading spooled payload: %v", err)
+			continue
+		}
+		if p == nil {
+			// the spool is empty
+			s.mu.RUnlock()
+			return
+		}
+		select {
+		case s.queue <- p:
+			atomic.AddInt32(&s.inflight, 1)
+			s.mu.RUnlock()
+			if err := s.cfg.spool.remove(f); err != nil {
+				log.Errorf("Error removing spooled payload: %v", err)
+			}
+		default:
+			// the queue was filled meanwhile; the payload stays in the spool
+			s.mu.RUnlock()
+			ppool.Put(p)
+			return
+		}
+	}
+}
+
 // waitForSenders blocks until all senders have sent their inflight payloads
 func waitForSenders(senders []*sender) {
 	var wg sync.WaitGroup
@@ -298,6 +410,21 @@ func waitForSenders(senders []*sender) {
 	wg.Wait()
 }
 
+// spoolStats returns the number of payloads stored in the spools of the senders and their total
+// size, in bytes. It reports whether any of the senders has a spool.
+func spoolStats(senders []*sender) (payloads, size int64, ok bool) {
+	for _, s := range senders {
+		if s.cfg.spool == nil {
+			continue
+		}
+		n, sz := s.cfg.spool.stats()
+		payloads += int64(n)
+		size += sz
+		ok = true
+	}
+	return payloads, size, ok
+}
+
 // releasePayload releases the payload p and records the specified event. The payload
 // should not be used again after a release.
 func (s *sender) releasePayload(p *payload, t eventType, data *eventData) {
@@ -311,8 +311,8 @@ func useBackoffDuration(d time.Duration) func() {
 
 // mockRecorder is a mock eventRecorder which records all calls to recordEvent.
 type mockRecorder struct {
-	mu                             sync.RWMutex
-	retry, sent, dropped, rejected []*eventData
+	mu                                      sync.RWMutex
+	retry, sent, dropped, rejected, spooled []*eventData
 }
 
 // data returns all call data for the given eventType.
@@ -328,6 +328,8 @@ func (r *mockRecorder) data(t eventType) []*eventData {
 		return r.dropped
 	case eventTypeRejected:
 		return r.rejected
+	case eventTypeSpooled:
+		return r.spooled
 	default:
 		panic("unknown event")
 	}
@@ -346,5 +348,7 @@ func (r *mockRecorder) recordEvent(t eventType, data *eventData) {
 		r.dropped = append(r.dropped, data)
 	case eventTypeRejected:
 		r.rejected = append(r.rejected, data)
+	case eventTypeSpooled:
+		r.spooled = append(r.spooled, data)
 	}
 }
@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// spoolFileExtension is the extension of the files holding spooled payloads.
+const spoolFileExtension = ".payload"
+
+// diskUsageRetriever returns the usage of the disk holding a path.
+type diskUsageRetriever interface {
+	GetUsage(path string) (*filesystem.DiskUsage, error)
+}
+
+// spoolFile is a file of the spool, holding a single payload.
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// spool is a size-limited on-disk queue of payloads. It stores the payloads which the sender
+// could not send nor keep in memory and gives them back from the oldest to the newest. When
+// it is full, the oldest payloads are removed to make room for the new ones. It is safe for
+// concurrent use.
+//
+// A payload is stored in its own file, as its headers encoded in JSON on the first line,
+// followed by its body. The file names start with the time at which they were written, so
+// that the files left by a previous run are reloaded in order.
+type spool struct {
+	dir          string
+	maxSize      int64   // maximum total size of the files
+	maxDiskRatio float64 // maximum ratio of the disk capacity in use
+	disk         diskUsageRetriever
+
+	mu    sync.Mutex
+	files []spoolFile // from the oldest to the newest
+	size  int64       // total size of files
+}
+
+// newSpool returns a new spool storing at most maxSize bytes of payloads in dir, reloading the
+// payloads stored there by a previous run.
+func newSpool(dir string, maxSize int64, maxDiskRatio float64, disk diskUsageRetriever) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := spool{
+		dir:          dir,
+		maxSize:      maxSize,
+		maxDiskRatio: maxDiskRatio,
+		disk:         disk,
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	// fail early if the disk usage can not be computed, rather than during an outage
+	if _, err := s.availableSpace(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// spoolDirName returns the name of the directory of the spool for the payloads sent to u with
+// apiKey. Endpoints sharing a host but using different API keys get distinct spools, and the
+// API key itself is never written to disk.
+func spoolDirName(u *url.URL, apiKey string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, strings.Trim(u.Host+u.Path, "/"))
+	sum := sha256.Sum256([]byte(apiKey))
+	return name + "_" + hex.EncodeToString(sum[:8])
+}
+
+// reload adds the files found in the directory of the spool, from the oldest to the newest.
+func (s *spool) reload() error {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && filepath.Ext(entry.Name()) == spoolFileExtension {
+			s.addFile(spoolFile{path: filepath.Join(s.dir, entry.Name()), size: entry.Size()})
+		}
+	}
+	if len(s.files) > 0 {
+		log.Infof("Reloaded %d payloads (%d bytes) from %s", len(s.files), s.size, s.dir)
+	}
+	return nil
+}
+
+// availableSpace returns the maximum size of the spool, limited by the disk usage.
+func (s *spool) availableSpace() (int64, error) {
+	usage, err := s.disk.GetUsage(s.dir)
+	if err != nil {
+		return 0, err
+	}
+	diskReserved := float64(usage.Total) * (1 - s.maxDiskRatio)
+	available := s.size + int64(usage.Available) - int64(math.Ceil(diskReserved))
+	if available < s.maxSize {
+		return available, nil
+	}
+	return s.maxSize, nil
+}
+
+// push stores the payload p, removing the oldest payloads if needed to make room for it. It
+// returns the sizes of the payloads which were removed.
+func (s *spool) push(p *payload) (removed []int64, err error) {
+	headers, err := json.Marshal(p.headers)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(headers) + 1 + p.body.Len())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if size > s.maxSize {
+		return nil, fmt.Errorf("payload is too big for the spool (%d bytes, maximum %d)", size, s.maxSize)
+	}
+	available, err := s.availableSpace()
+	if err != nil {
+		return nil, err
+	}
+	for len(s.files) > 0 && s.size+size > available {
+		f := s.files[0]
+		if err := s.removeFileAt(0); err != nil {
+			log.Errorf("Error removing spooled payload: %v", err)
+		}
+		removed = append(removed, f.size)
+	}
+	if s.size+size > available {
+		return removed, errors.New("not enough disk space to spool payload")
+	}
+
+	file, err := ioutil.TempFile(s.dir, time.Now().UTC().Format("20060102T150405.000000000")+"-*"+spoolFileExtension)
+	if err != nil {
+		return removed, err
+	}
+	defer file.Close()
+	for _, b := range [][]byte{headers, {'\n'}, p.body.Bytes()} {
+		if _, err := file.Write(b); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return removed, err
+		}
+	}
+	s.addFile(spoolFile{path: file.Name(), size: size})
+	return removed, nil
+}
+
+// peek returns the oldest payload of the spool, and its file. It returns a nil payload when the
+// spool is empty. A file which can not be read is removed and its error returned.
+func (s *spool) peek() (*payload, spoolFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.files) == 0 {
+		return nil, spoolFile{}, nil
+	}
+	f := s.files[0]
+	p, err := readSpoolFile(f.path)
+	if err != nil {
+		if errRemove := s.removeFileAt(0); errRemove != nil {
+			log.Errorf("Error removing spooled payload: %v", errRemove)
+		}
+		return nil, f, err
+	}
+	return p, f, nil
+}
+
+// remove removes the file f from the spool, if it is still there.
+func (s *spool) remove(f spoolFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.files {
+		if s.files[i].path == f.path {
+			return s.removeFileAt(i)
+		}
+	}
+	return nil
+}
+
+// stats returns the number of payloads in the spool and their total size, in bytes.
+func (s *spool) stats() (payloads int, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files), s.size
+}
+
+func (s *spool) addFile(f spoolFile) {
+	s.files = append(s.files, f)
+	s.size += f.size
+}
+
+func (s *spool) removeFileAt(i int) error {
+	f := s.files[i]
+	// remove the file from s.files also in case of error to not fail on the next call
+	s.files = append(s.files[:i], s.files[i+1:]...)
+	s.size -= f.size
+	return os.Remove(f.path)
+}
+
+// readSpoolFile reads the payload stored in the file at path.
+func readSpoolFile(path string) (*payload, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid spooled payload %s", path)
+	}
+	var headers map[string]string
+	if err := json.Unmarshal(data[:i], &headers); err != nil {
+		return nil, fmt.Errorf("invalid spooled payload %s: %v", path, err)
+	}
+	p := newPayload(headers)
+	p.body.Write(data[i+1:])
+	return p, nil
+}
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+// mockDisk is a diskUsageRetriever returning a fixed usage.
+type mockDisk filesystem.DiskUsage
+
+func (d mockDisk) GetUsage(string) (*filesystem.DiskUsage, error) {
+	usage := filesystem.DiskUsage(d)
+	return &usage, nil
+}
+
+// largeDisk is a mostly free disk.
+var largeDisk = mockDisk{Total: 1 << 40, Available: 1 << 39}
+
+func testPayload(body string) *payload {
+	p := newPayload(map[string]string{"Content-Type": "application/msgpack"})
+	p.body.WriteString(body)
+	return p
+}
+
+// payloadSize returns the size of testPayload(body) in the spool.
+func payloadSize(body string) int64 {
+	return int64(len(`{"Content-Type":"application/msgpack"}`) + 1 + len(body))
+}
+
+// popBodies removes all payloads from the spool, returning their bodies.
+func popBodies(t *testing.T, s *spool) []string {
+	var bodies []string
+	for {
+		p, f, err := s.peek()
+		if !assert.NoError(t, err) || p == nil {
+			return bodies
+		}
+		assert.Equal(t, map[string]string{"Content-Type": "application/msgpack"}, p.headers)
+		bodies = append(bodies, p.body.String())
+		assert.NoError(t, s.remove(f))
+	}
+}
+
+func TestSpool(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		s, err := newSpool(dir, 1024, 0.8, largeDisk)
+		assert.NoError(err)
+		for _, body := range []string{"1", "2", "3"} {
+			removed, err := s.push(testPayload(body))
+			assert.NoError(err)
+			assert.Empty(removed)
+		}
+		n, size := s.stats()
+		assert.Equal(3, n)
+		assert.Equal(3*payloadSize("1"), size)
+
+		// a new spool reloads the payloads in order
+		s, err = newSpool(dir, 1024, 0.8, largeDisk)
+		assert.NoError(err)
+		assert.Equal([]string{"1", "2", "3"}, popBodies(t, s))
+		files, _ := ioutil.ReadDir(dir)
+		assert.Empty(files)
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		assert := assert.New(t)
+		s, err := newSpool(t.TempDir(), 3*payloadSize("1"), 0.8, largeDisk)
+		assert.NoError(err)
+		for _, body := range []string{"1", "2", "3"} {
+			_, err := s.push(testPayload(body))
+			assert.NoError(err)
+		}
+		removed, err := s.push(testPayload("4"))
+		assert.NoError(err)
+		assert.Equal([]int64{payloadSize("1")}, removed)
+
+		_, err = s.push(testPayload(strings.Repeat("too big for the spool", 10)))
+		assert.Error(err)
+		assert.Equal([]string{"2", "3", "4"}, popBodies(t, s))
+	})
+
+	t.Run("disk-ratio", func(t *testing.T) {
+		assert := assert.New(t)
+		// 10 bytes of the disk can be used before reaching the ratio
+		disk := mockDisk{Total: 1000, Available: 210}
+		s, err := newSpool(t.TempDir(), 1024, 0.8, disk)
+		assert.NoError(err)
+		_, err = s.push(testPayload("1"))
+		assert.Error(err)
+		n, _ := s.stats()
+		assert.Zero(n)
+	})
+}
+
+func TestSpoolDirName(t *testing.T) {
+	assert := assert.New(t)
+	u, err := url.Parse("https://trace.agent.datadoghq.com/api/v0.2/traces")
+	assert.NoError(err)
+	name := spoolDirName(u, "key1")
+	assert.True(strings.HasPrefix(name, "trace.agent.datadoghq.com_api_v0.2_traces_"), name)
+	assert.NotContains(name, "key1")
+	assert.Equal(name, spoolDirName(u, "key1"))
+	assert.NotEqual(name, spoolDirName(u, "key2"))
+}
+
+func TestSenderSpool(t *testing.T) {
+	defer useBackoffDuration(time.Millisecond)()
+
+	var (
+		up       int32 // 1 when the server accepts payloads
+		mu       sync.Mutex
+		accepted []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		mu.Lock()
+		accepted = append(accepted, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+	acceptedBodies := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), accepted...)
+	}
+
+	u, err := url.Parse(server.URL + "/api/v0.2/traces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestSender := func(sp *spool, r eventRecorder) *sender {
+		return newSender(&senderConfig{
+			client:    httputils.NewResetClient(0, func() *http.Client { return &http.Client{} }),
+			url:       u,
+			maxConns:  1,
+			maxQueued: 1,
+			apiKey:    testAPIKey,
+			recorder:  r,
+			spool:     sp,
+		})
+	}
+
+	t.Run("outage", func(t *testing.T) {
+		assert := assert.New(t)
+		atomic.StoreInt32(&up, 0)
+		sp, err := newSpool(filepath.Join(t.TempDir(), spoolDirName(u, testAPIKey)), 1024, 0.8, largeDisk)
+		assert.NoError(err)
+		var recorder mockRecorder
+		s := newTestSender(sp, &recorder)
+		defer s.Stop()
+
+		for _, body := range []string{"1", "2", "3", "4"} {
+			s.Push(testPayload(body))
+		}
+		// the payloads which do not fit in the queue are spooled instead of being dropped
+		assert.Eventually(func() bool {
+			n, _ := sp.stats()
+			return n >= 2
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NotEmpty(recorder.data(eventTypeSpooled))
+		assert.Empty(recorder.data(eventTypeDropped))
+
+		// once the server is up, all payloads are sent
+		atomic.StoreInt32(&up, 1)
+		assert.Eventually(func() bool { return len(acceptedBodies()) == 4 }, 5*time.Second, 10*time.Millisecond)
+		assert.ElementsMatch([]string{"1", "2", "3", "4"}, acceptedBodies())
+		n, size := sp.stats()
+		assert.Zero(n)
+		assert.Zero(size)
+	})
+
+	t.Run("oldest-first", func(t *testing.T) {
+		assert := assert.New(t)
+		mu.Lock()
+		accepted = nil
+		mu.Unlock()
+		// payloads spooled by a previous run
+		dir := filepath.Join(t.TempDir(), spoolDirName(u, testAPIKey))
+		sp, err := newSpool(dir, 1024, 0.8, largeDisk)
+		assert.NoError(err)
+		for _, body := range []string{"1", "2", "3"} {
+			_, err := sp.push(testPayload(body))
+			assert.NoError(err)
+		}
+		sp, err = newSpool(dir, 1024, 0.8, largeDisk)
+		assert.NoError(err)
+		s := newTestSender(sp, nil)
+		defer s.Stop()
+
+		// they are sent once a payload goes through
+		s.Push(testPayload("4"))
+		assert.Eventually(func() bool { return len(acceptedBodies()) == 4 }, 5*time.Second, 10*time.Millisecond)
+		assert.Equal([]string{"4", "1", "2", "3"}, acceptedBodies())
+	})
+}
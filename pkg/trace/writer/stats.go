@@ -84,7 +84,7 @@ func NewStatsWriter(cfg *config.AgentConfig, in <-chan pb.StatsPayload) *StatsWr
 		qsize = int(math.Max(1, maxmem/payloadSize))
 	}
 	log.Debugf("Stats writer initialized (climit=%d qsize=%d)", climit, qsize)
-	sw.senders = newSenders(cfg, sw, pathStats, climit, qsize)
+	sw.senders = newSenders(cfg, sw, pathStats, climit, qsize, cfg.Spool.StatsMaxSize)
 	return sw
 }
 
@@ -322,6 +322,11 @@ func (w *StatsWriter) report() {
 	metrics.Count("datadog.trace_agent.stats_writer.retries", atomic.SwapInt64(&w.stats.Retries, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.splits", atomic.SwapInt64(&w.stats.Splits, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.errors", atomic.SwapInt64(&w.stats.Errors, 0), nil, 1)
+	if payloads, size, ok := spoolStats(w.senders); ok {
+		metrics.Gauge("datadog.trace_agent.stats_writer.spool_payloads", float64(payloads), nil, 1)
+		metrics.Gauge("datadog.trace_agent.stats_writer.spool_bytes", float64(size), nil, 1)
+		info.UpdateStatsWriterSpoolInfo(payloads, size)
+	}
 }
 
 // recordEvent implements eventRecorder.
@@ -349,5 +354,10 @@ func (w *StatsWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Stats writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpooled:
+		w.easylog.Warn("Stats writer queue full. Payload stored on disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.stats_writer.spooled", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.stats_writer.spooled_bytes", int64(data.bytes), nil, 1)
 	}
 }
@@ -104,7 +104,7 @@ func NewTraceWriter(cfg *config.AgentConfig) *TraceWriter {
 		tw.tick = time.Duration(s*1000) * time.Millisecond
 	}
 	log.Debugf("Trace writer initialized (climit=%d qsize=%d)", climit, qsize)
-	tw.senders = newSenders(cfg, tw, pathTraces, climit, qsize)
+	tw.senders = newSenders(cfg, tw, pathTraces, climit, qsize, cfg.Spool.TracesMaxSize)
 	return tw
 }
 
@@ -280,6 +280,11 @@ func (w *TraceWriter) report() {
 	metrics.Count("datadog.trace_agent.trace_writer.traces", atomic.SwapInt64(&w.stats.Traces, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.trace_writer.events", atomic.SwapInt64(&w.stats.Events, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.trace_writer.spans", atomic.SwapInt64(&w.stats.Spans, 0), nil, 1)
+	if payloads, size, ok := spoolStats(w.senders); ok {
+		metrics.Gauge("datadog.trace_agent.trace_writer.spool_payloads", float64(payloads), nil, 1)
+		metrics.Gauge("datadog.trace_agent.trace_writer.spool_bytes", float64(size), nil, 1)
+		info.UpdateTraceWriterSpoolInfo(payloads, size)
+	}
 }
 
 var _ eventRecorder = (*TraceWriter)(nil)
@@ -309,5 +314,10 @@ func (w *TraceWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Trace writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpooled:
+		w.easylog.Warn("Trace writer queue full. Payload stored on disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.trace_writer.spooled", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.trace_writer.spooled_bytes", int64(data.bytes), nil, 1)
 	}
 }
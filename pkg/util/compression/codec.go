@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Names of the codecs that can be selected at runtime
+const (
+	NoneKind = "none"
+	ZlibKind = "zlib"
+	ZstdKind = "zstd"
+)
+
+// Codec is a compression method that can be selected at runtime, as opposed
+// to the package level functions whose implementation is chosen with build tags.
+type Codec interface {
+	// Name returns the name used to select the codec in the configuration
+	Name() string
+	// ContentEncoding returns the HTTP header value associated with the codec
+	ContentEncoding() string
+	// Compress compresses src, dst can be used as a destination buffer
+	Compress(dst []byte, src []byte) ([]byte, error)
+	// Decompress decompresses src, dst can be used as a destination buffer
+	Decompress(dst []byte, src []byte) ([]byte, error)
+	// CompressBound returns the worst case size needed for a destination buffer
+	CompressBound(sourceLen int) int
+}
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[string]Codec{}
+
+	// defaultCodec holds the codec registered under defaultKind so that the
+	// package level functions don't take codecsMutex on the hot path
+	defaultCodec atomic.Value
+)
+
+func init() {
+	RegisterCodec(noneCodec{})
+	RegisterCodec(zlibCodec{})
+}
+
+// RegisterCodec makes a codec available at runtime under its name. Codecs
+// registered through this function report their compression telemetry.
+func RegisterCodec(codec Codec) {
+	instrumented := newInstrumentedCodec(codec)
+
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[codec.Name()] = instrumented
+	if codec.Name() == defaultKind {
+		defaultCodec.Store(Codec(instrumented))
+	}
+}
+
+// GetCodec returns the codec registered under the given name
+func GetCodec(name string) (Codec, error) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression codec %q, available codecs: %v", name, availableCodecs())
+	}
+	return codec, nil
+}
+
+// GetCodecForContentEncoding returns the codec matching the given HTTP
+// Content-Encoding header value
+func GetCodecForContentEncoding(contentEncoding string) (Codec, error) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	for _, codec := range codecs {
+		if codec.ContentEncoding() == contentEncoding {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("no compression codec for content encoding %q", contentEncoding)
+}
+
+// DefaultCodec returns the codec selected at build time, the one used by the
+// package level functions
+func DefaultCodec() Codec {
+	codec, ok := defaultCodec.Load().(Codec)
+	if !ok {
+		// the default codec is always registered by the build-tagged files
+		panic(fmt.Sprintf("default compression codec %q is not registered", defaultKind))
+	}
+	return codec
+}
+
+// Transcode decompresses a payload compressed with the from codec and
+// compresses it again with the to codec
+func Transcode(from Codec, to Codec, payload []byte) ([]byte, error) {
+	if from.Name() == to.Name() {
+		return payload, nil
+	}
+	decompressed, err := from.Decompress(nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	return to.Compress(nil, decompressed)
+}
+
+// availableCodecs returns the sorted names of the registered codecs, the
+// caller must hold codecsMutex
+func availableCodecs() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
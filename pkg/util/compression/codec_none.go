@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import "io"
+
+// noneCodec leaves the payloads untouched
+type noneCodec struct{}
+
+func (noneCodec) Name() string { return NoneKind }
+
+func (noneCodec) ContentEncoding() string { return "" }
+
+func (noneCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	dst = src
+	return dst, nil
+}
+
+func (noneCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	dst = src
+	return dst, nil
+}
+
+func (noneCodec) CompressBound(sourceLen int) int {
+	return sourceLen
+}
+
+func (noneCodec) newStreamWriter(w io.Writer) StreamWriter {
+	return nopFlushWriter{Writer: w}
+}
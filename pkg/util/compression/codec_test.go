@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCodec(t *testing.T) {
+	for _, name := range []string{NoneKind, ZlibKind} {
+		codec, err := GetCodec(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, codec.Name())
+	}
+
+	_, err := GetCodec("unknown")
+	assert.Error(t, err)
+}
+
+func TestGetCodecForContentEncoding(t *testing.T) {
+	codec, err := GetCodecForContentEncoding("deflate")
+	require.NoError(t, err)
+	assert.Equal(t, ZlibKind, codec.Name())
+
+	codec, err = GetCodecForContentEncoding("")
+	require.NoError(t, err)
+	assert.Equal(t, NoneKind, codec.Name())
+
+	_, err = GetCodecForContentEncoding("br")
+	assert.Error(t, err)
+}
+
+func TestDefaultCodec(t *testing.T) {
+	assert.Equal(t, ContentEncoding, DefaultCodec().ContentEncoding())
+}
+
+func TestZlibCodecRoundTrip(t *testing.T) {
+	codec, err := GetCodec(ZlibKind)
+	require.NoError(t, err)
+
+	payload := []byte("my payload, my payload, my payload")
+	compressed, err := codec.Compress(nil, payload)
+	require.NoError(t, err)
+	assert.NotEqual(t, payload, compressed)
+	assert.True(t, len(compressed) <= codec.CompressBound(len(payload)))
+
+	decompressed, err := codec.Decompress(nil, compressed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+}
+
+func TestTranscode(t *testing.T) {
+	none, err := GetCodec(NoneKind)
+	require.NoError(t, err)
+	zlib, err := GetCodec(ZlibKind)
+	require.NoError(t, err)
+
+	payload := []byte("my payload")
+	compressed, err := Transcode(none, zlib, payload)
+	require.NoError(t, err)
+
+	decompressed, err := zlib.Decompress(nil, compressed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+
+	back, err := Transcode(zlib, none, compressed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, back)
+
+	same, err := Transcode(zlib, zlib, compressed)
+	require.NoError(t, err)
+	assert.Equal(t, compressed, same)
+}
+
+func TestStreamWriterRoundTrip(t *testing.T) {
+	for _, name := range []string{NoneKind, ZlibKind} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := GetCodec(name)
+			require.NoError(t, err)
+			require.True(t, SupportsStream(codec))
+
+			var b bytes.Buffer
+			w, err := NewStreamWriter(codec, &b)
+			require.NoError(t, err)
+			_, err = w.Write([]byte("my payload, "))
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			_, err = w.Write([]byte("my payload"))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			decompressed, err := codec.Decompress(nil, b.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, []byte("my payload, my payload"), decompressed)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+)
+
+// zlibCodec compresses the payloads with zlib, it only relies on the standard
+// library so it is available in every build
+type zlibCodec struct{}
+
+func (zlibCodec) Name() string { return ZlibKind }
+
+func (zlibCodec) ContentEncoding() string { return "deflate" }
+
+func (zlibCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, err := w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	dst = b.Bytes()
+	return dst, nil
+}
+
+func (zlibCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	dst, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+func (zlibCodec) CompressBound(sourceLen int) int {
+	// From https://code.woboq.org/gcc/zlib/compress.c.html#compressBound
+	return sourceLen + (sourceLen >> 12) + (sourceLen >> 14) + (sourceLen >> 25) + 13
+}
+
+func (zlibCodec) newStreamWriter(w io.Writer) StreamWriter {
+	return zlib.NewWriter(w)
+}
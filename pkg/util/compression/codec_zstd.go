@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// +build zstd
+
+package compression
+
+import (
+	"io"
+
+	zstd_0 "github.com/DataDog/zstd_0"
+)
+
+func init() {
+	RegisterCodec(zstdCodec{})
+}
+
+// zstdCodec compresses the payloads with zstd, it relies on cgo so it is only
+// available in builds with the zstd tag
+type zstdCodec struct{}
+
+func (zstdCodec) Name() string { return ZstdKind }
+
+func (zstdCodec) ContentEncoding() string { return "zstd" }
+
+func (zstdCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	return zstd_0.Compress(dst, src)
+}
+
+func (zstdCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	return zstd_0.Decompress(dst, src)
+}
+
+func (zstdCodec) CompressBound(sourceLen int) int {
+	return zstd_0.CompressBound(sourceLen)
+}
+
+func (zstdCodec) newStreamWriter(w io.Writer) StreamWriter {
+	// the zstd writer compresses every write right away, it has nothing to flush
+	zw := zstd_0.NewWriter(w)
+	return nopFlushWriter{Writer: zw, close: zw.Close}
+}
@@ -7,6 +7,9 @@
 
 package compression
 
+// defaultKind is the name of the codec used by the package level functions
+const defaultKind = NoneKind
+
 // ContentEncoding describes the HTTP header value associated with the compression method
 // empty here since there's no compression
 // var instead of const to ease testing
@@ -14,17 +17,15 @@ var ContentEncoding = ""
 
 // Compress will not compress anything
 func Compress(dst []byte, src []byte) ([]byte, error) {
-	dst = src
-	return dst, nil
+	return DefaultCodec().Compress(dst, src)
 }
 
 // Decompress will not decompress anything
 func Decompress(dst []byte, src []byte) ([]byte, error) {
-	dst = src
-	return dst, nil
+	return DefaultCodec().Decompress(dst, src)
 }
 
 // CompressBound returns the worst case size needed for a destination buffer
 func CompressBound(sourceLen int) int {
-	return sourceLen
+	return noneCodec{}.CompressBound(sourceLen)
 }
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+)
+
+// StreamWriter compresses the data written to it into an underlying writer
+type StreamWriter interface {
+	io.WriteCloser
+	// Flush writes any pending compressed data to the underlying writer
+	Flush() error
+}
+
+// streamCodec is implemented by the codecs able to compress a stream
+type streamCodec interface {
+	newStreamWriter(w io.Writer) StreamWriter
+}
+
+// SupportsStream returns whether the codec can be used with NewStreamWriter
+func SupportsStream(codec Codec) bool {
+	_, ok := unwrapCodec(codec).(streamCodec)
+	return ok
+}
+
+// NewStreamWriter returns a writer compressing the data written to it with the
+// given codec into w
+func NewStreamWriter(codec Codec, w io.Writer) (StreamWriter, error) {
+	sc, ok := unwrapCodec(codec).(streamCodec)
+	if !ok {
+		return nil, fmt.Errorf("the %q compression codec does not support streams", codec.Name())
+	}
+	if instrumented, ok := codec.(*instrumentedCodec); ok {
+		return newInstrumentedStreamWriter(instrumented, sc, w), nil
+	}
+	return sc.newStreamWriter(w), nil
+}
+
+// unwrapCodec returns the codec instrumented by RegisterCodec
+func unwrapCodec(codec Codec) Codec {
+	if instrumented, ok := codec.(*instrumentedCodec); ok {
+		return instrumented.Codec
+	}
+	return codec
+}
+
+// nopFlushWriter is a StreamWriter for writers which never hold pending data
+type nopFlushWriter struct {
+	io.Writer
+	close func() error
+}
+
+func (w nopFlushWriter) Flush() error { return nil }
+
+func (w nopFlushWriter) Close() error {
+	if w.close == nil {
+		return nil
+	}
+	return w.close()
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	tlmBytesIn = telemetry.NewCounter("compression", "bytes_in",
+		[]string{"codec"}, "Count of uncompressed bytes given to the codec")
+	tlmBytesOut = telemetry.NewCounter("compression", "bytes_out",
+		[]string{"codec"}, "Count of compressed bytes returned by the codec")
+	tlmRatio = telemetry.NewGauge("compression", "ratio",
+		[]string{"codec"}, "Ratio between the uncompressed and compressed sizes of all the payloads compressed by the codec")
+	tlmCompressTime = telemetry.NewCounter("compression", "compress_time_ns",
+		[]string{"codec"}, "Time spent compressing payloads, in nanoseconds")
+	tlmDecompressTime = telemetry.NewCounter("compression", "decompress_time_ns",
+		[]string{"codec"}, "Time spent decompressing payloads, in nanoseconds")
+	tlmErrors = telemetry.NewCounter("compression", "errors",
+		[]string{"codec", "operation"}, "Count of compression and decompression errors")
+)
+
+// instrumentedCodec reports telemetry about the compression performed by a codec
+type instrumentedCodec struct {
+	Codec
+
+	// accessed atomically, the ratio is computed from two loads and can be
+	// slightly off while concurrent calls are in flight
+	bytesIn  uint64
+	bytesOut uint64
+}
+
+func newInstrumentedCodec(codec Codec) *instrumentedCodec {
+	return &instrumentedCodec{Codec: codec}
+}
+
+func (c *instrumentedCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	start := time.Now()
+	compressed, err := c.Codec.Compress(dst, src)
+	tlmCompressTime.Add(float64(time.Since(start).Nanoseconds()), c.Name())
+	if err != nil {
+		tlmErrors.Inc(c.Name(), "compress")
+		return compressed, err
+	}
+
+	c.record(uint64(len(src)), uint64(len(compressed)))
+	return compressed, nil
+}
+
+// record reports the given uncompressed and compressed sizes
+func (c *instrumentedCodec) record(in, out uint64) {
+	tlmBytesIn.Add(float64(in), c.Name())
+	tlmBytesOut.Add(float64(out), c.Name())
+
+	bytesIn := atomic.AddUint64(&c.bytesIn, in)
+	bytesOut := atomic.AddUint64(&c.bytesOut, out)
+	if bytesOut > 0 {
+		tlmRatio.Set(float64(bytesIn)/float64(bytesOut), c.Name())
+	}
+}
+
+func (c *instrumentedCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	start := time.Now()
+	decompressed, err := c.Codec.Decompress(dst, src)
+	tlmDecompressTime.Add(float64(time.Since(start).Nanoseconds()), c.Name())
+	if err != nil {
+		tlmErrors.Inc(c.Name(), "decompress")
+	}
+	return decompressed, err
+}
+
+// instrumentedStreamWriter reports telemetry about the compression performed by
+// a stream writer of an instrumented codec
+type instrumentedStreamWriter struct {
+	writer StreamWriter
+	codec  *instrumentedCodec
+
+	// out counts the compressed bytes written to the underlying writer, of
+	// which reportedOut were already reported
+	out         *countingWriter
+	reportedOut uint64
+}
+
+func newInstrumentedStreamWriter(codec *instrumentedCodec, sc streamCodec, w io.Writer) *instrumentedStreamWriter {
+	out := &countingWriter{Writer: w}
+	return &instrumentedStreamWriter{
+		writer: sc.newStreamWriter(out),
+		codec:  codec,
+		out:    out,
+	}
+}
+
+func (w *instrumentedStreamWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := w.writer.Write(p)
+	w.report(start, n, err)
+	return n, err
+}
+
+func (w *instrumentedStreamWriter) Flush() error {
+	start := time.Now()
+	err := w.writer.Flush()
+	w.report(start, 0, err)
+	return err
+}
+
+func (w *instrumentedStreamWriter) Close() error {
+	start := time.Now()
+	err := w.writer.Close()
+	w.report(start, 0, err)
+	return err
+}
+
+// report reports the time spent since start, the in bytes written to the
+// stream and the bytes it compressed since the previous report
+func (w *instrumentedStreamWriter) report(start time.Time, in int, err error) {
+	tlmCompressTime.Add(float64(time.Since(start).Nanoseconds()), w.codec.Name())
+	if err != nil {
+		tlmErrors.Inc(w.codec.Name(), "compress")
+	}
+
+	out := w.out.n - w.reportedOut
+	w.reportedOut = w.out.n
+	w.codec.record(uint64(in), out)
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	io.Writer
+	n uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += uint64(n)
+	return n, err
+}
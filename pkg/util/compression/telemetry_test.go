@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedCodec returns payloads of a fixed size, or an error when err is set
+type fixedCodec struct {
+	name string
+	size int
+	err  error
+}
+
+func (c fixedCodec) Name() string            { return c.name }
+func (c fixedCodec) ContentEncoding() string { return c.name }
+func (c fixedCodec) CompressBound(n int) int { return n }
+
+func (c fixedCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return make([]byte, c.size), nil
+}
+
+func (c fixedCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return make([]byte, c.size), nil
+}
+
+func counterValue(t *testing.T, c interface{}) float64 {
+	collector, ok := c.(prometheus.Collector)
+	require.True(t, ok)
+	return testutil.ToFloat64(collector)
+}
+
+func TestInstrumentedCodecBytes(t *testing.T) {
+	codec := newInstrumentedCodec(fixedCodec{name: "test_bytes", size: 10})
+
+	_, err := codec.Compress(nil, make([]byte, 40))
+	require.NoError(t, err)
+	_, err = codec.Compress(nil, make([]byte, 20))
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(60), counterValue(t, tlmBytesIn.WithValues("test_bytes")))
+	assert.Equal(t, float64(20), counterValue(t, tlmBytesOut.WithValues("test_bytes")))
+	assert.Equal(t, uint64(60), codec.bytesIn)
+	assert.Equal(t, uint64(20), codec.bytesOut)
+
+	// decompression doesn't count towards the ratio
+	_, err = codec.Decompress(nil, make([]byte, 10))
+	require.NoError(t, err)
+	assert.Equal(t, float64(60), counterValue(t, tlmBytesIn.WithValues("test_bytes")))
+	assert.Equal(t, float64(0), counterValue(t, tlmErrors.WithValues("test_bytes", "decompress")))
+}
+
+func TestInstrumentedCodecErrors(t *testing.T) {
+	codec := newInstrumentedCodec(fixedCodec{name: "test_errors", err: errors.New("boom")})
+
+	_, err := codec.Compress(nil, make([]byte, 40))
+	assert.Error(t, err)
+	_, err = codec.Decompress(nil, make([]byte, 40))
+	assert.Error(t, err)
+	_, err = codec.Decompress(nil, make([]byte, 40))
+	assert.Error(t, err)
+
+	assert.Equal(t, float64(1), counterValue(t, tlmErrors.WithValues("test_errors", "compress")))
+	assert.Equal(t, float64(2), counterValue(t, tlmErrors.WithValues("test_errors", "decompress")))
+	// failed calls don't count towards the compressed bytes
+	assert.Equal(t, float64(0), counterValue(t, tlmBytesIn.WithValues("test_errors")))
+	assert.Equal(t, uint64(0), codec.bytesOut)
+}
+
+func TestDefaultCodecIsInstrumented(t *testing.T) {
+	_, ok := DefaultCodec().(*instrumentedCodec)
+	assert.True(t, ok)
+}
+
+func TestInstrumentedStreamWriter(t *testing.T) {
+	codec := newInstrumentedCodec(namedZlibCodec{})
+
+	var b bytes.Buffer
+	w, err := NewStreamWriter(codec, &b)
+	require.NoError(t, err)
+	_, ok := w.(*instrumentedStreamWriter)
+	require.True(t, ok)
+
+	payload := bytes.Repeat([]byte("my payload, "), 100)
+	_, err = w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	_, err = w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, float64(2*len(payload)), counterValue(t, tlmBytesIn.WithValues("test_stream")))
+	assert.Equal(t, float64(b.Len()), counterValue(t, tlmBytesOut.WithValues("test_stream")))
+	assert.Equal(t, uint64(2*len(payload)), codec.bytesIn)
+	assert.Equal(t, uint64(b.Len()), codec.bytesOut)
+	assert.Greater(t, counterValue(t, tlmCompressTime.WithValues("test_stream")), float64(0))
+
+	decompressed, err := codec.Decompress(nil, b.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, append(payload, payload...), decompressed)
+}
+
+// namedZlibCodec is the zlib codec reporting its telemetry under its own name
+type namedZlibCodec struct {
+	zlibCodec
+}
+
+func (namedZlibCodec) Name() string { return "test_stream" }
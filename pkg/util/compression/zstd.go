@@ -7,28 +7,27 @@
 
 package compression
 
-import (
-	zstd_0 "github.com/DataDog/zstd_0"
-)
-
 // TODO: the intake still uses a pre-v1 (unstable) version of the zstd compression format.
 // The agent shouldn't use zstd compression until the intake supports a stable v1 format.
 
+// defaultKind is the name of the codec used by the package level functions
+const defaultKind = ZstdKind
+
 // ContentEncoding describes the HTTP header value associated with the compression method
 // var instead of const to ease testing
 var ContentEncoding = "zstd"
 
 // Compress will compress the data with zstd
 func Compress(dst []byte, src []byte) ([]byte, error) {
-	return zstd_0.Compress(dst, src)
+	return DefaultCodec().Compress(dst, src)
 }
 
 // Decompress will decompress the data with zstd
 func Decompress(dst []byte, src []byte) ([]byte, error) {
-	return zstd_0.Decompress(dst, src)
+	return DefaultCodec().Decompress(dst, src)
 }
 
 // CompressBound returns the worst case size needed for a destination buffer
 func CompressBound(sourceLen int) int {
-	return zstd_0.CompressBound(sourceLen)
+	return zstdCodec{}.CompressBound(sourceLen)
 }
@@ -52,6 +52,7 @@ AGENT_CORECHECKS = [
     "kubernetes_apiserver",
     "load",
     "memory",
+    "network_dns",
     "ntp",
     "oom_kill",
     "systemd",